@@ -0,0 +1,434 @@
+package handler
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/AliSleiman0/Lacpa/models"
+	"github.com/AliSleiman0/Lacpa/repository"
+	"github.com/AliSleiman0/Lacpa/utils"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// ChangePassword replaces the password of the current user after checking the
+// current one. Every other session is ended so only this device stays signed in.
+func (h *AuthHandler) ChangePassword(c *fiber.Ctx) error {
+	var req models.ChangePasswordRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Invalid request body. Please check your JSON format.",
+			"success": false,
+		})
+	}
+	if err := utils.ValidateStruct(req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   err.Error(),
+			"success": false,
+		})
+	}
+
+	user, status, message := h.currentUser(c)
+	if user == nil {
+		return c.Status(status).JSON(fiber.Map{
+			"error":   message,
+			"success": false,
+		})
+	}
+
+	if status, message := h.checkCurrentPassword(c, user, req.CurrentPassword); status != 0 {
+		return c.Status(status).JSON(fiber.Map{
+			"error":   message,
+			"success": false,
+		})
+	}
+	if ve := validateNewPassword("new_password", req.NewPassword, user); ve != nil {
+		return utils.SendValidationErrors(c, ve)
+	}
+
+	hashedPassword, err := utils.HashPassword(req.NewPassword)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   "Failed to process password",
+			"success": false,
+		})
+	}
+	if err := h.authRepo.UpdatePassword(user.ID, hashedPassword, utils.LoadPasswordPolicy().HistorySize); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   "Failed to update password",
+			"success": false,
+		})
+	}
+
+	revoked := h.revokeOtherSessions(c, user, models.SessionRevokedPassword)
+	recordSecurityEvent(h.authRepo, user, models.SecurityEventPasswordChanged,
+		"Password changed by the account owner", c.IP(), user.ID.Hex())
+	h.audit.Record(c, models.AuditPasswordChanged, models.AuditTargetUser, user.ID.Hex(), nil, nil)
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "Password changed successfully",
+		"data": fiber.Map{
+			"sessions_revoked": revoked,
+		},
+	})
+}
+
+// RequestEmailChange sends a code to the new address. The email is only changed
+// once that code is confirmed, proving the user controls the new mailbox.
+func (h *AuthHandler) RequestEmailChange(c *fiber.Ctx) error {
+	var req models.ChangeEmailRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Invalid request body. Please check your JSON format.",
+			"success": false,
+		})
+	}
+	if err := utils.ValidateStruct(req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   err.Error(),
+			"success": false,
+		})
+	}
+
+	user, status, message := h.currentUser(c)
+	if user == nil {
+		return c.Status(status).JSON(fiber.Map{
+			"error":   message,
+			"success": false,
+		})
+	}
+	if status, message := h.checkCurrentPassword(c, user, req.Password); status != 0 {
+		return c.Status(status).JSON(fiber.Map{
+			"error":   message,
+			"success": false,
+		})
+	}
+
+	newEmail := normalizeEmail(req.NewEmail)
+	if newEmail == normalizeEmail(user.Email) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "New email must be different from the current email",
+			"success": false,
+		})
+	}
+	if _, err := h.authRepo.GetUserByEmail(newEmail); err == nil {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error":   "Email already registered",
+			"success": false,
+		})
+	} else if err != mongo.ErrNoDocuments {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   "Failed to check email",
+			"success": false,
+		})
+	}
+
+	// The code only redeems for this account
+	expiresAt, err := h.verification.Issue(c.Context(), newEmail, user.FullName, models.PurposeChangeEmail, user.ID.Hex())
+	if err != nil {
+		fmt.Printf("Failed to send email change code to %s: %v\n", newEmail, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   "Failed to send verification code",
+			"success": false,
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "A verification code was sent to your new email address",
+		"data": fiber.Map{
+			"email":      newEmail,
+			"expires_at": expiresAt,
+		},
+	})
+}
+
+// ConfirmEmailChange completes an email change with the code sent to the new
+// address, then notifies the old address and ends every other session.
+func (h *AuthHandler) ConfirmEmailChange(c *fiber.Ctx) error {
+	var req models.ConfirmEmailChangeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Invalid request body. Please check your JSON format.",
+			"success": false,
+		})
+	}
+	if err := utils.ValidateStruct(req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   err.Error(),
+			"success": false,
+		})
+	}
+
+	user, status, message := h.currentUser(c)
+	if user == nil {
+		return c.Status(status).JSON(fiber.Map{
+			"error":   message,
+			"success": false,
+		})
+	}
+
+	// Wrong codes count towards the account's login lockout, like wrong passwords
+	reservation, wait, err := reserveAttempts(c, h.attemptRepo,
+		attemptGuard{key: loginAccountKey(user.LACPAID), policy: accountLoginPolicy})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   "Failed to check verification attempts",
+			"success": false,
+		})
+	}
+	if wait > 0 {
+		return sendTooManyAttempts(c, wait)
+	}
+
+	newEmail := normalizeEmail(req.NewEmail)
+	// The code only redeems for the account that requested it
+	_, err = h.verification.Verify(c.Context(), newEmail, models.PurposeChangeEmail, user.ID.Hex(), req.OTP)
+	switch err {
+	case nil:
+		reservation.succeed(c)
+	case ErrCodeNotFound:
+		reservation.cancel(c)
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Code expired or invalid. Please request a new one.",
+			"success": false,
+		})
+	case ErrCodeInvalid:
+		h.recordAttemptFailure(c, user, reservation)
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Invalid code",
+			"success": false,
+		})
+	case ErrCodeTooManyAttempts:
+		h.recordAttemptFailure(c, user, reservation)
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Too many wrong codes. Please request a new one.",
+			"success": false,
+		})
+	default:
+		reservation.cancel(c)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   "Failed to verify code",
+			"success": false,
+		})
+	}
+
+	// The unique email index settles races with a signup on the same address
+	if err := h.authRepo.UpdateEmail(user.ID, newEmail); err != nil {
+		if repository.IsDuplicateKeyOn(err, "email") {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error":   "Email already registered",
+				"success": false,
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   "Failed to update email",
+			"success": false,
+		})
+	}
+
+	before := user.ToResponse()
+	oldEmail := user.Email
+	user.Email = newEmail
+	h.audit.Record(c, models.AuditEmailChanged, models.AuditTargetUser, user.ID.Hex(), before, user.ToResponse())
+
+	notice := "The email address of your LACPA account was changed to <strong>" + maskEmail(newEmail) +
+		"</strong>. You will no longer receive account emails at this address."
+	if err := utils.SendEmail(oldEmail, "Your email was changed - LACPA", utils.NoticeEmailTemplate(user.FullName, notice)); err != nil {
+		fmt.Printf("Failed to notify %s of email change: %v\n", oldEmail, err)
+	}
+
+	revoked := h.revokeOtherSessions(c, user, models.SessionRevokedEmail)
+	recordSecurityEvent(h.authRepo, user, models.SecurityEventEmailChanged,
+		"Email changed from "+oldEmail+" to "+newEmail, c.IP(), user.ID.Hex())
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "Email changed successfully. Please use your new email to log in.",
+		"data": fiber.Map{
+			"email":            newEmail,
+			"sessions_revoked": revoked,
+		},
+	})
+}
+
+// UpdateName changes the full name of the current user
+func (h *AuthHandler) UpdateName(c *fiber.Ctx) error {
+	var req models.UpdateNameRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Invalid request body. Please check your JSON format.",
+			"success": false,
+		})
+	}
+	req.FullName = strings.TrimSpace(req.FullName)
+	if err := utils.ValidateStruct(req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   err.Error(),
+			"success": false,
+		})
+	}
+
+	user, status, message := h.currentUser(c)
+	if user == nil {
+		return c.Status(status).JSON(fiber.Map{
+			"error":   message,
+			"success": false,
+		})
+	}
+
+	if err := h.authRepo.UpdateFullName(user.ID, req.FullName); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   "Failed to update name",
+			"success": false,
+		})
+	}
+	before := user.ToResponse()
+	user.FullName = req.FullName
+	h.audit.Record(c, models.AuditUserNameChanged, models.AuditTargetUser, user.ID.Hex(), before, user.ToResponse())
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "Name updated successfully",
+		"data":    user.ToResponse(),
+	})
+}
+
+// DeactivateAccount disables the current account and ends all of its sessions.
+// The account is kept and can be reactivated by an administrator.
+func (h *AuthHandler) DeactivateAccount(c *fiber.Ctx) error {
+	user, status, message := h.confirmedUser(c)
+	if user == nil {
+		return c.Status(status).JSON(fiber.Map{
+			"error":   message,
+			"success": false,
+		})
+	}
+
+	if err := h.authRepo.SetActive(user.ID, false); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   "Failed to deactivate account",
+			"success": false,
+		})
+	}
+	if _, err := h.sessionRepo.RevokeAllForUser(c.Context(), user.ID, models.SessionRevokedDeactivated); err != nil {
+		fmt.Printf("Failed to revoke sessions of deactivated user %s: %v\n", user.Email, err)
+	}
+	recordSecurityEvent(h.authRepo, user, models.SecurityEventSelfDeactivated,
+		"Account deactivated by the account owner", c.IP(), user.ID.Hex())
+	before := user.ToResponse()
+	user.IsActive = false
+	h.audit.Record(c, models.AuditUserDeactivated, models.AuditTargetUser, user.ID.Hex(), before, user.ToResponse())
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "Your account has been deactivated",
+	})
+}
+
+// DeleteAccount permanently deletes the current account. Claimed member and firm
+// records are released first so they can be claimed again.
+func (h *AuthHandler) DeleteAccount(c *fiber.Ctx) error {
+	user, status, message := h.confirmedUser(c)
+	if user == nil {
+		return c.Status(status).JSON(fiber.Map{
+			"error":   message,
+			"success": false,
+		})
+	}
+
+	if user.IndividualMemberID != nil {
+		if err := h.membersRepo.UnlinkIndividualMemberUser(c.Context(), *user.IndividualMemberID, user.ID); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error":   "Failed to release member profile",
+				"success": false,
+			})
+		}
+	}
+	if user.FirmMemberID != nil {
+		if err := h.membersRepo.UnlinkFirmMemberUser(c.Context(), *user.FirmMemberID, user.ID); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error":   "Failed to release firm profile",
+				"success": false,
+			})
+		}
+	}
+
+	if _, err := h.sessionRepo.RevokeAllForUser(c.Context(), user.ID, models.SessionRevokedDeleted); err != nil {
+		fmt.Printf("Failed to revoke sessions of deleted user %s: %v\n", user.Email, err)
+	}
+	if err := h.authRepo.DeleteUser(user.ID); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   "Failed to delete account",
+			"success": false,
+		})
+	}
+	h.audit.Record(c, models.AuditUserDeleted, models.AuditTargetUser, user.ID.Hex(), user.ToResponse(), nil)
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "Your account has been deleted",
+	})
+}
+
+// confirmedUser loads the current user after checking the password in the body
+func (h *AuthHandler) confirmedUser(c *fiber.Ctx) (*models.User, int, string) {
+	var req models.ConfirmPasswordRequest
+	if err := c.BodyParser(&req); err != nil {
+		return nil, fiber.StatusBadRequest, "Invalid request body. Please check your JSON format."
+	}
+	if err := utils.ValidateStruct(req); err != nil {
+		return nil, fiber.StatusBadRequest, err.Error()
+	}
+
+	user, status, message := h.currentUser(c)
+	if user == nil {
+		return nil, status, message
+	}
+	if status, message := h.checkCurrentPassword(c, user, req.Password); status != 0 {
+		return nil, status, message
+	}
+	return user, 0, ""
+}
+
+// checkCurrentPassword confirms the signed-in user's password before a sensitive change.
+// Wrong guesses count towards the same lockout as failed logins, so a stolen session
+// cannot be used to guess the password. It returns a status and message on failure.
+func (h *AuthHandler) checkCurrentPassword(c *fiber.Ctx, user *models.User, password string) (int, string) {
+	reservation, wait, err := reserveAttempts(c, h.attemptRepo,
+		attemptGuard{key: loginAccountKey(user.LACPAID), policy: accountLoginPolicy})
+	if err != nil {
+		return fiber.StatusInternalServerError, "Failed to check password attempts"
+	}
+	if wait > 0 {
+		_, message := setRetryAfter(c, wait)
+		return fiber.StatusTooManyRequests, message
+	}
+	if !utils.CheckPassword(user.Password, password) {
+		h.recordAttemptFailure(c, user, reservation)
+		return fiber.StatusUnauthorized, "Current password is incorrect"
+	}
+	reservation.succeed(c)
+	return 0, ""
+}
+
+// revokeOtherSessions ends every session of the user except the one making the request
+func (h *AuthHandler) revokeOtherSessions(c *fiber.Ctx, user *models.User, reason string) int64 {
+	sessionID, err := primitive.ObjectIDFromHex(c.Locals("sessionID").(string))
+	if err != nil {
+		// No usable session on the token: end them all
+		revoked, err := h.sessionRepo.RevokeAllForUser(c.Context(), user.ID, reason)
+		if err != nil {
+			fmt.Printf("Failed to revoke sessions of %s: %v\n", user.Email, err)
+		}
+		return revoked
+	}
+
+	revoked, err := h.sessionRepo.RevokeOtherSessions(c.Context(), user.ID, sessionID, reason)
+	if err != nil {
+		fmt.Printf("Failed to revoke other sessions of %s: %v\n", user.Email, err)
+	}
+	return revoked
+}
@@ -5,19 +5,31 @@ import (
 	"strings"
 	"time"
 
+	"github.com/AliSleiman0/Lacpa/middleware"
 	"github.com/AliSleiman0/Lacpa/models"
 	"github.com/AliSleiman0/Lacpa/repository"
 	"github.com/AliSleiman0/Lacpa/utils"
 	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/mongo"
 )
 
 type AdminHandler struct {
-	authRepo *repository.AuthRepository
+	authRepo    *repository.AuthRepository
+	roleRepo    *repository.RoleRepository
+	sessionRepo *repository.SessionRepository
+	attemptRepo *repository.AttemptRepository
+	counterRepo *repository.CounterRepository
+	audit       *AuditService
 }
 
-func NewAdminHandler(authRepo *repository.AuthRepository) *AdminHandler {
+func NewAdminHandler(authRepo *repository.AuthRepository, roleRepo *repository.RoleRepository, sessionRepo *repository.SessionRepository, attemptRepo *repository.AttemptRepository, counterRepo *repository.CounterRepository, audit *AuditService) *AdminHandler {
 	return &AdminHandler{
-		authRepo: authRepo,
+		authRepo:    authRepo,
+		roleRepo:    roleRepo,
+		sessionRepo: sessionRepo,
+		attemptRepo: attemptRepo,
+		counterRepo: counterRepo,
+		audit:       audit,
 	}
 }
 
@@ -32,9 +44,8 @@ func (h *AdminHandler) CreateAdmin(c *fiber.Ctx) error {
 		})
 	}
 
-	// Get requesting user's role from context
-	requestingUserRole := c.Locals("role").(string)
-	if requestingUserRole != "admin" {
+	// Check the requesting user's permissions (set by the authorization layer)
+	if !middleware.HasPermission(c, models.PermUsersManage) {
 		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
 			"error":   "Only admins can create admin accounts",
 			"success": false,
@@ -57,8 +68,13 @@ func (h *AdminHandler) CreateAdmin(c *fiber.Ctx) error {
 		})
 	}
 
+	// Enforce the password policy
+	if ve := validateNewPassword("password", req.Password, nil); ve != nil {
+		return utils.SendValidationErrors(c, ve)
+	}
+
 	// Check if email already exists
-	existingUser, err := h.authRepo.GetUserByEmail(req.Email)
+	existingUser, err := h.authRepo.GetUserByEmail(strings.ToLower(strings.TrimSpace(req.Email)))
 	if err == nil && existingUser != nil {
 		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
 			"error":   "Email already registered",
@@ -75,26 +91,29 @@ func (h *AdminHandler) CreateAdmin(c *fiber.Ctx) error {
 		})
 	}
 
-	// Generate LACPA ID (format: LACPA-YYYY-XXXXX)
-	lacpaID := fmt.Sprintf("LACPA-%d-%05d", time.Now().Year(), time.Now().Unix()%100000)
-
-	// Create admin user
+	// Create admin user with the next sequential admin ID (format: LACPA-A-YYYY-NNNNN)
 	user := &models.User{
-		LACPAID:    lacpaID,
 		FullName:   req.FullName,
 		Email:      strings.ToLower(strings.TrimSpace(req.Email)),
 		Password:   hashedPassword,
-		Role:       "admin", // Set as admin
-		IsVerified: true,    // Auto-verify admin accounts
+		Role:       models.RoleAdmin, // Set as admin
+		IsVerified: true,             // Auto-verify admin accounts
 		IsActive:   true,
 	}
 
-	if err := h.authRepo.CreateUser(user); err != nil {
+	if err := createUserWithLACPAID(c.Context(), h.authRepo, h.counterRepo, user, models.LACPAIDAdmin); err != nil {
+		if err == ErrEmailTaken {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error":   "Email already registered",
+				"success": false,
+			})
+		}
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error":   "Failed to create admin user",
 			"success": false,
 		})
 	}
+	h.audit.Record(c, models.AuditAdminCreated, models.AuditTargetUser, user.ID.Hex(), nil, user.ToResponse())
 
 	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
 		"success": true,
@@ -139,15 +158,16 @@ func (h *AdminHandler) UpdateUserRole(c *fiber.Ctx) error {
 		})
 	}
 
-	// Validate role value
-	validRoles := map[string]bool{
-		"admin":  true,
-		"member": true,
-		"guest":  true,
-	}
-	if !validRoles[req.Role] {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error":   "Invalid role. Allowed roles: admin, member, guest",
+	// Validate role value against the roles collection
+	if _, err := h.roleRepo.GetRoleByName(c.Context(), req.Role); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error":   "Invalid role. Use GET /api/admin/roles to list available roles",
+				"success": false,
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   "Failed to retrieve role",
 			"success": false,
 		})
 	}
@@ -162,6 +182,7 @@ func (h *AdminHandler) UpdateUserRole(c *fiber.Ctx) error {
 	}
 
 	// Update role
+	before := user.ToResponse()
 	user.Role = req.Role
 	if err := h.authRepo.UpdateUser(user); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
@@ -170,6 +191,14 @@ func (h *AdminHandler) UpdateUserRole(c *fiber.Ctx) error {
 		})
 	}
 
+	// End every session so tokens carrying the old role's permissions stop working
+	if before.Role != user.Role {
+		if _, err := h.sessionRepo.RevokeAllForUser(c.Context(), user.ID, models.SessionRevokedRoleChanged); err != nil {
+			fmt.Printf("Failed to revoke sessions of %s: %v\n", user.Email, err)
+		}
+	}
+	h.audit.Record(c, models.AuditUserRoleChanged, models.AuditTargetUser, user.ID.Hex(), before, user.ToResponse())
+
 	return c.JSON(fiber.Map{
 		"success": true,
 		"message": fmt.Sprintf("User role updated to %s", req.Role),
@@ -210,6 +239,7 @@ func (h *AdminHandler) DeactivateUser(c *fiber.Ctx) error {
 	}
 
 	// Deactivate
+	before := user.ToResponse()
 	user.IsActive = false
 	if err := h.authRepo.UpdateUser(user); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
@@ -218,6 +248,12 @@ func (h *AdminHandler) DeactivateUser(c *fiber.Ctx) error {
 		})
 	}
 
+	// End every session so existing tokens stop working immediately
+	if _, err := h.sessionRepo.RevokeAllForUser(c.Context(), user.ID, models.SessionRevokedDeactivated); err != nil {
+		fmt.Printf("Failed to revoke sessions of %s: %v\n", user.Email, err)
+	}
+	h.audit.Record(c, models.AuditUserDeactivated, models.AuditTargetUser, user.ID.Hex(), before, user.ToResponse())
+
 	return c.JSON(fiber.Map{
 		"success": true,
 		"message": "User account deactivated successfully",
@@ -244,6 +280,7 @@ func (h *AdminHandler) ActivateUser(c *fiber.Ctx) error {
 	}
 
 	// Activate
+	before := user.ToResponse()
 	user.IsActive = true
 	if err := h.authRepo.UpdateUser(user); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
@@ -251,9 +288,302 @@ func (h *AdminHandler) ActivateUser(c *fiber.Ctx) error {
 			"success": false,
 		})
 	}
+	h.audit.Record(c, models.AuditUserActivated, models.AuditTargetUser, user.ID.Hex(), before, user.ToResponse())
 
 	return c.JSON(fiber.Map{
 		"success": true,
 		"message": "User account activated successfully",
 	})
 }
+
+// UnlockUser clears the failed password and 2FA counters of a locked-out account
+func (h *AdminHandler) UnlockUser(c *fiber.Ctx) error {
+	email := c.Query("email")
+	if email == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Email parameter is required",
+			"success": false,
+		})
+	}
+
+	// Get user
+	user, err := h.authRepo.GetUserByEmail(strings.ToLower(strings.TrimSpace(email)))
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error":   "User not found",
+			"success": false,
+		})
+	}
+
+	if err := h.attemptRepo.Reset(c.Context(), loginAccountKey(user.LACPAID), mfaUserKey(user.ID.Hex())); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   "Failed to unlock user",
+			"success": false,
+		})
+	}
+
+	actor, _ := c.Locals("lacpaID").(string)
+	recordSecurityEvent(h.authRepo, user, models.SecurityEventAccountUnlocked, "Account unlocked by an administrator", c.IP(), actor)
+	h.audit.Record(c, models.AuditUserUnlocked, models.AuditTargetUser, user.ID.Hex(), nil, nil)
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "User account unlocked successfully",
+	})
+}
+
+// ResetTwoFactor removes a user's 2FA enrollment after they lost their device and recovery codes.
+// If their role requires 2FA they will be asked to enroll again on next login.
+func (h *AdminHandler) ResetTwoFactor(c *fiber.Ctx) error {
+	email := c.Query("email")
+	if email == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Email parameter is required",
+			"success": false,
+		})
+	}
+
+	// Get user
+	user, err := h.authRepo.GetUserByEmail(strings.ToLower(strings.TrimSpace(email)))
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error":   "User not found",
+			"success": false,
+		})
+	}
+
+	if err := h.authRepo.DisableTwoFactor(user.ID); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   "Failed to reset two-factor authentication",
+			"success": false,
+		})
+	}
+
+	// Sessions opened with the old second factor are ended as well
+	if _, err := h.sessionRepo.RevokeAllForUser(c.Context(), user.ID, models.SessionRevokedMFAReset); err != nil {
+		fmt.Printf("Failed to revoke sessions for %s: %v\n", user.Email, err)
+	}
+
+	actor, _ := c.Locals("lacpaID").(string)
+	recordSecurityEvent(h.authRepo, user, models.SecurityEventTwoFactorDisabled, "Two-factor authentication reset by an administrator", c.IP(), actor)
+	h.audit.Record(c, models.AuditUserTwoFactorReset, models.AuditTargetUser, user.ID.Hex(),
+		fiber.Map{"two_factor_enabled": user.TwoFactor.Enabled}, fiber.Map{"two_factor_enabled": false})
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "Two-factor authentication reset successfully",
+	})
+}
+
+// GetSecurityHistory returns the security history and lockout state of a user
+func (h *AdminHandler) GetSecurityHistory(c *fiber.Ctx) error {
+	email := c.Query("email")
+	if email == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Email parameter is required",
+			"success": false,
+		})
+	}
+
+	// Get user
+	user, err := h.authRepo.GetUserByEmail(strings.ToLower(strings.TrimSpace(email)))
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error":   "User not found",
+			"success": false,
+		})
+	}
+
+	attempt, err := h.attemptRepo.GetAttempt(c.Context(), loginAccountKey(user.LACPAID))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   "Failed to retrieve login attempts",
+			"success": false,
+		})
+	}
+
+	history := user.SecurityHistory
+	if history == nil {
+		history = []models.SecurityEvent{}
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data": fiber.Map{
+			"email":            user.Email,
+			"locked":           attempt.IsLocked(time.Now()),
+			"login_attempts":   attempt,
+			"security_history": history,
+		},
+	})
+}
+
+// ListRoles returns every role with its permissions
+func (h *AdminHandler) ListRoles(c *fiber.Ctx) error {
+	roles, err := h.roleRepo.GetAllRoles(c.Context())
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   "Failed to retrieve roles",
+			"success": false,
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data": fiber.Map{
+			"roles":       roles,
+			"permissions": models.AllPermissions,
+		},
+	})
+}
+
+// CreateRole creates a custom role
+func (h *AdminHandler) CreateRole(c *fiber.Ctx) error {
+	var req models.CreateRoleRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Invalid request body. Please check your JSON format.",
+			"success": false,
+		})
+	}
+
+	// Validate request
+	if err := utils.ValidateStruct(req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   err.Error(),
+			"success": false,
+		})
+	}
+	if invalid := invalidPermissions(req.Permissions); len(invalid) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Unknown permissions: " + strings.Join(invalid, ", "),
+			"success": false,
+		})
+	}
+
+	role := &models.Role{
+		Name:        strings.ToLower(strings.TrimSpace(req.Name)),
+		Description: req.Description,
+		Permissions: req.Permissions,
+		RequireMFA:  req.RequireMFA,
+	}
+	if role.Permissions == nil {
+		role.Permissions = []models.Permission{}
+	}
+
+	if err := h.roleRepo.CreateRole(c.Context(), role); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error":   "Role already exists",
+				"success": false,
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   "Failed to create role",
+			"success": false,
+		})
+	}
+	h.audit.Record(c, models.AuditRoleCreated, models.AuditTargetRole, role.Name, nil, role)
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"success": true,
+		"message": "Role created successfully",
+		"data":    role,
+	})
+}
+
+// UpdateRole changes the description, permissions or MFA requirement of a role;
+// fields left out of the body are kept. Users holding the role must log in again
+// to pick up the change.
+func (h *AdminHandler) UpdateRole(c *fiber.Ctx) error {
+	var req models.UpdateRoleRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Invalid request body. Please check your JSON format.",
+			"success": false,
+		})
+	}
+	if invalid := invalidPermissions(req.Permissions); len(invalid) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Unknown permissions: " + strings.Join(invalid, ", "),
+			"success": false,
+		})
+	}
+
+	before, err := h.roleRepo.GetRoleByName(c.Context(), c.Params("name"))
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error":   "Role not found",
+				"success": false,
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   "Failed to retrieve role",
+			"success": false,
+		})
+	}
+
+	role, err := h.roleRepo.UpdateRole(c.Context(), before.Name, req)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error":   "Role not found",
+				"success": false,
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   "Failed to update role",
+			"success": false,
+		})
+	}
+	h.audit.Record(c, models.AuditRoleUpdated, models.AuditTargetRole, role.Name, before, role)
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "Role updated successfully",
+		"data":    role,
+	})
+}
+
+// DeleteRole deletes a custom role that is no longer assigned
+func (h *AdminHandler) DeleteRole(c *fiber.Ctx) error {
+	// Snapshot for the audit trail; a missing role is reported by DeleteRole below
+	before, _ := h.roleRepo.GetRoleByName(c.Context(), c.Params("name"))
+
+	err := h.roleRepo.DeleteRole(c.Context(), c.Params("name"))
+	switch {
+	case err == nil:
+		h.audit.Record(c, models.AuditRoleDeleted, models.AuditTargetRole, c.Params("name"), before, nil)
+		return c.JSON(fiber.Map{
+			"success": true,
+			"message": "Role deleted successfully",
+		})
+	case err == mongo.ErrNoDocuments:
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error":   "Role not found",
+			"success": false,
+		})
+	case err == repository.ErrSystemRole || err == repository.ErrRoleInUse:
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error":   err.Error(),
+			"success": false,
+		})
+	default:
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   "Failed to delete role",
+			"success": false,
+		})
+	}
+}
+
+// invalidPermissions returns the permissions that are not known to the system
+func invalidPermissions(permissions []models.Permission) []string {
+	var invalid []string
+	for _, permission := range permissions {
+		if !permission.IsValid() {
+			invalid = append(invalid, string(permission))
+		}
+	}
+	return invalid
+}
@@ -0,0 +1,326 @@
+package handler
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/AliSleiman0/Lacpa/models"
+	"github.com/AliSleiman0/Lacpa/utils"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// draftTokenHeader carries the resume token of a draft for API clients;
+// the resume link passes it as the token query parameter instead
+const draftTokenHeader = "X-Draft-Token"
+
+// CreateDraft handles POST /api/applications/drafts, starting a draft application.
+//
+// ROLE: Save-As-Draft Applications
+//   - An applicant starts a draft with the application type and an email; the resume
+//     link with the draft's secret token is emailed and the token is returned once
+//   - Each step of the form is saved on its own and validated with the same rules as a
+//     submission, limited to the step's fields; invalid input is still saved so nothing
+//     is lost, and the step stays incomplete until it passes
+//   - Submitting turns the draft into an application through the normal submission checks
+//   - Drafts expire APPLICATION_DRAFT_TTL_DAYS after their last save through a TTL index
+func (h *ApplicationHandler) CreateDraft(c *fiber.Ctx) error {
+	var req models.CreateDraftRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.SendBadRequest(c, "Invalid request body")
+	}
+	req.Email = normalizeEmail(req.Email)
+	if err := utils.ValidateStruct(req); err != nil {
+		if ve, ok := err.(*utils.ValidationErrors); ok {
+			return utils.SendValidationErrors(c, ve)
+		}
+		return utils.SendBadRequest(c, err.Error())
+	}
+
+	token, err := utils.GenerateResetToken()
+	if err != nil {
+		return utils.SendInternalError(c, "Failed to create draft")
+	}
+	draft := &models.ApplicationDraft{
+		ApplicationType: req.ApplicationType,
+		Email:           req.Email,
+		UserID:          submitterID(c),
+		TokenHash:       utils.HashToken(token),
+		Data:            map[string]interface{}{"email": req.Email},
+		ExpiresAt:       time.Now().Add(draftTTL()),
+	}
+	if err := h.repo.CreateDraft(c.Context(), draft); err != nil {
+		return utils.SendInternalError(c, err.Error())
+	}
+
+	if err := sendDraftResumeEmail(draft, token); err != nil {
+		fmt.Printf("Failed to send resume link for draft %s: %v\n", draft.ID.Hex(), err)
+	}
+
+	view := draftView(draft)
+	view["token"] = token
+	return utils.SendCreated(c, view, "")
+}
+
+// GetDraft handles GET /api/applications/drafts/:id
+func (h *ApplicationHandler) GetDraft(c *fiber.Ctx) error {
+	draft, err := h.authorizedDraft(c)
+	if draft == nil {
+		return err
+	}
+	return utils.SendSuccess(c, "Draft retrieved successfully", draftView(draft))
+}
+
+// SaveDraftStep handles PUT /api/applications/drafts/:id/steps/:step.
+// The body holds the step's fields under their application JSON names; other fields are
+// ignored and a null value clears a field. The fields are saved even when they are invalid,
+// in which case the errors are returned with 422 and the step is not complete.
+func (h *ApplicationHandler) SaveDraftStep(c *fiber.Ctx) error {
+	draft, err := h.authorizedDraft(c)
+	if draft == nil {
+		return err
+	}
+	step, ok := draft.ApplicationType.Step(c.Params("step"))
+	if !ok {
+		return utils.SendNotFound(c, "Step")
+	}
+
+	var fields map[string]interface{}
+	if err := json.Unmarshal(c.Body(), &fields); err != nil {
+		return utils.SendBadRequest(c, "Invalid request body")
+	}
+	if draft.Data == nil {
+		draft.Data = map[string]interface{}{}
+	}
+	for field, value := range fields {
+		if !step.HasField(field) {
+			continue
+		}
+		if value == nil {
+			delete(draft.Data, field)
+		} else {
+			draft.Data[field] = value
+		}
+	}
+
+	ve, err := h.validateDraftStep(c, draft, step)
+	if err != nil {
+		return utils.SendInternalError(c, err.Error())
+	}
+	draft.CompletedSteps = withoutStep(draft.CompletedSteps, step.Name)
+	if !ve.HasErrors() {
+		draft.CompletedSteps = append(draft.CompletedSteps, step.Name)
+	}
+	draft.ExpiresAt = time.Now().Add(draftTTL())
+
+	if err := h.repo.UpdateDraft(c.Context(), draft); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return utils.SendNotFound(c, "Draft")
+		}
+		return utils.SendInternalError(c, err.Error())
+	}
+
+	if ve.HasErrors() {
+		return sendApplicationErrors(c, ve)
+	}
+	return utils.SendSuccess(c, step.Title+" saved", draftView(draft))
+}
+
+// SubmitDraft handles POST /api/applications/drafts/:id/submit.
+// The draft becomes an application through the same checks as a direct submission
+// and is deleted once the application is stored.
+func (h *ApplicationHandler) SubmitDraft(c *fiber.Ctx) error {
+	draft, err := h.authorizedDraft(c)
+	if draft == nil {
+		return err
+	}
+
+	userID := submitterID(c)
+	if userID == nil {
+		userID = draft.UserID
+	}
+
+	var application interface{}
+	switch draft.ApplicationType {
+	case models.ApplicationTypeIndividual:
+		var individual models.IndividualApplication
+		if ve := decodeDraft(draft.Data, &individual); ve.HasErrors() {
+			return sendApplicationErrors(c, ve)
+		}
+		if created, err := h.createIndividualApplication(c, &individual, userID); !created {
+			return err
+		}
+		application = individual
+	case models.ApplicationTypeFirm:
+		var firm models.FirmApplication
+		if ve := decodeDraft(draft.Data, &firm); ve.HasErrors() {
+			return sendApplicationErrors(c, ve)
+		}
+		if created, err := h.createFirmApplication(c, &firm, userID); !created {
+			return err
+		}
+		application = firm
+	default:
+		return utils.SendNotFound(c, "Draft")
+	}
+
+	if err := h.repo.DeleteDraft(c.Context(), draft.ID); err != nil && err != mongo.ErrNoDocuments {
+		fmt.Printf("Failed to delete submitted draft %s: %v\n", draft.ID.Hex(), err)
+	}
+
+	return utils.SendCreated(c, application, "Application submitted successfully", "Your application has been received and is under review.")
+}
+
+// DiscardDraft handles DELETE /api/applications/drafts/:id
+func (h *ApplicationHandler) DiscardDraft(c *fiber.Ctx) error {
+	draft, err := h.authorizedDraft(c)
+	if draft == nil {
+		return err
+	}
+
+	if err := h.repo.DeleteDraft(c.Context(), draft.ID); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return utils.SendNotFound(c, "Draft")
+		}
+		return utils.SendInternalError(c, err.Error())
+	}
+	return utils.SendSuccess(c, "Draft discarded", nil)
+}
+
+// authorizedDraft loads the draft of the :id parameter for the holder of its resume token,
+// or for the signed-in account that started it. Drafts the caller may not open are reported
+// as not found. When it returns nil the error response has already been sent.
+func (h *ApplicationHandler) authorizedDraft(c *fiber.Ctx) (*models.ApplicationDraft, error) {
+	id, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return nil, utils.SendNotFound(c, "Draft")
+	}
+	draft, err := h.repo.GetDraftByID(c.Context(), id)
+	if err == mongo.ErrNoDocuments {
+		return nil, utils.SendNotFound(c, "Draft")
+	}
+	if err != nil {
+		return nil, utils.SendInternalError(c, err.Error())
+	}
+
+	token := c.Get(draftTokenHeader)
+	if token == "" {
+		token = c.Query("token")
+	}
+	if token != "" && subtle.ConstantTimeCompare([]byte(utils.HashToken(token)), []byte(draft.TokenHash)) == 1 {
+		return draft, nil
+	}
+	if userID := submitterID(c); userID != nil && draft.UserID != nil && *userID == *draft.UserID {
+		return draft, nil
+	}
+	return nil, utils.SendNotFound(c, "Draft")
+}
+
+// validateDraftStep checks the draft as a whole and keeps the errors of the step's fields
+func (h *ApplicationHandler) validateDraftStep(c *fiber.Ctx, draft *models.ApplicationDraft, step models.ApplicationStep) (*utils.ValidationErrors, error) {
+	var ve, checked *utils.ValidationErrors
+	var err error
+	switch draft.ApplicationType {
+	case models.ApplicationTypeIndividual:
+		var application models.IndividualApplication
+		ve = decodeDraft(draft.Data, &application)
+		_, checked, err = h.validateApplication(c, draft.ApplicationType, &application, application.DocumentReferences())
+	case models.ApplicationTypeFirm:
+		var application models.FirmApplication
+		ve = decodeDraft(draft.Data, &application)
+		_, checked, err = h.validateApplication(c, draft.ApplicationType, &application, application.DocumentReferences())
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// A field that could not be decoded is reported once, not again as missing
+	reported := map[string]bool{}
+	for _, fieldError := range ve.Errors {
+		reported[fieldError.Field] = true
+	}
+	if checked != nil {
+		for _, fieldError := range checked.Errors {
+			if !reported[fieldError.Field] {
+				ve.Errors = append(ve.Errors, fieldError)
+			}
+		}
+	}
+
+	stepErrors := utils.NewValidationErrors()
+	for _, fieldError := range ve.Errors {
+		if step.HasField(fieldError.Field) {
+			stepErrors.Errors = append(stepErrors.Errors, fieldError)
+		}
+	}
+	return stepErrors, nil
+}
+
+// decodeDraft fills an application from the draft's data one field at a time,
+// so a value of the wrong type is reported on its own field
+func decodeDraft(data map[string]interface{}, application interface{}) *utils.ValidationErrors {
+	ve := utils.NewValidationErrors()
+
+	fields := make([]string, 0, len(data))
+	for field := range data {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	for _, field := range fields {
+		raw, err := json.Marshal(map[string]interface{}{field: data[field]})
+		if err == nil {
+			err = json.Unmarshal(raw, application)
+		}
+		if err != nil {
+			ve.AddError(field, "Invalid value", fmt.Sprint(data[field]))
+		}
+	}
+	return ve
+}
+
+// draftView is the draft with its form steps, as returned to the applicant
+func draftView(draft *models.ApplicationDraft) fiber.Map {
+	return fiber.Map{
+		"draft":    draft,
+		"steps":    draft.ApplicationType.Steps(),
+		"complete": draft.IsComplete(),
+	}
+}
+
+// sendDraftResumeEmail emails the link that reopens the draft in the apply form
+func sendDraftResumeEmail(draft *models.ApplicationDraft, token string) error {
+	base, err := utils.PublicBaseURL()
+	if err != nil {
+		return err
+	}
+	link := fmt.Sprintf("%s/membership/apply/%s?draft=%s&token=%s",
+		base, strings.ToLower(string(draft.ApplicationType)), draft.ID.Hex(), token)
+
+	message := "You started a " + strings.ToLower(string(draft.ApplicationType)) + " membership application. " +
+		"Your progress is saved with every step; use the link below to continue where you left off."
+	validity := "The draft is kept until " + draft.ExpiresAt.Format("January 2, 2006") + " and is extended each time you save."
+
+	return utils.SendEmail(draft.Email, "Continue your LACPA application", utils.ActionEmailTemplate("", message, "Continue my application", link, validity))
+}
+
+// draftTTL returns how long an untouched draft is kept (APPLICATION_DRAFT_TTL_DAYS, default 30)
+func draftTTL() time.Duration {
+	return time.Duration(utils.GetEnvInt("APPLICATION_DRAFT_TTL_DAYS", 30)) * 24 * time.Hour
+}
+
+// withoutStep returns the completed steps without the named one
+func withoutStep(steps []string, name string) []string {
+	kept := make([]string, 0, len(steps))
+	for _, step := range steps {
+		if step != name {
+			kept = append(kept, step)
+		}
+	}
+	return kept
+}
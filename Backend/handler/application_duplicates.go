@@ -0,0 +1,269 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/AliSleiman0/Lacpa/models"
+	"github.com/AliSleiman0/Lacpa/utils"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Weights of the duplicate signals. Each is treated as independent evidence,
+// so two signals of 0.7 and 0.6 give a confidence of 1 - 0.3*0.4 = 0.88.
+const (
+	duplicateEmailWeight        = 0.9
+	duplicatePhoneWeight        = 0.7
+	duplicateRegistrationWeight = 0.95
+	duplicateNameWeight         = 0.6
+	duplicateBirthDateWeight    = 0.5
+
+	// Names below this similarity are not considered the same
+	duplicateNameThreshold = 0.85
+
+	// At most this many matches are kept, highest confidence first
+	maxDuplicateMatches = 10
+)
+
+// duplicateSubject is the normalized identity of an application or member
+type duplicateSubject struct {
+	name         string
+	namePrefix   string
+	emails       []string
+	phones       []string
+	registration string
+	dateOfBirth  time.Time
+}
+
+func individualApplicationSubject(a *models.IndividualApplication) duplicateSubject {
+	return duplicateSubject{
+		name:        utils.NormalizeName(a.FullName()),
+		namePrefix:  namePrefix(utils.NormalizeName(a.LastName), models.IndividualNamePrefixLength),
+		emails:      normalizedValues(utils.NormalizeEmail, a.Email),
+		phones:      normalizedValues(utils.NormalizePhone, a.Phone, a.MobilePhone),
+		dateOfBirth: a.DateOfBirth,
+	}
+}
+
+func individualMemberSubject(m *models.IndividualMember) duplicateSubject {
+	return duplicateSubject{
+		name:   utils.NormalizeName(m.GetFullName()),
+		emails: normalizedValues(utils.NormalizeEmail, m.Email),
+		phones: normalizedValues(utils.NormalizePhone, m.Phone),
+	}
+}
+
+func firmApplicationSubject(a *models.FirmApplication) duplicateSubject {
+	name := utils.NormalizeFirmName(a.FirmName)
+	return duplicateSubject{
+		name:         name,
+		namePrefix:   namePrefix(name, models.FirmNamePrefixLength),
+		emails:       normalizedValues(utils.NormalizeEmail, a.Email, a.RepresentativeEmail),
+		phones:       normalizedValues(utils.NormalizePhone, a.Phone, a.RepresentativePhone),
+		registration: utils.NormalizeRegistrationNumber(a.RegistrationNumber),
+	}
+}
+
+func firmMemberSubject(m *models.FirmMember) duplicateSubject {
+	return duplicateSubject{
+		name:         utils.NormalizeFirmName(m.FirmName),
+		emails:       normalizedValues(utils.NormalizeEmail, m.PrimaryEmail, m.ContactPersonEmail),
+		phones:       normalizedValues(utils.NormalizePhone, m.PrimaryPhone, m.SecondaryPhone, m.ContactPersonPhone),
+		registration: utils.NormalizeRegistrationNumber(m.RegistrationNumber),
+	}
+}
+
+// probe selects the candidates worth scoring against the subject
+func (s duplicateSubject) probe(excludeApplication, excludeMember primitive.ObjectID) models.DuplicateProbe {
+	return models.DuplicateProbe{
+		ExcludeApplicationID: excludeApplication,
+		ExcludeMemberID:      excludeMember,
+		Emails:               s.emails,
+		Phones:               s.phones,
+		RegistrationNumber:   s.registration,
+		NamePrefix:           s.namePrefix,
+	}
+}
+
+// findDuplicates returns the open applications and members the subject may duplicate,
+// highest confidence first
+func (h *ApplicationHandler) findDuplicates(ctx context.Context, appType models.ApplicationType, subject duplicateSubject, excludeApplication, excludeMember primitive.ObjectID) ([]models.DuplicateMatch, error) {
+	candidates, err := h.repo.FindDuplicateCandidates(ctx, appType, subject.probe(excludeApplication, excludeMember))
+	if err != nil {
+		return nil, err
+	}
+
+	matches := []models.DuplicateMatch{}
+	consider := func(match models.DuplicateMatch, candidate duplicateSubject) {
+		match.Confidence, match.Reasons = scoreDuplicate(subject, candidate)
+		if match.Confidence >= models.DuplicateThreshold {
+			matches = append(matches, match)
+		}
+	}
+
+	for i := range candidates.IndividualApplications {
+		a := &candidates.IndividualApplications[i]
+		consider(models.DuplicateMatch{Kind: models.DuplicateIndividualApplication, ID: a.ID, Name: a.FullName(), Reference: a.ReferenceNumber, Status: string(a.Status)}, individualApplicationSubject(a))
+	}
+	for i := range candidates.IndividualMembers {
+		m := &candidates.IndividualMembers[i]
+		consider(models.DuplicateMatch{Kind: models.DuplicateIndividualMember, ID: m.ID, Name: m.GetFullName(), Reference: m.LacpaID, Status: m.MembershipStatus}, individualMemberSubject(m))
+	}
+	for i := range candidates.FirmApplications {
+		a := &candidates.FirmApplications[i]
+		consider(models.DuplicateMatch{Kind: models.DuplicateFirmApplication, ID: a.ID, Name: a.FirmName, Reference: a.ReferenceNumber, Status: string(a.Status)}, firmApplicationSubject(a))
+	}
+	for i := range candidates.FirmMembers {
+		m := &candidates.FirmMembers[i]
+		consider(models.DuplicateMatch{Kind: models.DuplicateFirmMember, ID: m.ID, Name: m.FirmName, Reference: m.LacpaID, Status: m.MembershipStatus}, firmMemberSubject(m))
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].Confidence > matches[j].Confidence
+	})
+	if len(matches) > maxDuplicateMatches {
+		matches = matches[:maxDuplicateMatches]
+	}
+	return matches, nil
+}
+
+// submissionDuplicates runs the duplicate check for a new application.
+// A failed check is logged and never blocks the submission.
+func (h *ApplicationHandler) submissionDuplicates(c *fiber.Ctx, appType models.ApplicationType, subject duplicateSubject) []models.DuplicateMatch {
+	matches, err := h.findDuplicates(c.Context(), appType, subject, primitive.NilObjectID, primitive.NilObjectID)
+	if err != nil {
+		fmt.Printf("Duplicate check failed for %s application: %v\n", appType, err)
+		return nil
+	}
+	return matches
+}
+
+// scoreDuplicate combines the signals shared by two subjects into a confidence
+// and the reasons shown to the reviewer
+func scoreDuplicate(subject, candidate duplicateSubject) (float64, []string) {
+	unlikely := 1.0
+	reasons := []string{}
+	add := func(weight float64, reason string) {
+		unlikely *= 1 - weight
+		reasons = append(reasons, reason)
+	}
+
+	if sharesValue(subject.emails, candidate.emails) {
+		add(duplicateEmailWeight, "Same email address")
+	}
+	if sharesValue(subject.phones, candidate.phones) {
+		add(duplicatePhoneWeight, "Same phone number")
+	}
+	if subject.registration != "" && subject.registration == candidate.registration {
+		add(duplicateRegistrationWeight, "Same registration number")
+	}
+
+	// A matching birth date only counts for people with a similar name
+	if similarity := utils.NameSimilarity(subject.name, candidate.name); similarity >= duplicateNameThreshold {
+		if similarity == 1 {
+			add(duplicateNameWeight, "Same name")
+		} else {
+			add(duplicateNameWeight*similarity, fmt.Sprintf("Similar name (%d%%)", int(similarity*100)))
+		}
+		if !subject.dateOfBirth.IsZero() && !candidate.dateOfBirth.IsZero() &&
+			subject.dateOfBirth.Format("2006-01-02") == candidate.dateOfBirth.Format("2006-01-02") {
+			add(duplicateBirthDateWeight, "Same date of birth")
+		}
+	}
+
+	return math.Round((1-unlikely)*100) / 100, reasons
+}
+
+// GetIndividualApplicationDuplicates handles GET /api/applications/individual/:id/duplicates.
+// Matches are recomputed, so reviewers also see records created after the submission.
+func (h *ApplicationHandler) GetIndividualApplicationDuplicates(c *fiber.Ctx) error {
+	id, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return utils.SendBadRequest(c, "Invalid application ID")
+	}
+	application, err := h.repo.GetIndividualApplicationByID(c.Context(), id)
+	if err == mongo.ErrNoDocuments {
+		return utils.SendNotFound(c, "Application")
+	}
+	if err != nil {
+		return utils.SendInternalError(c, err.Error())
+	}
+
+	matches, err := h.findDuplicates(c.Context(), models.ApplicationTypeIndividual, individualApplicationSubject(application), application.ID, objectIDOrNil(application.MemberID))
+	if err != nil {
+		return utils.SendInternalError(c, err.Error())
+	}
+	return utils.SendSuccess(c, "Possible duplicates retrieved successfully", matches)
+}
+
+// GetFirmApplicationDuplicates handles GET /api/applications/firm/:id/duplicates
+func (h *ApplicationHandler) GetFirmApplicationDuplicates(c *fiber.Ctx) error {
+	id, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return utils.SendBadRequest(c, "Invalid application ID")
+	}
+	application, err := h.repo.GetFirmApplicationByID(c.Context(), id)
+	if err == mongo.ErrNoDocuments {
+		return utils.SendNotFound(c, "Application")
+	}
+	if err != nil {
+		return utils.SendInternalError(c, err.Error())
+	}
+
+	matches, err := h.findDuplicates(c.Context(), models.ApplicationTypeFirm, firmApplicationSubject(application), application.ID, objectIDOrNil(application.MemberID))
+	if err != nil {
+		return utils.SendInternalError(c, err.Error())
+	}
+	return utils.SendSuccess(c, "Possible duplicates retrieved successfully", matches)
+}
+
+// normalizedValues normalizes each value, dropping empty results and repeats
+func normalizedValues(normalize func(string) string, values ...string) []string {
+	var normalized []string
+	for _, value := range values {
+		if value = normalize(value); value != "" && !containsValue(normalized, value) {
+			normalized = append(normalized, value)
+		}
+	}
+	return normalized
+}
+
+// namePrefix is the start of the first word of a normalized name, used to find
+// candidates whose name may be spelled slightly differently
+func namePrefix(name string, length int) string {
+	prefixes := utils.NamePrefixes(name, length)
+	if len(prefixes) == 0 {
+		return ""
+	}
+	return prefixes[0]
+}
+
+func sharesValue(a, b []string) bool {
+	for _, value := range a {
+		if containsValue(b, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsValue(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func objectIDOrNil(id *primitive.ObjectID) primitive.ObjectID {
+	if id == nil {
+		return primitive.NilObjectID
+	}
+	return *id
+}
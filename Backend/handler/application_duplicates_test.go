@@ -0,0 +1,97 @@
+package handler
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/AliSleiman0/Lacpa/models"
+)
+
+func TestScoreDuplicate(t *testing.T) {
+	born := time.Date(1990, 5, 17, 0, 0, 0, 0, time.UTC)
+	applicant := individualApplicationSubject(&models.IndividualApplication{
+		FirstName:   "Boushra",
+		LastName:    "El Obeid",
+		Email:       "Boushra+lacpa@Example.com",
+		Phone:       "+961 3 123 456",
+		DateOfBirth: born,
+	})
+
+	tests := []struct {
+		name           string
+		candidate      duplicateSubject
+		wantConfidence float64
+		wantReasons    []string
+	}{
+		{
+			name:           "nothing shared",
+			candidate:      duplicateSubject{name: "rami khoury", emails: []string{"rami@example.com"}},
+			wantConfidence: 0,
+			wantReasons:    []string{},
+		},
+		{
+			name:           "same email after normalization",
+			candidate:      duplicateSubject{name: "lina saab", emails: []string{"boushra@example.com"}},
+			wantConfidence: 0.9,
+			wantReasons:    []string{"Same email address"},
+		},
+		{
+			name:           "same phone written differently",
+			candidate:      individualMemberSubject(&models.IndividualMember{FirstName: "Lina", LastName: "Saab", Phone: "03-123456"}),
+			wantConfidence: 0.7,
+			wantReasons:    []string{"Same phone number"},
+		},
+		{
+			// 1 - (1-0.6)(1-0.5)
+			name:           "same name and birth date",
+			candidate:      duplicateSubject{name: "obeid boushra", dateOfBirth: born},
+			wantConfidence: 0.8,
+			wantReasons:    []string{"Same name", "Same date of birth"},
+		},
+		{
+			name:           "birth date alone does not count",
+			candidate:      duplicateSubject{name: "rami khoury", dateOfBirth: born},
+			wantConfidence: 0,
+			wantReasons:    []string{},
+		},
+		{
+			// 1 - (1-0.9)(1-0.7)(1-0.6)
+			name:           "email, phone and name",
+			candidate:      duplicateSubject{name: "boushra obeid", emails: []string{"boushra@example.com"}, phones: []string{"3123456"}},
+			wantConfidence: 0.99,
+			wantReasons:    []string{"Same email address", "Same phone number", "Same name"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			confidence, reasons := scoreDuplicate(applicant, tt.candidate)
+			if confidence != tt.wantConfidence {
+				t.Fatalf("confidence = %v, want %v", confidence, tt.wantConfidence)
+			}
+			if !reflect.DeepEqual(reasons, tt.wantReasons) {
+				t.Fatalf("reasons = %q, want %q", reasons, tt.wantReasons)
+			}
+		})
+	}
+}
+
+func TestScoreDuplicateSimilarName(t *testing.T) {
+	subject := duplicateSubject{name: "mohammad haddad"}
+	confidence, reasons := scoreDuplicate(subject, duplicateSubject{name: "mohamad haddad"})
+	if len(reasons) != 1 || confidence <= 0.5 || confidence >= duplicateNameWeight {
+		t.Fatalf("confidence = %v, reasons = %q, want one similar-name reason weighted below an exact match", confidence, reasons)
+	}
+}
+
+func TestScoreDuplicateFirmRegistration(t *testing.T) {
+	application := firmApplicationSubject(&models.FirmApplication{FirmName: "Haddad & Partners SAL", RegistrationNumber: "CR-12345"})
+	member := firmMemberSubject(&models.FirmMember{FirmName: "Haddad", RegistrationNumber: "cr 12345"})
+
+	// 1 - (1-0.95)(1-0.6)
+	confidence, reasons := scoreDuplicate(application, member)
+	if confidence != 0.98 || !reflect.DeepEqual(reasons, []string{"Same registration number", "Same name"}) {
+		t.Fatalf("confidence = %v, reasons = %q", confidence, reasons)
+	}
+}
@@ -1,19 +1,25 @@
 package handler
 
 import (
+	"errors"
+	"fmt"
+
 	"github.com/AliSleiman0/Lacpa/models"
 	"github.com/AliSleiman0/Lacpa/repository"
 	"github.com/AliSleiman0/Lacpa/utils"
 	"github.com/gofiber/fiber/v2"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 )
 
 type ApplicationHandler struct {
-	repo repository.ApplicationRepository
+	repo     repository.ApplicationRepository
+	invoices repository.InvoiceRepository
+	audit    *AuditService
 }
 
-func NewApplicationHandler(repo repository.ApplicationRepository) *ApplicationHandler {
-	return &ApplicationHandler{repo: repo}
+func NewApplicationHandler(repo repository.ApplicationRepository, invoices repository.InvoiceRepository, audit *AuditService) *ApplicationHandler {
+	return &ApplicationHandler{repo: repo, invoices: invoices, audit: audit}
 }
 
 // GetApplyNowPage renders the application page with requirements
@@ -65,7 +71,9 @@ func (h *ApplicationHandler) GetApplyFirmPage(c *fiber.Ctx) error {
 
 	// Render the firm application form template
 	return c.Render("LACPA/membership/apply_firm", fiber.Map{
-		"Title": "Apply to Firm - LACPA",
+		"Title":           "Apply to Firm - LACPA",
+		"ApplicationType": models.ApplicationTypeFirm,
+		"DocumentSlots":   h.documentSlots(c, models.ApplicationTypeFirm),
 	})
 }
 
@@ -77,12 +85,25 @@ func (h *ApplicationHandler) GetApplyIndividualPage(c *fiber.Ctx) error {
 		return c.SendFile("../LACPA_Web/src/index.html")
 	}
 
-	// Render the individual application form template (to be created)
+	// Render the individual application form template
 	return c.Render("LACPA/membership/apply_individual", fiber.Map{
-		"Title": "Apply as Individual - LACPA",
+		"Title":           "Apply as Individual - LACPA",
+		"ApplicationType": models.ApplicationTypeIndividual,
+		"DocumentSlots":   h.documentSlots(c, models.ApplicationTypeIndividual),
 	})
 }
 
+// documentSlots returns the uploads the requirements of an application type ask for.
+// The form is still shown without upload fields if the requirements can't be loaded.
+func (h *ApplicationHandler) documentSlots(c *fiber.Ctx, appType models.ApplicationType) []models.DocumentSlot {
+	requirements, err := h.repo.GetRequirementsByType(c.Context(), appType)
+	if err != nil {
+		fmt.Printf("Failed to load %s requirements: %v\n", appType, err)
+		return []models.DocumentSlot{}
+	}
+	return models.DocumentSlots(requirements)
+}
+
 // SubmitIndividualApplication handles individual membership application submission
 func (h *ApplicationHandler) SubmitIndividualApplication(c *fiber.Ctx) error {
 	var application models.IndividualApplication
@@ -90,16 +111,8 @@ func (h *ApplicationHandler) SubmitIndividualApplication(c *fiber.Ctx) error {
 		return utils.SendBadRequest(c, "Invalid request body")
 	}
 
-	// Validate required fields
-	if application.FirstName == "" || application.LastName == "" {
-		return utils.SendBadRequest(c, "First name and last name are required")
-	}
-	if application.Email == "" || application.Phone == "" {
-		return utils.SendBadRequest(c, "Email and phone are required")
-	}
-
-	if err := h.repo.CreateIndividualApplication(c.Context(), &application); err != nil {
-		return utils.SendInternalError(c, err.Error())
+	if created, err := h.createIndividualApplication(c, &application, submitterID(c)); !created {
+		return err
 	}
 
 	return utils.SendCreated(c, application, "Application submitted successfully", "Your application has been received and is under review.")
@@ -112,100 +125,198 @@ func (h *ApplicationHandler) SubmitFirmApplication(c *fiber.Ctx) error {
 		return utils.SendBadRequest(c, "Invalid request body")
 	}
 
-	// Validate required fields
-	if application.FirmName == "" {
-		return utils.SendBadRequest(c, "Firm name is required")
+	if created, err := h.createFirmApplication(c, &application, submitterID(c)); !created {
+		return err
 	}
-	if application.Email == "" || application.Phone == "" {
-		return utils.SendBadRequest(c, "Email and phone are required")
+
+	return utils.SendCreated(c, application, "Application submitted successfully", "Your application has been received and is under review.")
+}
+
+// createIndividualApplication validates and stores a submitted individual application
+// and attaches its documents. When it returns false the error response has already been sent.
+func (h *ApplicationHandler) createIndividualApplication(c *fiber.Ctx, application *models.IndividualApplication, userID *primitive.ObjectID) (bool, error) {
+	documentIDs, ve, err := h.validateApplication(c, models.ApplicationTypeIndividual, application, application.DocumentReferences())
+	if err != nil {
+		return false, utils.SendInternalError(c, err.Error())
 	}
-	if application.RepresentativeName == "" {
-		return utils.SendBadRequest(c, "Representative name is required")
+	if ve.HasErrors() {
+		return false, sendApplicationErrors(c, ve)
 	}
 
-	if err := h.repo.CreateFirmApplication(c.Context(), &application); err != nil {
-		return utils.SendInternalError(c, err.Error())
+	application.UserID = userID
+	application.PossibleDuplicates = h.submissionDuplicates(c, models.ApplicationTypeIndividual, individualApplicationSubject(application))
+
+	if err := h.repo.CreateIndividualApplication(c.Context(), application); err != nil {
+		return false, utils.SendInternalError(c, err.Error())
+	}
+	if err := h.repo.AttachDocuments(c.Context(), documentIDs, application.ID); err != nil {
+		return false, utils.SendInternalError(c, err.Error())
 	}
+	h.issueInvoice(c, individualBilling(application))
 
-	return utils.SendCreated(c, application, "Application submitted successfully", "Your application has been received and is under review.")
+	// Matches name other people's records and are only shown to reviewers
+	application.PossibleDuplicates = nil
+	return true, nil
 }
 
-// GetAllIndividualApplications retrieves all individual applications
-func (h *ApplicationHandler) GetAllIndividualApplications(c *fiber.Ctx) error {
-	applications, err := h.repo.GetAllIndividualApplications(c.Context())
+// createFirmApplication validates and stores a submitted firm application
+// and attaches its documents. When it returns false the error response has already been sent.
+func (h *ApplicationHandler) createFirmApplication(c *fiber.Ctx, application *models.FirmApplication, userID *primitive.ObjectID) (bool, error) {
+	documentIDs, ve, err := h.validateApplication(c, models.ApplicationTypeFirm, application, application.DocumentReferences())
 	if err != nil {
-		return utils.SendInternalError(c, err.Error())
+		return false, utils.SendInternalError(c, err.Error())
+	}
+	if ve.HasErrors() {
+		return false, sendApplicationErrors(c, ve)
 	}
 
-	return utils.SendSuccess(c, "Individual applications retrieved successfully", applications)
-}
+	application.UserID = userID
+	application.PossibleDuplicates = h.submissionDuplicates(c, models.ApplicationTypeFirm, firmApplicationSubject(application))
 
-// GetAllFirmApplications retrieves all firm applications
-func (h *ApplicationHandler) GetAllFirmApplications(c *fiber.Ctx) error {
-	applications, err := h.repo.GetAllFirmApplications(c.Context())
-	if err != nil {
-		return utils.SendInternalError(c, err.Error())
+	if err := h.repo.CreateFirmApplication(c.Context(), application); err != nil {
+		return false, utils.SendInternalError(c, err.Error())
 	}
+	if err := h.repo.AttachDocuments(c.Context(), documentIDs, application.ID); err != nil {
+		return false, utils.SendInternalError(c, err.Error())
+	}
+	h.issueInvoice(c, firmBilling(application))
 
-	return utils.SendSuccess(c, "Firm applications retrieved successfully", applications)
+	// Matches name other people's records and are only shown to reviewers
+	application.PossibleDuplicates = nil
+	return true, nil
 }
 
-// UpdateIndividualApplicationStatus updates the status of an individual application
+// UpdateIndividualApplicationStatus moves an individual application to its next review status.
+// The reviewer is the authenticated user; the body only carries the status and notes.
 func (h *ApplicationHandler) UpdateIndividualApplicationStatus(c *fiber.Ctx) error {
 	id, err := primitive.ObjectIDFromHex(c.Params("id"))
 	if err != nil {
 		return utils.SendBadRequest(c, "Invalid application ID")
 	}
 
-	type StatusUpdate struct {
-		Status      models.ApplicationStatus `json:"status"`
-		ReviewNotes string                   `json:"review_notes"`
-		ReviewedBy  string                   `json:"reviewed_by"`
+	update, reviewedBy, err := parseStatusUpdate(c)
+	if err != nil {
+		return utils.SendBadRequest(c, err.Error())
 	}
 
-	var update StatusUpdate
-	if err := c.BodyParser(&update); err != nil {
-		return utils.SendBadRequest(c, "Invalid request body")
+	before, err := h.repo.GetIndividualApplicationByID(c.Context(), id)
+	if err == mongo.ErrNoDocuments {
+		return utils.SendNotFound(c, "Application")
 	}
-
-	reviewedBy, err := primitive.ObjectIDFromHex(update.ReviewedBy)
 	if err != nil {
-		return utils.SendBadRequest(c, "Invalid reviewer ID")
+		return utils.SendInternalError(c, err.Error())
 	}
 
-	if err := h.repo.UpdateIndividualApplicationStatus(c.Context(), id, update.Status, update.ReviewNotes, reviewedBy); err != nil {
-		return utils.SendInternalError(c, err.Error())
+	if update.MemberType != "" && !models.IsValidIndividualMemberType(update.MemberType) {
+		return utils.SendBadRequest(c, "Invalid member type")
 	}
 
-	return utils.SendSuccess(c, "Application status updated successfully", nil)
+	// Approval also creates the member record
+	if update.Status == models.ApplicationStatusApproved {
+		member, err := h.repo.ApproveIndividualApplication(c.Context(), id, update.ReviewNotes, reviewedBy, update.MemberType)
+		if err != nil {
+			return sendTransitionError(c, err)
+		}
+		h.audit.Record(c, models.AuditMemberCreated, models.AuditTargetIndividualMember, member.ID.Hex(), nil, member)
+	} else if err := h.repo.UpdateIndividualApplicationStatus(c.Context(), id, update.Status, update.ReviewNotes, reviewedBy); err != nil {
+		return sendTransitionError(c, err)
+	}
+
+	after, _ := h.repo.GetIndividualApplicationByID(c.Context(), id)
+	h.audit.Record(c, models.AuditApplicationStatus, models.AuditTargetIndividualApp, id.Hex(), before, after)
+
+	return utils.SendSuccess(c, "Application status updated successfully", after)
 }
 
-// UpdateFirmApplicationStatus updates the status of a firm application
+// UpdateFirmApplicationStatus moves a firm application to its next review status
 func (h *ApplicationHandler) UpdateFirmApplicationStatus(c *fiber.Ctx) error {
 	id, err := primitive.ObjectIDFromHex(c.Params("id"))
 	if err != nil {
 		return utils.SendBadRequest(c, "Invalid application ID")
 	}
 
-	type StatusUpdate struct {
-		Status      models.ApplicationStatus `json:"status"`
-		ReviewNotes string                   `json:"review_notes"`
-		ReviewedBy  string                   `json:"reviewed_by"`
+	update, reviewedBy, err := parseStatusUpdate(c)
+	if err != nil {
+		return utils.SendBadRequest(c, err.Error())
 	}
 
-	var update StatusUpdate
+	before, err := h.repo.GetFirmApplicationByID(c.Context(), id)
+	if err == mongo.ErrNoDocuments {
+		return utils.SendNotFound(c, "Application")
+	}
+	if err != nil {
+		return utils.SendInternalError(c, err.Error())
+	}
+
+	// Approval also creates the firm member record
+	if update.Status == models.ApplicationStatusApproved {
+		firm, err := h.repo.ApproveFirmApplication(c.Context(), id, update.ReviewNotes, reviewedBy)
+		if err != nil {
+			return sendTransitionError(c, err)
+		}
+		h.audit.Record(c, models.AuditMemberCreated, models.AuditTargetFirmMember, firm.ID.Hex(), nil, firm)
+	} else if err := h.repo.UpdateFirmApplicationStatus(c.Context(), id, update.Status, update.ReviewNotes, reviewedBy); err != nil {
+		return sendTransitionError(c, err)
+	}
+
+	after, _ := h.repo.GetFirmApplicationByID(c.Context(), id)
+	h.audit.Record(c, models.AuditApplicationStatus, models.AuditTargetFirmApp, id.Hex(), before, after)
+
+	return utils.SendSuccess(c, "Application status updated successfully", after)
+}
+
+// issueInvoice bills a new application its fee. A failure does not fail the submission;
+// finance staff can issue the invoice later.
+func (h *ApplicationHandler) issueInvoice(c *fiber.Ctx, billing invoiceBilling) {
+	if _, err := issueApplicationInvoice(c.Context(), h.invoices, billing); err != nil {
+		fmt.Printf("Failed to issue invoice for application %s: %v\n", billing.reference, err)
+	}
+}
+
+// parseStatusUpdate reads a status change and takes the reviewer from the JWT
+func parseStatusUpdate(c *fiber.Ctx) (*models.ApplicationStatusUpdateRequest, primitive.ObjectID, error) {
+	var update models.ApplicationStatusUpdateRequest
 	if err := c.BodyParser(&update); err != nil {
-		return utils.SendBadRequest(c, "Invalid request body")
+		return nil, primitive.NilObjectID, errors.New("Invalid request body")
+	}
+	if !update.Status.IsValid() {
+		return nil, primitive.NilObjectID, errors.New("Invalid application status")
+	}
+	switch update.Status {
+	case models.ApplicationStatusWithdrawn:
+		return nil, primitive.NilObjectID, errors.New("Only the applicant can withdraw an application")
+	case models.ApplicationStatusResubmitted:
+		return nil, primitive.NilObjectID, errors.New("Only the applicant can resubmit an application")
 	}
 
-	reviewedBy, err := primitive.ObjectIDFromHex(update.ReviewedBy)
+	userID, _ := c.Locals("userID").(string)
+	reviewedBy, err := primitive.ObjectIDFromHex(userID)
 	if err != nil {
-		return utils.SendBadRequest(c, "Invalid reviewer ID")
+		return nil, primitive.NilObjectID, errors.New("Invalid reviewer")
 	}
 
-	if err := h.repo.UpdateFirmApplicationStatus(c.Context(), id, update.Status, update.ReviewNotes, reviewedBy); err != nil {
-		return utils.SendInternalError(c, err.Error())
+	return &update, reviewedBy, nil
+}
+
+// submitterID returns the signed-in account submitting an application, if any,
+// so the applicant can find it again from their account
+func submitterID(c *fiber.Ctx) *primitive.ObjectID {
+	userID, _ := c.Locals("userID").(string)
+	id, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil
 	}
+	return &id
+}
 
-	return utils.SendSuccess(c, "Application status updated successfully", nil)
+// sendTransitionError maps a failed status change to a response
+func sendTransitionError(c *fiber.Ctx, err error) error {
+	switch {
+	case err == mongo.ErrNoDocuments:
+		return utils.SendNotFound(c, "Application")
+	case errors.Is(err, repository.ErrInvalidStatusTransition), errors.Is(err, repository.ErrQuorumNotMet), errors.Is(err, repository.ErrFeeUnpaid), err == repository.ErrStatusConflict:
+		return utils.SendError(c, fiber.StatusConflict, err.Error())
+	default:
+		return utils.SendInternalError(c, err.Error())
+	}
 }
@@ -0,0 +1,100 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/AliSleiman0/Lacpa/models"
+	"github.com/AliSleiman0/Lacpa/repository"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// feeGatedApplicationRepo refuses the move to Under Review like the repository does
+// while the application's invoice is unpaid
+type feeGatedApplicationRepo struct {
+	repository.ApplicationRepository
+	status models.ApplicationStatus
+	unpaid bool
+}
+
+func (r *feeGatedApplicationRepo) GetIndividualApplicationByID(ctx context.Context, id primitive.ObjectID) (*models.IndividualApplication, error) {
+	return &models.IndividualApplication{ID: id, Status: r.status}, nil
+}
+
+func (r *feeGatedApplicationRepo) GetFirmApplicationByID(ctx context.Context, id primitive.ObjectID) (*models.FirmApplication, error) {
+	return &models.FirmApplication{ID: id, Status: r.status}, nil
+}
+
+func (r *feeGatedApplicationRepo) UpdateIndividualApplicationStatus(ctx context.Context, id primitive.ObjectID, status models.ApplicationStatus, notes string, reviewedBy primitive.ObjectID) error {
+	return r.transition(status)
+}
+
+func (r *feeGatedApplicationRepo) UpdateFirmApplicationStatus(ctx context.Context, id primitive.ObjectID, status models.ApplicationStatus, notes string, reviewedBy primitive.ObjectID) error {
+	return r.transition(status)
+}
+
+func (r *feeGatedApplicationRepo) transition(status models.ApplicationStatus) error {
+	if status == models.ApplicationStatusUnderReview && r.unpaid {
+		return fmt.Errorf("%w: invoice INV-2025-00001 is unpaid", repository.ErrFeeUnpaid)
+	}
+	r.status = status
+	return nil
+}
+
+func TestUnderReviewRequiresPaidFee(t *testing.T) {
+	tests := []struct {
+		name   string
+		path   string
+		unpaid bool
+		status int
+	}{
+		{name: "individual unpaid", path: "individual", unpaid: true, status: fiber.StatusConflict},
+		{name: "individual paid", path: "individual", status: fiber.StatusOK},
+		{name: "firm unpaid", path: "firm", unpaid: true, status: fiber.StatusConflict},
+		{name: "firm paid", path: "firm", status: fiber.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &feeGatedApplicationRepo{status: models.ApplicationStatusPending, unpaid: tt.unpaid}
+			h := NewApplicationHandler(repo, nil, nil)
+
+			app := fiber.New()
+			app.Use(func(c *fiber.Ctx) error {
+				c.Locals("userID", primitive.NewObjectID().Hex())
+				return c.Next()
+			})
+			app.Put("/api/applications/individual/:id/status", h.UpdateIndividualApplicationStatus)
+			app.Put("/api/applications/firm/:id/status", h.UpdateFirmApplicationStatus)
+
+			body := `{"status":"` + string(models.ApplicationStatusUnderReview) + `"}`
+			req := httptest.NewRequest(fiber.MethodPut, "/api/applications/"+tt.path+"/"+primitive.NewObjectID().Hex()+"/status", strings.NewReader(body))
+			req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatalf("request failed: %v", err)
+			}
+			if resp.StatusCode != tt.status {
+				t.Fatalf("status = %d, want %d", resp.StatusCode, tt.status)
+			}
+
+			if tt.unpaid {
+				var response struct {
+					Error string `json:"error"`
+				}
+				json.NewDecoder(resp.Body).Decode(&response)
+				if !strings.Contains(response.Error, "fee must be paid") {
+					t.Fatalf("error = %q, want the unpaid fee explained", response.Error)
+				}
+				if repo.status != models.ApplicationStatusPending {
+					t.Fatalf("application moved to %s", repo.status)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,138 @@
+package handler
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/AliSleiman0/Lacpa/models"
+	"github.com/AliSleiman0/Lacpa/utils"
+	"github.com/gofiber/fiber/v2"
+)
+
+// ListIndividualApplications handles GET /api/applications/individual.
+// It takes the filters of parseApplicationFilter plus page and page_size.
+func (h *ApplicationHandler) ListIndividualApplications(c *fiber.Ctx) error {
+	filter, page, pageSize, err := parseApplicationFilter(c)
+	if err != nil {
+		return utils.SendBadRequest(c, err.Error())
+	}
+
+	applications, total, err := h.repo.ListIndividualApplications(c.Context(), filter)
+	if err != nil {
+		return utils.SendInternalError(c, err.Error())
+	}
+	_, _, meta := utils.Paginate(page, pageSize, int(total))
+
+	return utils.SendSuccess(c, "Individual applications retrieved successfully", fiber.Map{
+		"applications": applications,
+		"pagination":   meta,
+	})
+}
+
+// ListFirmApplications handles GET /api/applications/firm with the same filters
+func (h *ApplicationHandler) ListFirmApplications(c *fiber.Ctx) error {
+	filter, page, pageSize, err := parseApplicationFilter(c)
+	if err != nil {
+		return utils.SendBadRequest(c, err.Error())
+	}
+
+	applications, total, err := h.repo.ListFirmApplications(c.Context(), filter)
+	if err != nil {
+		return utils.SendInternalError(c, err.Error())
+	}
+	_, _, meta := utils.Paginate(page, pageSize, int(total))
+
+	return utils.SendSuccess(c, "Firm applications retrieved successfully", fiber.Map{
+		"applications": applications,
+		"pagination":   meta,
+	})
+}
+
+// ListApplications handles GET /api/applications, the reviewer dashboard list.
+// Individual and firm applications are listed together unless type is given, and the
+// response carries the number of applications in each status for the other filters.
+func (h *ApplicationHandler) ListApplications(c *fiber.Ctx) error {
+	filter, page, pageSize, err := parseApplicationFilter(c)
+	if err != nil {
+		return utils.SendBadRequest(c, err.Error())
+	}
+	switch filter.Type = models.ApplicationType(c.Query("type")); filter.Type {
+	case "", models.ApplicationTypeIndividual, models.ApplicationTypeFirm:
+	default:
+		return utils.SendBadRequest(c, "Invalid application type")
+	}
+
+	applications, total, err := h.repo.ListApplications(c.Context(), filter)
+	if err != nil {
+		return utils.SendInternalError(c, err.Error())
+	}
+	counts, err := h.repo.CountApplicationsByStatus(c.Context(), filter)
+	if err != nil {
+		return utils.SendInternalError(c, err.Error())
+	}
+	_, _, meta := utils.Paginate(page, pageSize, int(total))
+
+	return utils.SendSuccess(c, "Applications retrieved successfully", fiber.Map{
+		"applications":  applications,
+		"pagination":    meta,
+		"status_counts": counts,
+	})
+}
+
+// parseApplicationFilter reads the reviewer list filters from the query string:
+//   - status: one or more statuses, comma separated
+//   - from, to: submission date range (RFC 3339 or YYYY-MM-DD)
+//   - city: exact city, ignoring case
+//   - q: full-text search on names and emails
+//   - sort: one of models.ApplicationSortFields, prefixed with "-" for descending;
+//     newest submissions first by default
+//   - page, page_size
+func parseApplicationFilter(c *fiber.Ctx) (models.ApplicationFilter, int, int, error) {
+	filter := models.ApplicationFilter{
+		City:   strings.TrimSpace(c.Query("city")),
+		Search: strings.TrimSpace(c.Query("q")),
+	}
+
+	for _, value := range strings.Split(c.Query("status"), ",") {
+		if value = strings.TrimSpace(value); value == "" {
+			continue
+		}
+		status := models.ApplicationStatus(value)
+		if !status.IsValid() {
+			return filter, 0, 0, fmt.Errorf("invalid status %q", value)
+		}
+		filter.Statuses = append(filter.Statuses, status)
+	}
+
+	if value := c.Query("from"); value != "" {
+		from, err := parseQueryTime(value, false)
+		if err != nil {
+			return filter, 0, 0, fmt.Errorf("invalid from date: use RFC 3339 or YYYY-MM-DD")
+		}
+		filter.From = &from
+	}
+	if value := c.Query("to"); value != "" {
+		to, err := parseQueryTime(value, true)
+		if err != nil {
+			return filter, 0, 0, fmt.Errorf("invalid to date: use RFC 3339 or YYYY-MM-DD")
+		}
+		filter.To = &to
+	}
+	if filter.From != nil && filter.To != nil && filter.To.Before(*filter.From) {
+		return filter, 0, 0, fmt.Errorf("to date must not be before from date")
+	}
+
+	sort := c.Query("sort", "-submitted_at")
+	filter.Descending = strings.HasPrefix(sort, "-")
+	filter.SortBy = strings.TrimPrefix(sort, "-")
+	if !containsValue(models.ApplicationSortFields, filter.SortBy) {
+		return filter, 0, 0, fmt.Errorf("invalid sort: use one of %s", strings.Join(models.ApplicationSortFields, ", "))
+	}
+
+	// Paginate normalizes page and page_size; the metadata is rebuilt once the total is known
+	page := c.QueryInt("page", 1)
+	pageSize := c.QueryInt("page_size", 20)
+	filter.Offset, filter.Limit, _ = utils.Paginate(page, pageSize, 0)
+
+	return filter, page, pageSize, nil
+}
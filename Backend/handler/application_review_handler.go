@@ -0,0 +1,482 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/AliSleiman0/Lacpa/models"
+	"github.com/AliSleiman0/Lacpa/repository"
+	"github.com/AliSleiman0/Lacpa/utils"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// mentionPattern finds staff mentioned in a comment by LACPA ID, e.g. @LACPA-A-2025-00001
+var mentionPattern = regexp.MustCompile(`@(LACPA(?:-[A-Z])?-\d{4}-\d{5})\b`)
+
+// ReviewHandler runs the multi-reviewer sign-off of membership applications.
+//
+// ROLE: Application Review Workflow
+//   - Staff who can recommend on applications are assigned per application for an area:
+//     credentials, finance or the committee vote
+//   - Assigned reviewers each recommend approve, reject or abstain; a new
+//     recommendation replaces their previous one until the application is decided
+//   - Reviewers discuss the application in internal comment threads and mention
+//     each other as @LACPA-ID; mentioned staff are notified by email
+//   - The quorum rule of the application type decides when the recommendations
+//     allow the approval transition (see models.QuorumRule)
+type ReviewHandler struct {
+	repo  repository.ApplicationRepository
+	users *repository.AuthRepository
+	roles *repository.RoleRepository
+	audit *AuditService
+}
+
+func NewReviewHandler(repo repository.ApplicationRepository, users *repository.AuthRepository, roles *repository.RoleRepository, audit *AuditService) *ReviewHandler {
+	return &ReviewHandler{repo: repo, users: users, roles: roles, audit: audit}
+}
+
+// GetReview handles GET /api/applications/:type/:id/review, the reviewers,
+// their recommendations and how far the application is from its quorum
+func (h *ReviewHandler) GetReview(c *fiber.Ctx) error {
+	appType, id, ok := reviewParams(c)
+	if !ok {
+		return utils.SendNotFound(c, "Application")
+	}
+	review, err := loadApplicationReview(c.Context(), h.repo, appType, id)
+	if err == mongo.ErrNoDocuments {
+		return utils.SendNotFound(c, "Application")
+	}
+	if err != nil {
+		return utils.SendInternalError(c, err.Error())
+	}
+	return utils.SendSuccess(c, "Review retrieved successfully", review)
+}
+
+// AssignReviewer handles POST /api/applications/:type/:id/reviewers.
+// The reviewer must be an active user whose role can read and recommend on applications.
+func (h *ReviewHandler) AssignReviewer(c *fiber.Ctx) error {
+	appType, id, ok := reviewParams(c)
+	if !ok {
+		return utils.SendNotFound(c, "Application")
+	}
+
+	var req models.AssignReviewerRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.SendBadRequest(c, "Invalid request body")
+	}
+	if err := utils.ValidateStruct(req); err != nil {
+		if ve, ok := err.(*utils.ValidationErrors); ok {
+			return utils.SendValidationErrors(c, ve)
+		}
+		return utils.SendBadRequest(c, err.Error())
+	}
+	userID, err := primitive.ObjectIDFromHex(req.UserID)
+	if err != nil {
+		return utils.SendBadRequest(c, "Invalid user ID")
+	}
+	reviewer, err := h.users.GetUserByID(userID)
+	if err == mongo.ErrNoDocuments {
+		return utils.SendNotFound(c, "User")
+	}
+	if err != nil {
+		return utils.SendInternalError(c, err.Error())
+	}
+	if !h.canReview(c.Context(), reviewer) {
+		return utils.SendBadRequest(c, "This user cannot review applications")
+	}
+
+	before, err := h.repo.GetApplicationReview(c.Context(), appType, id)
+	if err == mongo.ErrNoDocuments {
+		return utils.SendNotFound(c, "Application")
+	}
+	if err != nil {
+		return utils.SendInternalError(c, err.Error())
+	}
+
+	assignment := models.ReviewerAssignment{
+		UserID:     reviewer.ID,
+		Name:       reviewer.FullName,
+		Area:       req.Area,
+		AssignedBy: submitterID(c),
+	}
+	if err := h.repo.AssignReviewer(c.Context(), appType, id, assignment); err != nil {
+		return sendReviewError(c, err)
+	}
+
+	after, err := loadApplicationReview(c.Context(), h.repo, appType, id)
+	if err != nil {
+		return utils.SendInternalError(c, err.Error())
+	}
+	h.audit.Record(c, models.AuditReviewerAssigned, applicationAuditTarget(appType), id.Hex(),
+		fiber.Map{"reviewers": before.Reviewers}, fiber.Map{"reviewers": after.Reviewers})
+
+	notifyStaff(reviewer, "You were assigned to review a LACPA application",
+		fmt.Sprintf("You were assigned as the %s reviewer of a %s membership application.", req.Area, strings.ToLower(string(appType))))
+
+	return utils.SendCreated(c, after, "")
+}
+
+// RemoveReviewer handles DELETE /api/applications/:type/:id/reviewers/:userId.
+// The reviewer's recommendation is removed with them.
+func (h *ReviewHandler) RemoveReviewer(c *fiber.Ctx) error {
+	appType, id, ok := reviewParams(c)
+	if !ok {
+		return utils.SendNotFound(c, "Application")
+	}
+	reviewerID, err := primitive.ObjectIDFromHex(c.Params("userId"))
+	if err != nil {
+		return utils.SendBadRequest(c, "Invalid user ID")
+	}
+
+	before, err := h.repo.GetApplicationReview(c.Context(), appType, id)
+	if err == mongo.ErrNoDocuments {
+		return utils.SendNotFound(c, "Application")
+	}
+	if err != nil {
+		return utils.SendInternalError(c, err.Error())
+	}
+	if err := h.repo.RemoveReviewer(c.Context(), appType, id, reviewerID); err != nil {
+		return sendReviewError(c, err)
+	}
+
+	after, err := loadApplicationReview(c.Context(), h.repo, appType, id)
+	if err != nil {
+		return utils.SendInternalError(c, err.Error())
+	}
+	h.audit.Record(c, models.AuditReviewerRemoved, applicationAuditTarget(appType), id.Hex(),
+		fiber.Map{"reviewers": before.Reviewers, "recommendations": before.Recommendations},
+		fiber.Map{"reviewers": after.Reviewers, "recommendations": after.Recommendations})
+
+	return utils.SendSuccess(c, "Reviewer removed", after)
+}
+
+// Recommend handles PUT /api/applications/:type/:id/recommendation, the current user's
+// recommendation as an assigned reviewer
+func (h *ReviewHandler) Recommend(c *fiber.Ctx) error {
+	appType, id, ok := reviewParams(c)
+	if !ok {
+		return utils.SendNotFound(c, "Application")
+	}
+	reviewerID := submitterID(c)
+	if reviewerID == nil {
+		return utils.SendError(c, fiber.StatusUnauthorized, "Authentication required")
+	}
+
+	var req models.RecommendationRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.SendBadRequest(c, "Invalid request body")
+	}
+	req.Notes = strings.TrimSpace(req.Notes)
+	if err := utils.ValidateStruct(req); err != nil {
+		if ve, ok := err.(*utils.ValidationErrors); ok {
+			return utils.SendValidationErrors(c, ve)
+		}
+		return utils.SendBadRequest(c, err.Error())
+	}
+
+	before, err := h.repo.GetApplicationReview(c.Context(), appType, id)
+	if err == mongo.ErrNoDocuments {
+		return utils.SendNotFound(c, "Application")
+	}
+	if err != nil {
+		return utils.SendInternalError(c, err.Error())
+	}
+	var area models.ReviewArea
+	for _, reviewer := range before.Reviewers {
+		if reviewer.UserID == *reviewerID {
+			area = reviewer.Area
+		}
+	}
+	if area == "" {
+		return utils.SendError(c, fiber.StatusForbidden, "Only assigned reviewers can recommend")
+	}
+
+	recommendation := models.ReviewRecommendation{
+		ReviewerID: *reviewerID,
+		Area:       area,
+		Decision:   req.Decision,
+		Notes:      req.Notes,
+	}
+	if err := h.repo.RecordRecommendation(c.Context(), appType, id, recommendation); err != nil {
+		if err == repository.ErrNotAssignedReviewer {
+			return utils.SendError(c, fiber.StatusForbidden, "Only assigned reviewers can recommend")
+		}
+		return sendReviewError(c, err)
+	}
+
+	after, err := loadApplicationReview(c.Context(), h.repo, appType, id)
+	if err != nil {
+		return utils.SendInternalError(c, err.Error())
+	}
+	h.audit.Record(c, models.AuditRecommendation, applicationAuditTarget(appType), id.Hex(),
+		fiber.Map{"recommendations": before.Recommendations}, fiber.Map{"recommendations": after.Recommendations})
+
+	return utils.SendSuccess(c, "Recommendation recorded", after)
+}
+
+// ListComments handles GET /api/applications/:type/:id/comments, the comment threads oldest first
+func (h *ReviewHandler) ListComments(c *fiber.Ctx) error {
+	appType, id, ok := reviewParams(c)
+	if !ok {
+		return utils.SendNotFound(c, "Application")
+	}
+	if _, err := h.repo.GetApplicationReview(c.Context(), appType, id); err == mongo.ErrNoDocuments {
+		return utils.SendNotFound(c, "Application")
+	} else if err != nil {
+		return utils.SendInternalError(c, err.Error())
+	}
+
+	comments, err := h.repo.GetComments(c.Context(), id)
+	if err != nil {
+		return utils.SendInternalError(c, err.Error())
+	}
+	return utils.SendSuccess(c, "Comments retrieved successfully", models.ThreadComments(comments))
+}
+
+// AddComment handles POST /api/applications/:type/:id/comments.
+// A reply to a reply joins the thread of the comment it answers.
+func (h *ReviewHandler) AddComment(c *fiber.Ctx) error {
+	appType, id, ok := reviewParams(c)
+	if !ok {
+		return utils.SendNotFound(c, "Application")
+	}
+	authorID := submitterID(c)
+	if authorID == nil {
+		return utils.SendError(c, fiber.StatusUnauthorized, "Authentication required")
+	}
+
+	var req models.CommentRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.SendBadRequest(c, "Invalid request body")
+	}
+	req.Body = strings.TrimSpace(req.Body)
+	if err := utils.ValidateStruct(req); err != nil {
+		if ve, ok := err.(*utils.ValidationErrors); ok {
+			return utils.SendValidationErrors(c, ve)
+		}
+		return utils.SendBadRequest(c, err.Error())
+	}
+
+	if _, err := h.repo.GetApplicationReview(c.Context(), appType, id); err == mongo.ErrNoDocuments {
+		return utils.SendNotFound(c, "Application")
+	} else if err != nil {
+		return utils.SendInternalError(c, err.Error())
+	}
+
+	comment := &models.ApplicationComment{
+		ApplicationType: appType,
+		ApplicationID:   id,
+		AuthorID:        *authorID,
+		Body:            req.Body,
+	}
+	if req.ParentID != "" {
+		parentID, err := primitive.ObjectIDFromHex(req.ParentID)
+		if err != nil {
+			return utils.SendBadRequest(c, "Invalid parent comment ID")
+		}
+		parent, err := h.repo.GetCommentByID(c.Context(), parentID)
+		if err == mongo.ErrNoDocuments || (err == nil && parent.ApplicationID != id) {
+			return utils.SendNotFound(c, "Comment")
+		}
+		if err != nil {
+			return utils.SendInternalError(c, err.Error())
+		}
+		comment.ParentID = &parent.ID
+		if parent.ParentID != nil {
+			comment.ParentID = parent.ParentID
+		}
+	}
+
+	author, err := h.users.GetUserByID(*authorID)
+	if err != nil {
+		return utils.SendInternalError(c, err.Error())
+	}
+	comment.AuthorName = author.FullName
+
+	mentioned := h.mentionedStaff(c.Context(), comment.Body, *authorID)
+	for _, user := range mentioned {
+		comment.Mentions = append(comment.Mentions, user.ID)
+	}
+	if err := h.repo.CreateComment(c.Context(), comment); err != nil {
+		return utils.SendInternalError(c, err.Error())
+	}
+
+	for _, user := range mentioned {
+		notifyStaff(user, "You were mentioned on a LACPA application",
+			fmt.Sprintf("%s mentioned you in a comment on a %s membership application: \"%s\"",
+				author.FullName, strings.ToLower(string(appType)), comment.Body))
+	}
+
+	return utils.SendCreated(c, comment, "")
+}
+
+// ListQuorumRules handles GET /api/admin/quorum-rules, the rule in force for each
+// application type including the default of types without a stored rule
+func (h *ReviewHandler) ListQuorumRules(c *fiber.Ctx) error {
+	rules := make([]*models.QuorumRule, 0, 2)
+	for _, appType := range []models.ApplicationType{models.ApplicationTypeIndividual, models.ApplicationTypeFirm} {
+		rule, err := quorumRule(c.Context(), h.repo, appType)
+		if err != nil {
+			return utils.SendInternalError(c, err.Error())
+		}
+		rules = append(rules, rule)
+	}
+	return utils.SendSuccess(c, "Quorum rules retrieved successfully", rules)
+}
+
+// UpdateQuorumRule handles PUT /api/admin/quorum-rules/:type.
+// The rule applies to the next approval of every undecided application of the type.
+func (h *ReviewHandler) UpdateQuorumRule(c *fiber.Ctx) error {
+	appType, ok := trackedType(c.Params("type"))
+	if !ok {
+		return utils.SendNotFound(c, "Application type")
+	}
+
+	var req models.QuorumRuleRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.SendBadRequest(c, "Invalid request body")
+	}
+	if err := utils.ValidateStruct(req); err != nil {
+		if ve, ok := err.(*utils.ValidationErrors); ok {
+			return utils.SendValidationErrors(c, ve)
+		}
+		return utils.SendBadRequest(c, err.Error())
+	}
+	areas := []models.ReviewArea{}
+	seen := map[models.ReviewArea]bool{}
+	for _, area := range req.RequiredAreas {
+		if !area.IsValid() {
+			return utils.SendBadRequest(c, fmt.Sprintf("Invalid review area %q", area))
+		}
+		if !seen[area] {
+			seen[area] = true
+			areas = append(areas, area)
+		}
+	}
+
+	before, err := quorumRule(c.Context(), h.repo, appType)
+	if err != nil {
+		return utils.SendInternalError(c, err.Error())
+	}
+
+	rule := &models.QuorumRule{
+		ApplicationType:    appType,
+		MinApprovals:       req.MinApprovals,
+		RequiredAreas:      areas,
+		RequireAllAssigned: req.RequireAllAssigned,
+		AllowRejections:    req.AllowRejections,
+		UpdatedBy:          submitterID(c),
+	}
+	if err := h.repo.SaveQuorumRule(c.Context(), rule); err != nil {
+		return utils.SendInternalError(c, err.Error())
+	}
+	h.audit.Record(c, models.AuditQuorumRuleUpdated, models.AuditTargetQuorumRule, rule.ID.Hex(), before, rule)
+
+	return utils.SendSuccess(c, "Quorum rule updated successfully", rule)
+}
+
+// canReview reports whether a user is active and their role can read and recommend on applications
+func (h *ReviewHandler) canReview(ctx context.Context, user *models.User) bool {
+	if !user.IsActive {
+		return false
+	}
+	role, err := h.roles.GetRoleByName(ctx, user.Role)
+	return err == nil && role.HasPermission(models.PermApplicationsRead) && role.HasPermission(models.PermApplicationsRecommend)
+}
+
+// mentionedStaff resolves the @LACPA-ID mentions of a comment to the staff who can
+// review applications, leaving out the author and unknown IDs
+func (h *ReviewHandler) mentionedStaff(ctx context.Context, body string, authorID primitive.ObjectID) []*models.User {
+	users := []*models.User{}
+	seen := map[string]bool{}
+	for _, match := range mentionPattern.FindAllStringSubmatch(body, -1) {
+		lacpaID := match[1]
+		if seen[lacpaID] {
+			continue
+		}
+		seen[lacpaID] = true
+
+		user, err := h.users.GetUserByLACPAID(lacpaID)
+		if err != nil || user.ID == authorID || !h.canReview(ctx, user) {
+			continue
+		}
+		users = append(users, user)
+	}
+	return users
+}
+
+// loadApplicationReview loads an application's reviewers and recommendations and
+// evaluates them against the quorum rule of its type
+func loadApplicationReview(ctx context.Context, repo repository.ApplicationRepository, appType models.ApplicationType, id primitive.ObjectID) (*models.ApplicationReview, error) {
+	review, err := repo.GetApplicationReview(ctx, appType, id)
+	if err != nil {
+		return nil, err
+	}
+	review.Rule, err = quorumRule(ctx, repo, appType)
+	if err != nil {
+		return nil, err
+	}
+	review.Quorum = review.Rule.Evaluate(review.Reviewers, review.Recommendations)
+	return review, nil
+}
+
+// quorumRule returns the stored quorum rule of an application type or the default one
+func quorumRule(ctx context.Context, repo repository.ApplicationRepository, appType models.ApplicationType) (*models.QuorumRule, error) {
+	rule, err := repo.GetQuorumRule(ctx, appType)
+	if err == mongo.ErrNoDocuments {
+		return models.DefaultQuorumRule(appType), nil
+	}
+	return rule, err
+}
+
+// reviewParams reads the :type and :id route parameters
+func reviewParams(c *fiber.Ctx) (models.ApplicationType, primitive.ObjectID, bool) {
+	appType, ok := trackedType(c.Params("type"))
+	if !ok {
+		return "", primitive.NilObjectID, false
+	}
+	id, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return "", primitive.NilObjectID, false
+	}
+	return appType, id, true
+}
+
+// applicationAuditTarget returns the audit target type of an application type
+func applicationAuditTarget(appType models.ApplicationType) string {
+	if appType == models.ApplicationTypeFirm {
+		return models.AuditTargetFirmApp
+	}
+	return models.AuditTargetIndividualApp
+}
+
+// notifyStaff emails a reviewer a link to the CMS; failures are only logged.
+// The message is escaped by the template, so it may quote comments as written.
+func notifyStaff(user *models.User, subject, message string) {
+	base, err := utils.PublicBaseURL()
+	if err != nil {
+		fmt.Printf("Failed to notify %s: %v\n", user.Email, err)
+		return
+	}
+	if err := utils.SendEmail(user.Email, subject, utils.NotificationEmailTemplate(user.FullName, message, "Open the CMS", base+"/admin")); err != nil {
+		fmt.Printf("Failed to notify %s: %v\n", user.Email, err)
+	}
+}
+
+// sendReviewError maps a failed reviewer update to a response
+func sendReviewError(c *fiber.Ctx, err error) error {
+	switch err {
+	case mongo.ErrNoDocuments:
+		return utils.SendNotFound(c, "Application")
+	case repository.ErrReviewerAssigned, repository.ErrReviewClosed:
+		return utils.SendError(c, fiber.StatusConflict, err.Error())
+	case repository.ErrNotAssignedReviewer:
+		return utils.SendNotFound(c, "Reviewer")
+	default:
+		return utils.SendInternalError(c, err.Error())
+	}
+}
@@ -0,0 +1,530 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/AliSleiman0/Lacpa/models"
+	"github.com/AliSleiman0/Lacpa/repository"
+	"github.com/AliSleiman0/Lacpa/utils"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	// applicationAccessPurpose scopes the token handed out after a tracking code is verified
+	applicationAccessPurpose = "application_access"
+
+	// applicationAccessCookie carries the same token for the HTMX pages
+	applicationAccessCookie = "application_access"
+
+	// applicationAccessHeader carries the token for API clients
+	applicationAccessHeader = "X-Application-Token"
+)
+
+// Templates of the tracking pages; the same handlers answer /api/applications/track
+// with JSON and /membership/track with these fragments
+const (
+	trackPageTemplate   = "LACPA/membership/track"
+	trackVerifyTemplate = "LACPA/membership/track_verify"
+	trackStatusTemplate = "LACPA/membership/track_status"
+	trackListTemplate   = "LACPA/membership/track_list"
+	trackErrorTemplate  = "LACPA/membership/track_error"
+)
+
+// ApplicationTrackingHandler lets applicants follow up on a submitted application.
+//
+// ROLE: Applicant Application Portal
+//   - Anyone holding the reference number and the application email can open it
+//     with a code sent to that email, which unlocks it for APPLICATION_ACCESS_TTL_MINUTES
+//   - Signed-in users see the applications submitted from their account or with their email
+//   - Applicants see the status, its history and what the reviewers asked for, upload the
+//     missing documents while the application needs information, resubmit and withdraw
+//   - Applicants see the invoice of the application fee and pay it online when a
+//     payment provider is configured
+type ApplicationTrackingHandler struct {
+	repo         repository.ApplicationRepository
+	invoices     repository.InvoiceRepository
+	provider     utils.PaymentProvider
+	verification *VerificationService
+	documents    *DocumentHandler
+}
+
+func NewApplicationTrackingHandler(repo repository.ApplicationRepository, invoices repository.InvoiceRepository, provider utils.PaymentProvider, verification *VerificationService, documents *DocumentHandler) *ApplicationTrackingHandler {
+	return &ApplicationTrackingHandler{repo: repo, invoices: invoices, provider: provider, verification: verification, documents: documents}
+}
+
+// trackedTarget is an individual or firm application loaded for its applicant
+type trackedTarget struct {
+	appType models.ApplicationType
+	id      primitive.ObjectID
+	status  models.ApplicationStatus
+	userID  *primitive.ObjectID
+	emails  []string
+	view    func(documents []models.ApplicationDocument) *models.TrackedApplication
+}
+
+// GetTrackPage renders the tracking page
+func (h *ApplicationTrackingHandler) GetTrackPage(c *fiber.Ctx) error {
+	if c.Get("HX-Request") != "true" {
+		return c.SendFile("../LACPA_Web/src/index.html")
+	}
+
+	return c.Render(trackPageTemplate, fiber.Map{
+		"Title":     "Track Your Application - LACPA",
+		"Reference": c.Query("reference"),
+	})
+}
+
+// RequestCode sends a tracking code to the application's email.
+// The response is the same whether or not the reference and email match,
+// so the endpoint cannot be used to find out who applied.
+func (h *ApplicationTrackingHandler) RequestCode(c *fiber.Ctx) error {
+	var req models.TrackApplicationRequest
+	if err := c.BodyParser(&req); err != nil {
+		return trackError(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+	req.ReferenceNumber = strings.ToUpper(strings.TrimSpace(req.ReferenceNumber))
+	req.Email = normalizeEmail(req.Email)
+	if err := utils.ValidateStruct(req); err != nil {
+		return trackError(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	target, err := h.findByReference(c, req.ReferenceNumber)
+	if err != nil && err != mongo.ErrNoDocuments {
+		return trackError(c, fiber.StatusInternalServerError, "Failed to retrieve application")
+	}
+	if target != nil && target.hasEmail(req.Email) {
+		if _, err := h.verification.Issue(c.Context(), req.Email, "", models.PurposeApplicationTrack, trackingSubject(target.appType, target.id)); err != nil {
+			fmt.Printf("Failed to send tracking code for %s: %v\n", req.ReferenceNumber, err)
+		}
+	}
+
+	return utils.SendSuccess(c, "If the reference number and email match an application, a verification code was sent to that email", fiber.Map{
+		"reference_number": req.ReferenceNumber,
+		"email":            req.Email,
+		"sent_to":          maskEmail(req.Email),
+	}, trackVerifyTemplate)
+}
+
+// VerifyCode exchanges a tracking code for an access token to the application.
+// The token is returned in the body for API clients and set as a cookie for the pages.
+func (h *ApplicationTrackingHandler) VerifyCode(c *fiber.Ctx) error {
+	var req models.VerifyTrackingRequest
+	if err := c.BodyParser(&req); err != nil {
+		return trackError(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+	req.ReferenceNumber = strings.ToUpper(strings.TrimSpace(req.ReferenceNumber))
+	req.Email = normalizeEmail(req.Email)
+	req.OTP = strings.TrimSpace(req.OTP)
+	if err := utils.ValidateStruct(req); err != nil {
+		return trackError(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	target, err := h.findByReference(c, req.ReferenceNumber)
+	if err != nil && err != mongo.ErrNoDocuments {
+		return trackError(c, fiber.StatusInternalServerError, "Failed to retrieve application")
+	}
+	if target == nil || !target.hasEmail(req.Email) {
+		return trackError(c, fiber.StatusBadRequest, "Code expired or invalid. Please request a new one.")
+	}
+
+	// The code only redeems for the application it was requested for
+	subject := trackingSubject(target.appType, target.id)
+	_, err = h.verification.Verify(c.Context(), req.Email, models.PurposeApplicationTrack, subject, req.OTP)
+	switch err {
+	case nil:
+	case ErrCodeNotFound:
+		return trackError(c, fiber.StatusBadRequest, "Code expired or invalid. Please request a new one.")
+	case ErrCodeInvalid:
+		return trackError(c, fiber.StatusBadRequest, "Invalid code")
+	case ErrCodeTooManyAttempts:
+		return trackError(c, fiber.StatusBadRequest, "Too many wrong codes. Please request a new one.")
+	default:
+		return trackError(c, fiber.StatusInternalServerError, "Failed to verify code")
+	}
+
+	ttl := utils.ApplicationAccessTTL()
+	token, err := utils.GeneratePurposeToken(subject, applicationAccessPurpose, ttl)
+	if err != nil {
+		return trackError(c, fiber.StatusInternalServerError, "Failed to open application")
+	}
+	expiresAt := time.Now().Add(ttl)
+	c.Cookie(&fiber.Cookie{
+		Name:     applicationAccessCookie,
+		Value:    token,
+		Path:     "/",
+		Expires:  expiresAt,
+		HTTPOnly: true,
+		Secure:   c.Protocol() == "https",
+		SameSite: fiber.CookieSameSiteLaxMode,
+	})
+
+	tracked, err := h.trackedView(c, target)
+	if err != nil {
+		return trackError(c, fiber.StatusInternalServerError, "Failed to retrieve application")
+	}
+
+	return utils.SendSuccess(c, "Application unlocked", fiber.Map{
+		"token":       token,
+		"expires_at":  expiresAt,
+		"application": tracked,
+	}, trackStatusTemplate)
+}
+
+// GetMyApplications lists the applications of the signed-in user.
+// The page calls it on load and shows nothing to anonymous visitors.
+func (h *ApplicationTrackingHandler) GetMyApplications(c *fiber.Ctx) error {
+	userID, _ := c.Locals("userID").(string)
+	uid, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		if utils.WantsJSON(c) {
+			return utils.SendError(c, fiber.StatusUnauthorized, "Sign in to see your applications")
+		}
+		return utils.SendSuccess(c, "", fiber.Map{"signed_in": false}, trackListTemplate)
+	}
+	email, _ := c.Locals("email").(string)
+
+	individual, err := h.repo.GetIndividualApplicationsByApplicant(c.Context(), uid, email)
+	if err != nil {
+		return trackError(c, fiber.StatusInternalServerError, "Failed to retrieve applications")
+	}
+	firm, err := h.repo.GetFirmApplicationsByApplicant(c.Context(), uid, email)
+	if err != nil {
+		return trackError(c, fiber.StatusInternalServerError, "Failed to retrieve applications")
+	}
+
+	applications := make([]*models.TrackedApplication, 0, len(individual)+len(firm))
+	for i := range individual {
+		applications = append(applications, individual[i].Tracked(nil))
+	}
+	for i := range firm {
+		applications = append(applications, firm[i].Tracked(nil))
+	}
+
+	return utils.SendSuccess(c, "Applications retrieved successfully", fiber.Map{
+		"signed_in":    true,
+		"applications": applications,
+	}, trackListTemplate)
+}
+
+// GetApplication shows an application to its applicant
+func (h *ApplicationTrackingHandler) GetApplication(c *fiber.Ctx) error {
+	target, err := h.authorizedTarget(c)
+	if target == nil {
+		return err
+	}
+	return h.sendTracked(c, target, "Application retrieved successfully")
+}
+
+// UploadDocument adds a missing document while the application needs information
+// (multipart: file, kind)
+func (h *ApplicationTrackingHandler) UploadDocument(c *fiber.Ctx) error {
+	target, err := h.authorizedTarget(c)
+	if target == nil {
+		return err
+	}
+	if target.status != models.ApplicationStatusNeedsInfo {
+		return trackError(c, fiber.StatusConflict, "Documents can only be added when LACPA has asked for more information")
+	}
+	kind := models.DocumentKind(c.FormValue("kind"))
+	if !target.appType.AcceptsDocument(kind) {
+		return trackError(c, fiber.StatusBadRequest, "Invalid document kind for this application type")
+	}
+
+	document, status, message := h.documents.storeUpload(c, target.appType, kind)
+	if document == nil {
+		return trackError(c, status, message)
+	}
+	err = h.repo.AddApplicationDocument(c.Context(), target.appType, target.id, document)
+	if err == repository.ErrDocumentsLocked {
+		return trackError(c, fiber.StatusConflict, "Documents can only be added when LACPA has asked for more information")
+	}
+	if err != nil {
+		return trackError(c, fiber.StatusInternalServerError, "Failed to attach document")
+	}
+
+	return h.sendTracked(c, target, "Document uploaded")
+}
+
+// PayInvoice starts the online payment of the application's unpaid invoice.
+// API clients get the checkout; the page is redirected to the provider's payment page.
+func (h *ApplicationTrackingHandler) PayInvoice(c *fiber.Ctx) error {
+	target, err := h.authorizedTarget(c)
+	if target == nil {
+		return err
+	}
+	if h.provider == nil {
+		return trackError(c, fiber.StatusNotFound, "Online payment is not available. Please contact LACPA to pay the invoice.")
+	}
+
+	invoice, err := h.invoices.GetInvoiceByApplication(c.Context(), target.id)
+	if err == mongo.ErrNoDocuments {
+		return trackError(c, fiber.StatusNotFound, "This application has no invoice")
+	}
+	if err != nil {
+		return trackError(c, fiber.StatusInternalServerError, "Failed to retrieve invoice")
+	}
+	if invoice.Status == models.InvoiceStatusPaid {
+		return trackError(c, fiber.StatusConflict, "This invoice is already paid")
+	}
+
+	base, err := utils.PublicBaseURL()
+	if err != nil {
+		fmt.Printf("Cannot start checkout for invoice %s: %v\n", invoice.Number, err)
+		return trackError(c, fiber.StatusInternalServerError, "Online payment is unavailable right now. Please try again later.")
+	}
+	returnURL := fmt.Sprintf("%s/membership/track?reference=%s", base, invoice.ReferenceNumber)
+	checkout, err := h.provider.CreateCheckout(c.Context(), utils.PaymentCheckoutRequest{
+		InvoiceNumber: invoice.Number,
+		Amount:        invoice.Amount,
+		Currency:      string(invoice.Currency),
+		Description:   "LACPA application fee " + invoice.ReferenceNumber,
+		ReturnURL:     returnURL,
+	})
+	if err != nil {
+		fmt.Printf("Failed to start checkout for invoice %s: %v\n", invoice.Number, err)
+		return trackError(c, fiber.StatusBadGateway, "Online payment is unavailable right now. Please try again later.")
+	}
+	if err := h.invoices.SetInvoiceCheckout(c.Context(), invoice.ID, h.provider.Name(), checkout.SessionID); err != nil {
+		if err == repository.ErrInvoicePaid {
+			return trackError(c, fiber.StatusConflict, "This invoice is already paid")
+		}
+		return trackError(c, fiber.StatusInternalServerError, "Failed to start payment")
+	}
+
+	if utils.WantsJSON(c) {
+		return utils.SendSuccess(c, "Checkout started", checkout)
+	}
+	c.Set("HX-Redirect", checkout.URL)
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// Resubmit sends the application back to the reviewers after the requested information was added
+func (h *ApplicationTrackingHandler) Resubmit(c *fiber.Ctx) error {
+	return h.applicantTransition(c, models.ApplicationStatusResubmitted, "Application resubmitted for review")
+}
+
+// Withdraw withdraws the application; this cannot be undone
+func (h *ApplicationTrackingHandler) Withdraw(c *fiber.Ctx) error {
+	return h.applicantTransition(c, models.ApplicationStatusWithdrawn, "Application withdrawn")
+}
+
+// applicantTransition moves the application to a status the applicant may choose.
+// The change is recorded in the status history without a reviewer.
+func (h *ApplicationTrackingHandler) applicantTransition(c *fiber.Ctx, status models.ApplicationStatus, message string) error {
+	target, err := h.authorizedTarget(c)
+	if target == nil {
+		return err
+	}
+
+	var req models.ApplicantActionRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return trackError(c, fiber.StatusBadRequest, "Invalid request body")
+		}
+	}
+	req.Notes = strings.TrimSpace(req.Notes)
+	if err := utils.ValidateStruct(req); err != nil {
+		return trackError(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	if target.appType == models.ApplicationTypeFirm {
+		err = h.repo.UpdateFirmApplicationStatus(c.Context(), target.id, status, req.Notes, primitive.NilObjectID)
+	} else {
+		err = h.repo.UpdateIndividualApplicationStatus(c.Context(), target.id, status, req.Notes, primitive.NilObjectID)
+	}
+	switch {
+	case err == nil:
+	case err == repository.ErrStatusConflict:
+		return trackError(c, fiber.StatusConflict, "The application changed in the meantime. Please reload it.")
+	case err == mongo.ErrNoDocuments:
+		return trackError(c, fiber.StatusNotFound, "Application not found")
+	case errors.Is(err, repository.ErrInvalidStatusTransition):
+		return trackError(c, fiber.StatusConflict, "This is not possible while the application is "+string(target.status))
+	default:
+		return trackError(c, fiber.StatusInternalServerError, "Failed to update application")
+	}
+
+	target.status = status
+	return h.sendTracked(c, target, message)
+}
+
+// sendTracked reloads the application and sends the applicant's view of it
+func (h *ApplicationTrackingHandler) sendTracked(c *fiber.Ctx, target *trackedTarget, message string) error {
+	current, err := h.findByID(c, target.appType, target.id)
+	if err != nil {
+		return trackError(c, fiber.StatusInternalServerError, "Failed to retrieve application")
+	}
+	tracked, err := h.trackedView(c, current)
+	if err != nil {
+		return trackError(c, fiber.StatusInternalServerError, "Failed to retrieve application")
+	}
+	return utils.SendSuccess(c, message, fiber.Map{"application": tracked}, trackStatusTemplate)
+}
+
+// trackedView builds the applicant's view with the attached documents and the invoice
+func (h *ApplicationTrackingHandler) trackedView(c *fiber.Ctx, target *trackedTarget) (*models.TrackedApplication, error) {
+	documents, err := h.repo.GetDocumentsByApplication(c.Context(), target.id)
+	if err != nil {
+		return nil, err
+	}
+	tracked := target.view(documents)
+
+	invoice, err := h.invoices.GetInvoiceByApplication(c.Context(), target.id)
+	if err != nil && err != mongo.ErrNoDocuments {
+		return nil, err
+	}
+	if invoice != nil {
+		tracked.Invoice = invoice
+		tracked.CanPay = h.provider != nil && invoice.Status == models.InvoiceStatusUnpaid
+	}
+	return tracked, nil
+}
+
+// authorizedTarget loads the application of the :type and :id parameters if the request
+// holds its access token or comes from the applicant's account.
+// When it returns nil the error response has already been sent.
+func (h *ApplicationTrackingHandler) authorizedTarget(c *fiber.Ctx) (*trackedTarget, error) {
+	appType, ok := trackedType(c.Params("type"))
+	if !ok {
+		return nil, trackError(c, fiber.StatusNotFound, "Application not found")
+	}
+	id, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return nil, trackError(c, fiber.StatusBadRequest, "Invalid application ID")
+	}
+
+	target, err := h.findByID(c, appType, id)
+	if err == mongo.ErrNoDocuments {
+		return nil, trackError(c, fiber.StatusNotFound, "Application not found")
+	}
+	if err != nil {
+		return nil, trackError(c, fiber.StatusInternalServerError, "Failed to retrieve application")
+	}
+
+	if !h.canAccess(c, target) {
+		// Not found rather than forbidden, so IDs cannot be probed
+		return nil, trackError(c, fiber.StatusNotFound, "Application not found. Please verify your email again.")
+	}
+	return target, nil
+}
+
+// canAccess accepts a valid access token for this application, or the account
+// that submitted it or is registered with its email
+func (h *ApplicationTrackingHandler) canAccess(c *fiber.Ctx, target *trackedTarget) bool {
+	token := c.Get(applicationAccessHeader)
+	if token == "" {
+		token = c.Cookies(applicationAccessCookie)
+	}
+	if token != "" {
+		claims, err := utils.ValidateChallengeToken(token, applicationAccessPurpose)
+		if err == nil && claims.UserID == trackingSubject(target.appType, target.id) {
+			return true
+		}
+	}
+
+	userID, _ := c.Locals("userID").(string)
+	if userID == "" {
+		return false
+	}
+	if target.userID != nil && target.userID.Hex() == userID {
+		return true
+	}
+	email, _ := c.Locals("email").(string)
+	return email != "" && target.hasEmail(email)
+}
+
+// findByReference loads an application by its reference number
+func (h *ApplicationTrackingHandler) findByReference(c *fiber.Ctx, reference string) (*trackedTarget, error) {
+	appType, ok := models.ApplicationTypeOfReference(reference)
+	if !ok {
+		return nil, mongo.ErrNoDocuments
+	}
+	if appType == models.ApplicationTypeFirm {
+		application, err := h.repo.GetFirmApplicationByReference(c.Context(), reference)
+		if err != nil {
+			return nil, err
+		}
+		return firmTarget(application), nil
+	}
+	application, err := h.repo.GetIndividualApplicationByReference(c.Context(), reference)
+	if err != nil {
+		return nil, err
+	}
+	return individualTarget(application), nil
+}
+
+// findByID loads an application by type and ID
+func (h *ApplicationTrackingHandler) findByID(c *fiber.Ctx, appType models.ApplicationType, id primitive.ObjectID) (*trackedTarget, error) {
+	if appType == models.ApplicationTypeFirm {
+		application, err := h.repo.GetFirmApplicationByID(c.Context(), id)
+		if err != nil {
+			return nil, err
+		}
+		return firmTarget(application), nil
+	}
+	application, err := h.repo.GetIndividualApplicationByID(c.Context(), id)
+	if err != nil {
+		return nil, err
+	}
+	return individualTarget(application), nil
+}
+
+func individualTarget(application *models.IndividualApplication) *trackedTarget {
+	return &trackedTarget{
+		appType: models.ApplicationTypeIndividual,
+		id:      application.ID,
+		status:  application.Status,
+		userID:  application.UserID,
+		emails:  []string{application.Email},
+		view:    application.Tracked,
+	}
+}
+
+// firmTarget accepts both the firm's email and its representative's
+func firmTarget(application *models.FirmApplication) *trackedTarget {
+	return &trackedTarget{
+		appType: models.ApplicationTypeFirm,
+		id:      application.ID,
+		status:  application.Status,
+		userID:  application.UserID,
+		emails:  []string{application.Email, application.RepresentativeEmail},
+		view:    application.Tracked,
+	}
+}
+
+// hasEmail reports whether email is one of the application's addresses
+func (t *trackedTarget) hasEmail(email string) bool {
+	email = normalizeEmail(email)
+	for _, candidate := range t.emails {
+		if candidate != "" && normalizeEmail(candidate) == email {
+			return true
+		}
+	}
+	return false
+}
+
+// trackError sends an error as JSON to API clients and as a message fragment to the pages
+func trackError(c *fiber.Ctx, status int, message string) error {
+	return utils.SendError(c, status, message, trackErrorTemplate)
+}
+
+// trackedType maps the :type route parameter to an application type
+func trackedType(param string) (models.ApplicationType, bool) {
+	switch strings.ToLower(param) {
+	case "individual":
+		return models.ApplicationTypeIndividual, true
+	case "firm":
+		return models.ApplicationTypeFirm, true
+	}
+	return "", false
+}
+
+// trackingSubject binds tracking codes and access tokens to one application
+func trackingSubject(appType models.ApplicationType, id primitive.ObjectID) string {
+	return strings.ToLower(string(appType)) + ":" + id.Hex()
+}
@@ -0,0 +1,99 @@
+package handler
+
+import (
+	"github.com/AliSleiman0/Lacpa/models"
+	"github.com/AliSleiman0/Lacpa/utils"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// applicationErrorsTemplate renders field errors inline for HTMX form posts
+const applicationErrorsTemplate = "LACPA/membership/application_errors"
+
+// validateApplication checks a submitted application.
+//
+// ROLE: Application Validation Schema
+//   - Field rules are the validate tags on IndividualApplication and FirmApplication
+//   - Every referenced document must be an unattached upload of the right type and slot
+//   - Each required application_requirements entry of the type with a document kind
+//     must be covered by a document of that kind
+//
+// It returns the document IDs to attach and the field errors, empty when the application is valid.
+func (h *ApplicationHandler) validateApplication(c *fiber.Ctx, appType models.ApplicationType, application interface{}, refs []models.DocumentReference) ([]primitive.ObjectID, *utils.ValidationErrors, error) {
+	ve := utils.NewValidationErrors()
+	if err := utils.ValidateStruct(application); err != nil {
+		if fieldErrors, ok := err.(*utils.ValidationErrors); ok {
+			ve.Errors = append(ve.Errors, fieldErrors.Errors...)
+		}
+	}
+
+	documentIDs := h.checkDocuments(c, ve, appType, refs)
+
+	requirements, err := h.repo.GetRequirementsByType(c.Context(), appType)
+	if err != nil {
+		return nil, nil, err
+	}
+	checkRequiredDocuments(ve, appType, requirements, refs)
+
+	return documentIDs, ve, nil
+}
+
+// checkDocuments verifies that every document an application refers to was uploaded
+// for this application type and slot and is not attached to another application.
+// Problems are reported on the document's field; the IDs of the good documents are returned.
+func (h *ApplicationHandler) checkDocuments(c *fiber.Ctx, ve *utils.ValidationErrors, appType models.ApplicationType, refs []models.DocumentReference) []primitive.ObjectID {
+	ids := make([]primitive.ObjectID, 0, len(refs))
+	for _, ref := range refs {
+		field, _ := models.DocumentField(ref.Kind)
+		id, err := primitive.ObjectIDFromHex(ref.ID)
+		if err != nil {
+			ve.AddError(field, "Invalid document ID", ref.ID)
+			continue
+		}
+		document, err := h.repo.GetDocumentByID(c.Context(), id)
+		if err != nil {
+			ve.AddError(field, "Uploaded document not found", ref.ID)
+			continue
+		}
+		if document.ApplicationType != appType || document.Kind != ref.Kind {
+			ve.AddError(field, "Document was not uploaded as a "+string(ref.Kind)+" document", ref.ID)
+			continue
+		}
+		if document.ApplicationID != nil {
+			ve.AddError(field, "Document is already attached to an application", ref.ID)
+			continue
+		}
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// checkRequiredDocuments reports each required document of the application type that
+// the application does not include, using the requirement's title as the message
+func checkRequiredDocuments(ve *utils.ValidationErrors, appType models.ApplicationType, requirements []models.ApplicationRequirement, refs []models.DocumentReference) {
+	provided := map[models.DocumentKind]bool{}
+	for _, ref := range refs {
+		provided[ref.Kind] = true
+	}
+
+	for _, requirement := range requirements {
+		if !requirement.IsRequired || requirement.ApplicationType != appType || !appType.AcceptsDocument(requirement.DocumentKind) {
+			continue
+		}
+		if !provided[requirement.DocumentKind] {
+			field, _ := models.DocumentField(requirement.DocumentKind)
+			ve.AddError(field, requirement.Title+" is required", "")
+		}
+	}
+}
+
+// sendApplicationErrors answers a rejected submission: an HTMX fragment that puts each
+// message next to its field, or the usual validation error JSON
+func sendApplicationErrors(c *fiber.Ctx, ve *utils.ValidationErrors) error {
+	if utils.WantsHTMX(c) {
+		return c.Status(fiber.StatusUnprocessableEntity).Render(applicationErrorsTemplate, fiber.Map{
+			"Errors": ve.Errors,
+		})
+	}
+	return utils.SendValidationErrors(c, ve)
+}
@@ -0,0 +1,179 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/AliSleiman0/Lacpa/models"
+	"github.com/AliSleiman0/Lacpa/utils"
+	"github.com/gofiber/fiber/v2"
+)
+
+// auditExportLimit caps the rows of one CSV export; narrow the filter for more
+const auditExportLimit = 10000
+
+// auditCSVHeader lists the columns of the CSV export
+var auditCSVHeader = []string{
+	"created_at", "action", "target_type", "target_id",
+	"actor_user_id", "actor_lacpa_id", "actor_email", "actor_role",
+	"ip_address", "user_agent", "changes",
+}
+
+// ListAuditEvents returns a filtered, paginated page of the audit trail.
+// Filters: actor (user ID, LACPA ID or email), action, target_type, target_id,
+// from and to (RFC 3339 or YYYY-MM-DD), page and page_size.
+func (h *AdminHandler) ListAuditEvents(c *fiber.Ctx) error {
+	filter, err := parseAuditFilter(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   err.Error(),
+			"success": false,
+		})
+	}
+
+	// Paginate normalizes page and page_size; the metadata is rebuilt once the total is known
+	page := c.QueryInt("page", 1)
+	pageSize := c.QueryInt("page_size", 20)
+	offset, limit, _ := utils.Paginate(page, pageSize, 0)
+	filter.Offset = offset
+	filter.Limit = limit
+
+	events, total, err := h.audit.List(c.Context(), filter)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   "Failed to retrieve audit events",
+			"success": false,
+		})
+	}
+	_, _, meta := utils.Paginate(page, pageSize, int(total))
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data": fiber.Map{
+			"events":     events,
+			"pagination": meta,
+		},
+	})
+}
+
+// ExportAuditEvents downloads the audit events matching the same filters as CSV
+func (h *AdminHandler) ExportAuditEvents(c *fiber.Ctx) error {
+	filter, err := parseAuditFilter(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   err.Error(),
+			"success": false,
+		})
+	}
+	filter.Limit = auditExportLimit
+
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+	if err := writer.Write(auditCSVHeader); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   "Failed to export audit events",
+			"success": false,
+		})
+	}
+
+	err = h.audit.Each(c.Context(), filter, func(event *models.AuditEvent) error {
+		changes := ""
+		if len(event.Changes) > 0 {
+			data, err := json.Marshal(event.Changes)
+			if err != nil {
+				return err
+			}
+			changes = string(data)
+		}
+		return writer.Write(csvSafeRow(
+			event.CreatedAt.UTC().Format(time.RFC3339),
+			event.Action,
+			event.TargetType,
+			event.TargetID,
+			event.Actor.UserID,
+			event.Actor.LACPAID,
+			event.Actor.Email,
+			event.Actor.Role,
+			event.IPAddress,
+			event.UserAgent,
+			changes,
+		))
+	})
+	if err == nil {
+		writer.Flush()
+		err = writer.Error()
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   "Failed to export audit events",
+			"success": false,
+		})
+	}
+
+	filename := fmt.Sprintf("audit-events-%s.csv", time.Now().Format("20060102-150405"))
+	c.Set(fiber.HeaderContentType, "text/csv; charset=utf-8")
+	c.Set(fiber.HeaderContentDisposition, `attachment; filename="`+filename+`"`)
+	return c.Send(buf.Bytes())
+}
+
+// csvSafeRow neutralizes cells a spreadsheet would run as a formula. The user agent,
+// emails and changed values come from callers, so a cell starting with =, +, -, @
+// (or a tab or carriage return) is prefixed with a single quote.
+func csvSafeRow(cells ...string) []string {
+	for i, cell := range cells {
+		if cell != "" && strings.ContainsRune("=+-@\t\r", rune(cell[0])) {
+			cells[i] = "'" + cell
+		}
+	}
+	return cells
+}
+
+// parseAuditFilter reads the audit filters from the query string
+func parseAuditFilter(c *fiber.Ctx) (models.AuditFilter, error) {
+	filter := models.AuditFilter{
+		Actor:      strings.TrimSpace(c.Query("actor")),
+		Action:     strings.TrimSpace(c.Query("action")),
+		TargetType: strings.TrimSpace(c.Query("target_type")),
+		TargetID:   strings.TrimSpace(c.Query("target_id")),
+	}
+
+	if value := c.Query("from"); value != "" {
+		from, err := parseQueryTime(value, false)
+		if err != nil {
+			return filter, fmt.Errorf("invalid from date: use RFC 3339 or YYYY-MM-DD")
+		}
+		filter.From = &from
+	}
+	if value := c.Query("to"); value != "" {
+		to, err := parseQueryTime(value, true)
+		if err != nil {
+			return filter, fmt.Errorf("invalid to date: use RFC 3339 or YYYY-MM-DD")
+		}
+		filter.To = &to
+	}
+	if filter.From != nil && filter.To != nil && filter.To.Before(*filter.From) {
+		return filter, fmt.Errorf("to date must not be before from date")
+	}
+
+	return filter, nil
+}
+
+// parseQueryTime accepts RFC 3339 timestamps or plain dates. A plain "to" date
+// covers the whole day.
+func parseQueryTime(value string, endOfDay bool) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if endOfDay {
+		t = t.Add(24*time.Hour - time.Nanosecond)
+	}
+	return t, nil
+}
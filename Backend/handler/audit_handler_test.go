@@ -0,0 +1,34 @@
+package handler
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCSVSafeRowNeutralizesFormulas(t *testing.T) {
+	got := csvSafeRow(
+		"=HYPERLINK(\"http://evil.example\")",
+		"+1",
+		"-2+3",
+		"@SUM(A1:A2)",
+		"\tcmd",
+		"\rcmd",
+		"user.role_changed",
+		"a=b",
+		"",
+	)
+	want := []string{
+		"'=HYPERLINK(\"http://evil.example\")",
+		"'+1",
+		"'-2+3",
+		"'@SUM(A1:A2)",
+		"'\tcmd",
+		"'\rcmd",
+		"user.role_changed",
+		"a=b",
+		"",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("csvSafeRow = %q, want %q", got, want)
+	}
+}
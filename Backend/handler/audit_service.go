@@ -0,0 +1,146 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/AliSleiman0/Lacpa/models"
+	"github.com/AliSleiman0/Lacpa/repository"
+	"github.com/gofiber/fiber/v2"
+)
+
+// auditWriteTimeout bounds the audit insert so a slow database cannot hold the response
+const auditWriteTimeout = 5 * time.Second
+
+// AuditService writes the audit trail of administrative actions and authentication events.
+//
+// ROLE: Audit Trail
+//   - Single entry point handlers call after a successful mutation
+//   - Reads the actor from the access token and the IP and user agent from the request
+//   - Authentication events before a token exists take the account as the actor
+//   - Stores only the fields that changed between the before and after snapshots
+//   - Snapshots are compared through their JSON form, so fields hidden from
+//     JSON (passwords, secrets, tokens) never reach the audit trail
+//   - A failed write is logged and never fails the request
+type AuditService struct {
+	repo *repository.AuditRepository
+}
+
+// NewAuditService creates the service
+func NewAuditService(repo *repository.AuditRepository) *AuditService {
+	return &AuditService{repo: repo}
+}
+
+// Record appends an event for an action performed by the current user.
+// before is nil for creations and after is nil for deletions.
+func (s *AuditService) Record(c *fiber.Ctx, action, targetType, targetID string, before, after interface{}) {
+	if s == nil {
+		return
+	}
+
+	s.insert(c, auditActor(c), action, targetType, targetID, auditDiff(before, after))
+}
+
+// RecordAuthentication appends a login, lockout or password event of an account that
+// has no access token yet, so the account is both the actor and the target
+func (s *AuditService) RecordAuthentication(c *fiber.Ctx, action string, user *models.User) {
+	if s == nil || user == nil {
+		return
+	}
+
+	actor := models.AuditActor{
+		UserID:  user.ID.Hex(),
+		LACPAID: user.LACPAID,
+		Email:   user.Email,
+		Role:    user.Role,
+	}
+	s.insert(c, actor, action, models.AuditTargetUser, user.ID.Hex(), nil)
+}
+
+// insert writes one event with the IP and user agent of the request
+func (s *AuditService) insert(c *fiber.Ctx, actor models.AuditActor, action, targetType, targetID string, changes map[string]models.AuditChange) {
+	event := &models.AuditEvent{
+		Actor:      actor,
+		Action:     action,
+		TargetType: targetType,
+		TargetID:   targetID,
+		Changes:    changes,
+		IPAddress:  c.IP(),
+		UserAgent:  c.Get(fiber.HeaderUserAgent),
+		CreatedAt:  time.Now(),
+	}
+
+	// The request context is recycled by fasthttp once the handler returns
+	ctx, cancel := context.WithTimeout(context.Background(), auditWriteTimeout)
+	defer cancel()
+	if err := s.repo.Insert(ctx, event); err != nil {
+		fmt.Printf("Failed to record audit event %s on %s %s: %v\n", action, targetType, targetID, err)
+	}
+}
+
+// auditActor reads the actor from the locals set by the auth middleware
+func auditActor(c *fiber.Ctx) models.AuditActor {
+	userID, _ := c.Locals("userID").(string)
+	lacpaID, _ := c.Locals("lacpaID").(string)
+	email, _ := c.Locals("email").(string)
+	role, _ := c.Locals("role").(string)
+	return models.AuditActor{
+		UserID:  userID,
+		LACPAID: lacpaID,
+		Email:   email,
+		Role:    role,
+	}
+}
+
+// auditDiff returns the top-level JSON fields that differ between two snapshots
+func auditDiff(before, after interface{}) map[string]models.AuditChange {
+	beforeFields := auditFields(before)
+	afterFields := auditFields(after)
+
+	changes := map[string]models.AuditChange{}
+	for field, value := range beforeFields {
+		if next, ok := afterFields[field]; !ok || !reflect.DeepEqual(value, next) {
+			changes[field] = models.AuditChange{Before: value, After: afterFields[field]}
+		}
+	}
+	for field, value := range afterFields {
+		if _, ok := beforeFields[field]; !ok {
+			changes[field] = models.AuditChange{Before: nil, After: value}
+		}
+	}
+
+	if len(changes) == 0 {
+		return nil
+	}
+	return changes
+}
+
+// auditFields flattens a snapshot to its top-level JSON fields
+func auditFields(snapshot interface{}) map[string]interface{} {
+	fields := map[string]interface{}{}
+	if snapshot == nil || reflect.ValueOf(snapshot).Kind() == reflect.Ptr && reflect.ValueOf(snapshot).IsNil() {
+		return fields
+	}
+
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return fields
+	}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return map[string]interface{}{}
+	}
+	return fields
+}
+
+// List returns a page of audit events with the total match count
+func (s *AuditService) List(ctx context.Context, filter models.AuditFilter) ([]models.AuditEvent, int64, error) {
+	return s.repo.List(ctx, filter)
+}
+
+// Each streams the audit events matching the filter, newest first
+func (s *AuditService) Each(ctx context.Context, filter models.AuditFilter, fn func(*models.AuditEvent) error) error {
+	return s.repo.Each(ctx, filter, fn)
+}
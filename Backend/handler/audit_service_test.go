@@ -0,0 +1,22 @@
+package handler
+
+import (
+	"testing"
+
+	"github.com/AliSleiman0/Lacpa/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestAuditDiffOfEmailChange(t *testing.T) {
+	user := &models.User{ID: primitive.NewObjectID(), FullName: "Rami Haddad", Email: "old@example.com", IsActive: true}
+	before := user.ToResponse()
+	user.Email = "new@example.com"
+
+	changes := auditDiff(before, user.ToResponse())
+	if len(changes) != 1 {
+		t.Fatalf("changes = %v, want only the email", changes)
+	}
+	if change := changes["email"]; change.Before != "old@example.com" || change.After != "new@example.com" {
+		t.Fatalf("email change = %+v, want old@example.com -> new@example.com", change)
+	}
+}
@@ -14,12 +14,26 @@ import (
 )
 
 type AuthHandler struct {
-	authRepo *repository.AuthRepository
+	authRepo     *repository.AuthRepository
+	roleRepo     *repository.RoleRepository
+	sessionRepo  *repository.SessionRepository
+	attemptRepo  *repository.AttemptRepository
+	membersRepo  repository.MembersRepository
+	counterRepo  *repository.CounterRepository
+	verification *VerificationService
+	audit        *AuditService
 }
 
-func NewAuthHandler(authRepo *repository.AuthRepository) *AuthHandler {
+func NewAuthHandler(authRepo *repository.AuthRepository, roleRepo *repository.RoleRepository, sessionRepo *repository.SessionRepository, attemptRepo *repository.AttemptRepository, membersRepo repository.MembersRepository, counterRepo *repository.CounterRepository, verification *VerificationService, audit *AuditService) *AuthHandler {
 	return &AuthHandler{
-		authRepo: authRepo,
+		authRepo:     authRepo,
+		roleRepo:     roleRepo,
+		sessionRepo:  sessionRepo,
+		attemptRepo:  attemptRepo,
+		membersRepo:  membersRepo,
+		counterRepo:  counterRepo,
+		verification: verification,
+		audit:        audit,
 	}
 }
 
@@ -50,8 +64,13 @@ func (h *AuthHandler) Signup(c *fiber.Ctx) error {
 		})
 	}
 
+	// Enforce the password policy
+	if ve := validateNewPassword("password", req.Password, nil); ve != nil {
+		return utils.SendValidationErrors(c, ve)
+	}
+
 	// Check if email already exists
-	existingUser, err := h.authRepo.GetUserByEmail(req.Email)
+	existingUser, err := h.authRepo.GetUserByEmail(strings.ToLower(strings.TrimSpace(req.Email)))
 	if err == nil && existingUser != nil {
 		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
 			"error":   "Email already registered",
@@ -68,51 +87,31 @@ func (h *AuthHandler) Signup(c *fiber.Ctx) error {
 		})
 	}
 
-	// Generate LACPA ID (format: LACPA-YYYY-XXXXX)
-	lacpaID := fmt.Sprintf("LACPA-%d-%05d", time.Now().Year(), time.Now().Unix()%100000)
-
-	// Create user
+	// Create user with the next sequential LACPA ID (format: LACPA-YYYY-NNNNN)
 	user := &models.User{
-		LACPAID:  lacpaID,
 		FullName: req.FullName,
 		Email:    strings.ToLower(strings.TrimSpace(req.Email)),
 		Password: hashedPassword,
-		Role:     "member", // Default role
+		Role:     models.RoleMember, // Default role
 	}
 
-	if err := h.authRepo.CreateUser(user); err != nil {
+	if err := createUserWithLACPAID(c.Context(), h.authRepo, h.counterRepo, user, models.LACPAIDIndividual); err != nil {
+		if err == ErrEmailTaken {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error":   "Email already registered",
+				"success": false,
+			})
+		}
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error":   "Failed to create user",
 			"success": false,
 		})
 	}
 
-	// Generate OTP for email verification
-	otp, err := utils.GenerateOTP()
-	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error":   "Failed to generate OTP",
-			"success": false,
-		})
-	}
-
-	// Set OTP with 10 minutes expiry
-	otpExpiry := time.Now().Add(10 * time.Minute)
-	if err := h.authRepo.SetOTP(user.Email, otp, otpExpiry); err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error":   "Failed to set OTP",
-			"success": false,
-		})
-	}
-
-	// Send OTP via email with beautiful HTML template (use the SAME OTP that's stored in DB)
-	_, err = utils.SendOTPEmailWithCode(user.Email, user.FullName, otp)
-	if err != nil {
-		// Log error but don't fail registration - user is created and OTP is stored in DB
-		fmt.Printf("Warning: Failed to send OTP email to %s: %v\n", user.Email, err)
-		fmt.Printf("OTP for %s (email failed): %s\n", user.Email, otp)
-	} else {
-		fmt.Printf("✅ OTP email sent successfully to %s\n", user.Email)
+	// Send the email verification code
+	if _, err := h.verification.Issue(c.Context(), user.Email, user.FullName, models.PurposeVerifyEmail, ""); err != nil {
+		// Log error but don't fail registration - the user can request a new code
+		fmt.Printf("Warning: Failed to send verification code to %s: %v\n", user.Email, err)
 	}
 
 	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
@@ -152,29 +151,43 @@ func (h *AuthHandler) Login(c *fiber.Ctx) error {
 		})
 	}
 
+	// Count the attempt up front, refusing it while the account or client is in backoff or locked out
+	reservation, wait, err := reserveAttempts(c, h.attemptRepo,
+		attemptGuard{key: loginAccountKey(req.LACPAID), policy: accountLoginPolicy},
+		attemptGuard{key: loginIPKey(c.IP()), policy: ipLoginPolicy, shared: true},
+	)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   "Failed to check login attempts",
+			"success": false,
+		})
+	}
+	if wait > 0 {
+		return sendTooManyAttempts(c, wait)
+	}
+
 	// Get user by LACPA ID
 	user, err := h.authRepo.GetUserByLACPAID(req.LACPAID)
-	if err != nil {
-		if err == mongo.ErrNoDocuments {
-			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-				"error":   "Invalid credentials",
-				"success": false,
-			})
-		}
+	if err != nil && err != mongo.ErrNoDocuments {
+		reservation.cancel(c)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error":   "Failed to retrieve user",
 			"success": false,
 		})
 	}
 
-	// Check password
-	if !utils.CheckPassword(user.Password, req.Password) {
+	// Check password (unknown accounts count as failures too)
+	if user == nil || !utils.CheckPassword(user.Password, req.Password) {
+		h.recordLoginFailure(c, user, reservation)
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
 			"error":   "Invalid credentials",
 			"success": false,
 		})
 	}
 
+	// Successful password check clears the account counter (the IP counter only gets this attempt back)
+	reservation.succeed(c)
+
 	// Check if user is active
 	if !user.IsActive {
 		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
@@ -191,29 +204,18 @@ func (h *AuthHandler) Login(c *fiber.Ctx) error {
 		})
 	}
 
-	// Update last login
-	if err := h.authRepo.UpdateLastLogin(user.ID); err != nil {
-		// Log error but don't fail the login
-		fmt.Printf("Failed to update last login: %v\n", err)
-	}
-
-	// Generate JWT token
-	token, err := utils.GenerateJWT(user.ID.Hex(), user.LACPAID, user.Email, user.Role)
+	purpose, err := h.secondFactor(c, user)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error":   "Failed to generate token",
+			"error":   "Failed to retrieve role",
 			"success": false,
 		})
 	}
+	if purpose != "" {
+		return h.sendChallenge(c, user, purpose)
+	}
 
-	return c.JSON(fiber.Map{
-		"success": true,
-		"message": "Login successful",
-		"data": models.AuthResponse{
-			Token: token,
-			User:  user.ToResponse(),
-		},
-	})
+	return h.completeLogin(c, user)
 }
 
 // ForgotPassword initiates password reset process
@@ -253,31 +255,9 @@ func (h *AuthHandler) ForgotPassword(c *fiber.Ctx) error {
 		})
 	}
 
-	// Generate OTP
-	otp, err := utils.GenerateOTP()
-	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error":   "Failed to generate OTP",
-			"success": false,
-		})
-	}
-
-	// Set OTP with 10 minutes expiry
-	otpExpiry := time.Now().Add(10 * time.Minute)
-	if err := h.authRepo.SetOTP(user.Email, otp, otpExpiry); err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error":   "Failed to set OTP",
-			"success": false,
-		})
-	}
-
-	// Send password reset OTP via email with beautiful HTML template (use the SAME OTP that's stored in DB)
-	_, err = utils.SendOTPEmailWithCode(user.Email, user.FullName, otp)
-	if err != nil {
-		fmt.Printf("Warning: Failed to send password reset OTP to %s: %v\n", user.Email, err)
-		fmt.Printf("Password Reset OTP for %s (email failed): %s\n", user.Email, otp)
-	} else {
-		fmt.Printf("✅ Password reset OTP email sent successfully to %s\n", user.Email)
+	// Send the password reset code
+	if _, err := h.verification.Issue(c.Context(), user.Email, user.FullName, models.PurposeResetPassword, ""); err != nil {
+		fmt.Printf("Warning: Failed to send password reset code to %s: %v\n", user.Email, err)
 	}
 
 	return c.JSON(fiber.Map{
@@ -313,50 +293,99 @@ func (h *AuthHandler) VerifyOTP(c *fiber.Ctx) error {
 		})
 	}
 
-	// Get user by email
-	user, err := h.authRepo.GetUserByEmail(strings.ToLower(strings.TrimSpace(req.Email)))
+	// Count the guess up front, refusing it while the client is in backoff or locked out
+	reservation, wait, err := reserveAttempts(c, h.attemptRepo,
+		attemptGuard{key: otpIPKey(c.IP()), policy: ipOTPPolicy, shared: true})
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error":   "Invalid OTP",
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   "Failed to check OTP attempts",
 			"success": false,
 		})
 	}
+	if wait > 0 {
+		return sendTooManyAttempts(c, wait)
+	}
 
-	// Check if OTP exists and not expired
-	if user.OTP == "" || time.Now().After(user.OTPExpiry) {
+	purpose := models.VerificationPurpose(req.Purpose)
+	if purpose == "" {
+		purpose = models.PurposeVerifyEmail
+	}
+	if purpose != models.PurposeVerifyEmail && purpose != models.PurposeResetPassword {
+		reservation.cancel(c)
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error":   "OTP expired or invalid",
+			"error":   "Unsupported purpose",
 			"success": false,
 		})
 	}
 
-	// Verify OTP
-	if user.OTP != req.OTP {
+	// Get user by email
+	user, err := h.authRepo.GetUserByEmail(strings.ToLower(strings.TrimSpace(req.Email)))
+	if err != nil {
+		// An unknown email keeps the reservation like a wrong code
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"error":   "Invalid OTP",
 			"success": false,
 		})
 	}
 
-	// Clear OTP
-	if err := h.authRepo.ClearOTP(user.Email); err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error":   "Failed to clear OTP",
-			"success": false,
+	// Redeem the code for the requested purpose only
+	if _, err := h.verification.Verify(c.Context(), user.Email, purpose, "", req.OTP); err != nil {
+		switch err {
+		case ErrCodeNotFound:
+			reservation.cancel(c)
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error":   "OTP expired or invalid",
+				"success": false,
+			})
+		case ErrCodeInvalid, ErrCodeTooManyAttempts:
+			// The wrong guess keeps the reservation
+			message := "Invalid OTP"
+			if err == ErrCodeTooManyAttempts {
+				recordSecurityEvent(h.authRepo, user, models.SecurityEventOTPInvalidated,
+					"Verification code invalidated after too many wrong guesses", c.IP(), "")
+				message = "Too many wrong codes. Please request a new OTP."
+			}
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error":   message,
+				"success": false,
+			})
+		default:
+			reservation.cancel(c)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error":   "Failed to verify OTP",
+				"success": false,
+			})
+		}
+	}
+	reservation.succeed(c)
+
+	if purpose == models.PurposeVerifyEmail {
+		if !user.IsVerified {
+			if err := h.authRepo.VerifyUser(user.Email); err != nil {
+				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+					"error":   "Failed to verify user",
+					"success": false,
+				})
+			}
+		}
+
+		return c.JSON(fiber.Map{
+			"success": true,
+			"message": "Email verified successfully",
+			"data": fiber.Map{
+				"verified": true,
+			},
 		})
 	}
 
-	// Verify user if not already verified
+	// Password reset: exchange the code for a short-lived reset token.
+	// Receiving the code also proves ownership of the email.
 	if !user.IsVerified {
 		if err := h.authRepo.VerifyUser(user.Email); err != nil {
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-				"error":   "Failed to verify user",
-				"success": false,
-			})
+			fmt.Printf("Failed to verify user %s: %v\n", user.Email, err)
 		}
 	}
 
-	// Generate reset token
 	resetToken, err := utils.GenerateResetToken()
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
@@ -365,9 +394,9 @@ func (h *AuthHandler) VerifyOTP(c *fiber.Ctx) error {
 		})
 	}
 
-	// Set reset token with 15 minutes expiry
+	// Store a hash of the reset token with 15 minutes expiry
 	resetTokenExpiry := time.Now().Add(15 * time.Minute)
-	if err := h.authRepo.SetResetToken(user.Email, resetToken, resetTokenExpiry); err != nil {
+	if err := h.authRepo.SetResetToken(user.Email, utils.HashToken(resetToken), resetTokenExpiry); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error":   "Failed to set reset token",
 			"success": false,
@@ -411,31 +440,27 @@ func (h *AuthHandler) ResendOTP(c *fiber.Ctx) error {
 		})
 	}
 
-	// Generate new OTP
-	otp, err := utils.GenerateOTP()
-	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error":   "Failed to generate OTP",
+	purpose := models.VerificationPurpose(req.Purpose)
+	if purpose == "" {
+		purpose = models.PurposeVerifyEmail
+	}
+	if purpose != models.PurposeVerifyEmail && purpose != models.PurposeResetPassword {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Unsupported purpose",
 			"success": false,
 		})
 	}
 
-	// Set OTP with 10 minutes expiry
-	otpExpiry := time.Now().Add(10 * time.Minute)
-	if err := h.authRepo.SetOTP(user.Email, otp, otpExpiry); err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error":   "Failed to set OTP",
-			"success": false,
+	// Verified accounts have nothing left to confirm
+	if purpose == models.PurposeVerifyEmail && user.IsVerified {
+		return c.JSON(fiber.Map{
+			"success": true,
+			"message": "If the email exists, a new OTP has been sent.",
 		})
 	}
 
-	// Resend OTP via email with beautiful HTML template (use the SAME OTP that's stored in DB)
-	_, err = utils.SendOTPEmailWithCode(user.Email, user.FullName, otp)
-	if err != nil {
-		fmt.Printf("Warning: Failed to resend OTP to %s: %v\n", user.Email, err)
-		fmt.Printf("Resend OTP for %s (email failed): %s\n", user.Email, otp)
-	} else {
-		fmt.Printf("✅ OTP resent successfully to %s\n", user.Email)
+	if _, err := h.verification.Issue(c.Context(), user.Email, user.FullName, purpose, ""); err != nil {
+		fmt.Printf("Warning: Failed to resend %s code to %s: %v\n", purpose, user.Email, err)
 	}
 
 	return c.JSON(fiber.Map{
@@ -463,7 +488,7 @@ func (h *AuthHandler) ResetPassword(c *fiber.Ctx) error {
 	}
 
 	// Get user by reset token
-	user, err := h.authRepo.GetUserByResetToken(req.Token)
+	user, err := h.authRepo.GetUserByResetToken(utils.HashToken(req.Token))
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"error":   "Invalid or expired reset token",
@@ -471,6 +496,11 @@ func (h *AuthHandler) ResetPassword(c *fiber.Ctx) error {
 		})
 	}
 
+	// Enforce the password policy, including reuse of recent passwords
+	if ve := validateNewPassword("new_password", req.NewPassword, user); ve != nil {
+		return utils.SendValidationErrors(c, ve)
+	}
+
 	// Hash new password
 	hashedPassword, err := utils.HashPassword(req.NewPassword)
 	if err != nil {
@@ -481,7 +511,7 @@ func (h *AuthHandler) ResetPassword(c *fiber.Ctx) error {
 	}
 
 	// Update password
-	if err := h.authRepo.UpdatePassword(user.ID, hashedPassword); err != nil {
+	if err := h.authRepo.UpdatePassword(user.ID, hashedPassword, utils.LoadPasswordPolicy().HistorySize); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error":   "Failed to update password",
 			"success": false,
@@ -494,45 +524,283 @@ func (h *AuthHandler) ResetPassword(c *fiber.Ctx) error {
 		fmt.Printf("Failed to clear reset token: %v\n", err)
 	}
 
+	// Sign out everywhere so whoever knew the old password loses access
+	revoked, err := h.sessionRepo.RevokeAllForUser(c.Context(), user.ID, models.SessionRevokedPassword)
+	if err != nil {
+		fmt.Printf("Failed to revoke sessions of %s: %v\n", user.Email, err)
+	}
+	recordSecurityEvent(h.authRepo, user, models.SecurityEventPasswordChanged,
+		"Password reset through an emailed link", c.IP(), user.ID.Hex())
+	h.audit.RecordAuthentication(c, models.AuditPasswordReset, user)
+
 	return c.JSON(fiber.Map{
 		"success": true,
 		"message": "Password reset successful. You can now login with your new password.",
+		"data": fiber.Map{
+			"sessions_revoked": revoked,
+		},
 	})
 }
 
 // GetProfile returns current user profile (requires authentication)
+// together with any claimed individual or firm member record
 func (h *AuthHandler) GetProfile(c *fiber.Ctx) error {
-	// Get user ID from context (set by auth middleware)
-	userIDStr := c.Locals("userID").(string)
-	userID, err := primitive.ObjectIDFromHex(userIDStr)
-	if err != nil {
+	user, status, message := h.currentUser(c)
+	if user == nil {
+		return c.Status(status).JSON(fiber.Map{
+			"error":   message,
+			"success": false,
+		})
+	}
+
+	profile := models.ProfileResponse{UserResponse: user.ToResponse()}
+
+	if user.IndividualMemberID != nil {
+		member, err := h.membersRepo.GetIndividualMemberByID(c.Context(), *user.IndividualMemberID)
+		if err != nil && err != mongo.ErrNoDocuments {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error":   "Failed to retrieve member profile",
+				"success": false,
+			})
+		}
+		profile.IndividualMember = member
+	}
+
+	if user.FirmMemberID != nil {
+		firm, err := h.membersRepo.GetFirmMemberByID(c.Context(), *user.FirmMemberID)
+		if err != nil && err != mongo.ErrNoDocuments {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error":   "Failed to retrieve firm profile",
+				"success": false,
+			})
+		}
+		profile.FirmMember = firm
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data":    profile,
+	})
+}
+
+// recordLoginFailure records a failed password or 2FA step at login
+func (h *AuthHandler) recordLoginFailure(c *fiber.Ctx, user *models.User, reservation *attemptReservation) {
+	h.audit.RecordAuthentication(c, models.AuditLoginFailed, user)
+	h.recordAttemptFailure(c, user, reservation)
+}
+
+// recordAttemptFailure records the account lockouts started by a wrong secret in the
+// user's security history and the audit trail. The failure was counted by the reservation.
+func (h *AuthHandler) recordAttemptFailure(c *fiber.Ctx, user *models.User, reservation *attemptReservation) {
+	if user == nil {
+		return
+	}
+	for _, attempt := range reservation.lockouts() {
+		recordSecurityEvent(h.authRepo, user, models.SecurityEventAccountLocked,
+			fmt.Sprintf("Account locked until %s after %d failed attempts", attempt.LockedUntil.Format(time.RFC3339), attempt.Failures),
+			c.IP(), "")
+		h.audit.RecordAuthentication(c, models.AuditAccountLocked, user)
+	}
+}
+
+// RefreshToken exchanges a refresh token for a new access token and a rotated refresh token
+func (h *AuthHandler) RefreshToken(c *fiber.Ctx) error {
+	var req models.RefreshTokenRequest
+	if err := c.BodyParser(&req); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error":   "Invalid user ID",
+			"error":   "Invalid request body",
+			"success": false,
+		})
+	}
+
+	// Validate request
+	if err := utils.ValidateStruct(req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   err.Error(),
+			"success": false,
+		})
+	}
+
+	tokenHash := utils.HashToken(req.RefreshToken)
+	session, err := h.sessionRepo.GetSessionByRefreshHash(c.Context(), tokenHash)
+	if err != nil || !session.IsActive() {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error":   "Invalid or expired refresh token",
+			"success": false,
+		})
+	}
+
+	// A refresh token that was already rotated is being replayed: the token
+	// leaked, so end the whole session for both the thief and the owner
+	if session.RefreshTokenHash != tokenHash {
+		if err := h.sessionRepo.RevokeSession(c.Context(), session.ID, models.SessionRevokedReuse); err != nil {
+			fmt.Printf("Failed to revoke session %s after refresh token reuse: %v\n", session.ID.Hex(), err)
+		}
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error":   "Invalid or expired refresh token",
+			"success": false,
+		})
+	}
+
+	user, err := h.authRepo.GetUserByID(session.UserID)
+	if err != nil || !user.IsActive {
+		if err := h.sessionRepo.RevokeSession(c.Context(), session.ID, models.SessionRevokedDeactivated); err != nil {
+			fmt.Printf("Failed to revoke session %s: %v\n", session.ID.Hex(), err)
+		}
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error":   "Account is deactivated",
+			"success": false,
+		})
+	}
+
+	refreshToken, err := utils.GenerateResetToken()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   "Failed to generate token",
+			"success": false,
+		})
+	}
+
+	rotated, err := h.sessionRepo.RotateRefreshToken(c.Context(), session.ID, tokenHash, utils.HashToken(refreshToken), c.IP(), c.Get("User-Agent"))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   "Failed to refresh session",
+			"success": false,
+		})
+	}
+	if !rotated {
+		// Another request rotated the token first
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error":   "Invalid or expired refresh token",
 			"success": false,
 		})
 	}
 
-	// Get user
-	user, err := h.authRepo.GetUserByID(userID)
+	token, err := h.generateAccessToken(c, user, session.ID.Hex())
 	if err != nil {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-			"error":   "User not found",
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   "Failed to generate token",
 			"success": false,
 		})
 	}
 
 	return c.JSON(fiber.Map{
 		"success": true,
-		"data":    user.ToResponse(),
+		"message": "Token refreshed successfully",
+		"data": models.AuthResponse{
+			Token:        token,
+			RefreshToken: refreshToken,
+			ExpiresIn:    int(utils.AccessTokenTTL().Seconds()),
+			User:         user.ToResponse(),
+		},
 	})
 }
 
-// Logout handles user logout (client-side token removal)
+// Logout revokes the session of the current access token
 func (h *AuthHandler) Logout(c *fiber.Ctx) error {
-	// In a stateless JWT system, logout is handled client-side by removing the token
-	// If you want to implement token blacklisting, you can add it here
+	sessionID, err := primitive.ObjectIDFromHex(c.Locals("sessionID").(string))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Invalid session",
+			"success": false,
+		})
+	}
+
+	if err := h.sessionRepo.RevokeSession(c.Context(), sessionID, models.SessionRevokedLogout); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   "Failed to end session",
+			"success": false,
+		})
+	}
+	userID, _ := c.Locals("userID").(string)
+	h.audit.Record(c, models.AuditLogout, models.AuditTargetUser, userID, nil, nil)
+
 	return c.JSON(fiber.Map{
 		"success": true,
 		"message": "Logout successful",
 	})
 }
+
+// LogoutAll revokes every session of the current user ("log out all devices")
+func (h *AuthHandler) LogoutAll(c *fiber.Ctx) error {
+	userID, err := primitive.ObjectIDFromHex(c.Locals("userID").(string))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Invalid user ID",
+			"success": false,
+		})
+	}
+
+	revoked, err := h.sessionRepo.RevokeAllForUser(c.Context(), userID, models.SessionRevokedLogoutAll)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   "Failed to end sessions",
+			"success": false,
+		})
+	}
+	h.audit.Record(c, models.AuditLogoutAll, models.AuditTargetUser, userID.Hex(), nil, nil)
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "Logged out from all devices",
+		"data": fiber.Map{
+			"sessions_revoked": revoked,
+		},
+	})
+}
+
+// startSession creates a server-side session, issues the token pair for it and
+// audits the login, whichever way it was completed
+func (h *AuthHandler) startSession(c *fiber.Ctx, user *models.User) (*models.AuthResponse, error) {
+	refreshToken, err := utils.GenerateResetToken()
+	if err != nil {
+		return nil, err
+	}
+
+	session := &models.Session{
+		UserID:           user.ID,
+		RefreshTokenHash: utils.HashToken(refreshToken),
+		UserAgent:        c.Get("User-Agent"),
+		IPAddress:        c.IP(),
+		ExpiresAt:        time.Now().Add(utils.RefreshTokenTTL()),
+	}
+	if err := h.sessionRepo.CreateSession(c.Context(), session); err != nil {
+		return nil, err
+	}
+
+	token, err := h.generateAccessToken(c, user, session.ID.Hex())
+	if err != nil {
+		return nil, err
+	}
+	h.audit.RecordAuthentication(c, models.AuditLogin, user)
+
+	return &models.AuthResponse{
+		Token:        token,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int(utils.AccessTokenTTL().Seconds()),
+		User:         user.ToResponse(),
+	}, nil
+}
+
+// generateAccessToken issues a JWT embedding the permissions and version of the user's role
+func (h *AuthHandler) generateAccessToken(c *fiber.Ctx, user *models.User, sessionID string) (string, error) {
+	subject := utils.TokenSubject{
+		UserID:      user.ID.Hex(),
+		LACPAID:     user.LACPAID,
+		Email:       user.Email,
+		Role:        user.Role,
+		Permissions: []string{},
+		SessionID:   sessionID,
+	}
+
+	role, err := h.roleRepo.GetRoleByName(c.Context(), user.Role)
+	if err != nil && err != mongo.ErrNoDocuments {
+		return "", err
+	}
+	if role != nil {
+		subject.Permissions = role.PermissionStrings()
+		subject.RoleVersion = role.Version
+	}
+
+	return utils.GenerateJWT(subject)
+}
@@ -0,0 +1,182 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/AliSleiman0/Lacpa/models"
+	"github.com/AliSleiman0/Lacpa/repository"
+	"github.com/AliSleiman0/Lacpa/utils"
+	"github.com/gofiber/fiber/v2"
+)
+
+// Lockout policies, configurable through the environment
+var (
+	// accountLoginPolicy protects a single account against password guessing
+	accountLoginPolicy = loadLockoutPolicy("LOGIN_ACCOUNT", 5, 15)
+	// ipLoginPolicy protects against one client spraying many accounts
+	ipLoginPolicy = loadLockoutPolicy("LOGIN_IP", 20, 15)
+	// ipOTPPolicy protects OTP verification against one client guessing codes
+	ipOTPPolicy = loadLockoutPolicy("OTP_IP", 20, 15)
+)
+
+// loadLockoutPolicy builds a lockout policy from <PREFIX>_MAX_FAILURES and <PREFIX>_LOCKOUT_MINUTES
+func loadLockoutPolicy(prefix string, maxFailures, lockoutMinutes int) models.LockoutPolicy {
+	return models.LockoutPolicy{
+		MaxFailures:      utils.GetEnvInt(prefix+"_MAX_FAILURES", maxFailures),
+		BackoffThreshold: 3,
+		BaseDelay:        time.Second,
+		MaxDelay:         time.Minute,
+		LockoutDuration:  time.Duration(utils.GetEnvInt(prefix+"_LOCKOUT_MINUTES", lockoutMinutes)) * time.Minute,
+		Window:           time.Hour,
+	}
+}
+
+// Attempt keys
+func loginAccountKey(lacpaID string) string { return "login:account:" + lacpaID }
+func loginIPKey(ip string) string           { return "login:ip:" + ip }
+func otpIPKey(ip string) string             { return "otp:ip:" + ip }
+
+// attemptStore keeps the failure counters; implemented by repository.AttemptRepository
+type attemptStore interface {
+	GetAttempt(ctx context.Context, key string) (*models.AuthAttempt, error)
+	RecordFailure(ctx context.Context, key string, policy models.LockoutPolicy) (*models.AuthAttempt, bool, error)
+	Release(ctx context.Context, key string, policy models.LockoutPolicy) error
+	Reset(ctx context.Context, keys ...string) error
+}
+
+// attemptGuard is a key paired with the policy that applies to it
+type attemptGuard struct {
+	key    string
+	policy models.LockoutPolicy
+	shared bool // Counts the attempts of many accounts, like a client IP: a success only gives its own back
+}
+
+// attemptReservation is an attempt counted against each guard before the secret is checked
+type attemptReservation struct {
+	store  attemptStore
+	guards []attemptGuard
+	locked []*models.AuthAttempt // Counter of each guard whose lockout this attempt started, or nil
+}
+
+// checkAttempts returns how long the caller must wait before the next attempt
+// for any of the guards, or zero if the attempt may proceed
+func checkAttempts(c *fiber.Ctx, attempts attemptStore, guards ...attemptGuard) (time.Duration, error) {
+	now := time.Now()
+	var wait time.Duration
+	for _, guard := range guards {
+		attempt, err := attempts.GetAttempt(c.Context(), guard.key)
+		if err != nil {
+			return 0, err
+		}
+		if retryAfter := attempt.RetryAfter(guard.policy, now); retryAfter > wait {
+			wait = retryAfter
+		}
+	}
+	return wait, nil
+}
+
+// reserveAttempts counts the attempt as a failure against every guard before the secret
+// is checked, so parallel requests cannot all pass the same "not locked" read. When a
+// guard is in backoff or its attempts are used up, it returns how long to wait instead.
+// A wrong secret keeps the reservation; otherwise the caller settles it with succeed or cancel.
+func reserveAttempts(c *fiber.Ctx, attempts attemptStore, guards ...attemptGuard) (*attemptReservation, time.Duration, error) {
+	wait, err := checkAttempts(c, attempts, guards...)
+	if err != nil || wait > 0 {
+		return nil, wait, err
+	}
+
+	reservation := &attemptReservation{store: attempts, guards: guards, locked: make([]*models.AuthAttempt, len(guards))}
+	now := time.Now()
+	for i, guard := range guards {
+		attempt, locked, err := attempts.RecordFailure(c.Context(), guard.key, guard.policy)
+		if err != nil {
+			return nil, 0, err
+		}
+		// Parallel requests took the last attempts since the check
+		if attempt.Failures > guard.policy.MaxFailures {
+			if wait = attempt.RetryAfter(guard.policy, now); wait < time.Second {
+				wait = time.Second
+			}
+			return nil, wait, nil
+		}
+		if locked {
+			reservation.locked[i] = attempt
+		}
+	}
+	return reservation, 0, nil
+}
+
+// succeed clears the counters of the account after a correct secret. Shared counters
+// only get this attempt back.
+func (r *attemptReservation) succeed(c *fiber.Ctx) {
+	for _, guard := range r.guards {
+		var err error
+		if guard.shared {
+			err = r.store.Release(c.Context(), guard.key, guard.policy)
+		} else {
+			err = r.store.Reset(c.Context(), guard.key)
+		}
+		if err != nil {
+			fmt.Printf("Failed to reset attempts for %s: %v\n", guard.key, err)
+		}
+	}
+}
+
+// cancel gives the attempt back when no secret was checked
+func (r *attemptReservation) cancel(c *fiber.Ctx) {
+	for _, guard := range r.guards {
+		if err := r.store.Release(c.Context(), guard.key, guard.policy); err != nil {
+			fmt.Printf("Failed to release attempt for %s: %v\n", guard.key, err)
+		}
+	}
+}
+
+// lockouts returns the counters of the account guards whose lockout this attempt started.
+// The failure itself was counted by the reservation.
+func (r *attemptReservation) lockouts() []*models.AuthAttempt {
+	var lockouts []*models.AuthAttempt
+	for i, attempt := range r.locked {
+		if attempt != nil && !r.guards[i].shared {
+			lockouts = append(lockouts, attempt)
+		}
+	}
+	return lockouts
+}
+
+// sendTooManyAttempts responds with 429 and a Retry-After header
+func sendTooManyAttempts(c *fiber.Ctx, wait time.Duration) error {
+	seconds, message := setRetryAfter(c, wait)
+	return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
+		"error":       message,
+		"retry_after": seconds,
+		"success":     false,
+	})
+}
+
+// setRetryAfter sets the Retry-After header and returns the wait in seconds with a message for the client
+func setRetryAfter(c *fiber.Ctx, wait time.Duration) (int, string) {
+	seconds := int(math.Ceil(wait.Seconds()))
+	c.Set("Retry-After", strconv.Itoa(seconds))
+	return seconds, fmt.Sprintf("Too many failed attempts. Try again in %d seconds.", seconds)
+}
+
+// recordSecurityEvent appends an event to a user's security history without failing the request
+func recordSecurityEvent(authRepo *repository.AuthRepository, user *models.User, eventType, description, ip, actor string) {
+	if user == nil {
+		return
+	}
+	event := models.SecurityEvent{
+		Type:        eventType,
+		Description: description,
+		IPAddress:   ip,
+		Actor:       actor,
+		At:          time.Now(),
+	}
+	if err := authRepo.AddSecurityEvent(user.ID, event); err != nil {
+		fmt.Printf("Failed to record security event %s for %s: %v\n", eventType, user.Email, err)
+	}
+}
@@ -0,0 +1,211 @@
+package handler
+
+import (
+	"context"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/AliSleiman0/Lacpa/models"
+	"github.com/gofiber/fiber/v2"
+)
+
+// memoryAttemptStore applies each counter operation atomically, like the collection
+type memoryAttemptStore struct {
+	mu       sync.Mutex
+	attempts map[string]*models.AuthAttempt
+}
+
+func newMemoryAttemptStore() *memoryAttemptStore {
+	return &memoryAttemptStore{attempts: map[string]*models.AuthAttempt{}}
+}
+
+func (s *memoryAttemptStore) GetAttempt(ctx context.Context, key string) (*models.AuthAttempt, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	attempt, ok := s.attempts[key]
+	if !ok {
+		return nil, nil
+	}
+	copied := *attempt
+	return &copied, nil
+}
+
+func (s *memoryAttemptStore) RecordFailure(ctx context.Context, key string, policy models.LockoutPolicy) (*models.AuthAttempt, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	attempt, ok := s.attempts[key]
+	if !ok || (attempt.LockedUntil != nil && !now.Before(*attempt.LockedUntil)) {
+		attempt = &models.AuthAttempt{Key: key}
+		s.attempts[key] = attempt
+	}
+	attempt.Failures++
+	attempt.LastFailureAt = now
+	locked := false
+	if attempt.Failures >= policy.MaxFailures && attempt.LockedUntil == nil {
+		lockedUntil := now.Add(policy.LockoutDuration)
+		attempt.LockedUntil = &lockedUntil
+		locked = true
+	}
+	copied := *attempt
+	return &copied, locked, nil
+}
+
+func (s *memoryAttemptStore) Release(ctx context.Context, key string, policy models.LockoutPolicy) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	attempt, ok := s.attempts[key]
+	if !ok || attempt.Failures == 0 {
+		return nil
+	}
+	attempt.Failures--
+	if attempt.Failures < policy.MaxFailures {
+		attempt.LockedUntil = nil
+	}
+	return nil
+}
+
+func (s *memoryAttemptStore) Reset(ctx context.Context, keys ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, key := range keys {
+		delete(s.attempts, key)
+	}
+	return nil
+}
+
+func (s *memoryAttemptStore) failures(key string) int {
+	attempt, _ := s.GetAttempt(context.Background(), key)
+	if attempt == nil {
+		return 0
+	}
+	return attempt.Failures
+}
+
+// testLockoutPolicy locks after five failures without backoff in between
+var testLockoutPolicy = models.LockoutPolicy{
+	MaxFailures:      5,
+	BackoffThreshold: 100,
+	LockoutDuration:  time.Minute,
+	Window:           time.Hour,
+}
+
+// newReservationApp reserves an attempt per request; the outcome header decides how it is settled
+func newReservationApp(store *memoryAttemptStore, guards ...attemptGuard) *fiber.App {
+	app := fiber.New()
+	app.Post("/", func(c *fiber.Ctx) error {
+		reservation, wait, err := reserveAttempts(c, store, guards...)
+		if err != nil {
+			return err
+		}
+		if wait > 0 {
+			return sendTooManyAttempts(c, wait)
+		}
+		switch c.Get("X-Outcome") {
+		case "success":
+			reservation.succeed(c)
+		case "cancel":
+			reservation.cancel(c)
+		}
+		return c.SendStatus(fiber.StatusOK)
+	})
+	return app
+}
+
+func TestReserveAttemptsLimitsParallelGuesses(t *testing.T) {
+	store := newMemoryAttemptStore()
+	app := newReservationApp(store, attemptGuard{key: "account", policy: testLockoutPolicy})
+
+	const guesses = 100
+	var mu sync.Mutex
+	statuses := map[int]int{}
+	var wg sync.WaitGroup
+	for i := 0; i < guesses; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp, err := app.Test(httptest.NewRequest(fiber.MethodPost, "/", nil), -1)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			mu.Lock()
+			statuses[resp.StatusCode]++
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	if statuses[fiber.StatusOK] != testLockoutPolicy.MaxFailures {
+		t.Fatalf("%d guesses were checked, want %d", statuses[fiber.StatusOK], testLockoutPolicy.MaxFailures)
+	}
+	if statuses[fiber.StatusTooManyRequests] != guesses-testLockoutPolicy.MaxFailures {
+		t.Fatalf("%d guesses were refused, want %d", statuses[fiber.StatusTooManyRequests], guesses-testLockoutPolicy.MaxFailures)
+	}
+}
+
+func TestReservationSettlement(t *testing.T) {
+	tests := []struct {
+		name          string
+		outcome       string
+		wantAccount   int
+		wantSharedKey int
+	}{
+		{name: "wrong secret keeps the failure", outcome: "", wantAccount: 3, wantSharedKey: 3},
+		{name: "success clears the account and releases the shared attempt", outcome: "success", wantAccount: 0, wantSharedKey: 2},
+		{name: "cancel releases every attempt", outcome: "cancel", wantAccount: 2, wantSharedKey: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newMemoryAttemptStore()
+			app := newReservationApp(store,
+				attemptGuard{key: "account", policy: testLockoutPolicy},
+				attemptGuard{key: "ip", policy: testLockoutPolicy, shared: true},
+			)
+			for i := 0; i < 2; i++ {
+				if _, err := app.Test(httptest.NewRequest(fiber.MethodPost, "/", nil), -1); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			req := httptest.NewRequest(fiber.MethodPost, "/", nil)
+			req.Header.Set("X-Outcome", tt.outcome)
+			if _, err := app.Test(req, -1); err != nil {
+				t.Fatal(err)
+			}
+			if got := store.failures("account"); got != tt.wantAccount {
+				t.Fatalf("account failures = %d, want %d", got, tt.wantAccount)
+			}
+			if got := store.failures("ip"); got != tt.wantSharedKey {
+				t.Fatalf("shared failures = %d, want %d", got, tt.wantSharedKey)
+			}
+		})
+	}
+}
+
+func TestCancelLiftsLockout(t *testing.T) {
+	store := newMemoryAttemptStore()
+	app := newReservationApp(store, attemptGuard{key: "account", policy: testLockoutPolicy})
+	for i := 0; i < testLockoutPolicy.MaxFailures-1; i++ {
+		if _, err := app.Test(httptest.NewRequest(fiber.MethodPost, "/", nil), -1); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The last attempt locks the account while in flight, but no secret was checked
+	req := httptest.NewRequest(fiber.MethodPost, "/", nil)
+	req.Header.Set("X-Outcome", "cancel")
+	if _, err := app.Test(req, -1); err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := app.Test(httptest.NewRequest(fiber.MethodPost, "/", nil), -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("status = %d after a cancelled attempt, want %d", resp.StatusCode, fiber.StatusOK)
+	}
+}
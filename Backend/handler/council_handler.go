@@ -1,6 +1,9 @@
 package handler
 
 import (
+	"errors"
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/AliSleiman0/Lacpa/models"
@@ -8,14 +11,16 @@ import (
 	"github.com/AliSleiman0/Lacpa/utils"
 	"github.com/gofiber/fiber/v2"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 )
 
 type CouncilHandler struct {
-	repo repository.Repository
+	repo  repository.Repository
+	audit *AuditService
 }
 
-func NewCouncilHandler(repo repository.Repository) *CouncilHandler {
-	return &CouncilHandler{repo: repo}
+func NewCouncilHandler(repo repository.Repository, audit *AuditService) *CouncilHandler {
+	return &CouncilHandler{repo: repo, audit: audit}
 }
 
 // GetActiveCouncil retrieves the currently active council
@@ -71,6 +76,7 @@ func (h *CouncilHandler) CreateCouncil(c *fiber.Ctx) error {
 	if err := h.repo.CreateCouncil(c.Context(), &council); err != nil {
 		return utils.SendInternalError(c, err.Error())
 	}
+	h.audit.Record(c, models.AuditCouncilCreated, models.AuditTargetCouncil, council.ID.Hex(), nil, council)
 
 	return utils.SendCreated(c, council, "", "")
 }
@@ -87,9 +93,19 @@ func (h *CouncilHandler) UpdateCouncil(c *fiber.Ctx) error {
 		return utils.SendBadRequest(c, "Invalid request body")
 	}
 
+	before, err := h.repo.GetCouncilByID(c.Context(), id)
+	if err != nil {
+		return sendSnapshotError(c, err, "Council")
+	}
 	if err := h.repo.UpdateCouncil(c.Context(), id, &council); err != nil {
 		return utils.SendInternalError(c, err.Error())
 	}
+	after, err := h.repo.GetCouncilByID(c.Context(), id)
+	if err != nil {
+		// The change is saved; audit it against the before snapshot only
+		fmt.Printf("Failed to reload council %s for the audit trail: %v\n", id.Hex(), err)
+	}
+	h.audit.Record(c, models.AuditCouncilUpdated, models.AuditTargetCouncil, id.Hex(), before, after)
 
 	return utils.SendSuccess(c, "Council updated successfully", council)
 }
@@ -101,9 +117,19 @@ func (h *CouncilHandler) DeactivateCouncil(c *fiber.Ctx) error {
 		return utils.SendBadRequest(c, "Invalid council ID")
 	}
 
+	before, err := h.repo.GetCouncilByID(c.Context(), id)
+	if err != nil {
+		return sendSnapshotError(c, err, "Council")
+	}
 	if err := h.repo.DeactivateCouncil(c.Context(), id); err != nil {
 		return utils.SendInternalError(c, err.Error())
 	}
+	after, err := h.repo.GetCouncilByID(c.Context(), id)
+	if err != nil {
+		// The change is saved; audit it against the before snapshot only
+		fmt.Printf("Failed to reload council %s for the audit trail: %v\n", id.Hex(), err)
+	}
+	h.audit.Record(c, models.AuditCouncilDeactivated, models.AuditTargetCouncil, id.Hex(), before, after)
 
 	return utils.SendSuccess(c, "Council deactivated successfully", nil)
 }
@@ -162,6 +188,7 @@ func (h *CouncilHandler) AssignCouncilPosition(c *fiber.Ctx) error {
 	if err := h.repo.AssignCouncilPosition(c.Context(), &position); err != nil {
 		return utils.SendError(c, fiber.StatusBadRequest, err.Error())
 	}
+	h.audit.Record(c, models.AuditCouncilPositionAssign, models.AuditTargetCouncilPosition, position.ID.Hex(), nil, position)
 
 	return utils.SendCreated(c, position, "", "")
 }
@@ -173,9 +200,19 @@ func (h *CouncilHandler) RemoveCouncilPosition(c *fiber.Ctx) error {
 		return utils.SendBadRequest(c, "Invalid position ID")
 	}
 
+	before, err := h.repo.GetPositionByID(c.Context(), positionID)
+	if err != nil {
+		return sendSnapshotError(c, err, "Council position")
+	}
 	if err := h.repo.RemoveCouncilPosition(c.Context(), positionID); err != nil {
 		return utils.SendInternalError(c, err.Error())
 	}
+	after, err := h.repo.GetPositionByID(c.Context(), positionID)
+	if err != nil {
+		// The change is saved; audit it against the before snapshot only
+		fmt.Printf("Failed to reload council position %s for the audit trail: %v\n", positionID.Hex(), err)
+	}
+	h.audit.Record(c, models.AuditCouncilPositionRemoved, models.AuditTargetCouncilPosition, positionID.Hex(), before, after)
 
 	return utils.SendSuccess(c, "Council position removed successfully", nil)
 }
@@ -192,9 +229,19 @@ func (h *CouncilHandler) UpdateCouncilPosition(c *fiber.Ctx) error {
 		return utils.SendBadRequest(c, "Invalid request body")
 	}
 
+	before, err := h.repo.GetPositionByID(c.Context(), positionID)
+	if err != nil {
+		return sendSnapshotError(c, err, "Council position")
+	}
 	if err := h.repo.UpdateCouncilPosition(c.Context(), positionID, &position); err != nil {
 		return utils.SendInternalError(c, err.Error())
 	}
+	after, err := h.repo.GetPositionByID(c.Context(), positionID)
+	if err != nil {
+		// The change is saved; audit it against the before snapshot only
+		fmt.Printf("Failed to reload council position %s for the audit trail: %v\n", positionID.Hex(), err)
+	}
+	h.audit.Record(c, models.AuditCouncilPositionUpdate, models.AuditTargetCouncilPosition, positionID.Hex(), before, after)
 
 	return utils.SendSuccess(c, "Council position updated successfully", position)
 }
@@ -311,3 +358,11 @@ func (h *CouncilHandler) GetBoardMembersPage(c *fiber.Ctx) error {
 		"Councils": councilsWithMembers,
 	})
 }
+
+// sendSnapshotError answers a failed load of the record an audited change is about to modify
+func sendSnapshotError(c *fiber.Ctx, err error, resource string) error {
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return utils.SendNotFound(c, resource)
+	}
+	return utils.SendInternalError(c, "Failed to load "+strings.ToLower(resource))
+}
@@ -0,0 +1,272 @@
+package handler
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"mime"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/AliSleiman0/Lacpa/models"
+	"github.com/AliSleiman0/Lacpa/repository"
+	"github.com/AliSleiman0/Lacpa/utils"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// DocumentHandler handles application document uploads and downloads.
+//
+// ROLE: Application Documents
+//   - Applicants upload files before submitting and put the returned IDs in the application
+//   - The file type is detected from its magic bytes; only PDF, PNG and JPEG are accepted
+//   - Files live in the BlobStore and are only served through this handler: staff download
+//     them directly or hand out a short-lived signed link
+//   - Uploads never attached to an application are deleted by RunUnattachedCleanup
+type DocumentHandler struct {
+	repo  repository.ApplicationRepository
+	store utils.BlobStore
+}
+
+func NewDocumentHandler(repo repository.ApplicationRepository, store utils.BlobStore) *DocumentHandler {
+	return &DocumentHandler{repo: repo, store: store}
+}
+
+// UploadDocument handles POST /api/applications/documents (multipart: file, kind, application_type)
+func (h *DocumentHandler) UploadDocument(c *fiber.Ctx) error {
+	appType := models.ApplicationType(c.FormValue("application_type"))
+	kind := models.DocumentKind(c.FormValue("kind"))
+	if !appType.AcceptsDocument(kind) {
+		return utils.SendBadRequest(c, "Invalid document kind for this application type")
+	}
+
+	document, status, message := h.storeUpload(c, appType, kind)
+	if document == nil {
+		return utils.SendError(c, status, message)
+	}
+
+	return utils.SendCreated(c, document, "")
+}
+
+// storeUpload checks the multipart "file" field, stores it and records its metadata.
+// On failure it returns nil with the status and message to respond with.
+func (h *DocumentHandler) storeUpload(c *fiber.Ctx, appType models.ApplicationType, kind models.DocumentKind) (*models.ApplicationDocument, int, string) {
+	file, err := c.FormFile("file")
+	if err != nil {
+		return nil, fiber.StatusBadRequest, "No file provided"
+	}
+	maxSize := utils.DocumentMaxSize()
+	if file.Size > maxSize {
+		return nil, fiber.StatusRequestEntityTooLarge, fmt.Sprintf("File size must be at most %d MB", maxSize>>20)
+	}
+	if file.Size == 0 {
+		return nil, fiber.StatusBadRequest, "File is empty"
+	}
+
+	src, err := file.Open()
+	if err != nil {
+		return nil, fiber.StatusInternalServerError, "Failed to read file"
+	}
+	defer src.Close()
+
+	head := make([]byte, utils.DocumentSniffLength)
+	n, _ := io.ReadFull(src, head)
+	contentType, ok := utils.DetectDocumentType(head[:n])
+	if !ok {
+		return nil, fiber.StatusBadRequest, "Only PDF, PNG and JPEG files are accepted"
+	}
+
+	hash := sha256.New()
+	if _, err := src.Seek(0, io.SeekStart); err != nil {
+		return nil, fiber.StatusInternalServerError, "Failed to read file"
+	}
+	if _, err := io.Copy(hash, src); err != nil {
+		return nil, fiber.StatusInternalServerError, "Failed to read file"
+	}
+	if _, err := src.Seek(0, io.SeekStart); err != nil {
+		return nil, fiber.StatusInternalServerError, "Failed to read file"
+	}
+
+	key := fmt.Sprintf("applications/%s/%s%s", time.Now().Format("2006/01"), uuid.New().String(), utils.DocumentExtension(contentType))
+	if err := h.store.Put(c.Context(), key, src, file.Size, contentType); err != nil {
+		fmt.Printf("Failed to store document %s: %v\n", key, err)
+		return nil, fiber.StatusInternalServerError, "Failed to store file"
+	}
+
+	document := &models.ApplicationDocument{
+		ApplicationType: appType,
+		Kind:            kind,
+		FileName:        cleanFileName(file.Filename),
+		ContentType:     contentType,
+		Size:            file.Size,
+		SHA256:          hex.EncodeToString(hash.Sum(nil)),
+		StorageKey:      key,
+		UploaderIP:      c.IP(),
+	}
+	if userID, ok := c.Locals("userID").(string); ok {
+		if id, err := primitive.ObjectIDFromHex(userID); err == nil {
+			document.UploadedBy = &id
+		}
+	}
+
+	if err := h.repo.CreateDocument(c.Context(), document); err != nil {
+		if err := h.store.Delete(c.Context(), key); err != nil {
+			fmt.Printf("Failed to remove orphaned document %s: %v\n", key, err)
+		}
+		return nil, fiber.StatusInternalServerError, err.Error()
+	}
+
+	return document, 0, ""
+}
+
+// DownloadDocument handles GET /api/applications/documents/:id for staff
+func (h *DocumentHandler) DownloadDocument(c *fiber.Ctx) error {
+	document, err := h.findDocument(c)
+	if document == nil {
+		return err
+	}
+	return h.serve(c, document)
+}
+
+// DocumentLink handles GET /api/applications/documents/:id/link.
+// It returns a signed URL that downloads the document without a token until it expires,
+// for opening files in a new tab or an external viewer.
+func (h *DocumentHandler) DocumentLink(c *fiber.Ctx) error {
+	document, err := h.findDocument(c)
+	if document == nil {
+		return err
+	}
+
+	expiresAt := time.Now().Add(utils.DocumentLinkTTL())
+	query := url.Values{
+		"expires":   {strconv.FormatInt(expiresAt.Unix(), 10)},
+		"signature": {utils.SignDocumentLink(document.ID.Hex(), expiresAt)},
+	}
+
+	return utils.SendSuccess(c, "Download link created", fiber.Map{
+		"url":        "/api/documents/" + document.ID.Hex() + "?" + query.Encode(),
+		"expires_at": expiresAt,
+	})
+}
+
+// DownloadSignedDocument handles GET /api/documents/:id?expires=...&signature=...
+func (h *DocumentHandler) DownloadSignedDocument(c *fiber.Ctx) error {
+	if !utils.VerifyDocumentLink(c.Params("id"), c.Query("expires"), c.Query("signature")) {
+		return utils.SendError(c, fiber.StatusForbidden, "Download link is invalid or has expired")
+	}
+
+	document, err := h.findDocument(c)
+	if document == nil {
+		return err
+	}
+	return h.serve(c, document)
+}
+
+// findDocument loads the document of the :id parameter.
+// When it returns nil the error response has already been sent.
+func (h *DocumentHandler) findDocument(c *fiber.Ctx) (*models.ApplicationDocument, error) {
+	id, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return nil, utils.SendBadRequest(c, "Invalid document ID")
+	}
+	document, err := h.repo.GetDocumentByID(c.Context(), id)
+	if err == mongo.ErrNoDocuments {
+		return nil, utils.SendNotFound(c, "Document")
+	}
+	if err != nil {
+		return nil, utils.SendInternalError(c, err.Error())
+	}
+	return document, nil
+}
+
+// serve streams a document as an attachment so browsers never render it inline
+func (h *DocumentHandler) serve(c *fiber.Ctx, document *models.ApplicationDocument) error {
+	body, err := h.store.Get(c.Context(), document.StorageKey)
+	if err == utils.ErrBlobNotFound {
+		return utils.SendNotFound(c, "Document")
+	}
+	if err != nil {
+		fmt.Printf("Failed to read document %s: %v\n", document.StorageKey, err)
+		return utils.SendInternalError(c, "Failed to read file")
+	}
+
+	c.Set(fiber.HeaderContentType, document.ContentType)
+	disposition := mime.FormatMediaType("attachment", map[string]string{"filename": document.FileName})
+	if disposition == "" {
+		disposition = "attachment"
+	}
+	c.Set(fiber.HeaderContentDisposition, disposition)
+	c.Set(fiber.HeaderXContentTypeOptions, "nosniff")
+	c.Set(fiber.HeaderCacheControl, "private, no-store")
+	return c.SendStream(body, int(document.Size))
+}
+
+// documentCleanupBatch bounds the documents removed per cleanup run
+const documentCleanupBatch = 100
+
+// RunUnattachedCleanup deletes, every interval until ctx is done, the uploads that were not
+// attached to an application within utils.DocumentUnattachedTTL, together with their files
+func (h *DocumentHandler) RunUnattachedCleanup(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		removed, err := h.removeUnattached(ctx)
+		if err != nil {
+			fmt.Printf("Failed to clean up unattached documents: %v\n", err)
+		}
+		if removed > 0 {
+			fmt.Printf("Removed %d unattached documents\n", removed)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// removeUnattached deletes one batch of expired unattached uploads and returns how many went
+func (h *DocumentHandler) removeUnattached(ctx context.Context) (int, error) {
+	documents, err := h.repo.UnattachedDocumentsBefore(ctx, time.Now().Add(-utils.DocumentUnattachedTTL()), documentCleanupBatch)
+	if err != nil {
+		return 0, err
+	}
+
+	removed := 0
+	for _, document := range documents {
+		// The metadata goes first so a document attached meanwhile keeps its file
+		deleted, err := h.repo.DeleteUnattachedDocument(ctx, document.ID)
+		if err != nil {
+			return removed, err
+		}
+		if !deleted {
+			continue
+		}
+		if err := h.store.Delete(ctx, document.StorageKey); err != nil && err != utils.ErrBlobNotFound {
+			fmt.Printf("Failed to delete file %s of unattached document %s: %v\n", document.StorageKey, document.ID.Hex(), err)
+		}
+		removed++
+	}
+	return removed, nil
+}
+
+// cleanFileName keeps the base name of an uploaded file for display
+func cleanFileName(name string) string {
+	name = strings.TrimSpace(filepath.Base(strings.ReplaceAll(name, "\\", "/")))
+	if name == "." || name == "/" || name == "" {
+		return "document"
+	}
+	if len(name) > 255 {
+		name = name[:255]
+	}
+	return name
+}
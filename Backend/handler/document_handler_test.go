@@ -0,0 +1,71 @@
+package handler
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AliSleiman0/Lacpa/models"
+	"github.com/AliSleiman0/Lacpa/repository"
+	"github.com/AliSleiman0/Lacpa/utils"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// unattachedDocumentRepo serves the document cleanup from memory. Documents listed in
+// attachedMeanwhile are attached between the listing and the delete.
+type unattachedDocumentRepo struct {
+	repository.ApplicationRepository
+	expired           []models.ApplicationDocument
+	attachedMeanwhile map[primitive.ObjectID]bool
+	deleted           []primitive.ObjectID
+}
+
+func (r *unattachedDocumentRepo) UnattachedDocumentsBefore(ctx context.Context, cutoff time.Time, limit int64) ([]models.ApplicationDocument, error) {
+	return r.expired, nil
+}
+
+func (r *unattachedDocumentRepo) DeleteUnattachedDocument(ctx context.Context, id primitive.ObjectID) (bool, error) {
+	if r.attachedMeanwhile[id] {
+		return false, nil
+	}
+	r.deleted = append(r.deleted, id)
+	return true, nil
+}
+
+func TestRemoveUnattachedDeletesMetadataAndFiles(t *testing.T) {
+	store, err := utils.NewLocalBlobStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewLocalBlobStore: %v", err)
+	}
+
+	abandoned := models.ApplicationDocument{ID: primitive.NewObjectID(), StorageKey: "abandoned.pdf"}
+	attached := models.ApplicationDocument{ID: primitive.NewObjectID(), StorageKey: "attached.pdf"}
+	for _, document := range []models.ApplicationDocument{abandoned, attached} {
+		if err := store.Put(context.Background(), document.StorageKey, strings.NewReader("%PDF-1.7"), 8, "application/pdf"); err != nil {
+			t.Fatalf("Put: %v", err)
+		}
+	}
+
+	repo := &unattachedDocumentRepo{
+		expired:           []models.ApplicationDocument{abandoned, attached},
+		attachedMeanwhile: map[primitive.ObjectID]bool{attached.ID: true},
+	}
+	h := NewDocumentHandler(repo, store)
+
+	removed, err := h.removeUnattached(context.Background())
+	if err != nil {
+		t.Fatalf("removeUnattached: %v", err)
+	}
+	if removed != 1 || len(repo.deleted) != 1 || repo.deleted[0] != abandoned.ID {
+		t.Fatalf("removed %d documents %v, want only %s", removed, repo.deleted, abandoned.ID.Hex())
+	}
+	if _, err := store.Get(context.Background(), abandoned.StorageKey); err != utils.ErrBlobNotFound {
+		t.Fatalf("file of the abandoned upload: err = %v, want ErrBlobNotFound", err)
+	}
+	body, err := store.Get(context.Background(), attached.StorageKey)
+	if err != nil {
+		t.Fatalf("file of the document attached meanwhile was removed: %v", err)
+	}
+	body.Close()
+}
@@ -0,0 +1,392 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/AliSleiman0/Lacpa/models"
+	"github.com/AliSleiman0/Lacpa/repository"
+	"github.com/AliSleiman0/Lacpa/utils"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// InvoiceHandler manages application fees and the invoices issued for them.
+//
+// ROLE: Application Fee Invoicing
+//   - Finance staff set the fee of each application type (amount, LBP or USD, days to pay)
+//   - Submitting an application issues its invoice while the type has an active fee;
+//     invoice numbers are sequential per year (INV-2025-00001)
+//   - An application cannot move to Under Review until its invoice is paid
+//   - Payments are recorded by finance staff, or reported by the payment provider
+//     configured with PAYMENT_PROVIDER after the applicant paid online
+type InvoiceHandler struct {
+	invoices     repository.InvoiceRepository
+	applications repository.ApplicationRepository
+	provider     utils.PaymentProvider
+	audit        *AuditService
+}
+
+func NewInvoiceHandler(invoices repository.InvoiceRepository, applications repository.ApplicationRepository, provider utils.PaymentProvider, audit *AuditService) *InvoiceHandler {
+	return &InvoiceHandler{invoices: invoices, applications: applications, provider: provider, audit: audit}
+}
+
+// ListFees handles GET /api/finance/fees
+func (h *InvoiceHandler) ListFees(c *fiber.Ctx) error {
+	fees, err := h.invoices.GetFees(c.Context())
+	if err != nil {
+		return utils.SendInternalError(c, err.Error())
+	}
+	return utils.SendSuccess(c, "Fees retrieved successfully", fees)
+}
+
+// UpdateFee handles PUT /api/finance/fees/:type, setting the fee of individual or firm
+// applications. The new fee applies to invoices issued from now on.
+func (h *InvoiceHandler) UpdateFee(c *fiber.Ctx) error {
+	appType, ok := trackedType(c.Params("type"))
+	if !ok {
+		return utils.SendNotFound(c, "Application type")
+	}
+
+	var req models.FeeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.SendBadRequest(c, "Invalid request body")
+	}
+	req.Currency = models.Currency(strings.ToUpper(string(req.Currency)))
+	if err := utils.ValidateStruct(req); err != nil {
+		if ve, ok := err.(*utils.ValidationErrors); ok {
+			return utils.SendValidationErrors(c, ve)
+		}
+		return utils.SendBadRequest(c, err.Error())
+	}
+
+	before, err := h.invoices.GetFee(c.Context(), appType)
+	if err != nil && err != mongo.ErrNoDocuments {
+		return utils.SendInternalError(c, err.Error())
+	}
+
+	fee := &models.ApplicationFee{
+		ApplicationType: appType,
+		Amount:          models.RoundAmount(req.Amount),
+		Currency:        req.Currency,
+		DueDays:         req.DueDays,
+		IsActive:        req.IsActive,
+		UpdatedBy:       submitterID(c),
+	}
+	if err := h.invoices.SaveFee(c.Context(), fee); err != nil {
+		return utils.SendInternalError(c, err.Error())
+	}
+	h.audit.Record(c, models.AuditFeeUpdated, models.AuditTargetFee, fee.ID.Hex(), before, fee)
+
+	return utils.SendSuccess(c, "Fee updated successfully", fee)
+}
+
+// ListInvoices handles GET /api/finance/invoices with the filters:
+//   - status: unpaid or paid
+//   - type: Individual or Firm
+//   - overdue: true for unpaid invoices past their due date
+//   - q: search on the invoice number, application reference, name and email
+//   - page, page_size
+func (h *InvoiceHandler) ListInvoices(c *fiber.Ctx) error {
+	filter := models.InvoiceFilter{
+		Status:          models.InvoiceStatus(c.Query("status")),
+		ApplicationType: models.ApplicationType(c.Query("type")),
+		Overdue:         c.QueryBool("overdue"),
+		Search:          strings.TrimSpace(c.Query("q")),
+	}
+	switch filter.Status {
+	case "", models.InvoiceStatusUnpaid, models.InvoiceStatusPaid:
+	default:
+		return utils.SendBadRequest(c, "Invalid invoice status")
+	}
+	if filter.ApplicationType != "" && !isApplicationType(filter.ApplicationType) {
+		return utils.SendBadRequest(c, "Invalid application type")
+	}
+
+	// Paginate normalizes page and page_size; the metadata is rebuilt once the total is known
+	page := c.QueryInt("page", 1)
+	pageSize := c.QueryInt("page_size", 20)
+	filter.Offset, filter.Limit, _ = utils.Paginate(page, pageSize, 0)
+
+	invoices, total, err := h.invoices.ListInvoices(c.Context(), filter)
+	if err != nil {
+		return utils.SendInternalError(c, err.Error())
+	}
+	_, _, meta := utils.Paginate(page, pageSize, int(total))
+
+	return utils.SendSuccess(c, "Invoices retrieved successfully", fiber.Map{
+		"invoices":   invoices,
+		"pagination": meta,
+	})
+}
+
+// GetInvoice handles GET /api/finance/invoices/:id
+func (h *InvoiceHandler) GetInvoice(c *fiber.Ctx) error {
+	invoice, err := h.findInvoice(c)
+	if invoice == nil {
+		return err
+	}
+	return utils.SendSuccess(c, "Invoice retrieved successfully", invoice)
+}
+
+// IssueInvoice handles POST /api/finance/invoices, issuing the invoice of an application
+// that has none, e.g. one submitted before the fee of its type was set
+func (h *InvoiceHandler) IssueInvoice(c *fiber.Ctx) error {
+	var req models.IssueInvoiceRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.SendBadRequest(c, "Invalid request body")
+	}
+	if err := utils.ValidateStruct(req); err != nil {
+		if ve, ok := err.(*utils.ValidationErrors); ok {
+			return utils.SendValidationErrors(c, ve)
+		}
+		return utils.SendBadRequest(c, err.Error())
+	}
+	applicationID, err := primitive.ObjectIDFromHex(req.ApplicationID)
+	if err != nil {
+		return utils.SendBadRequest(c, "Invalid application ID")
+	}
+
+	var billing invoiceBilling
+	if req.ApplicationType == models.ApplicationTypeFirm {
+		application, err := h.applications.GetFirmApplicationByID(c.Context(), applicationID)
+		if err == mongo.ErrNoDocuments {
+			return utils.SendNotFound(c, "Application")
+		}
+		if err != nil {
+			return utils.SendInternalError(c, err.Error())
+		}
+		billing = firmBilling(application)
+	} else {
+		application, err := h.applications.GetIndividualApplicationByID(c.Context(), applicationID)
+		if err == mongo.ErrNoDocuments {
+			return utils.SendNotFound(c, "Application")
+		}
+		if err != nil {
+			return utils.SendInternalError(c, err.Error())
+		}
+		billing = individualBilling(application)
+	}
+
+	if _, err := h.invoices.GetInvoiceByApplication(c.Context(), applicationID); err == nil {
+		return utils.SendError(c, fiber.StatusConflict, "This application already has an invoice")
+	} else if err != mongo.ErrNoDocuments {
+		return utils.SendInternalError(c, err.Error())
+	}
+
+	invoice, err := issueApplicationInvoice(c.Context(), h.invoices, billing)
+	if mongo.IsDuplicateKeyError(err) {
+		return utils.SendError(c, fiber.StatusConflict, "This application already has an invoice")
+	}
+	if err != nil {
+		return utils.SendInternalError(c, err.Error())
+	}
+	if invoice == nil {
+		return utils.SendError(c, fiber.StatusConflict, "No active fee is set for "+strings.ToLower(string(req.ApplicationType))+" applications")
+	}
+	h.audit.Record(c, models.AuditInvoiceIssued, models.AuditTargetInvoice, invoice.ID.Hex(), nil, invoice)
+
+	return utils.SendCreated(c, invoice, "")
+}
+
+// RecordPayment handles POST /api/finance/invoices/:id/payments, recording a payment
+// received outside the system. The amount and currency must match the invoice.
+func (h *InvoiceHandler) RecordPayment(c *fiber.Ctx) error {
+	before, err := h.findInvoice(c)
+	if before == nil {
+		return err
+	}
+
+	var req models.RecordPaymentRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.SendBadRequest(c, "Invalid request body")
+	}
+	req.Currency = models.Currency(strings.ToUpper(string(req.Currency)))
+	req.Reference = strings.TrimSpace(req.Reference)
+	req.Notes = strings.TrimSpace(req.Notes)
+	if err := utils.ValidateStruct(req); err != nil {
+		if ve, ok := err.(*utils.ValidationErrors); ok {
+			return utils.SendValidationErrors(c, ve)
+		}
+		return utils.SendBadRequest(c, err.Error())
+	}
+
+	paidAt := time.Now()
+	if req.PaidAt != nil {
+		if req.PaidAt.After(paidAt) {
+			return utils.SendBadRequest(c, "Payment date cannot be in the future")
+		}
+		paidAt = *req.PaidAt
+	}
+	if !paymentMatches(before, req.Amount, req.Currency) {
+		return utils.SendBadRequest(c, fmt.Sprintf("Payment must be %.2f %s", before.Amount, before.Currency))
+	}
+
+	payment := &models.InvoicePayment{
+		Method:     models.PaymentMethodManual,
+		Reference:  req.Reference,
+		Amount:     models.RoundAmount(req.Amount),
+		Currency:   req.Currency,
+		Notes:      req.Notes,
+		RecordedBy: submitterID(c),
+		PaidAt:     paidAt,
+	}
+	if err := h.invoices.MarkInvoicePaid(c.Context(), before.ID, payment); err != nil {
+		return sendPaymentError(c, err)
+	}
+
+	after, _ := h.invoices.GetInvoiceByID(c.Context(), before.ID)
+	h.audit.Record(c, models.AuditInvoicePaid, models.AuditTargetInvoice, before.ID.Hex(), before, after)
+
+	return utils.SendSuccess(c, "Payment recorded successfully", after)
+}
+
+// PaymentNotification handles POST /api/payments/:provider/notify, the payment provider's
+// report on a checkout. Repeated notifications of a paid invoice are acknowledged
+// without recording the payment again.
+func (h *InvoiceHandler) PaymentNotification(c *fiber.Ctx) error {
+	if h.provider == nil || c.Params("provider") != h.provider.Name() {
+		return utils.SendNotFound(c, "Payment provider")
+	}
+
+	notification, err := h.provider.ParseNotification(c.Context(), c.Body(), func(key string) string { return c.Get(key) })
+	if err != nil {
+		return utils.SendBadRequest(c, err.Error())
+	}
+
+	before, err := h.invoices.GetInvoiceByNumber(c.Context(), notification.InvoiceNumber)
+	if err == mongo.ErrNoDocuments {
+		return utils.SendNotFound(c, "Invoice")
+	}
+	if err != nil {
+		return utils.SendInternalError(c, err.Error())
+	}
+	if before.PaymentProvider != h.provider.Name() || before.PaymentSessionID != notification.SessionID {
+		return utils.SendBadRequest(c, "Notification does not match the invoice's checkout")
+	}
+	if !notification.Paid {
+		return utils.SendSuccess(c, "Notification received", nil)
+	}
+	if before.Status == models.InvoiceStatusPaid {
+		return utils.SendSuccess(c, "Payment already recorded", nil)
+	}
+	if !paymentMatches(before, notification.Amount, models.Currency(notification.Currency)) {
+		fmt.Printf("Payment notification for invoice %s does not match: %.2f %s\n", before.Number, notification.Amount, notification.Currency)
+		return utils.SendBadRequest(c, "Payment does not match the invoice")
+	}
+
+	payment := &models.InvoicePayment{
+		Method:    h.provider.Name(),
+		Reference: notification.Reference,
+		Amount:    models.RoundAmount(notification.Amount),
+		Currency:  before.Currency,
+		PaidAt:    time.Now(),
+	}
+	if err := h.invoices.MarkInvoicePaid(c.Context(), before.ID, payment); err != nil {
+		if err == repository.ErrInvoicePaid {
+			return utils.SendSuccess(c, "Payment already recorded", nil)
+		}
+		return sendPaymentError(c, err)
+	}
+
+	after, _ := h.invoices.GetInvoiceByID(c.Context(), before.ID)
+	h.audit.Record(c, models.AuditInvoicePaid, models.AuditTargetInvoice, before.ID.Hex(), before, after)
+
+	return utils.SendSuccess(c, "Payment recorded", nil)
+}
+
+// findInvoice loads the invoice of the :id parameter.
+// When it returns nil the error response has already been sent.
+func (h *InvoiceHandler) findInvoice(c *fiber.Ctx) (*models.Invoice, error) {
+	id, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return nil, utils.SendBadRequest(c, "Invalid invoice ID")
+	}
+	invoice, err := h.invoices.GetInvoiceByID(c.Context(), id)
+	if err == mongo.ErrNoDocuments {
+		return nil, utils.SendNotFound(c, "Invoice")
+	}
+	if err != nil {
+		return nil, utils.SendInternalError(c, err.Error())
+	}
+	return invoice, nil
+}
+
+// invoiceBilling is the application an invoice is issued for
+type invoiceBilling struct {
+	appType   models.ApplicationType
+	id        primitive.ObjectID
+	reference string
+	name      string
+	email     string
+}
+
+func individualBilling(application *models.IndividualApplication) invoiceBilling {
+	return invoiceBilling{
+		appType:   models.ApplicationTypeIndividual,
+		id:        application.ID,
+		reference: application.ReferenceNumber,
+		name:      application.FullName(),
+		email:     application.Email,
+	}
+}
+
+func firmBilling(application *models.FirmApplication) invoiceBilling {
+	return invoiceBilling{
+		appType:   models.ApplicationTypeFirm,
+		id:        application.ID,
+		reference: application.ReferenceNumber,
+		name:      application.FirmName,
+		email:     application.Email,
+	}
+}
+
+// issueApplicationInvoice bills an application the current fee of its type.
+// It returns a nil invoice when the type has no active, non-zero fee.
+func issueApplicationInvoice(ctx context.Context, invoices repository.InvoiceRepository, billing invoiceBilling) (*models.Invoice, error) {
+	fee, err := invoices.GetFee(ctx, billing.appType)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !fee.Charged() {
+		return nil, nil
+	}
+
+	invoice := &models.Invoice{
+		ApplicationType: billing.appType,
+		ApplicationID:   billing.id,
+		ReferenceNumber: billing.reference,
+		BilledTo:        billing.name,
+		Email:           billing.email,
+		Amount:          fee.Amount,
+		Currency:        fee.Currency,
+		DueDate:         time.Now().AddDate(0, 0, fee.DueDays),
+	}
+	if err := invoices.CreateInvoice(ctx, invoice); err != nil {
+		return nil, err
+	}
+	return invoice, nil
+}
+
+// paymentMatches reports whether a payment settles the invoice in full
+func paymentMatches(invoice *models.Invoice, amount float64, currency models.Currency) bool {
+	return currency == invoice.Currency && models.RoundAmount(amount) == models.RoundAmount(invoice.Amount)
+}
+
+// sendPaymentError maps a failed payment update to a response
+func sendPaymentError(c *fiber.Ctx, err error) error {
+	switch err {
+	case mongo.ErrNoDocuments:
+		return utils.SendNotFound(c, "Invoice")
+	case repository.ErrInvoicePaid:
+		return utils.SendError(c, fiber.StatusConflict, err.Error())
+	default:
+		return utils.SendInternalError(c, err.Error())
+	}
+}
@@ -0,0 +1,131 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/AliSleiman0/Lacpa/models"
+	"github.com/AliSleiman0/Lacpa/repository"
+	"github.com/AliSleiman0/Lacpa/utils"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// memoryInvoiceRepo keeps one invoice for the payment notification tests
+type memoryInvoiceRepo struct {
+	repository.InvoiceRepository
+	invoice *models.Invoice
+}
+
+func (r *memoryInvoiceRepo) GetInvoiceByNumber(ctx context.Context, number string) (*models.Invoice, error) {
+	if r.invoice.Number != number {
+		return nil, mongo.ErrNoDocuments
+	}
+	invoice := *r.invoice
+	return &invoice, nil
+}
+
+func (r *memoryInvoiceRepo) GetInvoiceByID(ctx context.Context, id primitive.ObjectID) (*models.Invoice, error) {
+	if r.invoice.ID != id {
+		return nil, mongo.ErrNoDocuments
+	}
+	invoice := *r.invoice
+	return &invoice, nil
+}
+
+func (r *memoryInvoiceRepo) MarkInvoicePaid(ctx context.Context, id primitive.ObjectID, payment *models.InvoicePayment) error {
+	if r.invoice.Status == models.InvoiceStatusPaid {
+		return repository.ErrInvoicePaid
+	}
+	r.invoice.Status = models.InvoiceStatusPaid
+	r.invoice.Payment = payment
+	return nil
+}
+
+func TestPaymentNotification(t *testing.T) {
+	provider := utils.NewFakePaymentProvider("test-payment-secret", "")
+	forger := utils.NewFakePaymentProvider("guessed-secret", "")
+
+	valid := utils.PaymentNotification{
+		SessionID:     "fake_session",
+		InvoiceNumber: "INV-2025-00001",
+		Amount:        150,
+		Currency:      string(models.CurrencyUSD),
+		Paid:          true,
+		Reference:     "TX-1",
+	}
+	with := func(change func(*utils.PaymentNotification)) utils.PaymentNotification {
+		notification := valid
+		change(&notification)
+		return notification
+	}
+
+	tests := []struct {
+		name         string
+		provider     string
+		signer       *utils.FakePaymentProvider
+		notification utils.PaymentNotification
+		signature    string // Replaces the signature when set
+		status       int
+		paid         bool
+	}{
+		{name: "valid", notification: valid, status: fiber.StatusOK, paid: true},
+		{name: "forged signature", signer: forger, notification: valid, status: fiber.StatusBadRequest},
+		{name: "missing signature", notification: valid, signature: "-", status: fiber.StatusBadRequest},
+		{name: "short amount", notification: with(func(n *utils.PaymentNotification) { n.Amount = 1.5 }), status: fiber.StatusBadRequest},
+		{name: "other currency", notification: with(func(n *utils.PaymentNotification) { n.Currency = string(models.CurrencyLBP) }), status: fiber.StatusBadRequest},
+		{name: "other checkout", notification: with(func(n *utils.PaymentNotification) { n.SessionID = "fake_other" }), status: fiber.StatusBadRequest},
+		{name: "unknown invoice", notification: with(func(n *utils.PaymentNotification) { n.InvoiceNumber = "INV-2025-00002" }), status: fiber.StatusNotFound},
+		{name: "not paid", notification: with(func(n *utils.PaymentNotification) { n.Paid = false }), status: fiber.StatusOK},
+		{name: "other provider", provider: "stripe", notification: valid, status: fiber.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &memoryInvoiceRepo{invoice: &models.Invoice{
+				ID:               primitive.NewObjectID(),
+				Number:           "INV-2025-00001",
+				Amount:           150,
+				Currency:         models.CurrencyUSD,
+				Status:           models.InvoiceStatusUnpaid,
+				PaymentProvider:  "fake",
+				PaymentSessionID: "fake_session",
+			}}
+			app := fiber.New()
+			app.Post("/api/payments/:provider/notify", NewInvoiceHandler(repo, nil, provider, nil).PaymentNotification)
+
+			signer := tt.signer
+			if signer == nil {
+				signer = provider
+			}
+			body, signature, err := signer.FakeNotification(tt.notification)
+			if err != nil {
+				t.Fatalf("FakeNotification: %v", err)
+			}
+			if tt.signature == "-" {
+				signature = ""
+			}
+			name := tt.provider
+			if name == "" {
+				name = "fake"
+			}
+
+			req := httptest.NewRequest(fiber.MethodPost, "/api/payments/"+name+"/notify", bytes.NewReader(body))
+			req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+			req.Header.Set(utils.FakePaymentSignatureHeader, signature)
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatalf("request failed: %v", err)
+			}
+			if resp.StatusCode != tt.status {
+				t.Fatalf("status = %d, want %d", resp.StatusCode, tt.status)
+			}
+			if paid := repo.invoice.Status == models.InvoiceStatusPaid; paid != tt.paid {
+				t.Fatalf("invoice paid = %v, want %v", paid, tt.paid)
+			}
+		})
+	}
+}
@@ -0,0 +1,41 @@
+package handler
+
+import (
+	"context"
+	"errors"
+
+	"github.com/AliSleiman0/Lacpa/models"
+	"github.com/AliSleiman0/Lacpa/repository"
+)
+
+// maxLACPAIDAttempts bounds retries when an allocated number is already taken,
+// which only happens for IDs generated before sequential allocation
+const maxLACPAIDAttempts = 5
+
+// ErrEmailTaken is returned when the email is already registered
+var ErrEmailTaken = errors.New("email already registered")
+
+// createUserWithLACPAID allocates the next LACPA ID for the entity type and creates the user.
+// The unique index on users.lacpa_id is the final guard against collisions.
+func createUserWithLACPAID(ctx context.Context, authRepo *repository.AuthRepository, counters *repository.CounterRepository, user *models.User, entity string) error {
+	for attempt := 0; attempt < maxLACPAIDAttempts; attempt++ {
+		lacpaID, err := counters.NextLACPAID(ctx, entity)
+		if err != nil {
+			return err
+		}
+		user.LACPAID = lacpaID
+
+		err = authRepo.CreateUser(user)
+		switch {
+		case err == nil:
+			return nil
+		case repository.IsDuplicateKeyOn(err, "email"):
+			return ErrEmailTaken
+		case repository.IsDuplicateKeyOn(err, "lacpa_id"):
+			continue
+		default:
+			return err
+		}
+	}
+	return errors.New("could not allocate a free LACPA ID")
+}
@@ -0,0 +1,301 @@
+package handler
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/AliSleiman0/Lacpa/models"
+	"github.com/AliSleiman0/Lacpa/utils"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// claimTarget is the member record a claim refers to
+type claimTarget struct {
+	id        primitive.ObjectID
+	email     string // Registered email the code is sent to
+	name      string
+	claimedBy *primitive.ObjectID
+	userField string // Field on the user document that stores the link
+}
+
+// ClaimProfile starts linking the current account to a registry record.
+// A code is sent to the email registered on the record, not to the account email.
+func (h *AuthHandler) ClaimProfile(c *fiber.Ctx) error {
+	var req models.ClaimProfileRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Invalid request body. Please check your JSON format.",
+			"success": false,
+		})
+	}
+	if err := utils.ValidateStruct(req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   err.Error(),
+			"success": false,
+		})
+	}
+
+	// Anything but "firm" would otherwise be looked up as an individual record
+	if !models.IsValidClaimMemberType(req.MemberType) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "member_type must be individual or firm",
+			"success": false,
+		})
+	}
+
+	user, status, message := h.currentUser(c)
+	if user == nil {
+		return c.Status(status).JSON(fiber.Map{
+			"error":   message,
+			"success": false,
+		})
+	}
+	if alreadyLinked(user, req.MemberType) {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error":   "Your account is already linked to a " + req.MemberType + " profile",
+			"success": false,
+		})
+	}
+
+	target, err := h.findClaimTarget(c, req.MemberType, req.LacpaID)
+	if err == mongo.ErrNoDocuments {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error":   "No member found with this LACPA ID",
+			"success": false,
+		})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   "Failed to retrieve member",
+			"success": false,
+		})
+	}
+	if target.claimedBy != nil && *target.claimedBy != user.ID {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error":   "This profile is already linked to another account",
+			"success": false,
+		})
+	}
+	if target.email == "" {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+			"error":   "This profile has no registered email. Please contact LACPA to link it.",
+			"success": false,
+		})
+	}
+
+	// The code only redeems for this user and this record
+	subject := claimSubject(user.ID, req.MemberType, target.id)
+	if _, err := h.verification.Issue(c.Context(), target.email, target.name, models.PurposeClaimProfile, subject); err != nil {
+		fmt.Printf("Failed to send claim code for %s %s: %v\n", req.MemberType, req.LacpaID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   "Failed to send verification code",
+			"success": false,
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "A verification code was sent to the email registered on this profile",
+		"data": fiber.Map{
+			"sent_to": maskEmail(target.email),
+		},
+	})
+}
+
+// VerifyClaim completes a claim with the code and links account and record both ways
+func (h *AuthHandler) VerifyClaim(c *fiber.Ctx) error {
+	var req models.VerifyClaimRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Invalid request body. Please check your JSON format.",
+			"success": false,
+		})
+	}
+	if err := utils.ValidateStruct(req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   err.Error(),
+			"success": false,
+		})
+	}
+
+	// Anything but "firm" would otherwise be looked up as an individual record
+	if !models.IsValidClaimMemberType(req.MemberType) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "member_type must be individual or firm",
+			"success": false,
+		})
+	}
+
+	user, status, message := h.currentUser(c)
+	if user == nil {
+		return c.Status(status).JSON(fiber.Map{
+			"error":   message,
+			"success": false,
+		})
+	}
+	if alreadyLinked(user, req.MemberType) {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error":   "Your account is already linked to a " + req.MemberType + " profile",
+			"success": false,
+		})
+	}
+
+	target, err := h.findClaimTarget(c, req.MemberType, req.LacpaID)
+	if err == mongo.ErrNoDocuments {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error":   "No member found with this LACPA ID",
+			"success": false,
+		})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   "Failed to retrieve member",
+			"success": false,
+		})
+	}
+
+	// The code only redeems for this user and this record
+	_, err = h.verification.Verify(c.Context(), target.email, models.PurposeClaimProfile, claimSubject(user.ID, req.MemberType, target.id), req.OTP)
+	switch err {
+	case nil:
+	case ErrCodeNotFound:
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Code expired or invalid. Please request a new one.",
+			"success": false,
+		})
+	case ErrCodeInvalid:
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Invalid code",
+			"success": false,
+		})
+	case ErrCodeTooManyAttempts:
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Too many wrong codes. Please request a new one.",
+			"success": false,
+		})
+	default:
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   "Failed to verify code",
+			"success": false,
+		})
+	}
+
+	// Claim the record first; it only succeeds if nobody else holds it
+	linked, err := h.linkMember(c, req.MemberType, target.id, user.ID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   "Failed to link profile",
+			"success": false,
+		})
+	}
+	if !linked {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error":   "This profile is already linked to another account",
+			"success": false,
+		})
+	}
+
+	if err := h.authRepo.LinkMemberProfile(user.ID, target.userField, target.id); err != nil {
+		// Release the record so the claim can be retried
+		if err := h.unlinkMember(c, req.MemberType, target.id, user.ID); err != nil {
+			fmt.Printf("Failed to release %s %s after link failure: %v\n", req.MemberType, target.id.Hex(), err)
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   "Failed to link profile",
+			"success": false,
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "Profile linked successfully",
+		"data": fiber.Map{
+			"member_type": req.MemberType,
+			"member_id":   target.id,
+		},
+	})
+}
+
+// currentUser loads the user of the access token
+func (h *AuthHandler) currentUser(c *fiber.Ctx) (*models.User, int, string) {
+	userID, err := primitive.ObjectIDFromHex(c.Locals("userID").(string))
+	if err != nil {
+		return nil, fiber.StatusBadRequest, "Invalid user ID"
+	}
+	user, err := h.authRepo.GetUserByID(userID)
+	if err != nil {
+		return nil, fiber.StatusNotFound, "User not found"
+	}
+	return user, 0, ""
+}
+
+// findClaimTarget looks up the record a claim refers to by registry number
+func (h *AuthHandler) findClaimTarget(c *fiber.Ctx, memberType, lacpaID string) (*claimTarget, error) {
+	lacpaID = strings.TrimSpace(lacpaID)
+	if memberType == models.MemberTypeFirm {
+		firm, err := h.membersRepo.GetFirmMemberByLacpaID(c.Context(), lacpaID)
+		if err != nil {
+			return nil, err
+		}
+		return &claimTarget{
+			id:        firm.ID,
+			email:     firm.PrimaryEmail,
+			name:      firm.FirmName,
+			claimedBy: firm.UserID,
+			userField: "firm_member_id",
+		}, nil
+	}
+
+	member, err := h.membersRepo.GetIndividualMemberByLacpaID(c.Context(), lacpaID)
+	if err != nil {
+		return nil, err
+	}
+	return &claimTarget{
+		id:        member.ID,
+		email:     member.Email,
+		name:      member.GetFullName(),
+		claimedBy: member.UserID,
+		userField: "individual_member_id",
+	}, nil
+}
+
+// linkMember sets the user on the claimed record
+func (h *AuthHandler) linkMember(c *fiber.Ctx, memberType string, id, userID primitive.ObjectID) (bool, error) {
+	if memberType == models.MemberTypeFirm {
+		return h.membersRepo.LinkFirmMemberUser(c.Context(), id, userID)
+	}
+	return h.membersRepo.LinkIndividualMemberUser(c.Context(), id, userID)
+}
+
+// unlinkMember clears the user from a claimed record
+func (h *AuthHandler) unlinkMember(c *fiber.Ctx, memberType string, id, userID primitive.ObjectID) error {
+	if memberType == models.MemberTypeFirm {
+		return h.membersRepo.UnlinkFirmMemberUser(c.Context(), id, userID)
+	}
+	return h.membersRepo.UnlinkIndividualMemberUser(c.Context(), id, userID)
+}
+
+// alreadyLinked reports whether the account already holds a record of the type
+func alreadyLinked(user *models.User, memberType string) bool {
+	if memberType == models.MemberTypeFirm {
+		return user.FirmMemberID != nil
+	}
+	return user.IndividualMemberID != nil
+}
+
+// claimSubject binds a claim code to the user and record it was issued for
+func claimSubject(userID primitive.ObjectID, memberType string, memberID primitive.ObjectID) string {
+	return userID.Hex() + ":" + memberType + ":" + memberID.Hex()
+}
+
+// maskEmail hides most of the local part, e.g. "b*****a@gmail.com"
+func maskEmail(email string) string {
+	at := strings.LastIndex(email, "@")
+	if at <= 1 {
+		return email
+	}
+	local := email[:at]
+	return local[:1] + strings.Repeat("*", len(local)-2) + local[len(local)-1:] + email[at:]
+}
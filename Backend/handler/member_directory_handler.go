@@ -0,0 +1,183 @@
+package handler
+
+import (
+	"strings"
+
+	"github.com/AliSleiman0/Lacpa/models"
+	"github.com/AliSleiman0/Lacpa/utils"
+	"github.com/gofiber/fiber/v2"
+)
+
+// SearchIndividuals handles GET /members/individuals/search, the HTMX fragment of the
+// individual directory, and GET /api/members/individuals/search with the same filters
+func (h *MembersHandler) SearchIndividuals(c *fiber.Ctx) error {
+	filter, page, pageSize := parseDirectoryFilter(c)
+	members, total, err := h.repo.SearchIndividualMembers(c.Context(), filter)
+	if err != nil {
+		return utils.SendInternalError(c, err.Error())
+	}
+	facets, err := h.repo.CountIndividualMemberFacets(c.Context(), filter)
+	if err != nil {
+		return utils.SendInternalError(c, err.Error())
+	}
+	keepChosenFacets(facets, filter)
+
+	if utils.WantsJSON(c) {
+		_, _, meta := utils.Paginate(page, pageSize, int(total))
+		return utils.SendSuccess(c, "Members retrieved successfully", fiber.Map{
+			"members":    directoryIndividuals(members),
+			"facets":     facets,
+			"pagination": meta,
+		})
+	}
+	return c.Render("LACPA/members/individuals_directory", individualDirectoryData(filter, facets, members, page, pageSize, total))
+}
+
+// SearchFirms handles GET /membership/firms/search, the HTMX fragment of the firm
+// directory, and GET /api/members/firms/search with the same filters
+func (h *MembersHandler) SearchFirms(c *fiber.Ctx) error {
+	filter, page, pageSize := parseDirectoryFilter(c)
+	firms, total, err := h.repo.SearchFirmMembers(c.Context(), filter)
+	if err != nil {
+		return utils.SendInternalError(c, err.Error())
+	}
+	facets, err := h.repo.CountFirmMemberFacets(c.Context(), filter)
+	if err != nil {
+		return utils.SendInternalError(c, err.Error())
+	}
+	keepChosenFacets(facets, filter)
+
+	if utils.WantsJSON(c) {
+		_, _, meta := utils.Paginate(page, pageSize, int(total))
+		return utils.SendSuccess(c, "Firms retrieved successfully", fiber.Map{
+			"firms":      directoryFirms(firms),
+			"facets":     facets,
+			"pagination": meta,
+		})
+	}
+	return c.Render("LACPA/members/firms_directory", firmDirectoryData(filter, facets, firms, page, pageSize, total))
+}
+
+// parseDirectoryFilter reads the directory search from the query string:
+//   - q: full-text search on names, firm, specializations and city
+//   - type, governorate, district, specialization, dues: facet values, "all" or empty for any;
+//     type is the firm type for firms
+//   - size: firm size, firms only
+//   - page, pageSize: 4 members per page by default, at most 100
+func parseDirectoryFilter(c *fiber.Ctx) (models.MemberDirectoryFilter, int, int) {
+	filter := models.MemberDirectoryFilter{
+		Search:         strings.TrimSpace(c.Query("q")),
+		MemberType:     facetQuery(c, "type"),
+		Governorate:    facetQuery(c, "governorate"),
+		District:       facetQuery(c, "district"),
+		Specialization: facetQuery(c, "specialization"),
+		DuesStatus:     facetQuery(c, "dues"),
+		FirmSize:       facetQuery(c, "size"),
+	}
+
+	page := c.QueryInt("page", 1)
+	pageSize := c.QueryInt("pageSize", 4)
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 4
+	}
+	filter.Offset, filter.Limit, _ = utils.Paginate(page, pageSize, 0)
+	if page < 1 {
+		page = 1
+	}
+	return filter, page, pageSize
+}
+
+// facetQuery reads a facet value, treating "all" as no value
+func facetQuery(c *fiber.Ctx, key string) string {
+	value := strings.TrimSpace(c.Query(key))
+	if strings.EqualFold(value, "all") {
+		return ""
+	}
+	return value
+}
+
+// keepChosenFacets lists each chosen facet value even when no member matches it,
+// so the directory still shows it as selected
+func keepChosenFacets(facets *models.MemberFacets, filter models.MemberDirectoryFilter) {
+	keep := func(counts []models.FacetCount, value string) []models.FacetCount {
+		if value == "" {
+			return counts
+		}
+		for _, count := range counts {
+			if count.Value == value {
+				return counts
+			}
+		}
+		return append(counts, models.FacetCount{Value: value})
+	}
+	facets.MemberType = keep(facets.MemberType, filter.MemberType)
+	facets.Governorate = keep(facets.Governorate, filter.Governorate)
+	facets.District = keep(facets.District, filter.District)
+	facets.Specialization = keep(facets.Specialization, filter.Specialization)
+	facets.DuesStatus = keep(facets.DuesStatus, filter.DuesStatus)
+	facets.FirmSize = keep(facets.FirmSize, filter.FirmSize)
+}
+
+// individualDirectoryData is the template data of the individuals page and its search fragment
+func individualDirectoryData(filter models.MemberDirectoryFilter, facets *models.MemberFacets, members []*models.IndividualMember, page, pageSize int, total int64) fiber.Map {
+	data := directoryData(filter, facets, page, pageSize, total)
+	data["Title"] = "Individual Members"
+	data["Members"] = directoryIndividuals(members)
+	data["AllCount"] = facetTotal(facets.MemberType)
+	data["ShowIndividuals"] = true
+	data["ShowFirms"] = false
+	return data
+}
+
+// firmDirectoryData is the template data of the firms page and its search fragment
+func firmDirectoryData(filter models.MemberDirectoryFilter, facets *models.MemberFacets, firms []*models.FirmMember, page, pageSize int, total int64) fiber.Map {
+	data := directoryData(filter, facets, page, pageSize, total)
+	data["Title"] = "Firm Members"
+	data["Firms"] = directoryFirms(firms)
+	data["AllCount"] = facetTotal(facets.FirmSize)
+	data["ShowIndividuals"] = false
+	data["ShowFirms"] = true
+	return data
+}
+
+// directoryIndividuals is the public view of a page of individual members
+func directoryIndividuals(members []*models.IndividualMember) []models.DirectoryIndividual {
+	cards := make([]models.DirectoryIndividual, 0, len(members))
+	for _, member := range members {
+		cards = append(cards, models.NewDirectoryIndividual(member))
+	}
+	return cards
+}
+
+// directoryFirms is the public view of a page of firm members
+func directoryFirms(firms []*models.FirmMember) []models.DirectoryFirm {
+	cards := make([]models.DirectoryFirm, 0, len(firms))
+	for _, firm := range firms {
+		cards = append(cards, models.NewDirectoryFirm(firm))
+	}
+	return cards
+}
+
+func directoryData(filter models.MemberDirectoryFilter, facets *models.MemberFacets, page, pageSize int, total int64) fiber.Map {
+	totalPages := (int(total) + pageSize - 1) / pageSize
+	if totalPages < 1 {
+		totalPages = 1
+	}
+	return fiber.Map{
+		"Filter":      filter,
+		"Facets":      facets,
+		"CurrentPage": page,
+		"TotalPages":  totalPages,
+		"PageSize":    pageSize,
+		"TotalCount":  total,
+	}
+}
+
+// facetTotal is the number of members counted by a facet, shown on its "All" pill
+func facetTotal(counts []models.FacetCount) int64 {
+	var total int64
+	for _, count := range counts {
+		total += count.Count
+	}
+	return total
+}
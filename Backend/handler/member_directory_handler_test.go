@@ -0,0 +1,136 @@
+package handler
+
+import (
+	"context"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/AliSleiman0/Lacpa/models"
+	"github.com/AliSleiman0/Lacpa/repository"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// directoryRepo returns one private member and one private firm for any search
+type directoryRepo struct {
+	repository.Repository
+	member *models.IndividualMember
+	firm   *models.FirmMember
+}
+
+func (r *directoryRepo) SearchIndividualMembers(ctx context.Context, filter models.MemberDirectoryFilter) ([]*models.IndividualMember, int64, error) {
+	return []*models.IndividualMember{r.member}, 1, nil
+}
+
+func (r *directoryRepo) CountIndividualMemberFacets(ctx context.Context, filter models.MemberDirectoryFilter) (*models.MemberFacets, error) {
+	return &models.MemberFacets{}, nil
+}
+
+func (r *directoryRepo) SearchFirmMembers(ctx context.Context, filter models.MemberDirectoryFilter) ([]*models.FirmMember, int64, error) {
+	return []*models.FirmMember{r.firm}, 1, nil
+}
+
+func (r *directoryRepo) CountFirmMemberFacets(ctx context.Context, filter models.MemberDirectoryFilter) (*models.MemberFacets, error) {
+	return &models.MemberFacets{}, nil
+}
+
+func TestDirectoryHidesPrivateFields(t *testing.T) {
+	userID := primitive.NewObjectID()
+	repo := &directoryRepo{
+		member: &models.IndividualMember{
+			ID:            primitive.NewObjectID(),
+			UserID:        &userID,
+			LacpaID:       "3666",
+			FirstName:     "Boushra",
+			LastName:      "Obeid",
+			Email:         "hidden-email@example.com",
+			Phone:         "+961 01 111 222",
+			LinkedInURL:   "linkedin.com/in/hidden-profile",
+			FullAddress:   "Hidden Street 1",
+			City:          "HiddenCity",
+			LicenseNumber: "LIC-HIDDEN",
+			CPECredits:    42,
+		},
+		firm: &models.FirmMember{
+			ID:                 primitive.NewObjectID(),
+			UserID:             &userID,
+			LacpaID:            "F-1234",
+			FirmName:           "Obeid & Partners",
+			PrimaryEmail:       "hidden-firm@example.com",
+			PrimaryPhone:       "+961 01 333 444",
+			Website:            "hidden-site.example.com",
+			City:               "HiddenCity",
+			ContactPersonName:  "Hidden Contact",
+			ContactPersonEmail: "hidden-contact@example.com",
+			TaxIDNumber:        "TAX-HIDDEN",
+			RegistrationNumber: "REG-HIDDEN",
+			CommercialLicense:  "CL-HIDDEN",
+			AnnualRevenue:      "$1M - $5M",
+			NumberOfEmployees:  4321,
+		},
+	}
+	h := NewMembersHandler(repo)
+	app := fiber.New()
+	app.Get("/api/members/individuals/search", h.SearchIndividuals)
+	app.Get("/api/members/firms/search", h.SearchFirms)
+
+	tests := []struct {
+		path    string
+		visible []string
+		hidden  []string
+	}{
+		{
+			path:    "/api/members/individuals/search",
+			visible: []string{"Boushra", "3666"},
+			hidden: []string{"hidden-email", "01 111 222", "hidden-profile", "Hidden Street", "HiddenCity",
+				"LIC-HIDDEN", userID.Hex(), "license_number", "cpe_credits", "user_id"},
+		},
+		{
+			path:    "/api/members/firms/search",
+			visible: []string{"Obeid \\u0026 Partners", "F-1234"},
+			hidden: []string{"hidden-firm", "01 333 444", "hidden-site", "HiddenCity", "Hidden Contact", "hidden-contact",
+				"TAX-HIDDEN", "REG-HIDDEN", "CL-HIDDEN", "$1M", "4321", userID.Hex(), "contact_person", "tax_id_number", "user_id"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			req := httptest.NewRequest(fiber.MethodGet, tt.path, nil)
+			req.Header.Set(fiber.HeaderAccept, fiber.MIMEApplicationJSON)
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatalf("request failed: %v", err)
+			}
+			if resp.StatusCode != fiber.StatusOK {
+				t.Fatalf("status = %d, want %d", resp.StatusCode, fiber.StatusOK)
+			}
+			body, _ := io.ReadAll(resp.Body)
+			for _, value := range tt.visible {
+				if !strings.Contains(string(body), value) {
+					t.Errorf("response lacks public value %q", value)
+				}
+			}
+			for _, value := range tt.hidden {
+				if strings.Contains(string(body), value) {
+					t.Errorf("response exposes %q", value)
+				}
+			}
+		})
+	}
+}
+
+func TestDirectoryShowsWhatMembersAllow(t *testing.T) {
+	card := models.NewDirectoryIndividual(&models.IndividualMember{
+		Email: "public@example.com", ShowEmail: true,
+		Phone: "+961 01 111 222",
+		City:  "Zalqa", ShowAddress: true,
+	})
+	if card.Email != "public@example.com" || card.City != "Zalqa" {
+		t.Fatalf("shared details were dropped: %+v", card)
+	}
+	if card.Phone != "" {
+		t.Fatalf("hidden phone %q is shown", card.Phone)
+	}
+}
@@ -1,8 +1,7 @@
 package handler
 
 import (
-	"strconv"
-
+	"github.com/AliSleiman0/Lacpa/models"
 	"github.com/AliSleiman0/Lacpa/repository"
 	"github.com/gofiber/fiber/v2"
 )
@@ -15,7 +14,8 @@ func NewMembersHandler(repo repository.Repository) *MembersHandler {
 	return &MembersHandler{repo: repo}
 }
 
-// GetIndividualsPage renders the individual members HTML page
+// GetIndividualsPage renders the individual members HTML page with the directory search
+// of parseDirectoryFilter; the search box then loads results from SearchIndividuals
 func (h *MembersHandler) GetIndividualsPage(c *fiber.Ctx) error {
 	// Check if this is an HTMX request (fragment) or browser request (need full page)
 	if c.Get("HX-Request") != "true" {
@@ -23,69 +23,24 @@ func (h *MembersHandler) GetIndividualsPage(c *fiber.Ctx) error {
 		return c.SendFile("../LACPA_Web/src/index.html")
 	}
 
-	// Get pagination parameters
-	page, err := strconv.Atoi(c.Query("page", "1"))
-	if err != nil || page < 1 {
-		page = 1
-	}
-
-	pageSize, err := strconv.Atoi(c.Query("pageSize", "4"))
-	if err != nil || pageSize < 1 || pageSize > 100 {
-		pageSize = 4 // Default to 4 members per page
-	}
-
-	// Get member type filter (optional)
-	memberType := c.Query("type", "all") // "all", "Apprentices", "Practicing", "Non-Practicing", "Retired"
-
-	// Get members from repository
-	var members interface{}
-	var total int64
-
-	if memberType == "all" || memberType == "" {
-		members, total, err = h.repo.GetAllIndividualMembers(c.Context(), page, pageSize)
-	} else {
-		members, total, err = h.repo.GetIndividualMembersByType(c.Context(), memberType, page, pageSize)
-	}
-
+	filter, page, pageSize := parseDirectoryFilter(c)
+	members, total, err := h.repo.SearchIndividualMembers(c.Context(), filter)
 	if err != nil {
 		// If error, render with empty data
-		return c.Render("LACPA/members/individuals", fiber.Map{
-			"Title":           "Individual Members",
-			"Members":         nil,
-			"CurrentPage":     1,
-			"TotalPages":      1,
-			"PageSize":        pageSize,
-			"TotalCount":      0,
-			"MemberType":      memberType,
-			"ShowIndividuals": true,
-			"ShowFirms":       false,
-		})
+		return c.Render("LACPA/members/individuals", individualDirectoryData(filter, &models.MemberFacets{}, nil, 1, pageSize, 0))
 	}
-
-	// Calculate total pages
-	totalPages := int(total) / pageSize
-	if int(total)%pageSize > 0 {
-		totalPages++
-	}
-	if totalPages < 1 {
-		totalPages = 1
+	facets, err := h.repo.CountIndividualMemberFacets(c.Context(), filter)
+	if err != nil {
+		facets = &models.MemberFacets{}
 	}
+	keepChosenFacets(facets, filter)
 
 	// Render the members page template
-	return c.Render("LACPA/members/individuals", fiber.Map{
-		"Title":           "Individual Members",
-		"Members":         members,
-		"CurrentPage":     page,
-		"TotalPages":      totalPages,
-		"PageSize":        pageSize,
-		"TotalCount":      total,
-		"MemberType":      memberType,
-		"ShowIndividuals": true,
-		"ShowFirms":       false,
-	})
+	return c.Render("LACPA/members/individuals", individualDirectoryData(filter, facets, members, page, pageSize, total))
 }
 
-// GetFirmsPage renders the firm members HTML page
+// GetFirmsPage renders the firm members HTML page with the directory search
+// of parseDirectoryFilter; the search box then loads results from SearchFirms
 func (h *MembersHandler) GetFirmsPage(c *fiber.Ctx) error {
 	// Check if this is an HTMX request (fragment) or browser request (need full page)
 	if c.Get("HX-Request") != "true" {
@@ -93,70 +48,18 @@ func (h *MembersHandler) GetFirmsPage(c *fiber.Ctx) error {
 		return c.SendFile("../LACPA_Web/src/index.html")
 	}
 
-	// Get pagination parameters
-	page, err := strconv.Atoi(c.Query("page", "1"))
-	if err != nil || page < 1 {
-		page = 1
-	}
-
-	pageSize, err := strconv.Atoi(c.Query("pageSize", "4"))
-	if err != nil || pageSize < 1 || pageSize > 100 {
-		pageSize = 4 // Default to 4 firms per page
-	}
-
-	// Get firm type/size filter (optional)
-	firmType := c.Query("type", "all") // "all", "Audit Firm", "Accounting Firm", "Consultancy"
-	firmSize := c.Query("size", "all") // "all", "Big 4", "Large", "Medium", "Small"
-
-	// Get firms from repository
-	var firms interface{}
-	var total int64
-
-	// Priority: size filter > type filter > all
-	if firmSize != "all" && firmSize != "" {
-		firms, total, err = h.repo.GetFirmMembersBySize(c.Context(), firmSize, page, pageSize)
-	} else if firmType != "all" && firmType != "" {
-		firms, total, err = h.repo.GetFirmMembersByType(c.Context(), firmType, page, pageSize)
-	} else {
-		firms, total, err = h.repo.GetAllFirmMembers(c.Context(), page, pageSize)
-	}
-
+	filter, page, pageSize := parseDirectoryFilter(c)
+	firms, total, err := h.repo.SearchFirmMembers(c.Context(), filter)
 	if err != nil {
 		// If error, render with empty data
-		return c.Render("LACPA/members/firms", fiber.Map{
-			"Title":           "Firm Members",
-			"Firms":           nil,
-			"CurrentPage":     1,
-			"TotalPages":      1,
-			"PageSize":        pageSize,
-			"TotalCount":      0,
-			"FirmType":        firmType,
-			"FirmSize":        firmSize,
-			"ShowIndividuals": false,
-			"ShowFirms":       true,
-		})
+		return c.Render("LACPA/members/firms", firmDirectoryData(filter, &models.MemberFacets{}, nil, 1, pageSize, 0))
 	}
-
-	// Calculate total pages
-	totalPages := int(total) / pageSize
-	if int(total)%pageSize > 0 {
-		totalPages++
-	}
-	if totalPages < 1 {
-		totalPages = 1
+	facets, err := h.repo.CountFirmMemberFacets(c.Context(), filter)
+	if err != nil {
+		facets = &models.MemberFacets{}
 	}
+	keepChosenFacets(facets, filter)
 
 	// Render the firms page template
-	return c.Render("LACPA/members/firms", fiber.Map{
-		"Title":           "Firm Members",
-		"Firms":           firms,
-		"CurrentPage":     page,
-		"TotalPages":      totalPages,
-		"PageSize":        pageSize,
-		"TotalCount":      total,
-		"FirmType":        firmType,
-		"FirmSize":        firmSize,
-		"ShowIndividuals": false,
-		"ShowFirms":       true,
-	})
+	return c.Render("LACPA/members/firms", firmDirectoryData(filter, facets, firms, page, pageSize, total))
 }
@@ -0,0 +1,300 @@
+package handler
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/AliSleiman0/Lacpa/models"
+	"github.com/AliSleiman0/Lacpa/repository"
+	"github.com/AliSleiman0/Lacpa/utils"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// oidcStateTTL is how long a user has to complete the login at the provider
+const oidcStateTTL = 10 * time.Minute
+
+// oidcStateCookie binds a pending login to the browser that started it
+const oidcStateCookie = "oidc_state"
+
+// OIDCHandler signs staff in through the organization's identity provider.
+//
+// ROLE: Staff Single Sign-On
+// - Runs the authorization code flow with PKCE
+// - Maps the provider's group claim to a LACPA role on every login
+// - Links an existing account by verified email or creates one on first login
+// - Ends in the same session and token pair as a password login
+//
+// The provider is only trusted for the second factor when the ID token asserts it
+// through its "amr" or "acr" claim (see utils.OIDCConfig.MFAAsserted). Otherwise the
+// login continues with the same TOTP challenge as a password login.
+type OIDCHandler struct {
+	auth     *AuthHandler
+	provider *utils.OIDCProvider
+	states   *repository.OIDCStateRepository
+}
+
+func NewOIDCHandler(auth *AuthHandler, provider *utils.OIDCProvider, states *repository.OIDCStateRepository) *OIDCHandler {
+	return &OIDCHandler{
+		auth:     auth,
+		provider: provider,
+		states:   states,
+	}
+}
+
+// Login redirects the browser to the identity provider
+func (h *OIDCHandler) Login(c *fiber.Ctx) error {
+	state, err := utils.GenerateResetToken()
+	if err != nil {
+		return h.fail(c, "Failed to start single sign-on")
+	}
+	nonce, err := utils.GenerateResetToken()
+	if err != nil {
+		return h.fail(c, "Failed to start single sign-on")
+	}
+	verifier, err := utils.GeneratePKCEVerifier()
+	if err != nil {
+		return h.fail(c, "Failed to start single sign-on")
+	}
+
+	authURL, err := h.provider.AuthCodeURL(c.Context(), state, nonce, utils.PKCEChallenge(verifier))
+	if err != nil {
+		fmt.Printf("OIDC provider unavailable: %v\n", err)
+		return h.fail(c, "Single sign-on provider is unavailable")
+	}
+
+	pending := &models.OIDCLoginState{
+		StateHash:    utils.HashToken(state),
+		CodeVerifier: verifier,
+		Nonce:        nonce,
+		ExpiresAt:    time.Now().Add(oidcStateTTL),
+	}
+	if err := h.states.CreateState(c.Context(), pending); err != nil {
+		return h.fail(c, "Failed to start single sign-on")
+	}
+
+	c.Cookie(&fiber.Cookie{
+		Name:     oidcStateCookie,
+		Value:    state,
+		Path:     "/api/auth/oidc",
+		Expires:  pending.ExpiresAt,
+		HTTPOnly: true,
+		Secure:   c.Protocol() == "https",
+		SameSite: fiber.CookieSameSiteLaxMode,
+	})
+
+	return c.Redirect(authURL, fiber.StatusFound)
+}
+
+// Callback completes the login when the provider sends the browser back
+func (h *OIDCHandler) Callback(c *fiber.Ctx) error {
+	// The provider's error is only logged; the page shows a fixed message
+	if providerError := c.Query("error"); providerError != "" {
+		fmt.Printf("OIDC provider returned error %q\n", providerError)
+		return h.fail(c, "Single sign-on was cancelled")
+	}
+
+	state := c.Query("state")
+	cookieState := c.Cookies(oidcStateCookie)
+	c.ClearCookie(oidcStateCookie)
+	if state == "" || subtle.ConstantTimeCompare([]byte(state), []byte(cookieState)) != 1 {
+		return h.fail(c, "Single sign-on session is invalid. Please try again.")
+	}
+
+	pending, err := h.states.ConsumeState(c.Context(), utils.HashToken(state))
+	if err != nil {
+		return h.fail(c, "Single sign-on session expired. Please try again.")
+	}
+
+	rawIDToken, err := h.provider.Exchange(c.Context(), c.Query("code"), pending.CodeVerifier)
+	if err != nil {
+		fmt.Printf("OIDC code exchange failed: %v\n", err)
+		return h.fail(c, "Single sign-on failed. Please try again.")
+	}
+	identity, err := h.provider.VerifyIDToken(c.Context(), rawIDToken, pending.Nonce)
+	if err != nil {
+		fmt.Printf("OIDC ID token rejected: %v\n", err)
+		return h.fail(c, "Single sign-on failed. Please try again.")
+	}
+
+	role, ok := h.provider.Config.RoleForGroups(identity.Groups)
+	if !ok {
+		return h.fail(c, "Your organization account is not assigned a LACPA role")
+	}
+	if _, err := h.auth.roleRepo.GetRoleByName(c.Context(), role); err != nil {
+		fmt.Printf("OIDC group mapping points to unknown role %q: %v\n", role, err)
+		return h.fail(c, "Your organization account is not assigned a LACPA role")
+	}
+
+	user, message := h.resolveUser(c, identity, role)
+	if user == nil {
+		return h.fail(c, message)
+	}
+	if !user.IsActive {
+		return h.fail(c, "Account is deactivated. Please contact support.")
+	}
+
+	// The provider's groups are the source of truth for staff roles
+	if user.Role != role {
+		if err := h.auth.authRepo.UpdateRole(user.ID, role); err != nil {
+			return h.fail(c, "Failed to update account role")
+		}
+		recordSecurityEvent(h.auth.authRepo, user, models.SecurityEventOIDCRoleChanged,
+			"Role changed from "+user.Role+" to "+role+" by single sign-on groups", c.IP(), "oidc")
+		if _, err := h.auth.sessionRepo.RevokeAllForUser(c.Context(), user.ID, models.SessionRevokedRoleChanged); err != nil {
+			fmt.Printf("Failed to revoke sessions of %s: %v\n", user.Email, err)
+		}
+		user.Role = role
+	}
+
+	// Without a second factor asserted by the provider, finish with the TOTP step
+	if !h.provider.Config.MFAAsserted(identity) {
+		purpose, err := h.auth.secondFactor(c, user)
+		if err != nil {
+			return h.fail(c, "Failed to retrieve role")
+		}
+		if purpose != "" {
+			return h.challenge(c, user, purpose)
+		}
+	}
+
+	if err := h.auth.authRepo.UpdateLastLogin(user.ID); err != nil {
+		// Log error but don't fail the login
+		fmt.Printf("Failed to update last login: %v\n", err)
+	}
+	authResponse, err := h.auth.startSession(c, user)
+	if err != nil {
+		return h.fail(c, "Failed to generate token")
+	}
+
+	// Tokens travel in the fragment so they never reach server logs
+	fragment := url.Values{
+		"token":         {authResponse.Token},
+		"refresh_token": {authResponse.RefreshToken},
+		"expires_in":    {strconv.Itoa(authResponse.ExpiresIn)},
+	}
+	return c.Redirect(h.provider.Config.PostLoginRedirect+"#"+fragment.Encode(), fiber.StatusFound)
+}
+
+// resolveUser finds the account of an identity, linking or creating it on first login.
+// On failure it returns a nil user and the message to show.
+func (h *OIDCHandler) resolveUser(c *fiber.Ctx, identity *utils.OIDCIdentity, role string) (*models.User, string) {
+	user, err := h.auth.authRepo.GetUserByOIDCSubject(identity.Subject)
+	if err == nil {
+		return user, ""
+	}
+	if err != mongo.ErrNoDocuments {
+		return nil, "Failed to retrieve account"
+	}
+
+	email := normalizeEmail(identity.Email)
+	if email == "" || !identity.EmailVerified {
+		return nil, "Your organization account has no verified email address"
+	}
+
+	// Link an existing account with the same email
+	user, err = h.auth.authRepo.GetUserByEmail(email)
+	if err == nil {
+		return h.linkUser(c, user, identity)
+	}
+	if err != mongo.ErrNoDocuments {
+		return nil, "Failed to retrieve account"
+	}
+
+	// First login: create a staff account. The random password is never shown,
+	// so the account can only sign in through the provider unless reset.
+	hashedPassword, err := randomPasswordHash()
+	if err != nil {
+		return nil, "Failed to create account"
+	}
+
+	fullName := strings.TrimSpace(identity.Name)
+	if fullName == "" {
+		fullName = email
+	}
+	user = &models.User{
+		FullName:    fullName,
+		Email:       email,
+		Password:    hashedPassword,
+		Role:        role,
+		IsVerified:  true,
+		IsActive:    true,
+		OIDCSubject: identity.Subject,
+	}
+	if err := createUserWithLACPAID(c.Context(), h.auth.authRepo, h.auth.counterRepo, user, models.LACPAIDAdmin); err != nil {
+		fmt.Printf("Failed to create SSO user %s: %v\n", email, err)
+		return nil, "Failed to create account"
+	}
+	return user, ""
+}
+
+// linkUser links an existing account with the same email to the identity. An account
+// whose email was never verified may have been registered by someone else ahead of the
+// owner, so its password is replaced before the provider's verification is trusted.
+func (h *OIDCHandler) linkUser(c *fiber.Ctx, user *models.User, identity *utils.OIDCIdentity) (*models.User, string) {
+	if !user.IsVerified {
+		hashedPassword, err := randomPasswordHash()
+		if err != nil {
+			return nil, "Failed to link account"
+		}
+		if err := h.auth.authRepo.UpdatePassword(user.ID, hashedPassword, 0); err != nil {
+			return nil, "Failed to link account"
+		}
+		user.Password = hashedPassword
+	}
+
+	linked, err := h.auth.authRepo.LinkOIDCSubject(user.ID, identity.Subject)
+	if err != nil {
+		return nil, "Failed to link account"
+	}
+	if !linked {
+		return nil, "This account is already linked to another organization identity"
+	}
+	user.OIDCSubject = identity.Subject
+
+	description := "Account linked to single sign-on"
+	if !user.IsVerified {
+		// The provider verified the email
+		if err := h.auth.authRepo.VerifyUser(user.Email); err != nil {
+			return nil, "Failed to link account"
+		}
+		user.IsVerified = true
+		description = "Unverified account linked to single sign-on; its password was replaced"
+	}
+	recordSecurityEvent(h.auth.authRepo, user, models.SecurityEventOIDCLinked, description, c.IP(), "oidc")
+	return user, ""
+}
+
+// randomPasswordHash hashes a random password that is never shown to anyone
+func randomPasswordHash() (string, error) {
+	randomPassword, err := utils.GenerateResetToken()
+	if err != nil {
+		return "", err
+	}
+	return utils.HashPassword(randomPassword)
+}
+
+// challenge sends the browser back to the login page with a challenge token in the
+// fragment, so the page completes the login like a password login needing 2FA
+func (h *OIDCHandler) challenge(c *fiber.Ctx, user *models.User, purpose string) error {
+	token, err := utils.GenerateChallengeToken(user.ID.Hex(), purpose)
+	if err != nil {
+		return h.fail(c, "Failed to generate token")
+	}
+	fragment := url.Values{
+		"challenge_token": {token},
+		"purpose":         {purpose},
+		"expires_in":      {strconv.Itoa(int(utils.ChallengeTokenTTL.Seconds()))},
+	}
+	return c.Redirect(h.provider.Config.PostLoginRedirect+"#"+fragment.Encode(), fiber.StatusFound)
+}
+
+// fail sends the browser back to the login page with an error in the fragment
+func (h *OIDCHandler) fail(c *fiber.Ctx, message string) error {
+	fragment := url.Values{"error": {message}}
+	return c.Redirect(h.provider.Config.PostLoginRedirect+"#"+fragment.Encode(), fiber.StatusFound)
+}
@@ -0,0 +1,87 @@
+package handler
+
+import (
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/AliSleiman0/Lacpa/utils"
+	"github.com/gofiber/fiber/v2"
+)
+
+// newCallbackTestApp serves the callback of a handler whose login never gets past the
+// checks under test, so it needs neither the database nor a reachable provider
+func newCallbackTestApp() *fiber.App {
+	h := NewOIDCHandler(nil, utils.NewOIDCProvider(utils.OIDCConfig{
+		Issuer:            "http://127.0.0.1:1",
+		ClientID:          "lacpa-cms",
+		PostLoginRedirect: "/admin/src/login.html",
+	}), nil)
+
+	app := fiber.New()
+	app.Get("/api/auth/oidc/callback", h.Callback)
+	return app
+}
+
+// callbackError returns the error the callback put in the redirect fragment
+func callbackError(t *testing.T, app *fiber.App, query, cookie string) string {
+	t.Helper()
+
+	req := httptest.NewRequest(fiber.MethodGet, "/api/auth/oidc/callback?"+query, nil)
+	if cookie != "" {
+		req.Header.Set("Cookie", oidcStateCookie+"="+cookie)
+	}
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	if resp.StatusCode != fiber.StatusFound {
+		t.Fatalf("status = %d, want %d", resp.StatusCode, fiber.StatusFound)
+	}
+
+	location := resp.Header.Get("Location")
+	base, fragment, _ := strings.Cut(location, "#")
+	if base != "/admin/src/login.html" {
+		t.Fatalf("redirect to %q, want the login page", base)
+	}
+	values, err := url.ParseQuery(fragment)
+	if err != nil {
+		t.Fatalf("invalid fragment %q: %v", fragment, err)
+	}
+	if values.Get("token") != "" || values.Get("challenge_token") != "" {
+		t.Fatalf("fragment %q carries a token", fragment)
+	}
+	return values.Get("error")
+}
+
+func TestOIDCCallbackRejectsStateMismatch(t *testing.T) {
+	app := newCallbackTestApp()
+
+	tests := []struct {
+		name   string
+		query  string
+		cookie string
+	}{
+		{name: "no state", query: "code=abc", cookie: "state-1"},
+		{name: "no cookie", query: "code=abc&state=state-1"},
+		{name: "different cookie", query: "code=abc&state=state-1", cookie: "state-2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := callbackError(t, app, tt.query, tt.cookie); got != "Single sign-on session is invalid. Please try again." {
+				t.Fatalf("error = %q", got)
+			}
+		})
+	}
+}
+
+func TestOIDCCallbackHidesProviderError(t *testing.T) {
+	app := newCallbackTestApp()
+
+	query := url.Values{"error": {"<script>alert(1)</script> Call +1-555 to restore access"}, "state": {"state-1"}}
+	if got := callbackError(t, app, query.Encode(), "state-1"); got != "Single sign-on was cancelled" {
+		t.Fatalf("error = %q, want the fixed message", got)
+	}
+}
@@ -1,32 +1,43 @@
 package handler
 
 import (
-	"sync"
+	"fmt"
 
+	"github.com/AliSleiman0/Lacpa/models"
 	"github.com/AliSleiman0/Lacpa/utils"
 	"github.com/gofiber/fiber/v2"
 )
 
-// In-memory OTP storage (for testing - use Redis or database in production)
-var (
-	otpStore = make(map[string]*utils.OTPEmail)
-	otpMutex sync.RWMutex
-)
-
-// OTPHandler handles OTP-related requests
-type OTPHandler struct{}
+// OTPHandler handles standalone email confirmation codes, such as the one
+// confirming the email on a membership application. Account flows use the
+// same VerificationService through /api/auth.
+type OTPHandler struct {
+	verification *VerificationService
+}
 
 // NewOTPHandler creates a new OTP handler
-func NewOTPHandler() *OTPHandler {
-	return &OTPHandler{}
+func NewOTPHandler(verification *VerificationService) *OTPHandler {
+	return &OTPHandler{
+		verification: verification,
+	}
+}
+
+// otpRequestPurpose returns the purpose requested on /api/otp/*.
+// Only application confirmation is served here; account purposes go through /api/auth.
+func otpRequestPurpose(purpose string) (models.VerificationPurpose, bool) {
+	if purpose == "" {
+		return models.PurposeApplicationConfirm, true
+	}
+	return models.VerificationPurpose(purpose), models.VerificationPurpose(purpose) == models.PurposeApplicationConfirm
 }
 
 // SendOTP sends an OTP to the specified email
 // POST /api/otp/send
-// Body: { "email": "user@example.com" }
+// Body: { "email": "user@example.com", "purpose": "application_confirm" }
 func (h *OTPHandler) SendOTP(c *fiber.Ctx) error {
 	var req struct {
-		Email string `json:"email"`
+		Email   string `json:"email"`
+		Purpose string `json:"purpose"`
 	}
 
 	if err := c.BodyParser(&req); err != nil {
@@ -42,30 +53,32 @@ func (h *OTPHandler) SendOTP(c *fiber.Ctx) error {
 		return utils.SendBadRequest(c, "Invalid email format")
 	}
 
-	// Send OTP email
-	otpData, err := utils.SendOTPEmail(req.Email)
+	purpose, ok := otpRequestPurpose(req.Purpose)
+	if !ok {
+		return utils.SendBadRequest(c, "Unsupported purpose")
+	}
+
+	expiresAt, err := h.verification.Issue(c.Context(), req.Email, "", purpose, "")
 	if err != nil {
+		fmt.Printf("Failed to send %s code to %s: %v\n", purpose, req.Email, err)
 		return utils.SendInternalError(c, "Failed to send OTP email")
 	}
 
-	// Store OTP in memory (use Redis or database in production)
-	otpMutex.Lock()
-	otpStore[req.Email] = otpData
-	otpMutex.Unlock()
-
 	return utils.SendSuccess(c, "OTP sent successfully", fiber.Map{
-		"email":      otpData.Email,
-		"expires_at": otpData.ExpiresAt,
+		"email":      normalizeEmail(req.Email),
+		"purpose":    purpose,
+		"expires_at": expiresAt,
 	})
 }
 
 // VerifyOTP verifies the OTP provided by the user
 // POST /api/otp/verify
-// Body: { "email": "user@example.com", "otp": "123456" }
+// Body: { "email": "user@example.com", "otp": "123456", "purpose": "application_confirm" }
 func (h *OTPHandler) VerifyOTP(c *fiber.Ctx) error {
 	var req struct {
-		Email string `json:"email"`
-		OTP   string `json:"otp"`
+		Email   string `json:"email"`
+		OTP     string `json:"otp"`
+		Purpose string `json:"purpose"`
 	}
 
 	if err := c.BodyParser(&req); err != nil {
@@ -76,27 +89,26 @@ func (h *OTPHandler) VerifyOTP(c *fiber.Ctx) error {
 		return utils.SendBadRequest(c, "Email and OTP are required")
 	}
 
-	// Get stored OTP
-	otpMutex.RLock()
-	storedOTP, exists := otpStore[req.Email]
-	otpMutex.RUnlock()
-
-	if !exists {
-		return utils.SendError(c, fiber.StatusNotFound, "No OTP found for this email")
+	purpose, ok := otpRequestPurpose(req.Purpose)
+	if !ok {
+		return utils.SendBadRequest(c, "Unsupported purpose")
 	}
 
-	// Verify OTP
-	if !utils.VerifyOTP(storedOTP, req.OTP) {
-		return utils.SendError(c, fiber.StatusUnauthorized, "Invalid or expired OTP")
+	if _, err := h.verification.Verify(c.Context(), req.Email, purpose, "", req.OTP); err != nil {
+		switch err {
+		case ErrCodeNotFound:
+			return utils.SendError(c, fiber.StatusNotFound, "No OTP found for this email or it has expired")
+		case ErrCodeInvalid:
+			return utils.SendError(c, fiber.StatusUnauthorized, "Invalid OTP")
+		case ErrCodeTooManyAttempts:
+			return utils.SendError(c, fiber.StatusUnauthorized, "Too many wrong codes. Please request a new OTP.")
+		default:
+			return utils.SendInternalError(c, "Failed to verify OTP")
+		}
 	}
 
-	// Remove OTP from store after successful verification
-	otpMutex.Lock()
-	delete(otpStore, req.Email)
-	otpMutex.Unlock()
-
 	return utils.SendSuccess(c, "OTP verified successfully", fiber.Map{
-		"email":    req.Email,
+		"email":    normalizeEmail(req.Email),
 		"verified": true,
 	})
 }
@@ -0,0 +1,43 @@
+package handler
+
+import (
+	"fmt"
+
+	"github.com/AliSleiman0/Lacpa/models"
+	"github.com/AliSleiman0/Lacpa/utils"
+)
+
+// validateNewPassword checks a new password against the configured policy and,
+// for an existing user, against the current and recently used passwords.
+// It returns nil when the password is acceptable.
+func validateNewPassword(field, password string, user *models.User) *utils.ValidationErrors {
+	policy := utils.LoadPasswordPolicy()
+	ve := utils.NewValidationErrors()
+	policy.Validate(ve, field, password)
+
+	if user != nil && policy.HistorySize > 0 && reusesPassword(user, password, policy.HistorySize) {
+		ve.AddError(field, fmt.Sprintf("Password must not match your current password or any of your last %d previous passwords", policy.HistorySize), "")
+	}
+
+	if ve.HasErrors() {
+		return ve
+	}
+	return nil
+}
+
+// reusesPassword reports whether the password matches the current hash or one of
+// the last historySize previous hashes
+func reusesPassword(user *models.User, password string, historySize int) bool {
+	if user.Password != "" && utils.CheckPassword(user.Password, password) {
+		return true
+	}
+	for i, hash := range user.PasswordHistory {
+		if i >= historySize {
+			break
+		}
+		if utils.CheckPassword(hash, password) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,64 @@
+package handler
+
+import (
+	"testing"
+
+	"github.com/AliSleiman0/Lacpa/models"
+	"github.com/AliSleiman0/Lacpa/utils"
+)
+
+func TestReusesPassword(t *testing.T) {
+	hash := func(password string) string {
+		hashed, err := utils.HashPassword(password)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return hashed
+	}
+	// History is kept newest first
+	user := &models.User{
+		Password:        hash("Current-Pass1"),
+		PasswordHistory: []string{hash("Previous-Pass1"), hash("Older-Pass1"), hash("Oldest-Pass1")},
+	}
+
+	tests := []struct {
+		name        string
+		password    string
+		historySize int
+		want        bool
+	}{
+		{name: "current password", password: "Current-Pass1", historySize: 3, want: true},
+		{name: "current password without history", password: "Current-Pass1", historySize: 0, want: true},
+		{name: "most recent previous password", password: "Previous-Pass1", historySize: 3, want: true},
+		{name: "oldest password inside the history", password: "Oldest-Pass1", historySize: 3, want: true},
+		{name: "password older than the history size", password: "Oldest-Pass1", historySize: 2, want: false},
+		{name: "new password", password: "Brand-New-Pass1", historySize: 3, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := reusesPassword(user, tt.password, tt.historySize); got != tt.want {
+				t.Fatalf("reusesPassword = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateNewPasswordChecksHistory(t *testing.T) {
+	t.Setenv("PASSWORD_HISTORY_SIZE", "1")
+	previous, err := utils.HashPassword("Previous-Pass1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	user := &models.User{PasswordHistory: []string{previous}}
+
+	if ve := validateNewPassword("new_password", "Previous-Pass1", user); ve == nil || len(ve.Errors) != 1 {
+		t.Fatalf("reused password: errors = %v, want one history error", ve)
+	}
+	if ve := validateNewPassword("new_password", "Previous-Pass1", nil); ve != nil {
+		t.Fatalf("new account: errors = %v, want none", ve)
+	}
+	if ve := validateNewPassword("new_password", "Brand-New-Pass1", user); ve != nil {
+		t.Fatalf("new password: errors = %v, want none", ve)
+	}
+}
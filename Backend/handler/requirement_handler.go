@@ -0,0 +1,333 @@
+package handler
+
+import (
+	"strings"
+
+	"github.com/AliSleiman0/Lacpa/models"
+	"github.com/AliSleiman0/Lacpa/repository"
+	"github.com/AliSleiman0/Lacpa/utils"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// requirementsTemplate is the CMS fragment listing the requirements of one application type
+const requirementsTemplate = "Admin_Dashboard/requirements/list"
+
+// RequirementHandler manages membership application requirements from the CMS.
+//
+// ROLE: Application Requirements Management
+//   - Requirements are what the apply-now page lists for each application type
+//   - A requirement may link to a document slot; the apply form then asks for that
+//     upload and submission enforces it while the requirement is required
+//   - HTMX requests get the re-rendered list of the requirement's type back,
+//     API clients get JSON
+type RequirementHandler struct {
+	repo  repository.ApplicationRepository
+	audit *AuditService
+}
+
+func NewRequirementHandler(repo repository.ApplicationRepository, audit *AuditService) *RequirementHandler {
+	return &RequirementHandler{repo: repo, audit: audit}
+}
+
+// ListRequirements handles GET /api/admin/requirements, optionally filtered by ?type=
+func (h *RequirementHandler) ListRequirements(c *fiber.Ctx) error {
+	appType := models.ApplicationType(c.Query("type"))
+
+	var requirements []models.ApplicationRequirement
+	var err error
+	switch appType {
+	case "":
+		requirements, err = h.repo.GetAllRequirements(c.Context())
+	case models.ApplicationTypeIndividual, models.ApplicationTypeFirm:
+		requirements, err = h.repo.GetRequirementsByType(c.Context(), appType)
+	default:
+		return utils.SendBadRequest(c, "Invalid application type")
+	}
+	if err != nil {
+		return utils.SendInternalError(c, err.Error())
+	}
+
+	return utils.SendSuccess(c, "Requirements retrieved successfully", requirements)
+}
+
+// RenderRequirements handles GET /api/admin/requirements/render?type=Individual&edit=<id>,
+// the CMS fragment for one application type with the requirement being edited in its form
+func (h *RequirementHandler) RenderRequirements(c *fiber.Ctx) error {
+	appType := models.ApplicationType(c.Query("type", string(models.ApplicationTypeIndividual)))
+	if !isApplicationType(appType) {
+		return utils.SendBadRequest(c, "Invalid application type")
+	}
+
+	var editing *models.ApplicationRequirement
+	if id, err := primitive.ObjectIDFromHex(c.Query("edit")); err == nil {
+		if requirement, err := h.repo.GetRequirementByID(c.Context(), id); err == nil && requirement.ApplicationType == appType {
+			editing = requirement
+		}
+	}
+
+	return h.render(c, fiber.StatusOK, appType, editing, "", nil)
+}
+
+// CreateRequirement handles POST /api/admin/requirements.
+// New requirements go to the end of their type's list.
+func (h *RequirementHandler) CreateRequirement(c *fiber.Ctx) error {
+	var req models.RequirementRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.SendBadRequest(c, "Invalid request body")
+	}
+
+	existing, ve, err := h.validate(c, &req, primitive.NilObjectID)
+	if err != nil {
+		return utils.SendInternalError(c, err.Error())
+	}
+	if ve.HasErrors() {
+		return h.sendErrors(c, req.ApplicationType, nil, ve)
+	}
+
+	requirement := &models.ApplicationRequirement{OrderIndex: nextOrderIndex(existing)}
+	req.Apply(requirement)
+	if err := h.repo.CreateRequirement(c.Context(), requirement); err != nil {
+		return utils.SendInternalError(c, err.Error())
+	}
+	h.audit.Record(c, models.AuditRequirementCreated, models.AuditTargetRequirement, requirement.ID.Hex(), nil, requirement)
+
+	if utils.WantsHTMX(c) {
+		return h.render(c, fiber.StatusCreated, requirement.ApplicationType, nil, "Requirement added", nil)
+	}
+	return utils.SendCreated(c, requirement, "")
+}
+
+// UpdateRequirement handles PUT /api/admin/requirements/:id.
+// A requirement moved to the other application type goes to the end of that type's list.
+func (h *RequirementHandler) UpdateRequirement(c *fiber.Ctx) error {
+	before, err := h.findRequirement(c)
+	if before == nil {
+		return err
+	}
+
+	var req models.RequirementRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.SendBadRequest(c, "Invalid request body")
+	}
+
+	existing, ve, err := h.validate(c, &req, before.ID)
+	if err != nil {
+		return utils.SendInternalError(c, err.Error())
+	}
+	if ve.HasErrors() {
+		return h.sendErrors(c, req.ApplicationType, before, ve)
+	}
+
+	after := *before
+	req.Apply(&after)
+	if after.ApplicationType != before.ApplicationType {
+		after.OrderIndex = nextOrderIndex(existing)
+	}
+	return h.save(c, before, &after, "Requirement updated")
+}
+
+// ToggleRequired handles PATCH /api/admin/requirements/:id/required, flipping is_required
+func (h *RequirementHandler) ToggleRequired(c *fiber.Ctx) error {
+	before, err := h.findRequirement(c)
+	if before == nil {
+		return err
+	}
+
+	after := *before
+	after.IsRequired = !before.IsRequired
+	message := "Requirement is now optional"
+	if after.IsRequired {
+		message = "Requirement is now required"
+	}
+	return h.save(c, before, &after, message)
+}
+
+// ReorderRequirements handles PUT /api/admin/requirements/order.
+// The body lists every requirement ID of one application type in the new order,
+// as sent by the CMS drag-and-drop list.
+func (h *RequirementHandler) ReorderRequirements(c *fiber.Ctx) error {
+	var req models.RequirementOrderRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.SendBadRequest(c, "Invalid request body")
+	}
+	if !isApplicationType(req.ApplicationType) {
+		return utils.SendBadRequest(c, "Invalid application type")
+	}
+
+	existing, err := h.repo.GetRequirementsByType(c.Context(), req.ApplicationType)
+	if err != nil {
+		return utils.SendInternalError(c, err.Error())
+	}
+
+	// The new order must be a permutation of the current one
+	remaining := make(map[primitive.ObjectID]bool, len(existing))
+	before := make([]string, 0, len(existing))
+	for _, requirement := range existing {
+		remaining[requirement.ID] = true
+		before = append(before, requirement.ID.Hex())
+	}
+	ids := make([]primitive.ObjectID, 0, len(req.IDs))
+	for _, hex := range req.IDs {
+		id, err := primitive.ObjectIDFromHex(hex)
+		if err != nil || !remaining[id] {
+			return utils.SendBadRequest(c, "The order must list every requirement of the type once")
+		}
+		delete(remaining, id)
+		ids = append(ids, id)
+	}
+	if len(remaining) > 0 {
+		return utils.SendBadRequest(c, "The order must list every requirement of the type once")
+	}
+
+	if err := h.repo.ReorderRequirements(c.Context(), ids); err != nil {
+		return utils.SendInternalError(c, err.Error())
+	}
+	h.audit.Record(c, models.AuditRequirementsReordered, models.AuditTargetRequirement, string(req.ApplicationType), before, req.IDs)
+
+	if utils.WantsHTMX(c) {
+		return h.render(c, fiber.StatusOK, req.ApplicationType, nil, "Order saved", nil)
+	}
+	return utils.SendSuccess(c, "Requirements reordered successfully", nil)
+}
+
+// DeleteRequirement handles DELETE /api/admin/requirements/:id
+func (h *RequirementHandler) DeleteRequirement(c *fiber.Ctx) error {
+	before, err := h.findRequirement(c)
+	if before == nil {
+		return err
+	}
+
+	if err := h.repo.DeleteRequirement(c.Context(), before.ID); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return utils.SendNotFound(c, "Requirement")
+		}
+		return utils.SendInternalError(c, err.Error())
+	}
+	h.audit.Record(c, models.AuditRequirementDeleted, models.AuditTargetRequirement, before.ID.Hex(), before, nil)
+
+	if utils.WantsHTMX(c) {
+		return h.render(c, fiber.StatusOK, before.ApplicationType, nil, "Requirement deleted", nil)
+	}
+	return utils.SendSuccess(c, "Requirement deleted successfully", nil)
+}
+
+// save stores an edited requirement and records the change
+func (h *RequirementHandler) save(c *fiber.Ctx, before, after *models.ApplicationRequirement, message string) error {
+	if err := h.repo.UpdateRequirement(c.Context(), after.ID, after); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return utils.SendNotFound(c, "Requirement")
+		}
+		return utils.SendInternalError(c, err.Error())
+	}
+	h.audit.Record(c, models.AuditRequirementUpdated, models.AuditTargetRequirement, after.ID.Hex(), before, after)
+
+	if utils.WantsHTMX(c) {
+		return h.render(c, fiber.StatusOK, after.ApplicationType, nil, message, nil)
+	}
+	return utils.SendSuccess(c, message, after)
+}
+
+// validate checks a requirement request and returns the other requirements of its type.
+// A document slot can only be linked by one requirement of a type, so the apply form
+// asks for each upload once.
+func (h *RequirementHandler) validate(c *fiber.Ctx, req *models.RequirementRequest, self primitive.ObjectID) ([]models.ApplicationRequirement, *utils.ValidationErrors, error) {
+	req.Title = strings.TrimSpace(req.Title)
+	req.Description = strings.TrimSpace(req.Description)
+	req.Icon = strings.TrimSpace(req.Icon)
+
+	ve := utils.NewValidationErrors()
+	if err := utils.ValidateStruct(req); err != nil {
+		if fieldErrors, ok := err.(*utils.ValidationErrors); ok {
+			ve.Errors = append(ve.Errors, fieldErrors.Errors...)
+		}
+	}
+	if !isApplicationType(req.ApplicationType) {
+		return nil, ve, nil
+	}
+	if req.DocumentKind != "" && !req.ApplicationType.AcceptsDocument(req.DocumentKind) {
+		ve.AddError("document_kind", "Not a document slot of "+string(req.ApplicationType)+" applications", string(req.DocumentKind))
+	}
+
+	requirements, err := h.repo.GetRequirementsByType(c.Context(), req.ApplicationType)
+	if err != nil {
+		return nil, nil, err
+	}
+	others := make([]models.ApplicationRequirement, 0, len(requirements))
+	for _, requirement := range requirements {
+		if requirement.ID == self {
+			continue
+		}
+		if req.DocumentKind != "" && requirement.DocumentKind == req.DocumentKind {
+			ve.AddError("document_kind", "This document is already asked for by \""+requirement.Title+"\"", string(req.DocumentKind))
+		}
+		others = append(others, requirement)
+	}
+	return others, ve, nil
+}
+
+// findRequirement loads the requirement of the :id parameter.
+// When it returns nil the error response has already been sent.
+func (h *RequirementHandler) findRequirement(c *fiber.Ctx) (*models.ApplicationRequirement, error) {
+	id, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return nil, utils.SendBadRequest(c, "Invalid requirement ID")
+	}
+	requirement, err := h.repo.GetRequirementByID(c.Context(), id)
+	if err == mongo.ErrNoDocuments {
+		return nil, utils.SendNotFound(c, "Requirement")
+	}
+	if err != nil {
+		return nil, utils.SendInternalError(c, err.Error())
+	}
+	return requirement, nil
+}
+
+// sendErrors answers an invalid request: the list with the form errors for HTMX,
+// the usual validation error JSON otherwise
+func (h *RequirementHandler) sendErrors(c *fiber.Ctx, appType models.ApplicationType, editing *models.ApplicationRequirement, ve *utils.ValidationErrors) error {
+	if !utils.WantsHTMX(c) {
+		return utils.SendValidationErrors(c, ve)
+	}
+	if !isApplicationType(appType) {
+		appType = models.ApplicationTypeIndividual
+	}
+	if editing != nil && editing.ApplicationType != appType {
+		appType = editing.ApplicationType
+	}
+	return h.render(c, fiber.StatusUnprocessableEntity, appType, editing, "", ve.Errors)
+}
+
+// render sends the CMS fragment of an application type's requirements
+func (h *RequirementHandler) render(c *fiber.Ctx, status int, appType models.ApplicationType, editing *models.ApplicationRequirement, notice string, errors []utils.ValidationError) error {
+	requirements, err := h.repo.GetRequirementsByType(c.Context(), appType)
+	if err != nil {
+		return utils.SendInternalError(c, err.Error())
+	}
+
+	return c.Status(status).Render(requirementsTemplate, fiber.Map{
+		"Type":          appType,
+		"Types":         []models.ApplicationType{models.ApplicationTypeIndividual, models.ApplicationTypeFirm},
+		"Requirements":  requirements,
+		"DocumentKinds": appType.DocumentKinds(),
+		"Editing":       editing,
+		"Notice":        notice,
+		"Errors":        errors,
+	})
+}
+
+// nextOrderIndex places a requirement after the given ones
+func nextOrderIndex(requirements []models.ApplicationRequirement) int {
+	next := 1
+	for _, requirement := range requirements {
+		if requirement.OrderIndex >= next {
+			next = requirement.OrderIndex + 1
+		}
+	}
+	return next
+}
+
+func isApplicationType(appType models.ApplicationType) bool {
+	return appType == models.ApplicationTypeIndividual || appType == models.ApplicationTypeFirm
+}
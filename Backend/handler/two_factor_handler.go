@@ -0,0 +1,563 @@
+package handler
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/AliSleiman0/Lacpa/models"
+	"github.com/AliSleiman0/Lacpa/utils"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// recoveryCodeCount is the number of recovery codes issued on enrollment
+const recoveryCodeCount = 10
+
+// mfaUserKey is the attempt key for TOTP guesses against one user
+func mfaUserKey(userID string) string { return "mfa:user:" + userID }
+
+// LoginTwoFactor completes a login that returned a challenge token.
+// Accepts either the current TOTP code or one unused recovery code.
+func (h *AuthHandler) LoginTwoFactor(c *fiber.Ctx) error {
+	var req models.TwoFactorLoginRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Invalid request body. Please check your JSON format.",
+			"success": false,
+		})
+	}
+	if err := utils.ValidateStruct(req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   err.Error(),
+			"success": false,
+		})
+	}
+	if req.Code == "" && req.RecoveryCode == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "A verification code or recovery code is required",
+			"success": false,
+		})
+	}
+
+	claims, err := utils.ValidateChallengeToken(req.ChallengeToken, models.ChallengeMFALogin)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error":   "Invalid or expired challenge. Please log in again.",
+			"success": false,
+		})
+	}
+
+	reservation, wait, err := reserveAttempts(c, h.attemptRepo,
+		attemptGuard{key: mfaUserKey(claims.UserID), policy: accountLoginPolicy},
+		attemptGuard{key: loginIPKey(c.IP()), policy: ipLoginPolicy, shared: true},
+	)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   "Failed to check login attempts",
+			"success": false,
+		})
+	}
+	if wait > 0 {
+		return sendTooManyAttempts(c, wait)
+	}
+
+	user, err := h.challengeUser(claims)
+	if err != nil || !user.TwoFactor.Enabled {
+		reservation.cancel(c)
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error":   "Invalid or expired challenge. Please log in again.",
+			"success": false,
+		})
+	}
+
+	var verified bool
+	if req.RecoveryCode != "" {
+		verified, err = consumeRecoveryCode(h.authRepo, user, req.RecoveryCode)
+		if verified {
+			recordSecurityEvent(h.authRepo, user, models.SecurityEventRecoveryCodeUsed,
+				fmt.Sprintf("Recovery code used to log in (%d left)", len(user.TwoFactor.RecoveryCodes)-1), c.IP(), "")
+		}
+	} else {
+		verified, err = h.verifyTOTP(user, req.Code)
+	}
+	if err != nil {
+		reservation.cancel(c)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   "Failed to verify code",
+			"success": false,
+		})
+	}
+	if !verified {
+		h.recordLoginFailure(c, user, reservation)
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error":   "Invalid verification code",
+			"success": false,
+		})
+	}
+
+	reservation.succeed(c)
+
+	return h.completeLogin(c, user)
+}
+
+// SetupTwoFactor generates a new TOTP secret and returns its provisioning URI.
+// The secret stays pending until confirmed with EnableTwoFactor.
+//
+// Callable with an access token, or with an enrollment challenge token when
+// the user's role requires 2FA and the user is not enrolled yet.
+func (h *AuthHandler) SetupTwoFactor(c *fiber.Ctx) error {
+	var req models.TwoFactorSetupRequest
+	_ = c.BodyParser(&req)
+
+	user, status, message := h.twoFactorUser(c, req.ChallengeToken)
+	if user == nil {
+		return c.Status(status).JSON(fiber.Map{
+			"error":   message,
+			"success": false,
+		})
+	}
+	if user.TwoFactor.Enabled {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error":   "Two-factor authentication is already enabled",
+			"success": false,
+		})
+	}
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   "Failed to generate secret",
+			"success": false,
+		})
+	}
+	encrypted, err := utils.EncryptSecret(secret)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   "Failed to generate secret",
+			"success": false,
+		})
+	}
+	if err := h.authRepo.SetPendingTwoFactorSecret(user.ID, encrypted); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   "Failed to save secret",
+			"success": false,
+		})
+	}
+
+	uri := utils.TOTPProvisioningURI(utils.GetEnv("TOTP_ISSUER", "LACPA"), user.LACPAID, secret)
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "Scan the QR code with your authenticator app, then confirm with a code",
+		"data": models.TwoFactorSetupResponse{
+			Secret:          secret,
+			ProvisioningURI: uri,
+			QRPayload:       uri,
+		},
+	})
+}
+
+// EnableTwoFactor confirms the pending secret with a code and returns the recovery codes.
+// When called with an enrollment challenge token it also completes the login.
+func (h *AuthHandler) EnableTwoFactor(c *fiber.Ctx) error {
+	var req models.TwoFactorEnableRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Invalid request body. Please check your JSON format.",
+			"success": false,
+		})
+	}
+	if err := utils.ValidateStruct(req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   err.Error(),
+			"success": false,
+		})
+	}
+
+	user, status, message := h.twoFactorUser(c, req.ChallengeToken)
+	if user == nil {
+		return c.Status(status).JSON(fiber.Map{
+			"error":   message,
+			"success": false,
+		})
+	}
+	if user.TwoFactor.Enabled {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error":   "Two-factor authentication is already enabled",
+			"success": false,
+		})
+	}
+	if user.TwoFactor.PendingSecret == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Start two-factor setup first",
+			"success": false,
+		})
+	}
+
+	secret, err := utils.DecryptSecret(user.TwoFactor.PendingSecret)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   "Failed to read secret",
+			"success": false,
+		})
+	}
+	step, ok := utils.ValidateTOTP(secret, req.Code, time.Now())
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Invalid verification code",
+			"success": false,
+		})
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   "Failed to generate recovery codes",
+			"success": false,
+		})
+	}
+	if err := h.authRepo.EnableTwoFactor(user.ID, user.TwoFactor.PendingSecret, hashes, step); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   "Failed to enable two-factor authentication",
+			"success": false,
+		})
+	}
+	user.TwoFactor.Enabled = true
+	recordSecurityEvent(h.authRepo, user, models.SecurityEventTwoFactorEnabled, "Two-factor authentication enabled", c.IP(), "")
+
+	data := fiber.Map{"recovery_codes": codes}
+
+	// Enrolling during login: the user has now passed both factors
+	if req.ChallengeToken != "" {
+		if err := h.authRepo.UpdateLastLogin(user.ID); err != nil {
+			fmt.Printf("Failed to update last login: %v\n", err)
+		}
+		authResponse, err := h.startSession(c, user)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error":   "Failed to generate token",
+				"success": false,
+			})
+		}
+		data["auth"] = authResponse
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "Two-factor authentication enabled. Store your recovery codes somewhere safe.",
+		"data":    data,
+	})
+}
+
+// DisableTwoFactor turns 2FA off after checking the password and a current code.
+// Refused while the user's role requires 2FA.
+func (h *AuthHandler) DisableTwoFactor(c *fiber.Ctx) error {
+	var req models.TwoFactorDisableRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Invalid request body. Please check your JSON format.",
+			"success": false,
+		})
+	}
+	if err := utils.ValidateStruct(req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   err.Error(),
+			"success": false,
+		})
+	}
+
+	user, status, message := h.twoFactorUser(c, "")
+	if user == nil {
+		return c.Status(status).JSON(fiber.Map{
+			"error":   message,
+			"success": false,
+		})
+	}
+	if !user.TwoFactor.Enabled {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Two-factor authentication is not enabled",
+			"success": false,
+		})
+	}
+
+	required, err := h.roleRequiresMFA(c, user.Role)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   "Failed to retrieve role",
+			"success": false,
+		})
+	}
+	if required {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error":   "Your role requires two-factor authentication",
+			"success": false,
+		})
+	}
+
+	if !utils.CheckPassword(user.Password, req.Password) {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error":   "Invalid password",
+			"success": false,
+		})
+	}
+	verified, err := h.verifyTOTP(user, req.Code)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   "Failed to verify code",
+			"success": false,
+		})
+	}
+	if !verified {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error":   "Invalid verification code",
+			"success": false,
+		})
+	}
+
+	if err := h.authRepo.DisableTwoFactor(user.ID); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   "Failed to disable two-factor authentication",
+			"success": false,
+		})
+	}
+	recordSecurityEvent(h.authRepo, user, models.SecurityEventTwoFactorDisabled, "Two-factor authentication disabled", c.IP(), "")
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "Two-factor authentication disabled",
+	})
+}
+
+// RegenerateRecoveryCodes replaces all recovery codes after checking a current code
+func (h *AuthHandler) RegenerateRecoveryCodes(c *fiber.Ctx) error {
+	var req models.TwoFactorCodeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Invalid request body. Please check your JSON format.",
+			"success": false,
+		})
+	}
+	if err := utils.ValidateStruct(req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   err.Error(),
+			"success": false,
+		})
+	}
+
+	user, status, message := h.twoFactorUser(c, "")
+	if user == nil {
+		return c.Status(status).JSON(fiber.Map{
+			"error":   message,
+			"success": false,
+		})
+	}
+	if !user.TwoFactor.Enabled {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Two-factor authentication is not enabled",
+			"success": false,
+		})
+	}
+
+	verified, err := h.verifyTOTP(user, req.Code)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   "Failed to verify code",
+			"success": false,
+		})
+	}
+	if !verified {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error":   "Invalid verification code",
+			"success": false,
+		})
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   "Failed to generate recovery codes",
+			"success": false,
+		})
+	}
+	if err := h.authRepo.ReplaceRecoveryCodes(user.ID, hashes); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   "Failed to save recovery codes",
+			"success": false,
+		})
+	}
+	recordSecurityEvent(h.authRepo, user, models.SecurityEventRecoveryCodesRenewed, "Recovery codes regenerated", c.IP(), "")
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "New recovery codes generated. Previous codes no longer work.",
+		"data": fiber.Map{
+			"recovery_codes": codes,
+		},
+	})
+}
+
+// sendChallenge answers a password-verified login that still needs a second step
+func (h *AuthHandler) sendChallenge(c *fiber.Ctx, user *models.User, purpose string) error {
+	token, err := utils.GenerateChallengeToken(user.ID.Hex(), purpose)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   "Failed to generate token",
+			"success": false,
+		})
+	}
+
+	message := "Enter the code from your authenticator app"
+	if purpose == models.ChallengeMFAEnroll {
+		message = "Your role requires two-factor authentication. Set it up to continue."
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": message,
+		"data": models.ChallengeResponse{
+			ChallengeToken: token,
+			Purpose:        purpose,
+			ExpiresIn:      int(utils.ChallengeTokenTTL.Seconds()),
+		},
+	})
+}
+
+// completeLogin records the login and issues the session tokens
+func (h *AuthHandler) completeLogin(c *fiber.Ctx, user *models.User) error {
+	if err := h.authRepo.UpdateLastLogin(user.ID); err != nil {
+		// Log error but don't fail the login
+		fmt.Printf("Failed to update last login: %v\n", err)
+	}
+
+	authResponse, err := h.startSession(c, user)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   "Failed to generate token",
+			"success": false,
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "Login successful",
+		"data":    authResponse,
+	})
+}
+
+// twoFactorUser resolves the user managing 2FA, either from the access token
+// or from an enrollment challenge token. On failure it returns a nil user with
+// the status and message to send back.
+func (h *AuthHandler) twoFactorUser(c *fiber.Ctx, challengeToken string) (*models.User, int, string) {
+	if userID, ok := c.Locals("userID").(string); ok && userID != "" {
+		id, err := primitive.ObjectIDFromHex(userID)
+		if err != nil {
+			return nil, fiber.StatusBadRequest, "Invalid user ID"
+		}
+		user, err := h.authRepo.GetUserByID(id)
+		if err != nil {
+			return nil, fiber.StatusNotFound, "User not found"
+		}
+		return user, 0, ""
+	}
+
+	if challengeToken == "" {
+		return nil, fiber.StatusUnauthorized, "Missing authorization header"
+	}
+	claims, err := utils.ValidateChallengeToken(challengeToken, models.ChallengeMFAEnroll)
+	if err != nil {
+		return nil, fiber.StatusUnauthorized, "Invalid or expired challenge. Please log in again."
+	}
+	user, err := h.challengeUser(claims)
+	if err != nil {
+		return nil, fiber.StatusUnauthorized, "Invalid or expired challenge. Please log in again."
+	}
+	return user, 0, ""
+}
+
+// challengeUser loads the active user a challenge token was issued to
+func (h *AuthHandler) challengeUser(claims *utils.JWTClaims) (*models.User, error) {
+	id, err := primitive.ObjectIDFromHex(claims.UserID)
+	if err != nil {
+		return nil, err
+	}
+	user, err := h.authRepo.GetUserByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if !user.IsActive {
+		return nil, mongo.ErrNoDocuments
+	}
+	return user, nil
+}
+
+// verifyTOTP checks a code against the user's active secret and marks its time step as used
+func (h *AuthHandler) verifyTOTP(user *models.User, code string) (bool, error) {
+	return verifyTOTPCode(h.authRepo, user, code, time.Now())
+}
+
+// secondFactorStore marks TOTP steps and recovery codes as used; implemented by repository.AuthRepository
+type secondFactorStore interface {
+	UseTOTPStep(userID primitive.ObjectID, step int64) (bool, error)
+	ConsumeRecoveryCode(userID primitive.ObjectID, codeHash string) (bool, error)
+}
+
+// verifyTOTPCode checks a code against the user's active secret at now. A time step
+// only verifies once, so an observed code cannot be replayed inside its window.
+func verifyTOTPCode(store secondFactorStore, user *models.User, code string, now time.Time) (bool, error) {
+	secret, err := utils.DecryptSecret(user.TwoFactor.Secret)
+	if err != nil {
+		return false, err
+	}
+	step, ok := utils.ValidateTOTP(secret, code, now)
+	if !ok {
+		return false, nil
+	}
+	return store.UseTOTPStep(user.ID, step)
+}
+
+// consumeRecoveryCode redeems a recovery code as the user typed it. Each code works once.
+func consumeRecoveryCode(store secondFactorStore, user *models.User, code string) (bool, error) {
+	return store.ConsumeRecoveryCode(user.ID, utils.HashToken(utils.NormalizeRecoveryCode(code)))
+}
+
+// secondFactor returns the challenge a login must pass after the first factor, or ""
+// when none is needed: enrolled users confirm a TOTP code, users whose role requires
+// 2FA but who have not enrolled yet must set it up first
+func (h *AuthHandler) secondFactor(c *fiber.Ctx, user *models.User) (string, error) {
+	if user.TwoFactor.Enabled {
+		return models.ChallengeMFALogin, nil
+	}
+	required, err := h.roleRequiresMFA(c, user.Role)
+	if err != nil {
+		return "", err
+	}
+	if required {
+		return models.ChallengeMFAEnroll, nil
+	}
+	return "", nil
+}
+
+// roleRequiresMFA reports whether the named role requires two-factor login
+func (h *AuthHandler) roleRequiresMFA(c *fiber.Ctx, roleName string) (bool, error) {
+	role, err := h.roleRepo.GetRoleByName(c.Context(), roleName)
+	if err == mongo.ErrNoDocuments {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return role.RequireMFA, nil
+}
+
+// newRecoveryCodes returns fresh recovery codes and the hashes to store
+func newRecoveryCodes() ([]string, []string, error) {
+	codes, err := utils.GenerateRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		return nil, nil, err
+	}
+	hashes := make([]string, 0, len(codes))
+	for _, code := range codes {
+		hashes = append(hashes, utils.HashToken(code))
+	}
+	return codes, hashes, nil
+}
@@ -0,0 +1,121 @@
+package handler
+
+import (
+	"encoding/base32"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/AliSleiman0/Lacpa/models"
+	"github.com/AliSleiman0/Lacpa/utils"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// memorySecondFactorStore applies each update atomically, like the users collection
+type memorySecondFactorStore struct {
+	mu            sync.Mutex
+	lastUsedStep  int64
+	recoveryCodes []string
+}
+
+func (s *memorySecondFactorStore) UseTOTPStep(userID primitive.ObjectID, step int64) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if step <= s.lastUsedStep {
+		return false, nil
+	}
+	s.lastUsedStep = step
+	return true, nil
+}
+
+func (s *memorySecondFactorStore) ConsumeRecoveryCode(userID primitive.ObjectID, codeHash string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, hash := range s.recoveryCodes {
+		if hash == codeHash {
+			s.recoveryCodes = append(s.recoveryCodes[:i], s.recoveryCodes[i+1:]...)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// newTwoFactorUser returns a user enrolled with a known secret
+func newTwoFactorUser(t *testing.T) (*models.User, string) {
+	t.Helper()
+	secret := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+	encrypted, err := utils.EncryptSecret(secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &models.User{ID: primitive.NewObjectID(), TwoFactor: models.TwoFactor{Enabled: true, Secret: encrypted}}, secret
+}
+
+func TestVerifyTOTPCodeRefusesReplay(t *testing.T) {
+	user, secret := newTwoFactorUser(t)
+	store := &memorySecondFactorStore{}
+	now := time.Unix(1234567890, 0)
+	step := utils.TOTPStep(now)
+
+	code := func(step int64) string {
+		code, err := utils.TOTPCode(secret, step)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return code
+	}
+
+	tests := []struct {
+		name string
+		code string
+		want bool
+	}{
+		{name: "current code", code: code(step), want: true},
+		{name: "same code again", code: code(step), want: false},
+		{name: "earlier code inside the window", code: code(step - 1), want: false},
+		{name: "next code", code: code(step + 1), want: true},
+		{name: "wrong code", code: "000000", want: false},
+	}
+	for _, tt := range tests {
+		verified, err := verifyTOTPCode(store, user, tt.code, now)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if verified != tt.want {
+			t.Fatalf("%s: verified = %v, want %v", tt.name, verified, tt.want)
+		}
+	}
+}
+
+func TestConsumeRecoveryCodeWorksOnce(t *testing.T) {
+	user, _ := newTwoFactorUser(t)
+	codes, err := utils.GenerateRecoveryCodes(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := &memorySecondFactorStore{}
+	for _, code := range codes {
+		store.recoveryCodes = append(store.recoveryCodes, utils.HashToken(code))
+	}
+
+	tests := []struct {
+		name string
+		code string
+		want bool
+	}{
+		{name: "first code as typed without dash", code: codes[0][:5] + codes[0][6:], want: true},
+		{name: "first code again", code: codes[0], want: false},
+		{name: "second code in capitals", code: " " + strings.ToUpper(codes[1]) + " ", want: true},
+		{name: "second code again", code: codes[1], want: false},
+	}
+	for _, tt := range tests {
+		verified, err := consumeRecoveryCode(store, user, tt.code)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if verified != tt.want {
+			t.Fatalf("%s: verified = %v, want %v", tt.name, verified, tt.want)
+		}
+	}
+}
@@ -0,0 +1,187 @@
+package handler
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/AliSleiman0/Lacpa/models"
+	"github.com/AliSleiman0/Lacpa/repository"
+	"github.com/AliSleiman0/Lacpa/utils"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Verification errors returned by VerificationService.Verify
+var (
+	ErrCodeNotFound        = errors.New("code expired or not found")
+	ErrCodeInvalid         = errors.New("invalid code")
+	ErrCodeTooManyAttempts = errors.New("too many wrong codes")
+)
+
+// verificationEmail is the wording of the email sent for a purpose
+type verificationEmail struct {
+	subject string
+	message string
+}
+
+var verificationEmails = map[models.VerificationPurpose]verificationEmail{
+	models.PurposeVerifyEmail: {
+		subject: "Verify your email - LACPA",
+		message: "We received a request to verify your email address. Please use the One-Time Password (OTP) below to complete your verification.",
+	},
+	models.PurposeResetPassword: {
+		subject: "Reset your password - LACPA",
+		message: "We received a request to reset your password. Please use the One-Time Password (OTP) below to choose a new password.",
+	},
+	models.PurposeChangeEmail: {
+		subject: "Confirm your new email - LACPA",
+		message: "We received a request to change the email address of your LACPA account to this address. Please use the One-Time Password (OTP) below to confirm the change.",
+	},
+	models.PurposeClaimProfile: {
+		subject: "Link your LACPA member profile - LACPA",
+		message: "We received a request to link the LACPA member profile registered with this address to an online account. Please use the One-Time Password (OTP) below to confirm. If you did not request this, ignore this email.",
+	},
+	models.PurposeApplicationConfirm: {
+		subject: "Confirm your membership application - LACPA",
+		message: "Please use the One-Time Password (OTP) below to confirm the email address on your LACPA membership application.",
+	},
+	models.PurposeApplicationTrack: {
+		subject: "View your membership application - LACPA",
+		message: "We received a request to view the status of your LACPA membership application. Please use the One-Time Password (OTP) below to open it. If you did not request this, ignore this email.",
+	},
+}
+
+// VerificationService issues and checks the one-time codes sent by email.
+//
+// ROLE: Email Verification
+// - One code per email and purpose; issuing a new one replaces the old
+// - Codes are stored hashed and expire through a TTL index
+// - Wrong guesses are counted and the code is destroyed after too many
+// - A code only redeems for the purpose and subject it was issued for
+type VerificationService struct {
+	repo        verificationStore
+	ttl         time.Duration
+	maxAttempts int
+}
+
+// verificationStore keeps the codes; implemented by repository.VerificationRepository
+type verificationStore interface {
+	ReplaceCode(ctx context.Context, code *models.VerificationCode) error
+	GetCode(ctx context.Context, email string, purpose models.VerificationPurpose, subject string) (*models.VerificationCode, error)
+	ReserveAttempt(ctx context.Context, email string, purpose models.VerificationPurpose, subject string, maxAttempts int) (*models.VerificationCode, error)
+	DeleteCode(ctx context.Context, id primitive.ObjectID) (bool, error)
+}
+
+// NewVerificationService creates the service. Code lifetime and allowed wrong
+// guesses come from VERIFICATION_CODE_TTL_MINUTES and VERIFICATION_CODE_MAX_ATTEMPTS.
+func NewVerificationService(repo *repository.VerificationRepository) *VerificationService {
+	return &VerificationService{
+		repo:        repo,
+		ttl:         time.Duration(utils.GetEnvInt("VERIFICATION_CODE_TTL_MINUTES", 10)) * time.Minute,
+		maxAttempts: utils.GetEnvInt("VERIFICATION_CODE_MAX_ATTEMPTS", 5),
+	}
+}
+
+// Issue generates a code for the email and purpose, stores its hash and emails it.
+// subject records what the code confirms (e.g. the new address for change_email).
+// The code is stored even if the email fails so a resend can recover.
+func (s *VerificationService) Issue(ctx context.Context, email, recipientName string, purpose models.VerificationPurpose, subject string) (time.Time, error) {
+	email = normalizeEmail(email)
+
+	code, err := utils.GenerateOTP()
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	record := &models.VerificationCode{
+		Email:     email,
+		Purpose:   purpose,
+		CodeHash:  hashVerificationCode(email, purpose, code),
+		Subject:   subject,
+		ExpiresAt: time.Now().Add(s.ttl),
+	}
+	if err := s.repo.ReplaceCode(ctx, record); err != nil {
+		return time.Time{}, err
+	}
+
+	wording := verificationEmails[purpose]
+	if err := utils.SendVerificationEmail(email, recipientName, code, wording.subject, wording.message, s.ttl); err != nil {
+		return record.ExpiresAt, fmt.Errorf("code stored but email failed: %w", err)
+	}
+
+	return record.ExpiresAt, nil
+}
+
+// Verify redeems a code issued for the subject ("" for flows without one). On success
+// the code is deleted and returned. A code issued for another subject is reported as
+// not found and left untouched. Every guess is counted before it is checked, so parallel
+// requests cannot guess more often than VERIFICATION_CODE_MAX_ATTEMPTS allows.
+func (s *VerificationService) Verify(ctx context.Context, email string, purpose models.VerificationPurpose, subject, code string) (*models.VerificationCode, error) {
+	email = normalizeEmail(email)
+
+	record, err := s.repo.ReserveAttempt(ctx, email, purpose, subject, s.maxAttempts)
+	if err == mongo.ErrNoDocuments {
+		return nil, s.noAttemptLeft(ctx, email, purpose, subject)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	expected := hashVerificationCode(email, purpose, strings.TrimSpace(code))
+	if subtle.ConstantTimeCompare([]byte(expected), []byte(record.CodeHash)) != 1 {
+		if record.Attempts >= s.maxAttempts {
+			if _, err := s.repo.DeleteCode(ctx, record.ID); err != nil {
+				return nil, err
+			}
+			return nil, ErrCodeTooManyAttempts
+		}
+		return nil, ErrCodeInvalid
+	}
+
+	// Deleting is the redemption: only one concurrent request can win it
+	deleted, err := s.repo.DeleteCode(ctx, record.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !deleted {
+		return nil, ErrCodeNotFound
+	}
+
+	return record, nil
+}
+
+// noAttemptLeft explains why no guess could be reserved: the code is missing or
+// expired, or its guesses are used up, in which case it is destroyed
+func (s *VerificationService) noAttemptLeft(ctx context.Context, email string, purpose models.VerificationPurpose, subject string) error {
+	record, err := s.repo.GetCode(ctx, email, purpose, subject)
+	if err == mongo.ErrNoDocuments {
+		return ErrCodeNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if _, err := s.repo.DeleteCode(ctx, record.ID); err != nil {
+		return err
+	}
+	return ErrCodeTooManyAttempts
+}
+
+// TTL returns how long issued codes stay valid
+func (s *VerificationService) TTL() time.Duration {
+	return s.ttl
+}
+
+// hashVerificationCode binds the code to its email and purpose before hashing,
+// so a stored hash is useless for any other flow
+func hashVerificationCode(email string, purpose models.VerificationPurpose, code string) string {
+	return utils.HashToken(string(purpose) + ":" + email + ":" + code)
+}
+
+// normalizeEmail lowercases and trims an email address
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
@@ -0,0 +1,143 @@
+package handler
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/AliSleiman0/Lacpa/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// memoryVerificationStore keeps one code and applies each operation atomically, like the collection
+type memoryVerificationStore struct {
+	mu   sync.Mutex
+	code *models.VerificationCode
+}
+
+func (s *memoryVerificationStore) ReplaceCode(ctx context.Context, code *models.VerificationCode) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	code.ID = primitive.NewObjectID()
+	stored := *code
+	s.code = &stored
+	return nil
+}
+
+func (s *memoryVerificationStore) GetCode(ctx context.Context, email string, purpose models.VerificationPurpose, subject string) (*models.VerificationCode, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.code == nil || s.code.Email != email || s.code.Purpose != purpose || s.code.Subject != subject {
+		return nil, mongo.ErrNoDocuments
+	}
+	code := *s.code
+	return &code, nil
+}
+
+func (s *memoryVerificationStore) ReserveAttempt(ctx context.Context, email string, purpose models.VerificationPurpose, subject string, maxAttempts int) (*models.VerificationCode, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.code == nil || s.code.Email != email || s.code.Purpose != purpose || s.code.Subject != subject || s.code.Attempts >= maxAttempts {
+		return nil, mongo.ErrNoDocuments
+	}
+	s.code.Attempts++
+	code := *s.code
+	return &code, nil
+}
+
+func (s *memoryVerificationStore) DeleteCode(ctx context.Context, id primitive.ObjectID) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.code == nil || s.code.ID != id {
+		return false, nil
+	}
+	s.code = nil
+	return true, nil
+}
+
+func TestVerifyLimitsParallelGuesses(t *testing.T) {
+	const maxAttempts, guesses = 5, 200
+	store := &memoryVerificationStore{}
+	service := &VerificationService{repo: store, ttl: time.Minute, maxAttempts: maxAttempts}
+	email, purpose := "victim@example.com", models.PurposeResetPassword
+	store.ReplaceCode(context.Background(), &models.VerificationCode{
+		Email:    email,
+		Purpose:  purpose,
+		CodeHash: hashVerificationCode(email, purpose, "123456"),
+	})
+
+	var mu sync.Mutex
+	results := map[error]int{}
+	var wg sync.WaitGroup
+	for i := 0; i < guesses; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := service.Verify(context.Background(), email, purpose, "", "000000")
+			mu.Lock()
+			results[err]++
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	if checked := results[ErrCodeInvalid] + 1; checked != maxAttempts || results[ErrCodeTooManyAttempts] < 1 {
+		t.Fatalf("results = %v, want %d invalid guesses before the code is destroyed", results, maxAttempts-1)
+	}
+	if _, err := service.Verify(context.Background(), email, purpose, "", "123456"); err != ErrCodeNotFound {
+		t.Fatalf("right code after the limit: err = %v, want ErrCodeNotFound", err)
+	}
+}
+
+func TestVerifyRedeemsOnce(t *testing.T) {
+	store := &memoryVerificationStore{}
+	service := &VerificationService{repo: store, ttl: time.Minute, maxAttempts: 5}
+	email, purpose := "member@example.com", models.PurposeVerifyEmail
+	store.ReplaceCode(context.Background(), &models.VerificationCode{
+		Email:    email,
+		Purpose:  purpose,
+		CodeHash: hashVerificationCode(email, purpose, "123456"),
+	})
+
+	if _, err := service.Verify(context.Background(), email, purpose, "", "000000"); err != ErrCodeInvalid {
+		t.Fatalf("wrong code: err = %v, want ErrCodeInvalid", err)
+	}
+	if _, err := service.Verify(context.Background(), email, purpose, "", " 123456 "); err != nil {
+		t.Fatalf("right code: err = %v", err)
+	}
+	if _, err := service.Verify(context.Background(), email, purpose, "", "123456"); err != ErrCodeNotFound {
+		t.Fatalf("second redemption: err = %v, want ErrCodeNotFound", err)
+	}
+}
+
+func TestVerifyOnlyRedeemsForItsSubject(t *testing.T) {
+	const maxAttempts = 5
+	store := &memoryVerificationStore{}
+	service := &VerificationService{repo: store, ttl: time.Minute, maxAttempts: maxAttempts}
+	email, purpose := "member@example.com", models.PurposeClaimProfile
+	store.ReplaceCode(context.Background(), &models.VerificationCode{
+		Email:    email,
+		Purpose:  purpose,
+		CodeHash: hashVerificationCode(email, purpose, "123456"),
+		Subject:  "claimant",
+	})
+
+	// Another user can neither redeem the code nor spend its guesses
+	for i := 0; i < maxAttempts+1; i++ {
+		if _, err := service.Verify(context.Background(), email, purpose, "intruder", "000000"); err != ErrCodeNotFound {
+			t.Fatalf("guess %d for another subject: err = %v, want ErrCodeNotFound", i+1, err)
+		}
+	}
+	if _, err := service.Verify(context.Background(), email, purpose, "intruder", "123456"); err != ErrCodeNotFound {
+		t.Fatalf("right code for another subject: err = %v, want ErrCodeNotFound", err)
+	}
+	if store.code == nil || store.code.Attempts != 0 {
+		t.Fatalf("code after other subjects' guesses = %+v, want untouched", store.code)
+	}
+
+	if _, err := service.Verify(context.Background(), email, purpose, "claimant", "123456"); err != nil {
+		t.Fatalf("right code for its subject: err = %v", err)
+	}
+}
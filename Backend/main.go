@@ -9,9 +9,11 @@ import (
 	"github.com/AliSleiman0/Lacpa/config"
 	"github.com/AliSleiman0/Lacpa/handler"
 	adminHandler "github.com/AliSleiman0/Lacpa/handler/admin"
+	"github.com/AliSleiman0/Lacpa/middleware"
 	"github.com/AliSleiman0/Lacpa/repository"
 	adminRepo "github.com/AliSleiman0/Lacpa/repository/admin"
 	"github.com/AliSleiman0/Lacpa/routes"
+	"github.com/AliSleiman0/Lacpa/utils"
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
 	"github.com/gofiber/fiber/v2/middleware/logger"
@@ -43,6 +45,89 @@ func main() {
 	database := mongoClient.Database(getEnv("MONGO_DATABASE", "lacpa"))
 	repo := repository.NewMongoRepository(database)
 	authRepo := repository.NewAuthRepository(database)
+	if err := authRepo.EnsureIndexes(ctx); err != nil {
+		log.Println("Warning: failed to create user indexes (run scripts/check_duplicates):", err)
+	}
+	if err := repo.EnsureMemberIndexes(ctx); err != nil {
+		log.Println("Warning: failed to create member indexes (run scripts/check_duplicates):", err)
+	}
+	if err := repo.EnsureApplicationIndexes(ctx); err != nil {
+		log.Println("Warning: failed to create application indexes:", err)
+	}
+	if err := repo.EnsureInvoiceIndexes(ctx); err != nil {
+		log.Println("Warning: failed to create invoice indexes:", err)
+	}
+	counterRepo := repository.NewCounterRepository(database)
+	roleRepo := repository.NewRoleRepository(database)
+
+	// Seed built-in roles and make the middleware reject tokens of edited roles
+	if err := roleRepo.EnsureIndexes(ctx); err != nil {
+		log.Println("Warning: failed to create role indexes:", err)
+	}
+	if err := roleRepo.EnsureDefaultRoles(ctx); err != nil {
+		log.Fatal("Failed to seed default roles:", err)
+	}
+	middleware.UseRoleVersionSource(roleRepo)
+
+	// Server-side sessions back every access token so logout can revoke them
+	sessionRepo := repository.NewSessionRepository(database)
+	if err := sessionRepo.EnsureIndexes(ctx); err != nil {
+		log.Println("Warning: failed to create session indexes:", err)
+	}
+	middleware.UseSessionChecker(sessionRepo)
+
+	// Failed login and OTP attempts for backoff and lockout
+	attemptRepo := repository.NewAttemptRepository(database)
+	if err := attemptRepo.EnsureIndexes(ctx); err != nil {
+		log.Println("Warning: failed to create auth attempt indexes:", err)
+	}
+
+	// Purpose-scoped email verification codes for /api/auth and /api/otp
+	verificationRepo := repository.NewVerificationRepository(database)
+	if err := verificationRepo.EnsureIndexes(ctx); err != nil {
+		log.Println("Warning: failed to create verification code indexes:", err)
+	}
+	verificationService := handler.NewVerificationService(verificationRepo)
+
+	// Append-only audit trail of administrative actions
+	auditRepo := repository.NewAuditRepository(database)
+	if err := auditRepo.EnsureIndexes(ctx); err != nil {
+		log.Println("Warning: failed to create audit indexes:", err)
+	}
+	auditService := handler.NewAuditService(auditRepo)
+
+	// Emailed links are built from the configured address, never from the Host header
+	if _, err := utils.PublicBaseURL(); err != nil {
+		log.Fatal(err)
+	}
+
+	// Storage for application documents, never served as static files
+	blobStore, err := utils.NewBlobStoreFromEnv()
+	if err != nil {
+		log.Fatal("Failed to initialize document storage:", err)
+	}
+
+	// Online payment of application fee invoices; nil when PAYMENT_PROVIDER=none
+	paymentProvider, err := utils.NewPaymentProviderFromEnv()
+	if err != nil {
+		log.Fatal("Failed to initialize payment provider:", err)
+	}
+
+	appConfig, _ := utils.LoadConfig()
+
+	// Rate limiting for endpoints that send email or accept anonymous submissions
+	var rateLimitStore middleware.RateLimitStore
+	if appConfig.EnableRateLimit {
+		if appConfig.RateLimitStore == "mongo" {
+			rateLimitRepo := repository.NewRateLimitRepository(database)
+			if err := rateLimitRepo.EnsureIndexes(ctx); err != nil {
+				log.Println("Warning: failed to create rate limit indexes:", err)
+			}
+			rateLimitStore = rateLimitRepo
+		} else {
+			rateLimitStore = middleware.NewMemoryRateLimitStore()
+		}
+	}
 
 	// Initialize HTML template engine
 	// Templates will be loaded from "./templates" directory
@@ -74,14 +159,25 @@ func main() {
 	app := fiber.New(fiber.Config{
 		AppName: "Lacpa API",
 		Views:   engine,
+		// Bodies past the default limit are streamed to middleware.BodyLimit, which
+		// only lets the upload routes read them
+		StreamRequestBody:            true,
+		DisablePreParseMultipartForm: true,
+		// c.IP() keys rate limits and lockouts. The proxy header is only believed on
+		// requests from TRUSTED_PROXIES; anyone else gets their socket address.
+		ProxyHeader:             appConfig.ProxyHeader,
+		EnableTrustedProxyCheck: true,
+		TrustedProxies:          appConfig.TrustedProxies,
+		EnableIPValidation:      true,
 	})
 
 	// Middleware
 	app.Use(logger.New())
+	app.Use(middleware.BodyLimit(fiber.DefaultBodyLimit, routes.BodyLimitRules()))
 	app.Use(cors.New(cors.Config{
 		AllowOrigins: "*",
 		AllowMethods: "GET, POST, PUT, DELETE, OPTIONS",
-		AllowHeaders: "Origin, Content-Type, Accept, Authorization, X-Requested-With, HX-Request, HX-Trigger, HX-Target, HX-Current-URL, HX-Boosted, HX-History-Restore-Request",
+		AllowHeaders: "Origin, Content-Type, Accept, Authorization, X-Requested-With, HX-Request, HX-Trigger, HX-Target, HX-Current-URL, HX-Boosted, HX-History-Restore-Request, X-Application-Token",
 	}))
 
 	// Add no-cache headers for all static files during development
@@ -95,6 +191,13 @@ func main() {
 		return c.Next()
 	})
 
+	if rateLimitStore != nil {
+		app.Use(middleware.RateLimit(routes.RateLimitPolicies(), rateLimitStore))
+	}
+
+	// Enforce authentication and permissions for every protected route
+	app.Use(middleware.Authorize(routes.AccessTable))
+
 	// Serve static files from LACPA_Web (parent directory)
 	// Disable caching for development
 	app.Static("/", "../LACPA_Web", fiber.Static{
@@ -120,14 +223,38 @@ func main() {
 	})
 
 	// Setup all API routes (includes health check and all endpoints)
-	routes.SetupRoutes(app, repo)
+	routes.SetupRoutes(app, repo, verificationService, auditService)
 
 	// Setup authentication routes
-	authHandler := handler.NewAuthHandler(authRepo)
+	authHandler := handler.NewAuthHandler(authRepo, roleRepo, sessionRepo, attemptRepo, repo, counterRepo, verificationService, auditService)
 	routes.SetupAuthRoutes(app, authHandler)
 
+	// Application document uploads and downloads
+	documentHandler := handler.NewDocumentHandler(repo, blobStore)
+	routes.SetupDocumentRoutes(app, documentHandler)
+	go documentHandler.RunUnattachedCleanup(context.Background(), time.Hour)
+
+	// Applicant portal for following up on submitted applications
+	routes.SetupApplicationTrackingRoutes(app, handler.NewApplicationTrackingHandler(repo, repo, paymentProvider, verificationService, documentHandler))
+
+	// Reviewer assignment, recommendations and comments on applications
+	routes.SetupReviewRoutes(app, handler.NewReviewHandler(repo, authRepo, roleRepo, auditService))
+
+	// Application fees, invoices and payment notifications
+	routes.SetupInvoiceRoutes(app, handler.NewInvoiceHandler(repo, repo, paymentProvider, auditService))
+
+	// Staff single sign-on through the organization's OIDC provider
+	if oidcConfig, enabled := utils.LoadOIDCConfig(); enabled {
+		oidcStateRepo := repository.NewOIDCStateRepository(database)
+		if err := oidcStateRepo.EnsureIndexes(ctx); err != nil {
+			log.Println("Warning: failed to create OIDC state indexes:", err)
+		}
+		oidcHandler := handler.NewOIDCHandler(authHandler, utils.NewOIDCProvider(oidcConfig), oidcStateRepo)
+		routes.SetupOIDCRoutes(app, oidcHandler)
+	}
+
 	// Setup admin routes
-	adminUserHandler := handler.NewAdminHandler(authRepo)
+	adminUserHandler := handler.NewAdminHandler(authRepo, roleRepo, sessionRepo, attemptRepo, counterRepo, auditService)
 	heroSlideRepo := adminRepo.NewHeroSlideRepository(database)
 	heroSlideHandler := adminHandler.NewAdminHeroSlideHandler(heroSlideRepo)
 	routes.SetupAdminRoutes(app, adminUserHandler, heroSlideHandler)
@@ -9,40 +9,16 @@ import (
 
 // AuthMiddleware validates JWT token from Authorization header
 func AuthMiddleware(c *fiber.Ctx) error {
-	// Get Authorization header
-	authHeader := c.Get("Authorization")
-	if authHeader == "" {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error":   "Missing authorization header",
-			"success": false,
-		})
-	}
-
-	// Check if it starts with "Bearer "
-	tokenParts := strings.Split(authHeader, " ")
-	if len(tokenParts) != 2 || tokenParts[0] != "Bearer" {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error":   "Invalid authorization header format",
-			"success": false,
-		})
-	}
-
-	tokenString := tokenParts[1]
-
-	// Validate token
-	claims, err := utils.ValidateJWT(tokenString)
-	if err != nil {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error":   "Invalid or expired token",
+	claims, status, message := authenticate(c)
+	if claims == nil {
+		return c.Status(status).JSON(fiber.Map{
+			"error":   message,
 			"success": false,
 		})
 	}
 
 	// Store user info in context for later use
-	c.Locals("userID", claims.UserID)
-	c.Locals("lacpaID", claims.LACPAID)
-	c.Locals("email", claims.Email)
-	c.Locals("role", claims.Role)
+	storeClaims(c, claims)
 
 	return c.Next()
 }
@@ -50,15 +26,14 @@ func AuthMiddleware(c *fiber.Ctx) error {
 // RoleMiddleware checks if user has required role
 func RoleMiddleware(allowedRoles ...string) fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		role := c.Locals("role")
-		if role == nil {
+		userRole, ok := c.Locals("role").(string)
+		if !ok {
 			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
 				"error":   "No role found in context",
 				"success": false,
 			})
 		}
 
-		userRole := role.(string)
 		for _, allowedRole := range allowedRoles {
 			if userRole == allowedRole {
 				return c.Next()
@@ -74,27 +49,62 @@ func RoleMiddleware(allowedRoles ...string) fiber.Handler {
 
 // OptionalAuthMiddleware validates JWT token if present, but doesn't require it
 func OptionalAuthMiddleware(c *fiber.Ctx) error {
+	if c.Get("Authorization") == "" {
+		return c.Next()
+	}
+
+	claims, _, _ := authenticate(c)
+	if claims == nil {
+		return c.Next()
+	}
+
+	// Store user info in context
+	storeClaims(c, claims)
+
+	return c.Next()
+}
+
+// authenticate extracts and validates the bearer token of the request.
+// On failure it returns nil claims together with the HTTP status and
+// message that should be sent back to the client.
+func authenticate(c *fiber.Ctx) (*utils.JWTClaims, int, string) {
+	// Get Authorization header
 	authHeader := c.Get("Authorization")
 	if authHeader == "" {
-		return c.Next()
+		return nil, fiber.StatusUnauthorized, "Missing authorization header"
 	}
 
+	// Check if it starts with "Bearer "
 	tokenParts := strings.Split(authHeader, " ")
 	if len(tokenParts) != 2 || tokenParts[0] != "Bearer" {
-		return c.Next()
+		return nil, fiber.StatusUnauthorized, "Invalid authorization header format"
 	}
 
-	tokenString := tokenParts[1]
-	claims, err := utils.ValidateJWT(tokenString)
+	// Validate token
+	claims, err := utils.ValidateJWT(tokenParts[1])
 	if err != nil {
-		return c.Next()
+		return nil, fiber.StatusUnauthorized, "Invalid or expired token"
 	}
 
-	// Store user info in context
+	// Reject tokens whose session was revoked (logout, reuse detection, deactivation)
+	if !sessionActive(c.Context(), claims.SessionID) {
+		return nil, fiber.StatusUnauthorized, "Session has ended. Please log in again."
+	}
+
+	// Reject tokens minted before the user's role was edited
+	if !roleVersionCurrent(c.Context(), claims.Role, claims.RoleVersion) {
+		return nil, fiber.StatusUnauthorized, "Your permissions have changed. Please log in again."
+	}
+
+	return claims, 0, ""
+}
+
+// storeClaims copies the token claims into the request context
+func storeClaims(c *fiber.Ctx, claims *utils.JWTClaims) {
 	c.Locals("userID", claims.UserID)
 	c.Locals("lacpaID", claims.LACPAID)
 	c.Locals("email", claims.Email)
 	c.Locals("role", claims.Role)
-
-	return c.Next()
+	c.Locals("permissions", claims.Permissions)
+	c.Locals("sessionID", claims.SessionID)
 }
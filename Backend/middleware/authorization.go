@@ -0,0 +1,146 @@
+package middleware
+
+import (
+	"strings"
+
+	"github.com/AliSleiman0/Lacpa/models"
+	"github.com/gofiber/fiber/v2"
+)
+
+// HasPermission reports whether the authenticated user holds the permission.
+// Permissions are read from the access token by the authentication step.
+func HasPermission(c *fiber.Ctx, permission models.Permission) bool {
+	permissions, ok := c.Locals("permissions").([]string)
+	if !ok {
+		return false
+	}
+	for _, granted := range permissions {
+		if granted == string(permission) {
+			return true
+		}
+	}
+	return false
+}
+
+// RequirePermission checks that the authenticated user holds every listed permission.
+// It must run after AuthMiddleware.
+func RequirePermission(permissions ...models.Permission) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if _, ok := c.Locals("permissions").([]string); !ok {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error":   "Authentication required",
+				"success": false,
+			})
+		}
+
+		for _, permission := range permissions {
+			if !HasPermission(c, permission) {
+				return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+					"error":   "Insufficient permissions",
+					"success": false,
+				})
+			}
+		}
+
+		return c.Next()
+	}
+}
+
+// AccessRule binds a route pattern to the permission needed to call it.
+//
+// Method may be "*" to match every method. Path segments starting with ":"
+// match any single segment, and a trailing "/*" matches any sub-path.
+type AccessRule struct {
+	Method     string
+	Path       string
+	Permission models.Permission
+}
+
+// Authorize enforces a route-to-permission table in front of the router.
+//
+// ROLE: Central Authorization Layer
+// - Looks up the first rule matching the request method and path
+// - Requests that match no rule pass through untouched (public routes)
+// - Matching requests must carry a valid bearer token (401 otherwise)
+// - The token must carry the rule's permission (403 otherwise)
+//
+// PARAMETERS:
+//   - rules: Ordered access table; the first match wins, so list
+//     specific routes before catch-all prefixes
+func Authorize(rules []AccessRule) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		rule, ok := MatchAccessRule(rules, c.Method(), c.Path())
+		if !ok {
+			return c.Next()
+		}
+
+		claims, status, message := authenticate(c)
+		if claims == nil {
+			return c.Status(status).JSON(fiber.Map{
+				"error":   message,
+				"success": false,
+			})
+		}
+		storeClaims(c, claims)
+
+		if !HasPermission(c, rule.Permission) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error":   "Insufficient permissions",
+				"success": false,
+			})
+		}
+
+		return c.Next()
+	}
+}
+
+// MatchAccessRule returns the first rule matching the method and path
+func MatchAccessRule(rules []AccessRule, method, path string) (AccessRule, bool) {
+	// HEAD requests are served by GET handlers
+	if method == fiber.MethodHead {
+		method = fiber.MethodGet
+	}
+
+	for _, rule := range rules {
+		if rule.Method != "*" && rule.Method != method {
+			continue
+		}
+		if matchPath(rule.Path, path) {
+			return rule, true
+		}
+	}
+	return AccessRule{}, false
+}
+
+// matchPath compares a route pattern with a request path segment by segment.
+// Segments are compared case-insensitively because the router is.
+func matchPath(pattern, path string) bool {
+	patternParts := splitPath(pattern)
+	pathParts := splitPath(path)
+
+	for i, part := range patternParts {
+		if part == "*" && i == len(patternParts)-1 {
+			return len(pathParts) >= i
+		}
+		if i >= len(pathParts) {
+			return false
+		}
+		if strings.HasPrefix(part, ":") {
+			continue
+		}
+		if !strings.EqualFold(part, pathParts[i]) {
+			return false
+		}
+	}
+
+	return len(patternParts) == len(pathParts)
+}
+
+// splitPath splits a URL path into its non-empty segments
+func splitPath(path string) []string {
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	if len(parts) == 1 && parts[0] == "" {
+		return []string{}
+	}
+	return parts
+}
@@ -0,0 +1,73 @@
+package middleware
+
+import (
+	"io"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// BodyLimitRule raises the request body limit of one route
+type BodyLimitRule struct {
+	Method string // HTTP method, or "*" for any
+	Path   string // Route pattern, same syntax as AccessRule
+	Limit  int    // Largest accepted body in bytes
+}
+
+// BodyLimit caps request bodies per route.
+//
+// ROLE: Request Size Guard
+// - Applies defaultLimit to every route not raised by a rule (first match wins)
+// - Reads at most the limit from the body and responds 413 beyond it
+//
+// The server must run with StreamRequestBody and DisablePreParseMultipartForm.
+// It then buffers bodies up to its own BodyLimit and hands larger ones over as a
+// stream, so only the routes of the rules ever read a body past the default.
+// Register it before any middleware that reads the body.
+func BodyLimit(defaultLimit int, rules []BodyLimitRule) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		limit := matchBodyLimit(rules, c.Method(), c.Path(), defaultLimit)
+
+		req := c.Request()
+		if req.Header.ContentLength() > limit {
+			return sendBodyTooLarge(c)
+		}
+		if req.IsBodyStream() {
+			body, err := io.ReadAll(io.LimitReader(req.BodyStream(), int64(limit)+1))
+			if err != nil {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+					"error":   "Failed to read request body",
+					"success": false,
+				})
+			}
+			if len(body) > limit {
+				return sendBodyTooLarge(c)
+			}
+			req.SetBody(body)
+		}
+
+		return c.Next()
+	}
+}
+
+// matchBodyLimit returns the limit of the first rule matching the method and path
+func matchBodyLimit(rules []BodyLimitRule, method, path string, defaultLimit int) int {
+	for _, rule := range rules {
+		if rule.Method != "*" && rule.Method != method {
+			continue
+		}
+		if matchPath(rule.Path, path) {
+			return rule.Limit
+		}
+	}
+	return defaultLimit
+}
+
+// sendBodyTooLarge responds 413 and closes the connection, since the rest of the
+// body is still unread on it
+func sendBodyTooLarge(c *fiber.Ctx) error {
+	c.Context().SetConnectionClose()
+	return c.Status(fiber.StatusRequestEntityTooLarge).JSON(fiber.Map{
+		"error":   "Request body is too large",
+		"success": false,
+	})
+}
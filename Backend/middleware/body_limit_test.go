@@ -0,0 +1,119 @@
+package middleware
+
+import (
+	"bytes"
+	"io"
+	"mime/multipart"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// newBodyLimitTestApp buffers 16 bytes, limits bodies to 32 bytes and raises /upload to 1KB.
+// The handler echoes the body it received.
+func newBodyLimitTestApp() *fiber.App {
+	app := fiber.New(fiber.Config{
+		BodyLimit:                    16,
+		StreamRequestBody:            true,
+		DisablePreParseMultipartForm: true,
+	})
+	app.Use(BodyLimit(32, []BodyLimitRule{
+		{Method: fiber.MethodPost, Path: "/upload", Limit: 1024},
+	}))
+	app.Post("/*", func(c *fiber.Ctx) error {
+		return c.Send(c.Body())
+	})
+	return app
+}
+
+func TestBodyLimit(t *testing.T) {
+	app := newBodyLimitTestApp()
+
+	tests := []struct {
+		name    string
+		path    string
+		size    int
+		chunked bool
+		status  int
+	}{
+		{name: "under the buffer", path: "/other", size: 8, status: fiber.StatusOK},
+		{name: "streamed under the default", path: "/other", size: 24, status: fiber.StatusOK},
+		{name: "over the default", path: "/other", size: 64, status: fiber.StatusRequestEntityTooLarge},
+		{name: "chunked over the default", path: "/other", size: 64, chunked: true, status: fiber.StatusRequestEntityTooLarge},
+		{name: "raised route", path: "/upload", size: 512, status: fiber.StatusOK},
+		{name: "raised route mixed case", path: "/UpLoad", size: 512, status: fiber.StatusOK},
+		{name: "chunked raised route", path: "/upload", size: 512, chunked: true, status: fiber.StatusOK},
+		{name: "over the raised limit", path: "/upload", size: 2048, status: fiber.StatusRequestEntityTooLarge},
+		{name: "chunked over the raised limit", path: "/upload", size: 2048, chunked: true, status: fiber.StatusRequestEntityTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := bytes.Repeat([]byte("a"), tt.size)
+			req := httptest.NewRequest(fiber.MethodPost, tt.path, bytes.NewReader(body))
+			if tt.chunked {
+				req.ContentLength = -1
+				req.TransferEncoding = []string{"chunked"}
+			}
+
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatalf("request failed: %v", err)
+			}
+			if resp.StatusCode != tt.status {
+				t.Fatalf("status = %d, want %d", resp.StatusCode, tt.status)
+			}
+			if tt.status != fiber.StatusOK {
+				return
+			}
+
+			echoed, err := io.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatalf("read response: %v", err)
+			}
+			if !bytes.Equal(echoed, body) {
+				t.Fatalf("handler received %d bytes, want %d", len(echoed), len(body))
+			}
+		})
+	}
+}
+
+func TestBodyLimitKeepsStreamedUploadsReadable(t *testing.T) {
+	app := fiber.New(fiber.Config{
+		BodyLimit:                    16,
+		StreamRequestBody:            true,
+		DisablePreParseMultipartForm: true,
+	})
+	app.Use(BodyLimit(32, []BodyLimitRule{
+		{Method: fiber.MethodPost, Path: "/upload", Limit: 1024},
+	}))
+	app.Post("/upload", func(c *fiber.Ctx) error {
+		file, err := c.FormFile("file")
+		if err != nil {
+			return c.SendStatus(fiber.StatusBadRequest)
+		}
+		return c.SendString(strconv.FormatInt(file.Size, 10))
+	})
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, err := form.CreateFormFile("file", "statement.pdf")
+	if err != nil {
+		t.Fatalf("CreateFormFile: %v", err)
+	}
+	part.Write(bytes.Repeat([]byte("a"), 512))
+	form.Close()
+
+	req := httptest.NewRequest(fiber.MethodPost, "/upload", &body)
+	req.Header.Set(fiber.HeaderContentType, form.FormDataContentType())
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	size, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != fiber.StatusOK || string(size) != "512" {
+		t.Fatalf("status = %d, file size = %s, want 200 and 512", resp.StatusCode, size)
+	}
+}
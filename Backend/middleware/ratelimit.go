@@ -0,0 +1,141 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/AliSleiman0/Lacpa/models"
+	"github.com/gofiber/fiber/v2"
+)
+
+// RateLimitStore keeps token buckets.
+// MemoryRateLimitStore serves a single instance; repository.RateLimitRepository
+// shares buckets through Mongo when several instances run behind a load balancer.
+type RateLimitStore interface {
+	Take(ctx context.Context, key string, capacity int, refillEvery time.Duration) (models.RateLimitResult, error)
+}
+
+// Rate limit key dimensions
+const (
+	RateLimitByIP    = "ip"    // Client IP address
+	RateLimitByEmail = "email" // "email" field of the JSON or form body
+)
+
+// RateLimitPolicy is a token bucket applied to one route.
+// Every dimension in KeyBy gets its own bucket and all of them must allow the request.
+type RateLimitPolicy struct {
+	Name        string        // Used in bucket keys, e.g. "otp-send"
+	Method      string        // HTTP method, or "*" for any
+	Path        string        // Route pattern, same syntax as AccessRule
+	Capacity    int           // Bucket size: requests allowed in a burst
+	RefillEvery time.Duration // Time to regain one request
+	KeyBy       []string      // Dimensions the bucket is keyed by
+}
+
+// RateLimit enforces per-route token bucket policies.
+//
+// ROLE: Abuse Protection
+// - Matches the request against the policy table (first match wins)
+// - Takes one token per dimension (IP, target email) from the store
+// - Sets RateLimit-Limit, RateLimit-Remaining and RateLimit-Reset headers
+// - Responds 429 with Retry-After once any bucket is empty
+//
+// Store failures let the request through so an unavailable store does not
+// take the API down with it.
+func RateLimit(policies []RateLimitPolicy, store RateLimitStore) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		policy, ok := matchRateLimitPolicy(policies, c.Method(), c.Path())
+		if !ok {
+			return c.Next()
+		}
+
+		remaining := policy.Capacity
+		var resetAfter, retryAfter time.Duration
+		allowed := true
+
+		for _, dimension := range policy.KeyBy {
+			value := rateLimitKeyValue(c, dimension)
+			if value == "" {
+				continue
+			}
+
+			key := fmt.Sprintf("%s:%s:%s", policy.Name, dimension, value)
+			result, err := store.Take(c.Context(), key, policy.Capacity, policy.RefillEvery)
+			if err != nil {
+				fmt.Printf("Rate limit store error for %s: %v\n", key, err)
+				continue
+			}
+
+			if result.Remaining < remaining {
+				remaining = result.Remaining
+			}
+			if result.ResetAfter > resetAfter {
+				resetAfter = result.ResetAfter
+			}
+			if !result.Allowed {
+				allowed = false
+				if result.RetryAfter > retryAfter {
+					retryAfter = result.RetryAfter
+				}
+			}
+		}
+
+		c.Set("RateLimit-Limit", strconv.Itoa(policy.Capacity))
+		c.Set("RateLimit-Remaining", strconv.Itoa(remaining))
+		c.Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(resetAfter)))
+
+		if !allowed {
+			c.Set("Retry-After", strconv.Itoa(ceilSeconds(retryAfter)))
+			return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
+				"error":       "Too many requests. Please try again later.",
+				"retry_after": ceilSeconds(retryAfter),
+				"success":     false,
+			})
+		}
+
+		return c.Next()
+	}
+}
+
+// matchRateLimitPolicy returns the first policy matching the method and path.
+// Paths are matched like access rules, ignoring case as the router does.
+func matchRateLimitPolicy(policies []RateLimitPolicy, method, path string) (RateLimitPolicy, bool) {
+	for _, policy := range policies {
+		if policy.Method != "*" && policy.Method != method {
+			continue
+		}
+		if matchPath(policy.Path, path) {
+			return policy, true
+		}
+	}
+	return RateLimitPolicy{}, false
+}
+
+// rateLimitKeyValue extracts the value of a key dimension from the request
+func rateLimitKeyValue(c *fiber.Ctx, dimension string) string {
+	switch dimension {
+	case RateLimitByIP:
+		return c.IP()
+	case RateLimitByEmail:
+		var body struct {
+			Email string `json:"email" form:"email"`
+		}
+		if err := c.BodyParser(&body); err != nil {
+			return ""
+		}
+		return strings.ToLower(strings.TrimSpace(body.Email))
+	}
+	return ""
+}
+
+// ceilSeconds rounds a duration up to whole seconds
+func ceilSeconds(d time.Duration) int {
+	if d <= 0 {
+		return 0
+	}
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,73 @@
+package middleware
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/AliSleiman0/Lacpa/models"
+)
+
+// memoryBucket is the in-process state of a token bucket
+type memoryBucket struct {
+	tokens    float64
+	updatedAt time.Time
+	expiresAt time.Time
+}
+
+// MemoryRateLimitStore keeps token buckets in process memory.
+// Suitable for a single instance only; buckets are lost on restart.
+type MemoryRateLimitStore struct {
+	mutex     sync.Mutex
+	buckets   map[string]*memoryBucket
+	lastSweep time.Time
+}
+
+// NewMemoryRateLimitStore creates an empty in-memory store
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+	return &MemoryRateLimitStore{
+		buckets:   make(map[string]*memoryBucket),
+		lastSweep: time.Now(),
+	}
+}
+
+// Take refills the bucket for the elapsed time and removes one token if available
+func (s *MemoryRateLimitStore) Take(ctx context.Context, key string, capacity int, refillEvery time.Duration) (models.RateLimitResult, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	now := time.Now()
+	s.sweep(now)
+
+	bucket, ok := s.buckets[key]
+	if !ok {
+		bucket = &memoryBucket{tokens: float64(capacity), updatedAt: now}
+		s.buckets[key] = bucket
+	}
+
+	elapsed := now.Sub(bucket.updatedAt)
+	bucket.tokens = math.Min(float64(capacity), bucket.tokens+float64(elapsed)/float64(refillEvery))
+	bucket.updatedAt = now
+
+	allowed := bucket.tokens >= 1
+	if allowed {
+		bucket.tokens--
+	}
+	bucket.expiresAt = now.Add(time.Duration(float64(capacity) * float64(refillEvery)))
+
+	return models.NewRateLimitResult(allowed, bucket.tokens, capacity, refillEvery), nil
+}
+
+// sweep drops buckets that have refilled completely, at most once a minute
+func (s *MemoryRateLimitStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < time.Minute {
+		return
+	}
+	for key, bucket := range s.buckets {
+		if now.After(bucket.expiresAt) {
+			delete(s.buckets, key)
+		}
+	}
+	s.lastSweep = now
+}
@@ -0,0 +1,174 @@
+package middleware
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestMemoryRateLimitStoreTake(t *testing.T) {
+	store := NewMemoryRateLimitStore()
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		result, err := store.Take(ctx, "key", 2, 50*time.Millisecond)
+		if err != nil {
+			t.Fatalf("Take: %v", err)
+		}
+		if !result.Allowed {
+			t.Fatalf("take %d denied, want allowed", i+1)
+		}
+	}
+
+	result, _ := store.Take(ctx, "key", 2, 50*time.Millisecond)
+	if result.Allowed {
+		t.Fatal("take beyond capacity allowed, want denied")
+	}
+	if result.RetryAfter <= 0 {
+		t.Fatalf("RetryAfter = %v, want positive", result.RetryAfter)
+	}
+
+	if other, _ := store.Take(ctx, "other", 2, 50*time.Millisecond); !other.Allowed {
+		t.Fatal("separate key denied, want its own bucket")
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	if result, _ := store.Take(ctx, "key", 2, 50*time.Millisecond); !result.Allowed {
+		t.Fatal("take after refill denied, want allowed")
+	}
+}
+
+func newRateLimitTestApp() *fiber.App {
+	policies := []RateLimitPolicy{
+		{Name: "otp-send", Method: fiber.MethodPost, Path: "/api/otp/send", Capacity: 2, RefillEvery: time.Hour, KeyBy: []string{RateLimitByIP, RateLimitByEmail}},
+		{Name: "track", Method: fiber.MethodPost, Path: "/api/applications/track/:type/:id/documents", Capacity: 1, RefillEvery: time.Hour, KeyBy: []string{RateLimitByIP}},
+	}
+
+	app := fiber.New()
+	app.Use(RateLimit(policies, NewMemoryRateLimitStore()))
+	app.Use(func(c *fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusOK)
+	})
+	return app
+}
+
+func postJSON(t *testing.T, app *fiber.App, path, body string) int {
+	t.Helper()
+	req := httptest.NewRequest(fiber.MethodPost, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", fiber.MIMEApplicationJSON)
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	return resp.StatusCode
+}
+
+func TestRateLimitDeniesOnceBucketIsEmpty(t *testing.T) {
+	app := newRateLimitTestApp()
+
+	for i := 0; i < 2; i++ {
+		if status := postJSON(t, app, "/api/otp/send", `{"email":"a@example.com"}`); status != fiber.StatusOK {
+			t.Fatalf("request %d: status = %d, want %d", i+1, status, fiber.StatusOK)
+		}
+	}
+
+	req := httptest.NewRequest(fiber.MethodPost, "/api/otp/send", strings.NewReader(`{"email":"a@example.com"}`))
+	req.Header.Set("Content-Type", fiber.MIMEApplicationJSON)
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	if resp.StatusCode != fiber.StatusTooManyRequests {
+		t.Fatalf("status = %d, want %d", resp.StatusCode, fiber.StatusTooManyRequests)
+	}
+	if resp.Header.Get("Retry-After") == "" {
+		t.Fatal("Retry-After header missing")
+	}
+	if got := resp.Header.Get("RateLimit-Limit"); got != "2" {
+		t.Fatalf("RateLimit-Limit = %q, want 2", got)
+	}
+}
+
+func TestRateLimitIgnoresPathCase(t *testing.T) {
+	app := newRateLimitTestApp()
+
+	paths := []string{"/api/otp/send", "/API/otp/send", "/Api/OTP/Send/"}
+	for i, path := range paths {
+		want := fiber.StatusOK
+		if i == len(paths)-1 {
+			want = fiber.StatusTooManyRequests
+		}
+		// A new email each time so only the IP bucket is shared
+		body := `{"email":"user` + string(rune('a'+i)) + `@example.com"}`
+		if status := postJSON(t, app, path, body); status != want {
+			t.Fatalf("%s: status = %d, want %d", path, status, want)
+		}
+	}
+}
+
+func TestRateLimitMatchesRouteParameters(t *testing.T) {
+	app := newRateLimitTestApp()
+
+	if status := postJSON(t, app, "/api/applications/track/individual/1/documents", `{}`); status != fiber.StatusOK {
+		t.Fatalf("first upload: status = %d, want %d", status, fiber.StatusOK)
+	}
+	if status := postJSON(t, app, "/api/applications/track/FIRM/2/documents", `{}`); status != fiber.StatusTooManyRequests {
+		t.Fatalf("second upload: status = %d, want %d", status, fiber.StatusTooManyRequests)
+	}
+}
+
+func TestRateLimitPassesUnmatchedRoutes(t *testing.T) {
+	app := newRateLimitTestApp()
+
+	for i := 0; i < 5; i++ {
+		if status := postJSON(t, app, "/api/otp/verify", `{}`); status != fiber.StatusOK {
+			t.Fatalf("request %d: status = %d, want %d", i+1, status, fiber.StatusOK)
+		}
+	}
+}
+
+func TestRateLimitTrustsProxyHeaderOnlyFromProxies(t *testing.T) {
+	tests := []struct {
+		name           string
+		trustedProxies []string
+		wantSecond     int
+	}{
+		// app.Test requests come from 0.0.0.0
+		{name: "request from a trusted proxy", trustedProxies: []string{"0.0.0.0"}, wantSecond: fiber.StatusOK},
+		{name: "request from anyone else", trustedProxies: []string{"10.0.0.0/8"}, wantSecond: fiber.StatusTooManyRequests},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := fiber.New(fiber.Config{
+				ProxyHeader:             "X-Real-IP",
+				EnableTrustedProxyCheck: true,
+				TrustedProxies:          tt.trustedProxies,
+				EnableIPValidation:      true,
+			})
+			app.Use(RateLimit([]RateLimitPolicy{
+				{Name: "verify", Method: fiber.MethodPost, Path: "/api/otp/verify", Capacity: 1, RefillEvery: time.Hour, KeyBy: []string{RateLimitByIP}},
+			}, NewMemoryRateLimitStore()))
+			app.Use(func(c *fiber.Ctx) error {
+				return c.SendStatus(fiber.StatusOK)
+			})
+
+			statuses := make([]int, 0, 2)
+			for _, ip := range []string{"203.0.113.7", "198.51.100.1"} {
+				req := httptest.NewRequest(fiber.MethodPost, "/api/otp/verify", nil)
+				req.Header.Set("X-Real-IP", ip)
+				resp, err := app.Test(req)
+				if err != nil {
+					t.Fatalf("request failed: %v", err)
+				}
+				statuses = append(statuses, resp.StatusCode)
+			}
+			if statuses[0] != fiber.StatusOK || statuses[1] != tt.wantSecond {
+				t.Fatalf("statuses = %v, want [%d %d]", statuses, fiber.StatusOK, tt.wantSecond)
+			}
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package middleware
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// RoleVersionSource reports the current version of a role.
+// It is implemented by repository.RoleRepository.
+type RoleVersionSource interface {
+	GetRoleVersion(ctx context.Context, name string) (int, error)
+}
+
+// roleVersionCacheTTL bounds how long a role edit can take to invalidate tokens
+const roleVersionCacheTTL = 30 * time.Second
+
+type cachedRoleVersion struct {
+	version   int
+	fetchedAt time.Time
+}
+
+var (
+	roleVersions     RoleVersionSource
+	roleVersionCache = make(map[string]cachedRoleVersion)
+	roleVersionMutex sync.RWMutex
+)
+
+// UseRoleVersionSource enables role version checks on every authenticated request.
+// Without a source, tokens are trusted until they expire.
+func UseRoleVersionSource(source RoleVersionSource) {
+	roleVersionMutex.Lock()
+	defer roleVersionMutex.Unlock()
+	roleVersions = source
+	roleVersionCache = make(map[string]cachedRoleVersion)
+}
+
+// roleVersionCurrent checks a token's role version against the stored role
+func roleVersionCurrent(ctx context.Context, role string, version int) bool {
+	roleVersionMutex.RLock()
+	source := roleVersions
+	cached, found := roleVersionCache[role]
+	roleVersionMutex.RUnlock()
+
+	if source == nil {
+		return true
+	}
+
+	if !found || time.Since(cached.fetchedAt) > roleVersionCacheTTL {
+		current, err := source.GetRoleVersion(ctx, role)
+		if err != nil {
+			// Unknown or unreadable role: the token cannot be trusted
+			return false
+		}
+		cached = cachedRoleVersion{version: current, fetchedAt: time.Now()}
+
+		roleVersionMutex.Lock()
+		roleVersionCache[role] = cached
+		roleVersionMutex.Unlock()
+	}
+
+	return cached.version == version
+}
@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"context"
+	"sync"
+)
+
+// SessionChecker reports whether a server-side session is still active.
+// It is implemented by repository.SessionRepository.
+type SessionChecker interface {
+	IsSessionActive(ctx context.Context, id string) (bool, error)
+}
+
+var (
+	sessions      SessionChecker
+	sessionsMutex sync.RWMutex
+)
+
+// UseSessionChecker makes authentication reject tokens whose session was revoked.
+// Without a checker, tokens are trusted until they expire.
+func UseSessionChecker(checker SessionChecker) {
+	sessionsMutex.Lock()
+	defer sessionsMutex.Unlock()
+	sessions = checker
+}
+
+// sessionActive checks the session a token belongs to
+func sessionActive(ctx context.Context, sessionID string) bool {
+	sessionsMutex.RLock()
+	checker := sessions
+	sessionsMutex.RUnlock()
+
+	if checker == nil {
+		return true
+	}
+	if sessionID == "" {
+		return false
+	}
+
+	active, err := checker.IsSessionActive(ctx, sessionID)
+	return err == nil && active
+}
@@ -1,6 +1,8 @@
 package models
 
 import (
+	"slices"
+	"strings"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -20,17 +22,89 @@ type ApplicationStatus string
 const (
 	ApplicationStatusPending     ApplicationStatus = "Pending"
 	ApplicationStatusUnderReview ApplicationStatus = "Under Review"
+	ApplicationStatusNeedsInfo   ApplicationStatus = "Needs Info"
+	ApplicationStatusResubmitted ApplicationStatus = "Resubmitted"
 	ApplicationStatusApproved    ApplicationStatus = "Approved"
 	ApplicationStatusRejected    ApplicationStatus = "Rejected"
+	ApplicationStatusWithdrawn   ApplicationStatus = "Withdrawn"
 )
 
+// applicationTransitions lists the statuses staff may move each status to.
+// Approved, Rejected and Withdrawn are final.
+//
+//	Pending -> Under Review -> (Needs Info <-> Resubmitted) -> Approved / Rejected
+var applicationTransitions = map[ApplicationStatus][]ApplicationStatus{
+	ApplicationStatusPending:     {ApplicationStatusUnderReview},
+	ApplicationStatusUnderReview: {ApplicationStatusNeedsInfo, ApplicationStatusApproved, ApplicationStatusRejected},
+	ApplicationStatusResubmitted: {ApplicationStatusUnderReview, ApplicationStatusNeedsInfo, ApplicationStatusApproved, ApplicationStatusRejected},
+}
+
+// applicantTransitions lists the statuses only the applicant may move each status to:
+// answering a request for information and withdrawing until a decision
+var applicantTransitions = map[ApplicationStatus][]ApplicationStatus{
+	ApplicationStatusPending:     {ApplicationStatusWithdrawn},
+	ApplicationStatusUnderReview: {ApplicationStatusWithdrawn},
+	ApplicationStatusNeedsInfo:   {ApplicationStatusResubmitted, ApplicationStatusWithdrawn},
+	ApplicationStatusResubmitted: {ApplicationStatusWithdrawn},
+}
+
+// IsValid reports whether s is a known application status
+func (s ApplicationStatus) IsValid() bool {
+	switch s {
+	case ApplicationStatusPending, ApplicationStatusUnderReview, ApplicationStatusNeedsInfo,
+		ApplicationStatusResubmitted, ApplicationStatusApproved, ApplicationStatusRejected, ApplicationStatusWithdrawn:
+		return true
+	}
+	return false
+}
+
+// CanTransitionTo reports whether staff may move an application from s to next
+func (s ApplicationStatus) CanTransitionTo(next ApplicationStatus) bool {
+	return slices.Contains(applicationTransitions[s], next)
+}
+
+// CanApplicantTransitionTo reports whether the applicant may move an application from s to next
+func (s ApplicationStatus) CanApplicantTransitionTo(next ApplicationStatus) bool {
+	return slices.Contains(applicantTransitions[s], next)
+}
+
+// IsFinal reports whether s is a decision the application cannot move on from
+func (s ApplicationStatus) IsFinal() bool {
+	return len(applicationTransitions[s]) == 0 && len(applicantTransitions[s]) == 0
+}
+
+// NextStatuses returns the statuses staff may move an application to from s
+func (s ApplicationStatus) NextStatuses() []ApplicationStatus {
+	return append([]ApplicationStatus{}, applicationTransitions[s]...)
+}
+
+// ApplicationStatusChange is one entry in an application's status history
+type ApplicationStatusChange struct {
+	From      ApplicationStatus   `bson:"from,omitempty" json:"from,omitempty"` // Empty for the initial submission
+	To        ApplicationStatus   `bson:"to" json:"to"`
+	Notes     string              `bson:"notes,omitempty" json:"notes,omitempty"`
+	ChangedBy *primitive.ObjectID `bson:"changed_by,omitempty" json:"changed_by,omitempty"` // Empty when the applicant made the change
+	ChangedAt time.Time           `bson:"changed_at" json:"changed_at"`
+}
+
+// ApplicationStatusUpdateRequest is the body of a reviewer status change
+type ApplicationStatusUpdateRequest struct {
+	Status      ApplicationStatus `json:"status"`
+	ReviewNotes string            `json:"review_notes"`
+	MemberType  string            `json:"member_type,omitempty"` // Individual approvals only, defaults to Apprentices
+}
+
+// MembershipTerm is how long a membership runs before its first renewal
+const MembershipTerm = 1 // years
+
 // ApplicationRequirement represents a requirement for membership application
 type ApplicationRequirement struct {
 	ID              primitive.ObjectID `bson:"_id,omitempty" json:"id"`
 	Title           string             `bson:"title" json:"title"`
 	Description     string             `bson:"description" json:"description"`
-	Icon            string             `bson:"icon" json:"icon"`                         // Font Awesome icon class
-	ApplicationType ApplicationType    `bson:"application_type" json:"application_type"` // Individual or Firm
+	Icon            string             `bson:"icon" json:"icon"`                                       // Font Awesome icon class
+	ApplicationType ApplicationType    `bson:"application_type" json:"application_type"`               // Individual or Firm
+	DocumentKind    DocumentKind       `bson:"document_kind,omitempty" json:"document_kind,omitempty"` // Upload slot that satisfies it; empty when no document is involved
 	IsRequired      bool               `bson:"is_required" json:"is_required"`
 	OrderIndex      int                `bson:"order_index" json:"order_index"`
 	CreatedAt       time.Time          `bson:"created_at" json:"created_at"`
@@ -39,33 +113,35 @@ type ApplicationRequirement struct {
 
 // IndividualApplication represents an individual membership application
 type IndividualApplication struct {
-	ID primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	ID              primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	ReferenceNumber string              `bson:"reference_number" json:"reference_number"`   // Given to the applicant to track the application
+	UserID          *primitive.ObjectID `bson:"user_id,omitempty" json:"user_id,omitempty"` // Account that submitted it, if signed in
 	// Personal Information
-	FirstName   string    `bson:"first_name" json:"first_name"`
-	MiddleName  string    `bson:"middle_name,omitempty" json:"middle_name,omitempty"`
-	LastName    string    `bson:"last_name" json:"last_name"`
-	DateOfBirth time.Time `bson:"date_of_birth" json:"date_of_birth"`
-	Nationality string    `bson:"nationality" json:"nationality"`
+	FirstName   string    `bson:"first_name" json:"first_name" validate:"required,max=100"`
+	MiddleName  string    `bson:"middle_name,omitempty" json:"middle_name,omitempty" validate:"max=100"`
+	LastName    string    `bson:"last_name" json:"last_name" validate:"required,max=100"`
+	DateOfBirth time.Time `bson:"date_of_birth" json:"date_of_birth" validate:"required,notfuture,min_age=18"`
+	Nationality string    `bson:"nationality" json:"nationality" validate:"required,max=100"`
 
 	// Contact Information
-	Email       string `bson:"email" json:"email"`
-	Phone       string `bson:"phone" json:"phone"`
-	MobilePhone string `bson:"mobile_phone,omitempty" json:"mobile_phone,omitempty"`
+	Email       string `bson:"email" json:"email" validate:"required,email"`
+	Phone       string `bson:"phone" json:"phone" validate:"required,format=lb_phone"`
+	MobilePhone string `bson:"mobile_phone,omitempty" json:"mobile_phone,omitempty" validate:"format=lb_phone"`
 
 	// Address
 	Street     string `bson:"street" json:"street"`
-	City       string `bson:"city" json:"city"`
+	City       string `bson:"city" json:"city" validate:"required,max=100"`
 	District   string `bson:"district" json:"district"`
-	Country    string `bson:"country" json:"country"`
+	Country    string `bson:"country" json:"country" validate:"required,max=100"`
 	PostalCode string `bson:"postal_code,omitempty" json:"postal_code,omitempty"`
 
 	// Professional Information
-	ProfessionalTitle string   `bson:"professional_title" json:"professional_title"`
+	ProfessionalTitle string   `bson:"professional_title" json:"professional_title" validate:"required,max=150"`
 	Qualifications    []string `bson:"qualifications" json:"qualifications"`
-	YearsOfExperience int      `bson:"years_of_experience" json:"years_of_experience"`
+	YearsOfExperience int      `bson:"years_of_experience" json:"years_of_experience" validate:"gte=0,lte=60"`
 	CurrentEmployer   string   `bson:"current_employer,omitempty" json:"current_employer,omitempty"`
 
-	// Documents (file paths or URLs)
+	// Documents (IDs of uploaded ApplicationDocuments)
 	CVDocument            string   `bson:"cv_document,omitempty" json:"cv_document,omitempty"`
 	CertificatesDocuments []string `bson:"certificates_documents,omitempty" json:"certificates_documents,omitempty"`
 	IDDocument            string   `bson:"id_document,omitempty" json:"id_document,omitempty"`
@@ -77,44 +153,59 @@ type IndividualApplication struct {
 	ReviewedBy  *primitive.ObjectID `bson:"reviewed_by,omitempty" json:"reviewed_by,omitempty"`
 	ReviewNotes string              `bson:"review_notes,omitempty" json:"review_notes,omitempty"`
 
+	StatusHistory []ApplicationStatusChange `bson:"status_history,omitempty" json:"status_history,omitempty"`
+	MemberID      *primitive.ObjectID       `bson:"member_id,omitempty" json:"member_id,omitempty"` // Member record created on approval
+
+	// Open applications and members this one may duplicate, found at submission
+	PossibleDuplicates []DuplicateMatch `bson:"possible_duplicates,omitempty" json:"possible_duplicates,omitempty"`
+
+	// Normalized contact details and names, for finding duplicates
+	DuplicateKeys *DuplicateKeys `bson:"duplicate_keys,omitempty" json:"-"`
+
+	// Staff assigned to review the application and their recommendations
+	Reviewers       []ReviewerAssignment   `bson:"reviewers,omitempty" json:"reviewers,omitempty"`
+	Recommendations []ReviewRecommendation `bson:"recommendations,omitempty" json:"recommendations,omitempty"`
+
 	CreatedAt time.Time `bson:"created_at" json:"created_at"`
 	UpdatedAt time.Time `bson:"updated_at" json:"updated_at"`
 }
 
 // FirmApplication represents a firm membership application
 type FirmApplication struct {
-	ID primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	ID              primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	ReferenceNumber string              `bson:"reference_number" json:"reference_number"`   // Given to the applicant to track the application
+	UserID          *primitive.ObjectID `bson:"user_id,omitempty" json:"user_id,omitempty"` // Account that submitted it, if signed in
 
 	// Firm Information
-	FirmName           string `bson:"firm_name" json:"firm_name"`
-	TradeName          string `bson:"trade_name,omitempty" json:"trade_name,omitempty"`
-	RegistrationNumber string `bson:"registration_number" json:"registration_number"`
-	YearEstablished    int    `bson:"year_established" json:"year_established"`
+	FirmName           string `bson:"firm_name" json:"firm_name" validate:"required,max=200"`
+	TradeName          string `bson:"trade_name,omitempty" json:"trade_name,omitempty" validate:"max=200"`
+	RegistrationNumber string `bson:"registration_number" json:"registration_number" validate:"required,format=registration_number"`
+	YearEstablished    int    `bson:"year_established" json:"year_established" validate:"gte=1900,notfuture"`
 
 	// Contact Information
-	Email   string `bson:"email" json:"email"`
-	Phone   string `bson:"phone" json:"phone"`
-	Website string `bson:"website,omitempty" json:"website,omitempty"`
+	Email   string `bson:"email" json:"email" validate:"required,email"`
+	Phone   string `bson:"phone" json:"phone" validate:"required,format=lb_phone"`
+	Website string `bson:"website,omitempty" json:"website,omitempty" validate:"format=url"`
 
 	// Address
 	Street     string `bson:"street" json:"street"`
-	City       string `bson:"city" json:"city"`
+	City       string `bson:"city" json:"city" validate:"required,max=100"`
 	District   string `bson:"district" json:"district"`
-	Country    string `bson:"country" json:"country"`
+	Country    string `bson:"country" json:"country" validate:"required,max=100"`
 	PostalCode string `bson:"postal_code,omitempty" json:"postal_code,omitempty"`
 
 	// Firm Details
-	NumberOfPartners  int      `bson:"number_of_partners" json:"number_of_partners"`
-	NumberOfEmployees int      `bson:"number_of_employees" json:"number_of_employees"`
+	NumberOfPartners  int      `bson:"number_of_partners" json:"number_of_partners" validate:"gte=1,lte=10000"`
+	NumberOfEmployees int      `bson:"number_of_employees" json:"number_of_employees" validate:"gte=0,lte=100000"`
 	ServicesOffered   []string `bson:"services_offered" json:"services_offered"`
 
 	// Representative Information
-	RepresentativeName  string `bson:"representative_name" json:"representative_name"`
+	RepresentativeName  string `bson:"representative_name" json:"representative_name" validate:"required,max=150"`
 	RepresentativeTitle string `bson:"representative_title" json:"representative_title"`
-	RepresentativeEmail string `bson:"representative_email" json:"representative_email"`
-	RepresentativePhone string `bson:"representative_phone" json:"representative_phone"`
+	RepresentativeEmail string `bson:"representative_email" json:"representative_email" validate:"email"`
+	RepresentativePhone string `bson:"representative_phone" json:"representative_phone" validate:"format=lb_phone"`
 
-	// Documents (file paths or URLs)
+	// Documents (IDs of uploaded ApplicationDocuments)
 	RegistrationDocument string   `bson:"registration_document,omitempty" json:"registration_document,omitempty"`
 	LicenseDocuments     []string `bson:"license_documents,omitempty" json:"license_documents,omitempty"`
 	TaxCertificate       string   `bson:"tax_certificate,omitempty" json:"tax_certificate,omitempty"`
@@ -126,6 +217,134 @@ type FirmApplication struct {
 	ReviewedBy  *primitive.ObjectID `bson:"reviewed_by,omitempty" json:"reviewed_by,omitempty"`
 	ReviewNotes string              `bson:"review_notes,omitempty" json:"review_notes,omitempty"`
 
+	StatusHistory []ApplicationStatusChange `bson:"status_history,omitempty" json:"status_history,omitempty"`
+	MemberID      *primitive.ObjectID       `bson:"member_id,omitempty" json:"member_id,omitempty"` // Member record created on approval
+
+	// Open applications and members this one may duplicate, found at submission
+	PossibleDuplicates []DuplicateMatch `bson:"possible_duplicates,omitempty" json:"possible_duplicates,omitempty"`
+
+	// Normalized contact details and names, for finding duplicates
+	DuplicateKeys *DuplicateKeys `bson:"duplicate_keys,omitempty" json:"-"`
+
+	// Staff assigned to review the application and their recommendations
+	Reviewers       []ReviewerAssignment   `bson:"reviewers,omitempty" json:"reviewers,omitempty"`
+	Recommendations []ReviewRecommendation `bson:"recommendations,omitempty" json:"recommendations,omitempty"`
+
 	CreatedAt time.Time `bson:"created_at" json:"created_at"`
 	UpdatedAt time.Time `bson:"updated_at" json:"updated_at"`
 }
+
+// Helper Methods
+
+// FullName joins the applicant's first, middle and last names
+func (a *IndividualApplication) FullName() string {
+	return joinNonEmpty(" ", a.FirstName, a.MiddleName, a.LastName)
+}
+
+// ToMember maps an approved application to a new individual member record.
+// The LACPA ID and account link are filled in by the repository.
+func (a *IndividualApplication) ToMember(memberType string, startDate time.Time) *IndividualMember {
+	member := &IndividualMember{
+		CreatedAt:  startDate,
+		UpdatedAt:  startDate,
+		FirstName:  a.FirstName,
+		MiddleName: a.MiddleName,
+		LastName:   a.LastName,
+		MemberType: memberType,
+
+		Phone: a.Phone,
+		Email: a.Email,
+		Firm:  a.CurrentEmployer,
+
+		FullAddress: joinNonEmpty(", ", a.Street, a.City, a.District, a.Country),
+		District:    a.District,
+		City:        a.City,
+		Area:        a.Street,
+		Country:     a.Country,
+
+		Title:             a.ProfessionalTitle,
+		Qualifications:    a.Qualifications,
+		YearsOfExperience: a.YearsOfExperience,
+
+		MembershipStartDate: startDate,
+		MembershipStatus:    "Active",
+		RenewalDate:         startDate.AddDate(MembershipTerm, 0, 0),
+		IsActive:            true,
+		DuesStatus:          "Pending",
+		CouncilPosition:     "Non-Council Member",
+	}
+	if member.Phone == "" {
+		member.Phone = a.MobilePhone
+	}
+	member.FullName = member.GetFullName()
+	member.SearchTags = searchTags(member.FullName, memberType, a.ProfessionalTitle)
+	return member
+}
+
+// ToMember maps an approved application to a new firm member record.
+// The LACPA ID and account link are filled in by the repository.
+func (a *FirmApplication) ToMember(startDate time.Time) *FirmMember {
+	return &FirmMember{
+		CreatedAt: startDate,
+		UpdatedAt: startDate,
+		FirmName:  a.FirmName,
+
+		PrimaryPhone: a.Phone,
+		PrimaryEmail: a.Email,
+		Website:      a.Website,
+
+		ContactPersonName:  a.RepresentativeName,
+		ContactPersonTitle: a.RepresentativeTitle,
+		ContactPersonPhone: a.RepresentativePhone,
+		ContactPersonEmail: a.RepresentativeEmail,
+
+		FullAddress: joinNonEmpty(", ", a.Street, a.City, a.District, a.Country),
+		Street:      a.Street,
+		City:        a.City,
+		District:    a.District,
+		PostalCode:  a.PostalCode,
+		Country:     a.Country,
+
+		YearEstablished:   a.YearEstablished,
+		NumberOfPartners:  a.NumberOfPartners,
+		NumberOfEmployees: a.NumberOfEmployees,
+		ServicesOffered:   a.ServicesOffered,
+
+		RegistrationNumber: a.RegistrationNumber,
+
+		MembershipStartDate: startDate,
+		MembershipStatus:    "Active",
+		RenewalDate:         startDate.AddDate(MembershipTerm, 0, 0),
+		IsActive:            true,
+		DuesStatus:          "Pending",
+
+		SearchTags:    searchTags(a.FirmName, a.TradeName),
+		LastUpdatedAt: startDate,
+	}
+}
+
+// joinNonEmpty joins the non-empty parts with sep
+func joinNonEmpty(sep string, parts ...string) string {
+	var kept []string
+	for _, part := range parts {
+		if part = strings.TrimSpace(part); part != "" {
+			kept = append(kept, part)
+		}
+	}
+	return strings.Join(kept, sep)
+}
+
+// searchTags lowercases the words of the given values into unique tags
+func searchTags(values ...string) []string {
+	seen := map[string]bool{}
+	tags := []string{}
+	for _, value := range values {
+		for _, word := range strings.Fields(strings.ToLower(value)) {
+			if !seen[word] {
+				seen[word] = true
+				tags = append(tags, word)
+			}
+		}
+	}
+	return tags
+}
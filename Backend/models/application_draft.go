@@ -0,0 +1,95 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ApplicationDraft is an application being filled in step by step.
+// Data holds the fields saved so far under their application JSON names; the draft is
+// turned into an IndividualApplication or FirmApplication when it is submitted.
+type ApplicationDraft struct {
+	ID              primitive.ObjectID     `bson:"_id,omitempty" json:"id"`
+	ApplicationType ApplicationType        `bson:"application_type" json:"application_type"`
+	Email           string                 `bson:"email" json:"email"`                         // Where the resume link is sent
+	UserID          *primitive.ObjectID    `bson:"user_id,omitempty" json:"user_id,omitempty"` // Account that started it, if signed in
+	TokenHash       string                 `bson:"token_hash" json:"-"`                        // SHA-256 of the resume token
+	Data            map[string]interface{} `bson:"data" json:"data"`
+	CompletedSteps  []string               `bson:"completed_steps" json:"completed_steps"` // Steps whose fields passed validation
+	CreatedAt       time.Time              `bson:"created_at" json:"created_at"`
+	UpdatedAt       time.Time              `bson:"updated_at" json:"updated_at"`
+	ExpiresAt       time.Time              `bson:"expires_at" json:"expires_at"` // Pushed back on every save; the TTL index removes abandoned drafts
+}
+
+// CreateDraftRequest starts a draft application
+type CreateDraftRequest struct {
+	ApplicationType ApplicationType `json:"application_type" form:"application_type" validate:"required,oneof=Individual Firm"`
+	Email           string          `json:"email" form:"email" validate:"required,email"`
+}
+
+// ApplicationStep is one page of the multi-step application form
+type ApplicationStep struct {
+	Name   string   `json:"name"`
+	Title  string   `json:"title"`
+	Fields []string `json:"fields"` // Application JSON fields saved and validated by the step
+}
+
+// applicationSteps lists the steps of each application type in form order.
+// Every field an applicant fills in belongs to exactly one step.
+var applicationSteps = map[ApplicationType][]ApplicationStep{
+	ApplicationTypeIndividual: {
+		{Name: "personal", Title: "Personal Information", Fields: []string{"first_name", "middle_name", "last_name", "date_of_birth", "nationality"}},
+		{Name: "contact", Title: "Contact Information", Fields: []string{"email", "phone", "mobile_phone"}},
+		{Name: "address", Title: "Address", Fields: []string{"street", "city", "district", "country", "postal_code"}},
+		{Name: "professional", Title: "Professional Information", Fields: []string{"professional_title", "qualifications", "years_of_experience", "current_employer"}},
+		{Name: "documents", Title: "Documents", Fields: []string{"cv_document", "certificates_documents", "id_document"}},
+	},
+	ApplicationTypeFirm: {
+		{Name: "firm", Title: "Firm Information", Fields: []string{"firm_name", "trade_name", "registration_number", "year_established"}},
+		{Name: "contact", Title: "Contact Information", Fields: []string{"email", "phone", "website"}},
+		{Name: "address", Title: "Address", Fields: []string{"street", "city", "district", "country", "postal_code"}},
+		{Name: "business", Title: "Business Details", Fields: []string{"number_of_partners", "number_of_employees", "services_offered"}},
+		{Name: "representative", Title: "Representative", Fields: []string{"representative_name", "representative_title", "representative_email", "representative_phone"}},
+		{Name: "documents", Title: "Documents", Fields: []string{"registration_document", "license_documents", "tax_certificate"}},
+	},
+}
+
+// Steps returns the form steps of the application type
+func (t ApplicationType) Steps() []ApplicationStep {
+	return append([]ApplicationStep{}, applicationSteps[t]...)
+}
+
+// Step returns the named form step of the application type
+func (t ApplicationType) Step(name string) (ApplicationStep, bool) {
+	for _, step := range applicationSteps[t] {
+		if step.Name == name {
+			return step, true
+		}
+	}
+	return ApplicationStep{}, false
+}
+
+// HasField reports whether the step saves the application field
+func (s ApplicationStep) HasField(field string) bool {
+	for _, f := range s.Fields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
+// IsComplete reports whether every step of the draft has passed validation
+func (d *ApplicationDraft) IsComplete() bool {
+	completed := map[string]bool{}
+	for _, step := range d.CompletedSteps {
+		completed[step] = true
+	}
+	for _, step := range applicationSteps[d.ApplicationType] {
+		if !completed[step.Name] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,73 @@
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// DuplicateKind is the kind of record an application may duplicate
+type DuplicateKind string
+
+const (
+	DuplicateIndividualApplication DuplicateKind = "individual_application"
+	DuplicateFirmApplication       DuplicateKind = "firm_application"
+	DuplicateIndividualMember      DuplicateKind = "individual_member"
+	DuplicateFirmMember            DuplicateKind = "firm_member"
+)
+
+// DuplicateThreshold is the confidence from which a match is reported to reviewers
+const DuplicateThreshold = 0.5
+
+// Lengths of the name prefixes candidates are found by: a few letters of a word
+// still match names spelled slightly differently
+const (
+	IndividualNamePrefixLength = 3
+	FirmNamePrefixLength       = 4
+)
+
+// DuplicateKeys are the normalized values stored on applications and members so that
+// duplicate candidates are found by indexed equality. The repository sets them on every write.
+type DuplicateKeys struct {
+	Emails        []string `bson:"emails,omitempty"`
+	Phones        []string `bson:"phones,omitempty"` // Last seven national digits
+	Registrations []string `bson:"registrations,omitempty"`
+	Names         []string `bson:"names,omitempty"` // Prefix of each word of the name
+}
+
+// DuplicateMatch is an open application or existing member that an application may duplicate
+type DuplicateMatch struct {
+	Kind       DuplicateKind      `bson:"kind" json:"kind"`
+	ID         primitive.ObjectID `bson:"id" json:"id"`
+	Name       string             `bson:"name" json:"name"`
+	Reference  string             `bson:"reference,omitempty" json:"reference,omitempty"` // Application reference number or LACPA ID
+	Status     string             `bson:"status,omitempty" json:"status,omitempty"`       // Application or membership status
+	Confidence float64            `bson:"confidence" json:"confidence"`                   // 0 to 1
+	Reasons    []string           `bson:"reasons" json:"reasons"`                         // "Same email address", "Similar name (92%)"
+}
+
+// DuplicateProbe selects the candidate records an application is compared with.
+// Values are normalized with the utils.Normalize* helpers; empty values are ignored.
+type DuplicateProbe struct {
+	ExcludeApplicationID primitive.ObjectID // The application itself
+	ExcludeMemberID      primitive.ObjectID // The member record it was approved into
+
+	Emails             []string
+	Phones             []string
+	RegistrationNumber string
+	NamePrefix         string // Start of the first word of the last name or firm name
+}
+
+// DuplicateCandidates are the records that share at least one probe value
+type DuplicateCandidates struct {
+	IndividualApplications []IndividualApplication
+	FirmApplications       []FirmApplication
+	IndividualMembers      []IndividualMember
+	FirmMembers            []FirmMember
+}
+
+// OpenApplicationStatuses are the statuses of applications still awaiting a decision
+var OpenApplicationStatuses = []ApplicationStatus{
+	ApplicationStatusPending,
+	ApplicationStatusUnderReview,
+	ApplicationStatusNeedsInfo,
+	ApplicationStatusResubmitted,
+}
@@ -0,0 +1,57 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ApplicationStatuses lists every application status in review order
+var ApplicationStatuses = []ApplicationStatus{
+	ApplicationStatusPending,
+	ApplicationStatusUnderReview,
+	ApplicationStatusNeedsInfo,
+	ApplicationStatusResubmitted,
+	ApplicationStatusApproved,
+	ApplicationStatusRejected,
+	ApplicationStatusWithdrawn,
+}
+
+// ApplicationSortFields are the values ApplicationFilter.SortBy accepts
+var ApplicationSortFields = []string{"submitted_at", "updated_at", "status", "name", "city", "reference_number"}
+
+// ApplicationFilter selects a page of applications for reviewers
+type ApplicationFilter struct {
+	Type       ApplicationType // Combined list only; empty lists both types
+	Statuses   []ApplicationStatus
+	From       *time.Time // Submitted on or after
+	To         *time.Time // Submitted on or before
+	City       string     // Exact match, ignoring case
+	Search     string     // Full-text search on names and emails
+	SortBy     string     // One of ApplicationSortFields, submitted_at by default
+	Descending bool
+	Limit      int
+	Offset     int
+}
+
+// ApplicationSummary is one row of the combined individual and firm application list
+type ApplicationSummary struct {
+	ID                 primitive.ObjectID `bson:"_id" json:"id"`
+	Type               ApplicationType    `bson:"type" json:"type"`
+	ReferenceNumber    string             `bson:"reference_number" json:"reference_number"`
+	Name               string             `bson:"name" json:"name"` // Applicant or firm name
+	Email              string             `bson:"email" json:"email"`
+	City               string             `bson:"city" json:"city"`
+	Status             ApplicationStatus  `bson:"status" json:"status"`
+	PossibleDuplicates int                `bson:"possible_duplicates" json:"possible_duplicates"` // Number of matches found at submission
+	SubmittedAt        time.Time          `bson:"submitted_at" json:"submitted_at"`
+	UpdatedAt          time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+// ApplicationStatusCounts are the number of applications in each status, for the dashboard.
+// Every status is present, with zero when no application has it.
+type ApplicationStatusCounts struct {
+	Individual map[ApplicationStatus]int64 `json:"individual"`
+	Firm       map[ApplicationStatus]int64 `json:"firm"`
+	Total      map[ApplicationStatus]int64 `json:"total"`
+}
@@ -0,0 +1,66 @@
+package models
+
+// RequirementRequest is the body of a requirement create or update from the CMS
+type RequirementRequest struct {
+	Title           string          `json:"title" form:"title" validate:"required,max=150"`
+	Description     string          `json:"description" form:"description" validate:"max=1000"`
+	Icon            string          `json:"icon" form:"icon" validate:"max=100"` // Font Awesome icon class
+	ApplicationType ApplicationType `json:"application_type" form:"application_type" validate:"required,oneof=Individual Firm"`
+	DocumentKind    DocumentKind    `json:"document_kind" form:"document_kind"` // Empty when no upload is involved
+	IsRequired      bool            `json:"is_required" form:"is_required"`
+}
+
+// Apply copies the request onto a requirement, keeping its ID and order
+func (r *RequirementRequest) Apply(requirement *ApplicationRequirement) {
+	requirement.Title = r.Title
+	requirement.Description = r.Description
+	requirement.Icon = r.Icon
+	requirement.ApplicationType = r.ApplicationType
+	requirement.DocumentKind = r.DocumentKind
+	requirement.IsRequired = r.IsRequired
+}
+
+// RequirementOrderRequest lists the requirements of one application type in their new order
+type RequirementOrderRequest struct {
+	ApplicationType ApplicationType `json:"application_type" form:"application_type"`
+	IDs             []string        `json:"ids" form:"ids"`
+}
+
+// DocumentSlot is an upload field of the apply form, asked for by a requirement
+type DocumentSlot struct {
+	Kind        DocumentKind
+	Field       string // JSON field of the application that takes the document ID
+	Multiple    bool   // The field takes a list of IDs
+	Title       string
+	Description string
+	IsRequired  bool
+}
+
+// DocumentKinds returns the document slots of the application type
+func (t ApplicationType) DocumentKinds() []DocumentKind {
+	return append([]DocumentKind{}, documentKinds[t]...)
+}
+
+// DocumentSlots returns the upload fields the requirements ask for, in requirement order.
+// A slot linked by several requirements is asked for once.
+func DocumentSlots(requirements []ApplicationRequirement) []DocumentSlot {
+	slots := []DocumentSlot{}
+	seen := map[DocumentKind]bool{}
+	for _, requirement := range requirements {
+		if requirement.DocumentKind == "" || seen[requirement.DocumentKind] || !requirement.ApplicationType.AcceptsDocument(requirement.DocumentKind) {
+			continue
+		}
+		seen[requirement.DocumentKind] = true
+
+		field, multiple := DocumentField(requirement.DocumentKind)
+		slots = append(slots, DocumentSlot{
+			Kind:        requirement.DocumentKind,
+			Field:       field,
+			Multiple:    multiple,
+			Title:       requirement.Title,
+			Description: requirement.Description,
+			IsRequired:  requirement.IsRequired,
+		})
+	}
+	return slots
+}
@@ -0,0 +1,199 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ReviewArea is what an assigned reviewer checks
+type ReviewArea string
+
+const (
+	ReviewAreaCredentials ReviewArea = "credentials" // Qualifications, experience and documents
+	ReviewAreaFinance     ReviewArea = "finance"     // Fees and financial standing
+	ReviewAreaCommittee   ReviewArea = "committee"   // Admissions committee vote
+)
+
+// IsValid reports whether a is a known review area
+func (a ReviewArea) IsValid() bool {
+	return a == ReviewAreaCredentials || a == ReviewAreaFinance || a == ReviewAreaCommittee
+}
+
+// ReviewDecision is a reviewer's recommendation on an application
+type ReviewDecision string
+
+const (
+	ReviewDecisionApprove ReviewDecision = "approve"
+	ReviewDecisionReject  ReviewDecision = "reject"
+	ReviewDecisionAbstain ReviewDecision = "abstain"
+)
+
+// ReviewerAssignment is a staff member assigned to review an application
+type ReviewerAssignment struct {
+	UserID     primitive.ObjectID  `bson:"user_id" json:"user_id"`
+	Name       string              `bson:"name" json:"name"`
+	Area       ReviewArea          `bson:"area" json:"area"`
+	AssignedBy *primitive.ObjectID `bson:"assigned_by,omitempty" json:"assigned_by,omitempty"`
+	AssignedAt time.Time           `bson:"assigned_at" json:"assigned_at"`
+}
+
+// ReviewRecommendation is an assigned reviewer's recommendation.
+// A reviewer has at most one, replaced each time they recommend again.
+type ReviewRecommendation struct {
+	ReviewerID primitive.ObjectID `bson:"reviewer_id" json:"reviewer_id"`
+	Area       ReviewArea         `bson:"area" json:"area"`
+	Decision   ReviewDecision     `bson:"decision" json:"decision"`
+	Notes      string             `bson:"notes,omitempty" json:"notes,omitempty"`
+	RecordedAt time.Time          `bson:"recorded_at" json:"recorded_at"`
+}
+
+// AssignReviewerRequest assigns a staff member to an application
+type AssignReviewerRequest struct {
+	UserID string     `json:"user_id" form:"user_id" validate:"required"`
+	Area   ReviewArea `json:"area" form:"area" validate:"required,oneof=credentials finance committee"`
+}
+
+// RecommendationRequest records the current reviewer's recommendation
+type RecommendationRequest struct {
+	Decision ReviewDecision `json:"decision" form:"decision" validate:"required,oneof=approve reject abstain"`
+	Notes    string         `json:"notes" form:"notes" validate:"max=2000"`
+}
+
+// ApplicationComment is an internal reviewer comment on an application; applicants never see them.
+// Replies point to the first comment of their thread.
+type ApplicationComment struct {
+	ID              primitive.ObjectID   `bson:"_id,omitempty" json:"id"`
+	ApplicationType ApplicationType      `bson:"application_type" json:"application_type"`
+	ApplicationID   primitive.ObjectID   `bson:"application_id" json:"application_id"`
+	ParentID        *primitive.ObjectID  `bson:"parent_id,omitempty" json:"parent_id,omitempty"`
+	AuthorID        primitive.ObjectID   `bson:"author_id" json:"author_id"`
+	AuthorName      string               `bson:"author_name" json:"author_name"`
+	Body            string               `bson:"body" json:"body"`
+	Mentions        []primitive.ObjectID `bson:"mentions,omitempty" json:"mentions,omitempty"` // Staff mentioned as @LACPA-ID
+	CreatedAt       time.Time            `bson:"created_at" json:"created_at"`
+}
+
+// CommentRequest posts a comment, or a reply when ParentID is set
+type CommentRequest struct {
+	Body     string `json:"body" form:"body" validate:"required,max=5000"`
+	ParentID string `json:"parent_id" form:"parent_id"`
+}
+
+// CommentThread is a comment with its replies, oldest first
+type CommentThread struct {
+	ApplicationComment
+	Replies []ApplicationComment `json:"replies"`
+}
+
+// ThreadComments groups comments sorted oldest first into threads.
+// Replies whose thread is missing are shown as threads of their own.
+func ThreadComments(comments []ApplicationComment) []CommentThread {
+	threads := []CommentThread{}
+	index := map[primitive.ObjectID]int{}
+	for _, comment := range comments {
+		if comment.ParentID != nil {
+			if i, ok := index[*comment.ParentID]; ok {
+				threads[i].Replies = append(threads[i].Replies, comment)
+				continue
+			}
+		}
+		index[comment.ID] = len(threads)
+		threads = append(threads, CommentThread{ApplicationComment: comment, Replies: []ApplicationComment{}})
+	}
+	return threads
+}
+
+// QuorumRule decides when the recommendations of an application's reviewers allow its approval.
+// Only recommendations of currently assigned reviewers count.
+type QuorumRule struct {
+	ID                 primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	ApplicationType    ApplicationType     `bson:"application_type" json:"application_type"`
+	MinApprovals       int                 `bson:"min_approvals" json:"min_approvals"`               // Approve recommendations needed
+	RequiredAreas      []ReviewArea        `bson:"required_areas" json:"required_areas"`             // Areas that each need an approving reviewer
+	RequireAllAssigned bool                `bson:"require_all_assigned" json:"require_all_assigned"` // Every assigned reviewer must have recommended; abstaining counts
+	AllowRejections    bool                `bson:"allow_rejections" json:"allow_rejections"`         // When false, any reject recommendation blocks approval
+	UpdatedBy          *primitive.ObjectID `bson:"updated_by,omitempty" json:"updated_by,omitempty"`
+	UpdatedAt          time.Time           `bson:"updated_at" json:"updated_at"`
+}
+
+// QuorumRuleRequest sets the quorum rule of an application type
+type QuorumRuleRequest struct {
+	MinApprovals       int          `json:"min_approvals" form:"min_approvals" validate:"gte=1,lte=50"`
+	RequiredAreas      []ReviewArea `json:"required_areas" form:"required_areas"`
+	RequireAllAssigned bool         `json:"require_all_assigned" form:"require_all_assigned"`
+	AllowRejections    bool         `json:"allow_rejections" form:"allow_rejections"`
+}
+
+// DefaultQuorumRule applies to application types without a stored rule:
+// one assigned reviewer recommends approval and nobody recommends rejection
+func DefaultQuorumRule(appType ApplicationType) *QuorumRule {
+	return &QuorumRule{ApplicationType: appType, MinApprovals: 1, RequiredAreas: []ReviewArea{}}
+}
+
+// QuorumStatus is how far an application's recommendations are from its quorum
+type QuorumStatus struct {
+	Met         bool     `json:"met"`
+	Approvals   int      `json:"approvals"`
+	Rejections  int      `json:"rejections"`
+	Abstentions int      `json:"abstentions"`
+	Pending     int      `json:"pending"` // Assigned reviewers who have not recommended yet
+	Unmet       []string `json:"unmet"`   // Conditions of the rule that are not met
+}
+
+// Evaluate checks the recommendations of the assigned reviewers against the rule
+func (r *QuorumRule) Evaluate(reviewers []ReviewerAssignment, recommendations []ReviewRecommendation) QuorumStatus {
+	status := QuorumStatus{Unmet: []string{}}
+
+	assigned := map[primitive.ObjectID]bool{}
+	for _, reviewer := range reviewers {
+		assigned[reviewer.UserID] = true
+	}
+	approvedAreas := map[ReviewArea]bool{}
+	recommended := 0
+	for _, recommendation := range recommendations {
+		if !assigned[recommendation.ReviewerID] {
+			continue
+		}
+		recommended++
+		switch recommendation.Decision {
+		case ReviewDecisionApprove:
+			status.Approvals++
+			approvedAreas[recommendation.Area] = true
+		case ReviewDecisionReject:
+			status.Rejections++
+		case ReviewDecisionAbstain:
+			status.Abstentions++
+		}
+	}
+	status.Pending = len(assigned) - recommended
+
+	if status.Approvals < r.MinApprovals {
+		status.Unmet = append(status.Unmet, fmt.Sprintf("%d of %d approvals", status.Approvals, r.MinApprovals))
+	}
+	for _, area := range r.RequiredAreas {
+		if !approvedAreas[area] {
+			status.Unmet = append(status.Unmet, fmt.Sprintf("no %s reviewer has approved", area))
+		}
+	}
+	if r.RequireAllAssigned && status.Pending > 0 {
+		status.Unmet = append(status.Unmet, fmt.Sprintf("%d assigned reviewer(s) have not recommended", status.Pending))
+	}
+	if !r.AllowRejections && status.Rejections > 0 {
+		status.Unmet = append(status.Unmet, fmt.Sprintf("%d reviewer(s) recommend rejection", status.Rejections))
+	}
+	status.Met = len(status.Unmet) == 0
+	return status
+}
+
+// ApplicationReview is the reviewers' side of an application as shown to staff
+type ApplicationReview struct {
+	ApplicationType ApplicationType        `json:"application_type"`
+	ApplicationID   primitive.ObjectID     `json:"application_id"`
+	Status          ApplicationStatus      `json:"status"`
+	Reviewers       []ReviewerAssignment   `json:"reviewers"`
+	Recommendations []ReviewRecommendation `json:"recommendations"`
+	Rule            *QuorumRule            `json:"rule"`
+	Quorum          QuorumStatus           `json:"quorum"`
+}
@@ -0,0 +1,130 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestQuorumRuleEvaluate(t *testing.T) {
+	credentials, finance, committee, former := primitive.NewObjectID(), primitive.NewObjectID(), primitive.NewObjectID(), primitive.NewObjectID()
+	reviewers := []ReviewerAssignment{
+		{UserID: credentials, Area: ReviewAreaCredentials},
+		{UserID: finance, Area: ReviewAreaFinance},
+		{UserID: committee, Area: ReviewAreaCommittee},
+	}
+	recommend := func(reviewer primitive.ObjectID, area ReviewArea, decision ReviewDecision) ReviewRecommendation {
+		return ReviewRecommendation{ReviewerID: reviewer, Area: area, Decision: decision}
+	}
+
+	tests := []struct {
+		name            string
+		rule            QuorumRule
+		recommendations []ReviewRecommendation
+		want            QuorumStatus
+	}{
+		{
+			name: "no recommendations yet",
+			rule: *DefaultQuorumRule(ApplicationTypeIndividual),
+			want: QuorumStatus{Pending: 3, Unmet: []string{"0 of 1 approvals"}},
+		},
+		{
+			name:            "default rule met by one approval",
+			rule:            *DefaultQuorumRule(ApplicationTypeIndividual),
+			recommendations: []ReviewRecommendation{recommend(credentials, ReviewAreaCredentials, ReviewDecisionApprove)},
+			want:            QuorumStatus{Met: true, Approvals: 1, Pending: 2, Unmet: []string{}},
+		},
+		{
+			name: "unassigned reviewers are ignored",
+			rule: *DefaultQuorumRule(ApplicationTypeIndividual),
+			recommendations: []ReviewRecommendation{
+				recommend(former, ReviewAreaCredentials, ReviewDecisionApprove),
+				recommend(former, ReviewAreaFinance, ReviewDecisionReject),
+			},
+			want: QuorumStatus{Pending: 3, Unmet: []string{"0 of 1 approvals"}},
+		},
+		{
+			name: "any rejection blocks by default",
+			rule: *DefaultQuorumRule(ApplicationTypeIndividual),
+			recommendations: []ReviewRecommendation{
+				recommend(credentials, ReviewAreaCredentials, ReviewDecisionApprove),
+				recommend(finance, ReviewAreaFinance, ReviewDecisionReject),
+			},
+			want: QuorumStatus{Approvals: 1, Rejections: 1, Pending: 1, Unmet: []string{"1 reviewer(s) recommend rejection"}},
+		},
+		{
+			name: "rejections allowed",
+			rule: QuorumRule{MinApprovals: 1, AllowRejections: true},
+			recommendations: []ReviewRecommendation{
+				recommend(credentials, ReviewAreaCredentials, ReviewDecisionApprove),
+				recommend(finance, ReviewAreaFinance, ReviewDecisionReject),
+			},
+			want: QuorumStatus{Met: true, Approvals: 1, Rejections: 1, Pending: 1, Unmet: []string{}},
+		},
+		{
+			name: "required area without an approval",
+			rule: QuorumRule{MinApprovals: 1, RequiredAreas: []ReviewArea{ReviewAreaCredentials, ReviewAreaFinance}},
+			recommendations: []ReviewRecommendation{
+				recommend(credentials, ReviewAreaCredentials, ReviewDecisionApprove),
+				recommend(finance, ReviewAreaFinance, ReviewDecisionAbstain),
+			},
+			want: QuorumStatus{Approvals: 1, Abstentions: 1, Pending: 1, Unmet: []string{"no finance reviewer has approved"}},
+		},
+		{
+			name: "every required area approved",
+			rule: QuorumRule{MinApprovals: 2, RequiredAreas: []ReviewArea{ReviewAreaCredentials, ReviewAreaFinance}},
+			recommendations: []ReviewRecommendation{
+				recommend(credentials, ReviewAreaCredentials, ReviewDecisionApprove),
+				recommend(finance, ReviewAreaFinance, ReviewDecisionApprove),
+			},
+			want: QuorumStatus{Met: true, Approvals: 2, Pending: 1, Unmet: []string{}},
+		},
+		{
+			name: "all assigned reviewers must recommend",
+			rule: QuorumRule{MinApprovals: 1, RequireAllAssigned: true},
+			recommendations: []ReviewRecommendation{
+				recommend(credentials, ReviewAreaCredentials, ReviewDecisionApprove),
+				recommend(finance, ReviewAreaFinance, ReviewDecisionApprove),
+			},
+			want: QuorumStatus{Approvals: 2, Pending: 1, Unmet: []string{"1 assigned reviewer(s) have not recommended"}},
+		},
+		{
+			name: "abstaining counts as having recommended",
+			rule: QuorumRule{MinApprovals: 1, RequireAllAssigned: true},
+			recommendations: []ReviewRecommendation{
+				recommend(credentials, ReviewAreaCredentials, ReviewDecisionApprove),
+				recommend(finance, ReviewAreaFinance, ReviewDecisionAbstain),
+				recommend(committee, ReviewAreaCommittee, ReviewDecisionAbstain),
+			},
+			want: QuorumStatus{Met: true, Approvals: 1, Abstentions: 2, Unmet: []string{}},
+		},
+		{
+			name: "every unmet condition is listed",
+			rule: QuorumRule{MinApprovals: 3, RequiredAreas: []ReviewArea{ReviewAreaCommittee}, RequireAllAssigned: true},
+			recommendations: []ReviewRecommendation{
+				recommend(credentials, ReviewAreaCredentials, ReviewDecisionApprove),
+				recommend(committee, ReviewAreaCommittee, ReviewDecisionReject),
+			},
+			want: QuorumStatus{
+				Approvals:  1,
+				Rejections: 1,
+				Pending:    1,
+				Unmet: []string{
+					"1 of 3 approvals",
+					"no committee reviewer has approved",
+					"1 assigned reviewer(s) have not recommended",
+					"1 reviewer(s) recommend rejection",
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.rule.Evaluate(reviewers, tt.recommendations); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("Evaluate = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
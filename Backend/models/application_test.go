@@ -0,0 +1,20 @@
+package models
+
+import "testing"
+
+func TestResubmittedIsApplicantOnly(t *testing.T) {
+	if ApplicationStatusNeedsInfo.CanTransitionTo(ApplicationStatusResubmitted) {
+		t.Fatal("staff may move Needs Info to Resubmitted")
+	}
+	if !ApplicationStatusNeedsInfo.CanApplicantTransitionTo(ApplicationStatusResubmitted) {
+		t.Fatal("the applicant may not resubmit an application that needs information")
+	}
+	if ApplicationStatusNeedsInfo.IsFinal() {
+		t.Fatal("Needs Info is reported final")
+	}
+	for _, status := range []ApplicationStatus{ApplicationStatusApproved, ApplicationStatusRejected, ApplicationStatusWithdrawn} {
+		if !status.IsFinal() {
+			t.Fatalf("%s is not reported final", status)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// TrackApplicationRequest asks for a tracking code for an application
+type TrackApplicationRequest struct {
+	ReferenceNumber string `json:"reference_number" form:"reference_number" validate:"required"`
+	Email           string `json:"email" form:"email" validate:"required,email"`
+}
+
+// VerifyTrackingRequest exchanges the tracking code for access to the application
+type VerifyTrackingRequest struct {
+	ReferenceNumber string `json:"reference_number" form:"reference_number" validate:"required"`
+	Email           string `json:"email" form:"email" validate:"required,email"`
+	OTP             string `json:"otp" form:"otp" validate:"required,len=6"`
+}
+
+// ApplicantActionRequest carries the applicant's note when resubmitting or withdrawing
+type ApplicantActionRequest struct {
+	Notes string `json:"notes" form:"notes" validate:"max=2000"`
+}
+
+// TrackedApplication is the applicant's view of an application: its progress and what
+// the reviewers asked for, without the application data or reviewer identities
+type TrackedApplication struct {
+	ID              primitive.ObjectID        `json:"id"`
+	Type            ApplicationType           `json:"type"`
+	ReferenceNumber string                    `json:"reference_number"`
+	Name            string                    `json:"name"` // Applicant or firm name
+	Status          ApplicationStatus         `json:"status"`
+	ReviewerRequest string                    `json:"reviewer_request,omitempty"` // What the reviewers need, while the status is Needs Info
+	History         []ApplicationStatusChange `json:"history"`
+	Documents       []ApplicationDocument     `json:"documents"`
+	DocumentKinds   []DocumentKind            `json:"document_kinds"`    // Slots the applicant can upload to
+	Invoice         *Invoice                  `json:"invoice,omitempty"` // Application fee, once invoiced
+	SubmittedAt     time.Time                 `json:"submitted_at"`
+	UpdatedAt       time.Time                 `json:"updated_at"`
+
+	CanUpload   bool `json:"can_upload"`
+	CanResubmit bool `json:"can_resubmit"`
+	CanWithdraw bool `json:"can_withdraw"`
+	CanPay      bool `json:"can_pay"` // The invoice is unpaid and online payment is enabled
+}
+
+// Tracked builds the applicant's view of an individual application
+func (a *IndividualApplication) Tracked(documents []ApplicationDocument) *TrackedApplication {
+	return newTrackedApplication(ApplicationTypeIndividual, a.ID, a.ReferenceNumber, a.FullName(),
+		a.Status, a.ReviewNotes, a.StatusHistory, documents, a.SubmittedAt, a.UpdatedAt)
+}
+
+// Tracked builds the applicant's view of a firm application
+func (a *FirmApplication) Tracked(documents []ApplicationDocument) *TrackedApplication {
+	return newTrackedApplication(ApplicationTypeFirm, a.ID, a.ReferenceNumber, a.FirmName,
+		a.Status, a.ReviewNotes, a.StatusHistory, documents, a.SubmittedAt, a.UpdatedAt)
+}
+
+func newTrackedApplication(appType ApplicationType, id primitive.ObjectID, reference, name string, status ApplicationStatus, reviewNotes string, history []ApplicationStatusChange, documents []ApplicationDocument, submittedAt, updatedAt time.Time) *TrackedApplication {
+	tracked := &TrackedApplication{
+		ID:              id,
+		Type:            appType,
+		ReferenceNumber: reference,
+		Name:            name,
+		Status:          status,
+		History:         make([]ApplicationStatusChange, 0, len(history)),
+		Documents:       documents,
+		DocumentKinds:   append([]DocumentKind{}, documentKinds[appType]...),
+		SubmittedAt:     submittedAt,
+		UpdatedAt:       updatedAt,
+		CanUpload:       status == ApplicationStatusNeedsInfo,
+		CanResubmit:     status.CanApplicantTransitionTo(ApplicationStatusResubmitted),
+		CanWithdraw:     status.CanApplicantTransitionTo(ApplicationStatusWithdrawn),
+	}
+	if status == ApplicationStatusNeedsInfo {
+		tracked.ReviewerRequest = reviewNotes
+	}
+	if tracked.Documents == nil {
+		tracked.Documents = []ApplicationDocument{}
+	}
+
+	// Reviewer notes are internal unless they are addressed to the applicant
+	for _, change := range history {
+		entry := ApplicationStatusChange{From: change.From, To: change.To, ChangedAt: change.ChangedAt}
+		if change.ChangedBy == nil || change.To == ApplicationStatusNeedsInfo || change.To == ApplicationStatusRejected {
+			entry.Notes = change.Notes
+		}
+		tracked.History = append(tracked.History, entry)
+	}
+	return tracked
+}
+
+// RouteType is the application type as used in tracking URLs
+func (t *TrackedApplication) RouteType() string {
+	return strings.ToLower(string(t.Type))
+}
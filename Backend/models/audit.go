@@ -0,0 +1,104 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Audited actions
+const (
+	AuditAdminCreated           = "admin.created"
+	AuditUserRoleChanged        = "user.role_changed"
+	AuditUserDeactivated        = "user.deactivated"
+	AuditUserActivated          = "user.activated"
+	AuditUserUnlocked           = "user.unlocked"
+	AuditUserTwoFactorReset     = "user.two_factor_reset"
+	AuditUserNameChanged        = "user.name_changed"
+	AuditUserDeleted            = "user.deleted"
+	AuditRoleCreated            = "role.created"
+	AuditRoleUpdated            = "role.updated"
+	AuditRoleDeleted            = "role.deleted"
+	AuditApplicationStatus      = "application.status_changed"
+	AuditMemberCreated          = "member.created"
+	AuditReviewerAssigned       = "application.reviewer_assigned"
+	AuditReviewerRemoved        = "application.reviewer_removed"
+	AuditRecommendation         = "application.recommendation"
+	AuditQuorumRuleUpdated      = "quorum_rule.updated"
+	AuditRequirementCreated     = "requirement.created"
+	AuditRequirementUpdated     = "requirement.updated"
+	AuditRequirementDeleted     = "requirement.deleted"
+	AuditRequirementsReordered  = "requirement.reordered"
+	AuditFeeUpdated             = "fee.updated"
+	AuditInvoiceIssued          = "invoice.issued"
+	AuditInvoicePaid            = "invoice.paid"
+	AuditCouncilCreated         = "council.created"
+	AuditCouncilUpdated         = "council.updated"
+	AuditCouncilDeactivated     = "council.deactivated"
+	AuditCouncilPositionAssign  = "council.position_assigned"
+	AuditCouncilPositionUpdate  = "council.position_updated"
+	AuditCouncilPositionRemoved = "council.position_removed"
+	AuditLogin                  = "auth.login"
+	AuditLoginFailed            = "auth.login_failed"
+	AuditLogout                 = "auth.logout"
+	AuditLogoutAll              = "auth.logout_all"
+	AuditAccountLocked          = "auth.account_locked"
+	AuditPasswordChanged        = "auth.password_changed"
+	AuditPasswordReset          = "auth.password_reset"
+	AuditEmailChanged           = "auth.email_changed"
+)
+
+// Audit target types
+const (
+	AuditTargetUser             = "user"
+	AuditTargetRole             = "role"
+	AuditTargetIndividualApp    = "individual_application"
+	AuditTargetFirmApp          = "firm_application"
+	AuditTargetIndividualMember = "individual_member"
+	AuditTargetFirmMember       = "firm_member"
+	AuditTargetRequirement      = "application_requirement"
+	AuditTargetQuorumRule       = "quorum_rule"
+	AuditTargetFee              = "application_fee"
+	AuditTargetInvoice          = "invoice"
+	AuditTargetCouncil          = "council"
+	AuditTargetCouncilPosition  = "council_position"
+)
+
+// AuditActor identifies who performed an audited action, copied from the access token
+type AuditActor struct {
+	UserID  string `json:"user_id,omitempty" bson:"user_id,omitempty"`
+	LACPAID string `json:"lacpa_id,omitempty" bson:"lacpa_id,omitempty"`
+	Email   string `json:"email,omitempty" bson:"email,omitempty"`
+	Role    string `json:"role,omitempty" bson:"role,omitempty"`
+}
+
+// AuditChange is the value of one field before and after an action
+type AuditChange struct {
+	Before interface{} `json:"before" bson:"before"`
+	After  interface{} `json:"after" bson:"after"`
+}
+
+// AuditEvent is one entry of the append-only audit_events collection
+type AuditEvent struct {
+	ID         primitive.ObjectID     `json:"id" bson:"_id,omitempty"`
+	Actor      AuditActor             `json:"actor" bson:"actor"`
+	Action     string                 `json:"action" bson:"action"`
+	TargetType string                 `json:"target_type" bson:"target_type"`
+	TargetID   string                 `json:"target_id" bson:"target_id"`
+	Changes    map[string]AuditChange `json:"changes,omitempty" bson:"changes,omitempty"` // Only the fields that changed
+	IPAddress  string                 `json:"ip_address" bson:"ip_address"`
+	UserAgent  string                 `json:"user_agent" bson:"user_agent"`
+	CreatedAt  time.Time              `json:"created_at" bson:"created_at"`
+}
+
+// AuditFilter selects audit events; empty fields match everything
+type AuditFilter struct {
+	Actor      string // Matches the actor's user ID, LACPA ID or email
+	Action     string
+	TargetType string
+	TargetID   string
+	From       *time.Time
+	To         *time.Time
+	Limit      int
+	Offset     int
+}
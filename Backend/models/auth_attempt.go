@@ -0,0 +1,84 @@
+package models
+
+import (
+	"math"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// AuthAttempt tracks failed authentication attempts for one key,
+// such as an account or a client IP, for a given action
+type AuthAttempt struct {
+	ID            primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	Key           string             `json:"key" bson:"key"`           // e.g. "login:account:LACPA-2025-00001", "otp:ip:10.0.0.1"
+	Failures      int                `json:"failures" bson:"failures"` // Consecutive failures inside the window
+	LastFailureAt time.Time          `json:"last_failure_at" bson:"last_failure_at"`
+	LockedUntil   *time.Time         `json:"locked_until,omitempty" bson:"locked_until,omitempty"`
+	ExpiresAt     time.Time          `json:"expires_at" bson:"expires_at"` // TTL index forgets old failures
+}
+
+// LockoutPolicy configures backoff and lockout for one kind of attempt key
+type LockoutPolicy struct {
+	MaxFailures      int           // Failures before a temporary lockout
+	BackoffThreshold int           // Failures before exponential backoff kicks in
+	BaseDelay        time.Duration // Delay after the first failure past the threshold
+	MaxDelay         time.Duration // Upper bound for the backoff delay
+	LockoutDuration  time.Duration // How long a lockout lasts
+	Window           time.Duration // How long failures are remembered
+}
+
+// BackoffDelay returns how long the caller must wait after the last failure
+func (p LockoutPolicy) BackoffDelay(failures int) time.Duration {
+	if failures < p.BackoffThreshold {
+		return 0
+	}
+	delay := time.Duration(float64(p.BaseDelay) * math.Pow(2, float64(failures-p.BackoffThreshold)))
+	if delay > p.MaxDelay || delay <= 0 {
+		return p.MaxDelay
+	}
+	return delay
+}
+
+// RetryAfter returns how long until the next attempt is allowed, or zero
+func (a *AuthAttempt) RetryAfter(policy LockoutPolicy, now time.Time) time.Duration {
+	if a == nil {
+		return 0
+	}
+	if a.LockedUntil != nil && now.Before(*a.LockedUntil) {
+		return a.LockedUntil.Sub(now)
+	}
+	if a.LockedUntil != nil {
+		// Lockout elapsed; the counter is reset on the next failure
+		return 0
+	}
+	nextAllowed := a.LastFailureAt.Add(policy.BackoffDelay(a.Failures))
+	if now.Before(nextAllowed) {
+		return nextAllowed.Sub(now)
+	}
+	return 0
+}
+
+// IsLocked checks if the key is currently locked out
+func (a *AuthAttempt) IsLocked(now time.Time) bool {
+	return a != nil && a.LockedUntil != nil && now.Before(*a.LockedUntil)
+}
+
+// SecurityEvent is an entry in a user's security history
+type SecurityEvent struct {
+	Type        string    `json:"type" bson:"type"`
+	Description string    `json:"description" bson:"description"`
+	IPAddress   string    `json:"ip_address,omitempty" bson:"ip_address,omitempty"`
+	Actor       string    `json:"actor,omitempty" bson:"actor,omitempty"` // LACPA ID of the admin who triggered the event, if any
+	At          time.Time `json:"at" bson:"at"`
+}
+
+// Security event types
+const (
+	SecurityEventAccountLocked   = "account_locked"
+	SecurityEventAccountUnlocked = "account_unlocked"
+	SecurityEventOTPInvalidated  = "otp_invalidated"
+	SecurityEventPasswordChanged = "password_changed"
+	SecurityEventEmailChanged    = "email_changed"
+	SecurityEventSelfDeactivated = "self_deactivated"
+)
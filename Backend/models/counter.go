@@ -0,0 +1,68 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Counter is an atomic sequence stored in the counters collection
+type Counter struct {
+	Key   string `json:"key" bson:"_id"`     // e.g. "lacpa_id:individual:2025"
+	Value int64  `json:"value" bson:"value"` // Last value handed out
+}
+
+// Entity types LACPA IDs are allocated for, each with its own yearly sequence
+const (
+	LACPAIDIndividual = "individual"
+	LACPAIDFirm       = "firm"
+	LACPAIDAdmin      = "admin"
+)
+
+// lacpaIDPrefixes maps entity types to the prefix of their IDs
+var lacpaIDPrefixes = map[string]string{
+	LACPAIDIndividual: "LACPA",
+	LACPAIDFirm:       "LACPA-F",
+	LACPAIDAdmin:      "LACPA-A",
+}
+
+// LACPAIDCounterKey returns the counter key for an entity type and year
+func LACPAIDCounterKey(entity string, year int) string {
+	return fmt.Sprintf("lacpa_id:%s:%d", entity, year)
+}
+
+// FormatLACPAID formats a sequence number as a LACPA ID,
+// e.g. LACPA-2025-00042, LACPA-F-2025-00007 or LACPA-A-2025-00001
+func FormatLACPAID(entity string, year int, sequence int64) string {
+	prefix, ok := lacpaIDPrefixes[entity]
+	if !ok {
+		prefix = lacpaIDPrefixes[LACPAIDIndividual]
+	}
+	return fmt.Sprintf("%s-%d-%05d", prefix, year, sequence)
+}
+
+// applicationReferencePrefixes maps application types to the prefix of their reference numbers
+var applicationReferencePrefixes = map[ApplicationType]string{
+	ApplicationTypeIndividual: "APP-I",
+	ApplicationTypeFirm:       "APP-F",
+}
+
+// ApplicationReferenceCounterKey returns the counter key for an application type and year
+func ApplicationReferenceCounterKey(appType ApplicationType, year int) string {
+	return fmt.Sprintf("application_ref:%s:%d", strings.ToLower(string(appType)), year)
+}
+
+// FormatApplicationReference formats a sequence number as an application reference number,
+// e.g. APP-I-2025-00042 or APP-F-2025-00007
+func FormatApplicationReference(appType ApplicationType, year int, sequence int64) string {
+	return fmt.Sprintf("%s-%d-%05d", applicationReferencePrefixes[appType], year, sequence)
+}
+
+// ApplicationTypeOfReference returns the application type a reference number was issued for
+func ApplicationTypeOfReference(reference string) (ApplicationType, bool) {
+	for appType, prefix := range applicationReferencePrefixes {
+		if strings.HasPrefix(reference, prefix+"-") {
+			return appType, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,113 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// DocumentKind identifies which document slot of an application a file fills
+type DocumentKind string
+
+const (
+	// Individual applications
+	DocumentKindCV          DocumentKind = "cv"
+	DocumentKindCertificate DocumentKind = "certificate"
+	DocumentKindID          DocumentKind = "id"
+
+	// Firm applications
+	DocumentKindRegistration   DocumentKind = "registration"
+	DocumentKindLicense        DocumentKind = "license"
+	DocumentKindTaxCertificate DocumentKind = "tax_certificate"
+)
+
+// documentKinds lists the kinds each application type accepts
+var documentKinds = map[ApplicationType][]DocumentKind{
+	ApplicationTypeIndividual: {DocumentKindCV, DocumentKindCertificate, DocumentKindID},
+	ApplicationTypeFirm:       {DocumentKindRegistration, DocumentKindLicense, DocumentKindTaxCertificate},
+}
+
+// AcceptsDocument reports whether an application type has a slot for the document kind
+func (t ApplicationType) AcceptsDocument(kind DocumentKind) bool {
+	for _, accepted := range documentKinds[t] {
+		if accepted == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// ApplicationDocument is the metadata of an uploaded application file.
+// The file itself lives in the blob store under StorageKey and is never served
+// from a public static path.
+type ApplicationDocument struct {
+	ID              primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	ApplicationType ApplicationType     `bson:"application_type" json:"application_type"`
+	Kind            DocumentKind        `bson:"kind" json:"kind"`
+	FileName        string              `bson:"file_name" json:"file_name"`       // Name sent by the browser, for display only
+	ContentType     string              `bson:"content_type" json:"content_type"` // Detected from the file's magic bytes
+	Size            int64               `bson:"size" json:"size"`
+	SHA256          string              `bson:"sha256" json:"sha256"`
+	StorageKey      string              `bson:"storage_key" json:"-"`
+	UploadedBy      *primitive.ObjectID `bson:"uploaded_by,omitempty" json:"uploaded_by,omitempty"` // Empty for anonymous applicants
+	UploaderIP      string              `bson:"uploader_ip" json:"-"`
+	ApplicationID   *primitive.ObjectID `bson:"application_id,omitempty" json:"application_id,omitempty"` // Set once the application is submitted
+	CreatedAt       time.Time           `bson:"created_at" json:"created_at"`
+}
+
+// DocumentReference is a document an application points at, with the slot it fills
+type DocumentReference struct {
+	ID   string
+	Kind DocumentKind
+}
+
+// DocumentReferences lists the uploaded documents an individual application refers to
+func (a *IndividualApplication) DocumentReferences() []DocumentReference {
+	refs := []DocumentReference{}
+	if a.CVDocument != "" {
+		refs = append(refs, DocumentReference{ID: a.CVDocument, Kind: DocumentKindCV})
+	}
+	for _, id := range a.CertificatesDocuments {
+		refs = append(refs, DocumentReference{ID: id, Kind: DocumentKindCertificate})
+	}
+	if a.IDDocument != "" {
+		refs = append(refs, DocumentReference{ID: a.IDDocument, Kind: DocumentKindID})
+	}
+	return refs
+}
+
+// DocumentReferences lists the uploaded documents a firm application refers to
+func (a *FirmApplication) DocumentReferences() []DocumentReference {
+	refs := []DocumentReference{}
+	if a.RegistrationDocument != "" {
+		refs = append(refs, DocumentReference{ID: a.RegistrationDocument, Kind: DocumentKindRegistration})
+	}
+	for _, id := range a.LicenseDocuments {
+		refs = append(refs, DocumentReference{ID: id, Kind: DocumentKindLicense})
+	}
+	if a.TaxCertificate != "" {
+		refs = append(refs, DocumentReference{ID: a.TaxCertificate, Kind: DocumentKindTaxCertificate})
+	}
+	return refs
+}
+
+// documentFields maps each document kind to the application field holding it,
+// and whether that field is a list
+var documentFields = map[DocumentKind]struct {
+	field    string
+	multiple bool
+}{
+	DocumentKindCV:             {"cv_document", false},
+	DocumentKindCertificate:    {"certificates_documents", true},
+	DocumentKindID:             {"id_document", false},
+	DocumentKindRegistration:   {"registration_document", false},
+	DocumentKindLicense:        {"license_documents", true},
+	DocumentKindTaxCertificate: {"tax_certificate", false},
+}
+
+// DocumentField returns the application field a document kind is stored in.
+// Single slots are replaced by a new upload, list slots get it appended.
+func DocumentField(kind DocumentKind) (field string, multiple bool) {
+	slot := documentFields[kind]
+	return slot.field, slot.multiple
+}
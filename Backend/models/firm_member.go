@@ -13,9 +13,10 @@ type FirmMember struct {
 	UpdatedAt time.Time          `json:"updated_at" bson:"updated_at"`
 
 	// Basic Information
-	LacpaID  string `json:"lacpa_id" bson:"lacpa_id"`   // Unique LACPA Firm ID: "F-1234"
-	FirmName string `json:"firm_name" bson:"firm_name"` // "Deloitte Lebanon"
-	LogoURL  string `json:"logo_url" bson:"logo_url"`   // Path to firm logo image
+	LacpaID  string              `json:"lacpa_id" bson:"lacpa_id"`                   // Unique LACPA Firm ID: "F-1234"
+	UserID   *primitive.ObjectID `json:"user_id,omitempty" bson:"user_id,omitempty"` // Account that claimed this record
+	FirmName string              `json:"firm_name" bson:"firm_name"`                 // "Deloitte Lebanon"
+	LogoURL  string              `json:"logo_url" bson:"logo_url"`                   // Path to firm logo image
 
 	// Classification
 	FirmType   string `json:"firm_type" bson:"firm_type"`     // "Audit Firm", "Accounting Firm", "Consultancy"
@@ -105,7 +106,8 @@ type FirmMember struct {
 	ShowEmployeeCount bool `json:"show_employee_count" bson:"show_employee_count"` // Show employee count
 
 	// Search Optimization
-	SearchTags []string `json:"search_tags" bson:"search_tags"` // ["deloitte", "audit", "big4"]
+	SearchTags    []string       `json:"search_tags" bson:"search_tags"`    // ["deloitte", "audit", "big4"]
+	DuplicateKeys *DuplicateKeys `json:"-" bson:"duplicate_keys,omitempty"` // Normalized contact details, for finding duplicate applications
 
 	// Additional Metadata
 	ProfileViews      int       `json:"profile_views" bson:"profile_views"`           // Number of profile views
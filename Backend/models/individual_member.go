@@ -14,11 +14,12 @@ type IndividualMember struct {
 	UpdatedAt time.Time          `json:"updated_at" bson:"updated_at"`
 
 	// Basic Information (State 0 - Card Front)
-	LacpaID    string `json:"lacpa_id" bson:"lacpa_id"`       // Unique LACPA ID: "3666"
-	FirstName  string `json:"first_name" bson:"first_name"`   // "Boushra"
-	MiddleName string `json:"middle_name" bson:"middle_name"` // "El"
-	LastName   string `json:"last_name" bson:"last_name"`     // "Obeid"
-	FullName   string `json:"full_name" bson:"full_name"`     // Computed or stored: "Boushra El Obeid"
+	LacpaID    string              `json:"lacpa_id" bson:"lacpa_id"`                   // Unique LACPA ID: "3666"
+	UserID     *primitive.ObjectID `json:"user_id,omitempty" bson:"user_id,omitempty"` // Account that claimed this record
+	FirstName  string              `json:"first_name" bson:"first_name"`               // "Boushra"
+	MiddleName string              `json:"middle_name" bson:"middle_name"`             // "El"
+	LastName   string              `json:"last_name" bson:"last_name"`                 // "Obeid"
+	FullName   string              `json:"full_name" bson:"full_name"`                 // Computed or stored: "Boushra El Obeid"
 
 	AvatarURL  string `json:"avatar_url" bson:"avatar_url"`   // Path to profile image
 	MemberType string `json:"member_type" bson:"member_type"` // "Apprentices", "Practicing", "Non-Practicing", "Retired"
@@ -46,6 +47,7 @@ type IndividualMember struct {
 	// Professional Information
 	Title             string   `json:"title" bson:"title"`                             // "CPA", "Senior Auditor"
 	Position          string   `json:"position" bson:"position"`                       // Current job position
+	Qualifications    []string `json:"qualifications" bson:"qualifications"`           // ["CPA", "BSc Accounting"]
 	Specializations   []string `json:"specializations" bson:"specializations"`         // ["Auditing", "Tax"]
 	Services          []string `json:"services" bson:"services"`                       // Services offered
 	YearsOfExperience int      `json:"years_of_experience" bson:"years_of_experience"` // Years in profession
@@ -78,7 +80,8 @@ type IndividualMember struct {
 	ShowAddress  bool `json:"show_address" bson:"show_address"`   // Show address publicly
 
 	// Search Optimization
-	SearchTags []string `json:"search_tags" bson:"search_tags"` // ["boushra", "obeid", "apprentice", "auditor"]
+	SearchTags    []string       `json:"search_tags" bson:"search_tags"`    // ["boushra", "obeid", "apprentice", "auditor"]
+	DuplicateKeys *DuplicateKeys `json:"-" bson:"duplicate_keys,omitempty"` // Normalized contact details, for finding duplicate applications
 
 	// Additional Metadata
 	LastLoginAt      time.Time `json:"last_login_at" bson:"last_login_at"`         // Last login timestamp
@@ -88,6 +91,23 @@ type IndividualMember struct {
 	CommitteesServed []string  `json:"committees_served" bson:"committees_served"` // ["Audit", "Ethics"]
 }
 
+// Individual member types
+const (
+	MemberTypeApprentices   = "Apprentices"
+	MemberTypePracticing    = "Practicing"
+	MemberTypeNonPracticing = "Non-Practicing"
+	MemberTypeRetired       = "Retired"
+)
+
+// IsValidIndividualMemberType reports whether memberType is a known individual member type
+func IsValidIndividualMemberType(memberType string) bool {
+	switch memberType {
+	case MemberTypeApprentices, MemberTypePracticing, MemberTypeNonPracticing, MemberTypeRetired:
+		return true
+	}
+	return false
+}
+
 // MemberMetrics represents aggregate statistics for member filtering
 type MemberMetrics struct {
 	TotalMembers       int `json:"total_members"`        // Total count
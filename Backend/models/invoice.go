@@ -0,0 +1,140 @@
+package models
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Currency is the currency an application fee is charged in
+type Currency string
+
+const (
+	CurrencyLBP Currency = "LBP"
+	CurrencyUSD Currency = "USD"
+)
+
+// IsValid reports whether c is a supported currency
+func (c Currency) IsValid() bool {
+	return c == CurrencyLBP || c == CurrencyUSD
+}
+
+// ApplicationFee is the fee charged when an application of a type is submitted.
+// No invoice is issued while the fee of a type is missing or inactive.
+type ApplicationFee struct {
+	ID              primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	ApplicationType ApplicationType     `bson:"application_type" json:"application_type"`
+	Amount          float64             `bson:"amount" json:"amount"`
+	Currency        Currency            `bson:"currency" json:"currency"`
+	DueDays         int                 `bson:"due_days" json:"due_days"` // Days between submission and the invoice due date
+	IsActive        bool                `bson:"is_active" json:"is_active"`
+	UpdatedBy       *primitive.ObjectID `bson:"updated_by,omitempty" json:"updated_by,omitempty"`
+	UpdatedAt       time.Time           `bson:"updated_at" json:"updated_at"`
+}
+
+// Charged reports whether applications of the fee's type are billed
+func (f *ApplicationFee) Charged() bool {
+	return f.IsActive && f.Amount > 0
+}
+
+// FeeRequest sets the fee of an application type
+type FeeRequest struct {
+	Amount   float64  `json:"amount" form:"amount" validate:"gte=0"`
+	Currency Currency `json:"currency" form:"currency" validate:"required,oneof=LBP USD"`
+	DueDays  int      `json:"due_days" form:"due_days" validate:"gte=1,lte=365"`
+	IsActive bool     `json:"is_active" form:"is_active"`
+}
+
+// InvoiceStatus is the payment state of an invoice
+type InvoiceStatus string
+
+const (
+	InvoiceStatusUnpaid InvoiceStatus = "unpaid"
+	InvoiceStatusPaid   InvoiceStatus = "paid"
+)
+
+// Invoice is the application fee billed for one application
+type Invoice struct {
+	ID              primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Number          string             `bson:"number" json:"number"` // Sequential per year, e.g. INV-2025-00042
+	ApplicationType ApplicationType    `bson:"application_type" json:"application_type"`
+	ApplicationID   primitive.ObjectID `bson:"application_id" json:"application_id"`
+	ReferenceNumber string             `bson:"reference_number" json:"reference_number"` // Of the application
+	BilledTo        string             `bson:"billed_to" json:"billed_to"`               // Applicant or firm name
+	Email           string             `bson:"email" json:"email"`
+	Amount          float64            `bson:"amount" json:"amount"`
+	Currency        Currency           `bson:"currency" json:"currency"`
+	Status          InvoiceStatus      `bson:"status" json:"status"`
+	DueDate         time.Time          `bson:"due_date" json:"due_date"`
+	IssuedAt        time.Time          `bson:"issued_at" json:"issued_at"`
+
+	// Checkout started with a payment provider, matched against its notifications
+	PaymentProvider  string `bson:"payment_provider,omitempty" json:"payment_provider,omitempty"`
+	PaymentSessionID string `bson:"payment_session_id,omitempty" json:"-"`
+
+	Payment   *InvoicePayment `bson:"payment,omitempty" json:"payment,omitempty"`
+	PaidAt    *time.Time      `bson:"paid_at,omitempty" json:"paid_at,omitempty"`
+	UpdatedAt time.Time       `bson:"updated_at" json:"updated_at"`
+}
+
+// IsOverdue reports whether the invoice is unpaid after its due date
+func (i *Invoice) IsOverdue(now time.Time) bool {
+	return i.Status == InvoiceStatusUnpaid && now.After(i.DueDate)
+}
+
+// InvoicePayment records how an invoice was paid
+type InvoicePayment struct {
+	Method     string              `bson:"method" json:"method"`                           // "manual" or the payment provider name
+	Reference  string              `bson:"reference,omitempty" json:"reference,omitempty"` // Receipt, transfer or provider transaction number
+	Amount     float64             `bson:"amount" json:"amount"`
+	Currency   Currency            `bson:"currency" json:"currency"`
+	Notes      string              `bson:"notes,omitempty" json:"notes,omitempty"`
+	RecordedBy *primitive.ObjectID `bson:"recorded_by,omitempty" json:"recorded_by,omitempty"` // Finance staff; empty for provider payments
+	PaidAt     time.Time           `bson:"paid_at" json:"paid_at"`
+}
+
+// PaymentMethodManual marks payments recorded by finance staff
+const PaymentMethodManual = "manual"
+
+// RecordPaymentRequest is a payment received outside the system, entered by finance staff
+type RecordPaymentRequest struct {
+	Amount    float64    `json:"amount" form:"amount" validate:"gte=0"`
+	Currency  Currency   `json:"currency" form:"currency" validate:"required,oneof=LBP USD"`
+	Reference string     `json:"reference" form:"reference" validate:"max=100"`
+	Notes     string     `json:"notes" form:"notes" validate:"max=1000"`
+	PaidAt    *time.Time `json:"paid_at" form:"paid_at"` // Defaults to now; cannot be in the future
+}
+
+// IssueInvoiceRequest issues the invoice of an application that has none,
+// e.g. one submitted before its fee was set
+type IssueInvoiceRequest struct {
+	ApplicationType ApplicationType `json:"application_type" form:"application_type" validate:"required,oneof=Individual Firm"`
+	ApplicationID   string          `json:"application_id" form:"application_id" validate:"required"`
+}
+
+// InvoiceFilter selects a page of invoices for finance staff
+type InvoiceFilter struct {
+	Status          InvoiceStatus
+	ApplicationType ApplicationType
+	Overdue         bool // Unpaid and past the due date
+	Search          string
+	Limit           int
+	Offset          int
+}
+
+// InvoiceCounterKey returns the counter key of the invoice numbers of a year
+func InvoiceCounterKey(year int) string {
+	return fmt.Sprintf("invoice:%d", year)
+}
+
+// FormatInvoiceNumber formats a sequence number as an invoice number, e.g. INV-2025-00042
+func FormatInvoiceNumber(year int, sequence int64) string {
+	return fmt.Sprintf("INV-%d-%05d", year, sequence)
+}
+
+// RoundAmount rounds an amount to cents
+func RoundAmount(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
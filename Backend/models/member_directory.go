@@ -0,0 +1,213 @@
+package models
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+// MemberDirectoryFilter selects a page of the public member directory.
+// Facet values are matched exactly; empty means any value.
+type MemberDirectoryFilter struct {
+	Search         string // Full-text search on names, firm, specializations and city
+	MemberType     string // Individual member type, or firm type for firms
+	Governorate    string
+	District       string
+	Specialization string
+	DuesStatus     string
+	FirmSize       string // Firms only
+	Limit          int
+	Offset         int
+}
+
+// FacetCount is a facet value and the number of members having it
+type FacetCount struct {
+	Value string `bson:"_id" json:"value"`
+	Count int64  `bson:"count" json:"count"`
+}
+
+// MemberFacets are the values found in the directory for each facet, most common first.
+// A facet counts the members matching the search and every other facet's value, so the
+// counts show what choosing a value would return.
+type MemberFacets struct {
+	MemberType     []FacetCount `bson:"member_type" json:"member_type"`
+	Governorate    []FacetCount `bson:"governorate" json:"governorate"`
+	District       []FacetCount `bson:"district" json:"district"`
+	Specialization []FacetCount `bson:"specialization" json:"specialization"`
+	DuesStatus     []FacetCount `bson:"dues_status" json:"dues_status"`
+	FirmSize       []FacetCount `bson:"firm_size,omitempty" json:"firm_size,omitempty"` // Firms only
+}
+
+// DirectoryIndividual is the public card of an individual member in the directory.
+// Contact details the member chose to hide are left empty; the account link, license
+// and internal counters are never included.
+type DirectoryIndividual struct {
+	ID         primitive.ObjectID `json:"id"`
+	LacpaID    string             `json:"lacpa_id"`
+	FirstName  string             `json:"first_name"`
+	MiddleName string             `json:"middle_name"`
+	LastName   string             `json:"last_name"`
+	FullName   string             `json:"full_name"`
+	AvatarURL  string             `json:"avatar_url"`
+	MemberType string             `json:"member_type"`
+	BadgeEmoji string             `json:"badge_emoji"`
+	BadgeColor string             `json:"badge_color"`
+
+	Title               string   `json:"title"`
+	Position            string   `json:"position"`
+	Firm                string   `json:"firm"`
+	Qualifications      []string `json:"qualifications"`
+	Specializations     []string `json:"specializations"`
+	YearsOfExperience   int      `json:"years_of_experience"`
+	Biography           string   `json:"biography"`
+	ProfessionalSummary string   `json:"professional_summary"`
+	IsCouncilMember     bool     `json:"is_council_member"`
+	CouncilPosition     string   `json:"council_position"`
+	ProfileURL          string   `json:"profile_url"`
+
+	// Empty unless the matching Show* flag is set
+	Phone       string `json:"phone,omitempty"`
+	Email       string `json:"email,omitempty"`
+	LinkedInURL string `json:"linkedin_url,omitempty"`
+	Governorate string `json:"governorate,omitempty"`
+	District    string `json:"district,omitempty"`
+	City        string `json:"city,omitempty"`
+	Area        string `json:"area,omitempty"`
+	Country     string `json:"country,omitempty"`
+
+	ShowPhone    bool `json:"show_phone"`
+	ShowEmail    bool `json:"show_email"`
+	ShowLinkedIn bool `json:"show_linkedin"`
+	ShowAddress  bool `json:"show_address"`
+}
+
+// NewDirectoryIndividual builds the public card of a member, honouring their privacy settings
+func NewDirectoryIndividual(m *IndividualMember) DirectoryIndividual {
+	card := DirectoryIndividual{
+		ID:                  m.ID,
+		LacpaID:             m.LacpaID,
+		FirstName:           m.FirstName,
+		MiddleName:          m.MiddleName,
+		LastName:            m.LastName,
+		FullName:            m.FullName,
+		AvatarURL:           m.AvatarURL,
+		MemberType:          m.MemberType,
+		BadgeEmoji:          m.BadgeEmoji,
+		BadgeColor:          m.BadgeColor,
+		Title:               m.Title,
+		Position:            m.Position,
+		Firm:                m.Firm,
+		Qualifications:      m.Qualifications,
+		Specializations:     m.Specializations,
+		YearsOfExperience:   m.YearsOfExperience,
+		Biography:           m.Biography,
+		ProfessionalSummary: m.ProfessionalSummary,
+		IsCouncilMember:     m.IsCouncilMember,
+		CouncilPosition:     m.CouncilPosition,
+		ProfileURL:          m.ProfileURL,
+		ShowPhone:           m.ShowPhone,
+		ShowEmail:           m.ShowEmail,
+		ShowLinkedIn:        m.ShowLinkedIn,
+		ShowAddress:         m.ShowAddress,
+	}
+	if m.ShowPhone {
+		card.Phone = m.Phone
+	}
+	if m.ShowEmail {
+		card.Email = m.Email
+	}
+	if m.ShowLinkedIn {
+		card.LinkedInURL = m.LinkedInURL
+	}
+	if m.ShowAddress {
+		card.Governorate, card.District, card.City, card.Area, card.Country = m.Governorate, m.District, m.City, m.Area, m.Country
+	}
+	return card
+}
+
+// DirectoryFirm is the public card of a firm in the directory, like DirectoryIndividual.
+// The contact person, registration and tax numbers are never included.
+type DirectoryFirm struct {
+	ID         primitive.ObjectID `json:"id"`
+	LacpaID    string             `json:"lacpa_id"`
+	FirmName   string             `json:"firm_name"`
+	LogoURL    string             `json:"logo_url"`
+	FirmType   string             `json:"firm_type"`
+	FirmSize   string             `json:"firm_size"`
+	BadgeEmoji string             `json:"badge_emoji"`
+	BadgeColor string             `json:"badge_color"`
+
+	YearEstablished  int      `json:"year_established"`
+	NumberOfPartners int      `json:"number_of_partners"`
+	NumberOfCPAs     int      `json:"number_of_cpas"`
+	ServicesOffered  []string `json:"services_offered"`
+	Industries       []string `json:"industries"`
+	Specializations  []string `json:"specializations"`
+	ShortDescription string   `json:"short_description"`
+	FullDescription  string   `json:"full_description"`
+	LinkedInURL      string   `json:"linkedin_url"`
+	ProfileURL       string   `json:"profile_url"`
+
+	// Empty unless the matching Show* flag is set
+	PrimaryPhone      string `json:"primary_phone,omitempty"`
+	PrimaryEmail      string `json:"primary_email,omitempty"`
+	Website           string `json:"website,omitempty"`
+	Governorate       string `json:"governorate,omitempty"`
+	District          string `json:"district,omitempty"`
+	City              string `json:"city,omitempty"`
+	Country           string `json:"country,omitempty"`
+	NumberOfEmployees int    `json:"number_of_employees,omitempty"`
+	AnnualRevenue     string `json:"annual_revenue,omitempty"`
+
+	ShowPhone         bool `json:"show_phone"`
+	ShowEmail         bool `json:"show_email"`
+	ShowWebsite       bool `json:"show_website"`
+	ShowAddress       bool `json:"show_address"`
+	ShowEmployeeCount bool `json:"show_employee_count"`
+	ShowRevenue       bool `json:"show_revenue"`
+}
+
+// NewDirectoryFirm builds the public card of a firm, honouring its privacy settings
+func NewDirectoryFirm(f *FirmMember) DirectoryFirm {
+	card := DirectoryFirm{
+		ID:                f.ID,
+		LacpaID:           f.LacpaID,
+		FirmName:          f.FirmName,
+		LogoURL:           f.LogoURL,
+		FirmType:          f.FirmType,
+		FirmSize:          f.FirmSize,
+		BadgeEmoji:        f.BadgeEmoji,
+		BadgeColor:        f.BadgeColor,
+		YearEstablished:   f.YearEstablished,
+		NumberOfPartners:  f.NumberOfPartners,
+		NumberOfCPAs:      f.NumberOfCPAs,
+		ServicesOffered:   f.ServicesOffered,
+		Industries:        f.Industries,
+		Specializations:   f.Specializations,
+		ShortDescription:  f.ShortDescription,
+		FullDescription:   f.FullDescription,
+		LinkedInURL:       f.LinkedInURL,
+		ProfileURL:        f.ProfileURL,
+		ShowPhone:         f.ShowPhone,
+		ShowEmail:         f.ShowEmail,
+		ShowWebsite:       f.ShowWebsite,
+		ShowAddress:       f.ShowAddress,
+		ShowEmployeeCount: f.ShowEmployeeCount,
+		ShowRevenue:       f.ShowRevenue,
+	}
+	if f.ShowPhone {
+		card.PrimaryPhone = f.PrimaryPhone
+	}
+	if f.ShowEmail {
+		card.PrimaryEmail = f.PrimaryEmail
+	}
+	if f.ShowWebsite {
+		card.Website = f.Website
+	}
+	if f.ShowAddress {
+		card.Governorate, card.District, card.City, card.Country = f.Governorate, f.District, f.City, f.Country
+	}
+	if f.ShowEmployeeCount {
+		card.NumberOfEmployees = f.NumberOfEmployees
+	}
+	if f.ShowRevenue {
+		card.AnnualRevenue = f.AnnualRevenue
+	}
+	return card
+}
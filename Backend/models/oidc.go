@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// OIDCLoginState is a pending single sign-on login, created when the browser is
+// sent to the provider and consumed once by the callback
+type OIDCLoginState struct {
+	ID           primitive.ObjectID `bson:"_id,omitempty"`
+	StateHash    string             `bson:"state_hash"`    // SHA-256 of the state parameter
+	CodeVerifier string             `bson:"code_verifier"` // PKCE verifier sent with the code exchange
+	Nonce        string             `bson:"nonce"`         // Must come back inside the ID token
+	CreatedAt    time.Time          `bson:"created_at"`
+	ExpiresAt    time.Time          `bson:"expires_at"` // TTL index removes abandoned logins
+}
+
+// Security event types for single sign-on
+const (
+	SecurityEventOIDCLinked      = "oidc_linked"
+	SecurityEventOIDCRoleChanged = "oidc_role_changed"
+)
@@ -0,0 +1,33 @@
+package models
+
+import "time"
+
+// RateLimitResult is the outcome of taking a token from a rate limit bucket
+type RateLimitResult struct {
+	Allowed    bool          // Whether the request may proceed
+	Remaining  int           // Whole tokens left in the bucket
+	RetryAfter time.Duration // Time until the next token, when denied
+	ResetAfter time.Duration // Time until the bucket is full again
+}
+
+// RateLimitBucket is the persisted state of a token bucket
+type RateLimitBucket struct {
+	Key       string    `json:"key" bson:"_id"`
+	Tokens    float64   `json:"tokens" bson:"tokens"`
+	Allowed   bool      `json:"allowed" bson:"allowed"` // Outcome of the last take
+	UpdatedAt time.Time `json:"updated_at" bson:"updated_at"`
+	ExpiresAt time.Time `json:"expires_at" bson:"expires_at"` // TTL index drops idle buckets
+}
+
+// NewRateLimitResult derives the result of a take from the bucket state after it
+func NewRateLimitResult(allowed bool, tokens float64, capacity int, refillEvery time.Duration) RateLimitResult {
+	result := RateLimitResult{
+		Allowed:    allowed,
+		Remaining:  int(tokens),
+		ResetAfter: time.Duration((float64(capacity) - tokens) * float64(refillEvery)),
+	}
+	if !allowed {
+		result.RetryAfter = time.Duration((1 - tokens) * float64(refillEvery))
+	}
+	return result
+}
@@ -0,0 +1,168 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Permission names a capability that can be granted to a role
+type Permission string
+
+const (
+	PermAdminAccess           Permission = "admin:access"           // Any admin-only endpoint without a narrower permission
+	PermUsersManage           Permission = "users:manage"           // Create admins, change roles, (de)activate users
+	PermRolesManage           Permission = "roles:manage"           // Create, edit and delete roles
+	PermSlidesRead            Permission = "slides:read"            // Read hero slides in the CMS
+	PermSlidesWrite           Permission = "slides:write"           // Create, update and delete hero slides
+	PermEventsWrite           Permission = "events:write"           // Create, update and delete events
+	PermApplicationsRead      Permission = "applications:read"      // List membership applications
+	PermApplicationsReview    Permission = "applications:review"    // Change application status
+	PermApplicationsAssign    Permission = "applications:assign"    // Assign and remove application reviewers
+	PermApplicationsRecommend Permission = "applications:recommend" // Recommend on and discuss applications as an assigned reviewer
+	PermRequirementsManage    Permission = "requirements:manage"    // Create, edit, reorder and delete application requirements
+	PermFinanceRead           Permission = "finance:read"           // List application fees and invoices
+	PermFinanceManage         Permission = "finance:manage"         // Set application fees, issue invoices and record payments
+	PermCouncilWrite          Permission = "council:write"          // Create, update and deactivate councils
+	PermCouncilAssign         Permission = "council:assign"         // Assign, update and remove council positions
+	PermAuditRead             Permission = "audit:read"             // Read and export the audit trail
+)
+
+// AllPermissions lists every permission known to the system
+var AllPermissions = []Permission{
+	PermAdminAccess,
+	PermUsersManage,
+	PermRolesManage,
+	PermSlidesRead,
+	PermSlidesWrite,
+	PermEventsWrite,
+	PermApplicationsRead,
+	PermApplicationsReview,
+	PermApplicationsAssign,
+	PermApplicationsRecommend,
+	PermRequirementsManage,
+	PermFinanceRead,
+	PermFinanceManage,
+	PermCouncilWrite,
+	PermCouncilAssign,
+	PermAuditRead,
+}
+
+// IsValid checks if the permission is one known to the system
+func (p Permission) IsValid() bool {
+	for _, known := range AllPermissions {
+		if p == known {
+			return true
+		}
+	}
+	return false
+}
+
+// Built-in role names
+const (
+	RoleAdmin             = "admin"
+	RoleContentEditor     = "content_editor"
+	RoleMembershipOfficer = "membership_officer"
+	RoleCouncilSecretary  = "council_secretary"
+	RoleFinanceOfficer    = "finance_officer"
+	RoleMember            = "member"
+	RoleGuest             = "guest"
+)
+
+// Role represents a named set of permissions stored in the roles collection
+type Role struct {
+	ID          primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	Name        string             `json:"name" bson:"name"`               // Unique key referenced by User.Role
+	Description string             `json:"description" bson:"description"` // Human readable summary
+	Permissions []Permission       `json:"permissions" bson:"permissions"` // Granted permissions
+	Version     int                `json:"version" bson:"version"`         // Incremented on every change, embedded in JWTs
+	IsSystem    bool               `json:"is_system" bson:"is_system"`     // Built-in roles cannot be deleted
+	RequireMFA  bool               `json:"require_mfa" bson:"require_mfa"` // Holders must complete TOTP two-factor login
+	CreatedAt   time.Time          `json:"created_at" bson:"created_at"`
+	UpdatedAt   time.Time          `json:"updated_at" bson:"updated_at"`
+}
+
+// HasPermission checks if the role grants the given permission
+func (r *Role) HasPermission(permission Permission) bool {
+	for _, granted := range r.Permissions {
+		if granted == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// PermissionStrings returns the granted permissions as plain strings
+func (r *Role) PermissionStrings() []string {
+	permissions := make([]string, 0, len(r.Permissions))
+	for _, permission := range r.Permissions {
+		permissions = append(permissions, string(permission))
+	}
+	return permissions
+}
+
+// DefaultRoles returns the roles seeded on startup when missing
+func DefaultRoles() []Role {
+	return []Role{
+		{
+			Name:        RoleAdmin,
+			Description: "Full access to every administrative feature",
+			Permissions: AllPermissions,
+			IsSystem:    true,
+			RequireMFA:  true,
+		},
+		{
+			Name:        RoleContentEditor,
+			Description: "Manages hero slides and events",
+			Permissions: []Permission{PermSlidesRead, PermSlidesWrite, PermEventsWrite},
+			IsSystem:    true,
+		},
+		{
+			Name:        RoleMembershipOfficer,
+			Description: "Reviews membership applications and manages their requirements",
+			Permissions: []Permission{PermApplicationsRead, PermApplicationsReview, PermApplicationsAssign, PermApplicationsRecommend, PermRequirementsManage},
+			IsSystem:    true,
+		},
+		{
+			Name:        RoleFinanceOfficer,
+			Description: "Sets application fees and records their payments",
+			Permissions: []Permission{PermApplicationsRead, PermApplicationsRecommend, PermFinanceRead, PermFinanceManage},
+			IsSystem:    true,
+		},
+		{
+			Name:        RoleCouncilSecretary,
+			Description: "Manages council positions",
+			Permissions: []Permission{PermCouncilAssign},
+			IsSystem:    true,
+			RequireMFA:  true,
+		},
+		{
+			Name:        RoleMember,
+			Description: "Registered LACPA member",
+			Permissions: []Permission{},
+			IsSystem:    true,
+		},
+		{
+			Name:        RoleGuest,
+			Description: "Registered visitor without member privileges",
+			Permissions: []Permission{},
+			IsSystem:    true,
+		},
+	}
+}
+
+// CreateRoleRequest represents data for creating a role
+type CreateRoleRequest struct {
+	Name        string       `json:"name" validate:"required,min=3,max=50"`
+	Description string       `json:"description"`
+	Permissions []Permission `json:"permissions"`
+	RequireMFA  bool         `json:"require_mfa"`
+}
+
+// UpdateRoleRequest represents data for updating a role.
+// Fields left out of the request keep their current value.
+type UpdateRoleRequest struct {
+	Description *string      `json:"description"`
+	Permissions []Permission `json:"permissions"` // Nil when omitted; [] removes every permission
+	RequireMFA  *bool        `json:"require_mfa"`
+}
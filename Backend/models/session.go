@@ -0,0 +1,45 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Session represents a logged-in device backed by a rotating refresh token
+type Session struct {
+	ID                primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	UserID            primitive.ObjectID `json:"user_id" bson:"user_id"`
+	RefreshTokenHash  string             `json:"-" bson:"refresh_token_hash"`            // SHA-256 of the current refresh token
+	PreviousTokenHash string             `json:"-" bson:"previous_token_hash,omitempty"` // SHA-256 of the token it replaced, used for reuse detection
+	UserAgent         string             `json:"user_agent" bson:"user_agent"`
+	IPAddress         string             `json:"ip_address" bson:"ip_address"`
+	CreatedAt         time.Time          `json:"created_at" bson:"created_at"`
+	LastUsedAt        time.Time          `json:"last_used_at" bson:"last_used_at"`
+	ExpiresAt         time.Time          `json:"expires_at" bson:"expires_at"` // TTL index removes the document after this time
+	RevokedAt         *time.Time         `json:"revoked_at,omitempty" bson:"revoked_at,omitempty"`
+	RevokedReason     string             `json:"revoked_reason,omitempty" bson:"revoked_reason,omitempty"`
+}
+
+// IsActive checks if the session can still be used
+func (s *Session) IsActive() bool {
+	return s.RevokedAt == nil && time.Now().Before(s.ExpiresAt)
+}
+
+// Session revocation reasons
+const (
+	SessionRevokedLogout      = "logout"
+	SessionRevokedLogoutAll   = "logout_all"
+	SessionRevokedReuse       = "refresh_token_reuse"
+	SessionRevokedDeactivated = "account_deactivated"
+	SessionRevokedMFAReset    = "two_factor_reset"
+	SessionRevokedPassword    = "password_changed"
+	SessionRevokedEmail       = "email_changed"
+	SessionRevokedDeleted     = "account_deleted"
+	SessionRevokedRoleChanged = "role_changed"
+)
+
+// RefreshTokenRequest represents a refresh token exchange
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token" validate:"required"`
+}
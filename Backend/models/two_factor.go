@@ -0,0 +1,72 @@
+package models
+
+import "time"
+
+// TwoFactor holds a user's TOTP enrollment.
+// Secrets are encrypted at rest and recovery codes are stored as SHA-256 hashes.
+type TwoFactor struct {
+	Enabled       bool       `json:"enabled" bson:"enabled"`
+	Secret        string     `json:"-" bson:"secret,omitempty"`         // Encrypted secret of the active enrollment
+	PendingSecret string     `json:"-" bson:"pending_secret,omitempty"` // Encrypted secret awaiting confirmation
+	RecoveryCodes []string   `json:"-" bson:"recovery_codes,omitempty"` // Hashes of unused recovery codes
+	LastUsedStep  int64      `json:"-" bson:"last_used_step,omitempty"` // Last accepted time step, to reject replays
+	EnabledAt     *time.Time `json:"enabled_at,omitempty" bson:"enabled_at,omitempty"`
+}
+
+// Purposes of two-factor challenge tokens
+const (
+	ChallengeMFALogin  = "mfa_login"  // Password verified, TOTP or recovery code still required
+	ChallengeMFAEnroll = "mfa_enroll" // Password verified, the role requires 2FA but the user is not enrolled
+)
+
+// Security events related to two-factor authentication
+const (
+	SecurityEventTwoFactorEnabled     = "two_factor_enabled"
+	SecurityEventTwoFactorDisabled    = "two_factor_disabled"
+	SecurityEventRecoveryCodeUsed     = "recovery_code_used"
+	SecurityEventRecoveryCodesRenewed = "recovery_codes_renewed"
+)
+
+// TwoFactorLoginRequest completes a login that returned a challenge token
+type TwoFactorLoginRequest struct {
+	ChallengeToken string `json:"challenge_token" validate:"required"`
+	Code           string `json:"code"`          // Current TOTP code
+	RecoveryCode   string `json:"recovery_code"` // Or one unused recovery code
+}
+
+// TwoFactorSetupRequest starts enrollment. The challenge token is only needed
+// when enrolling during login, before an access token has been issued.
+type TwoFactorSetupRequest struct {
+	ChallengeToken string `json:"challenge_token"`
+}
+
+// TwoFactorEnableRequest confirms enrollment with a code from the authenticator app
+type TwoFactorEnableRequest struct {
+	ChallengeToken string `json:"challenge_token"`
+	Code           string `json:"code" validate:"required,len=6"`
+}
+
+// TwoFactorDisableRequest turns 2FA off
+type TwoFactorDisableRequest struct {
+	Password string `json:"password" validate:"required"`
+	Code     string `json:"code" validate:"required,len=6"`
+}
+
+// TwoFactorCodeRequest carries a TOTP code confirming a sensitive change
+type TwoFactorCodeRequest struct {
+	Code string `json:"code" validate:"required,len=6"`
+}
+
+// TwoFactorSetupResponse is returned when enrollment starts
+type TwoFactorSetupResponse struct {
+	Secret          string `json:"secret"`           // Base32 secret for manual entry
+	ProvisioningURI string `json:"provisioning_uri"` // otpauth:// URI
+	QRPayload       string `json:"qr_payload"`       // Text to encode in the QR code shown to the user
+}
+
+// ChallengeResponse is returned by login when a second step is required
+type ChallengeResponse struct {
+	ChallengeToken string `json:"challenge_token"`
+	Purpose        string `json:"purpose"`
+	ExpiresIn      int    `json:"expires_in"`
+}
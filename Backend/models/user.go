@@ -8,52 +8,121 @@ import (
 
 // User represents a user in the system
 type User struct {
-	ID                primitive.ObjectID `json:"id" bson:"_id,omitempty"`
-	LACPAID           string             `json:"lacpa_id" bson:"lacpa_id"`
-	FullName          string             `json:"full_name" bson:"full_name"`
-	Email             string             `json:"email" bson:"email"`
-	Password          string             `json:"-" bson:"password"` // Never expose in JSON
-	Role              string             `json:"role" bson:"role"`  // "admin", "member", "guest"
-	IsVerified        bool               `json:"is_verified" bson:"is_verified"`
-	IsActive          bool               `json:"is_active" bson:"is_active"`
-	VerificationToken string             `json:"-" bson:"verification_token,omitempty"`
-	ResetToken        string             `json:"-" bson:"reset_token,omitempty"`
-	ResetTokenExpiry  time.Time          `json:"-" bson:"reset_token_expiry,omitempty"`
-	OTP               string             `json:"-" bson:"otp,omitempty"`
-	OTPExpiry         time.Time          `json:"-" bson:"otp_expiry,omitempty"`
-	LastLogin         time.Time          `json:"last_login" bson:"last_login"`
-	CreatedAt         time.Time          `json:"created_at" bson:"created_at"`
-	UpdatedAt         time.Time          `json:"updated_at" bson:"updated_at"`
+	ID                 primitive.ObjectID  `json:"id" bson:"_id,omitempty"`
+	LACPAID            string              `json:"lacpa_id" bson:"lacpa_id"`
+	FullName           string              `json:"full_name" bson:"full_name"`
+	Email              string              `json:"email" bson:"email"`
+	Password           string              `json:"-" bson:"password"`                   // Never expose in JSON
+	PasswordHistory    []string            `json:"-" bson:"password_history,omitempty"` // Hashes of recent previous passwords, newest first
+	Role               string              `json:"role" bson:"role"`                    // Name of a document in the roles collection
+	IsVerified         bool                `json:"is_verified" bson:"is_verified"`
+	IsActive           bool                `json:"is_active" bson:"is_active"`
+	VerificationToken  string              `json:"-" bson:"verification_token,omitempty"`
+	ResetToken         string              `json:"-" bson:"reset_token,omitempty"`
+	ResetTokenExpiry   time.Time           `json:"-" bson:"reset_token_expiry,omitempty"`
+	LastLogin          time.Time           `json:"last_login" bson:"last_login"`
+	SecurityHistory    []SecurityEvent     `json:"-" bson:"security_history,omitempty"`                                  // Lockouts, unlocks and other security events
+	TwoFactor          TwoFactor           `json:"-" bson:"two_factor,omitempty"`                                        // TOTP enrollment
+	IndividualMemberID *primitive.ObjectID `json:"individual_member_id,omitempty" bson:"individual_member_id,omitempty"` // Claimed registry profile
+	FirmMemberID       *primitive.ObjectID `json:"firm_member_id,omitempty" bson:"firm_member_id,omitempty"`             // Claimed firm profile
+	OIDCSubject        string              `json:"-" bson:"oidc_subject,omitempty"`                                      // Identity at the staff SSO provider
+	CreatedAt          time.Time           `json:"created_at" bson:"created_at"`
+	UpdatedAt          time.Time           `json:"updated_at" bson:"updated_at"`
 }
 
 // UserResponse is the sanitized user response (without sensitive data)
 type UserResponse struct {
-	ID         primitive.ObjectID `json:"id"`
-	LACPAID    string             `json:"lacpa_id"`
-	FullName   string             `json:"full_name"`
-	Email      string             `json:"email"`
-	Role       string             `json:"role"`
-	IsVerified bool               `json:"is_verified"`
-	IsActive   bool               `json:"is_active"`
-	LastLogin  time.Time          `json:"last_login"`
-	CreatedAt  time.Time          `json:"created_at"`
+	ID                 primitive.ObjectID  `json:"id"`
+	LACPAID            string              `json:"lacpa_id"`
+	FullName           string              `json:"full_name"`
+	Email              string              `json:"email"`
+	Role               string              `json:"role"`
+	IsVerified         bool                `json:"is_verified"`
+	IsActive           bool                `json:"is_active"`
+	TwoFactor          bool                `json:"two_factor_enabled"`
+	IndividualMemberID *primitive.ObjectID `json:"individual_member_id,omitempty"`
+	FirmMemberID       *primitive.ObjectID `json:"firm_member_id,omitempty"`
+	LastLogin          time.Time           `json:"last_login"`
+	CreatedAt          time.Time           `json:"created_at"`
 }
 
 // ToResponse converts User to UserResponse
 func (u *User) ToResponse() UserResponse {
 	return UserResponse{
-		ID:         u.ID,
-		LACPAID:    u.LACPAID,
-		FullName:   u.FullName,
-		Email:      u.Email,
-		Role:       u.Role,
-		IsVerified: u.IsVerified,
-		IsActive:   u.IsActive,
-		LastLogin:  u.LastLogin,
-		CreatedAt:  u.CreatedAt,
+		ID:                 u.ID,
+		LACPAID:            u.LACPAID,
+		FullName:           u.FullName,
+		Email:              u.Email,
+		Role:               u.Role,
+		IsVerified:         u.IsVerified,
+		IsActive:           u.IsActive,
+		TwoFactor:          u.TwoFactor.Enabled,
+		IndividualMemberID: u.IndividualMemberID,
+		FirmMemberID:       u.FirmMemberID,
+		LastLogin:          u.LastLogin,
+		CreatedAt:          u.CreatedAt,
 	}
 }
 
+// ProfileResponse is the current user together with the member records linked to the account
+type ProfileResponse struct {
+	UserResponse
+	IndividualMember *IndividualMember `json:"individual_member,omitempty"`
+	FirmMember       *FirmMember       `json:"firm_member,omitempty"`
+}
+
+// Member record types that can be claimed by an account
+const (
+	MemberTypeIndividual = "individual"
+	MemberTypeFirm       = "firm"
+)
+
+// IsValidClaimMemberType reports whether memberType is a record type that can be claimed
+func IsValidClaimMemberType(memberType string) bool {
+	return memberType == MemberTypeIndividual || memberType == MemberTypeFirm
+}
+
+// ClaimProfileRequest starts claiming a registry record by its LACPA number
+type ClaimProfileRequest struct {
+	MemberType string `json:"member_type" validate:"required,oneof=individual firm"`
+	LacpaID    string `json:"lacpa_id" validate:"required"`
+}
+
+// VerifyClaimRequest completes a claim with the code sent to the record's email
+type VerifyClaimRequest struct {
+	MemberType string `json:"member_type" validate:"required,oneof=individual firm"`
+	LacpaID    string `json:"lacpa_id" validate:"required"`
+	OTP        string `json:"otp" validate:"required,len=6"`
+}
+
+// ChangePasswordRequest changes the password of the current user
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" validate:"required"`
+	NewPassword     string `json:"new_password" validate:"required"`
+}
+
+// ChangeEmailRequest starts changing the email of the current user
+type ChangeEmailRequest struct {
+	NewEmail string `json:"new_email" validate:"required,email"`
+	Password string `json:"password" validate:"required"`
+}
+
+// ConfirmEmailChangeRequest completes an email change with the code sent to the new address
+type ConfirmEmailChangeRequest struct {
+	NewEmail string `json:"new_email" validate:"required,email"`
+	OTP      string `json:"otp" validate:"required,len=6"`
+}
+
+// UpdateNameRequest updates the full name of the current user
+type UpdateNameRequest struct {
+	FullName string `json:"full_name" validate:"required,min=3,max=100"`
+}
+
+// ConfirmPasswordRequest confirms a destructive action with the current password
+type ConfirmPasswordRequest struct {
+	Password string `json:"password" validate:"required"`
+}
+
 // LoginRequest represents login credentials
 type LoginRequest struct {
 	LACPAID  string `json:"lacpa_id" validate:"required"`
@@ -64,7 +133,7 @@ type LoginRequest struct {
 type SignupRequest struct {
 	FullName string `json:"full_name" validate:"required,min=3,max=100"`
 	Email    string `json:"email" validate:"required,email"`
-	Password string `json:"password" validate:"required,min=8"`
+	Password string `json:"password" validate:"required"` // Strength is checked against the password policy
 }
 
 // ForgotPasswordRequest represents forgot password data
@@ -74,23 +143,27 @@ type ForgotPasswordRequest struct {
 
 // VerifyOTPRequest represents OTP verification data
 type VerifyOTPRequest struct {
-	Email string `json:"email" validate:"required,email"`
-	OTP   string `json:"otp" validate:"required,len=6"`
+	Email   string `json:"email" validate:"required,email"`
+	OTP     string `json:"otp" validate:"required,len=6"`
+	Purpose string `json:"purpose"` // verify_email (default) or reset_password
 }
 
 // ResetPasswordRequest represents password reset data
 type ResetPasswordRequest struct {
 	Token       string `json:"token" validate:"required"`
-	NewPassword string `json:"new_password" validate:"required,min=8"`
+	NewPassword string `json:"new_password" validate:"required"`
 }
 
 // ResendOTPRequest represents resend OTP data
 type ResendOTPRequest struct {
-	Email string `json:"email" validate:"required,email"`
+	Email   string `json:"email" validate:"required,email"`
+	Purpose string `json:"purpose"` // verify_email (default) or reset_password
 }
 
 // AuthResponse represents authentication response
 type AuthResponse struct {
-	Token string       `json:"token"`
-	User  UserResponse `json:"user"`
+	Token        string       `json:"token"`         // Short-lived access token
+	RefreshToken string       `json:"refresh_token"` // Rotating token for /api/auth/refresh
+	ExpiresIn    int          `json:"expires_in"`    // Access token lifetime in seconds
+	User         UserResponse `json:"user"`
 }
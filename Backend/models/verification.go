@@ -0,0 +1,42 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// VerificationPurpose scopes a verification code to the flow it was issued for,
+// so a code sent for one flow cannot be redeemed in another
+type VerificationPurpose string
+
+const (
+	PurposeVerifyEmail        VerificationPurpose = "verify_email"        // Confirm the email of a new account
+	PurposeResetPassword      VerificationPurpose = "reset_password"      // Exchange for a password reset token
+	PurposeChangeEmail        VerificationPurpose = "change_email"        // Confirm a new address before switching to it
+	PurposeApplicationConfirm VerificationPurpose = "application_confirm" // Confirm the email on a membership application
+	PurposeClaimProfile       VerificationPurpose = "claim_profile"       // Prove ownership of a member record's email
+	PurposeApplicationTrack   VerificationPurpose = "application_track"   // Open a submitted application by its reference number
+)
+
+// IsValid checks if the purpose is one known to the system
+func (p VerificationPurpose) IsValid() bool {
+	switch p {
+	case PurposeVerifyEmail, PurposeResetPassword, PurposeChangeEmail, PurposeApplicationConfirm, PurposeClaimProfile, PurposeApplicationTrack:
+		return true
+	}
+	return false
+}
+
+// VerificationCode is a one-time code sent by email, stored in the verification_codes collection.
+// Only a hash of the code is kept; there is at most one live code per email and purpose.
+type VerificationCode struct {
+	ID        primitive.ObjectID  `json:"id" bson:"_id,omitempty"`
+	Email     string              `json:"email" bson:"email"`
+	Purpose   VerificationPurpose `json:"purpose" bson:"purpose"`
+	CodeHash  string              `json:"-" bson:"code_hash"`
+	Attempts  int                 `json:"attempts" bson:"attempts"`   // Guesses so far, counted before each is checked
+	Subject   string              `json:"-" bson:"subject,omitempty"` // What the code confirms, e.g. the new email for change_email
+	CreatedAt time.Time           `json:"created_at" bson:"created_at"`
+	ExpiresAt time.Time           `json:"expires_at" bson:"expires_at"` // TTL index removes the document after this
+}
@@ -2,50 +2,226 @@ package repository
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/AliSleiman0/Lacpa/models"
+	"github.com/AliSleiman0/Lacpa/utils"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+// ErrInvalidStatusTransition is returned when an application cannot move to the requested status
+var ErrInvalidStatusTransition = errors.New("status transition is not allowed")
+
+// ErrStatusConflict is returned when the application status changed while it was being updated
+var ErrStatusConflict = errors.New("application status was changed by another reviewer")
+
+// ErrDocumentsLocked is returned when an applicant adds a document while no information was requested
+var ErrDocumentsLocked = errors.New("documents can only be added while the application needs information")
+
+// ErrReviewerAssigned is returned when a reviewer is assigned to an application twice
+var ErrReviewerAssigned = errors.New("reviewer is already assigned to this application")
+
+// ErrNotAssignedReviewer is returned when a recommendation or removal names a reviewer who is not assigned
+var ErrNotAssignedReviewer = errors.New("reviewer is not assigned to this application")
+
+// ErrReviewClosed is returned when reviewers or recommendations change after the application was decided
+var ErrReviewClosed = errors.New("the application has been decided")
+
+// ErrQuorumNotMet is returned when an application is approved before its reviewers' quorum is met
+var ErrQuorumNotMet = errors.New("the reviewers' quorum is not met")
+
+// ErrFeeUnpaid is returned when an application is put under review before its fee is paid
+var ErrFeeUnpaid = errors.New("the application fee must be paid before review")
+
 type ApplicationRepository interface {
+	EnsureApplicationIndexes(ctx context.Context) error
+
 	// Application Requirements
 	GetAllRequirements(ctx context.Context) ([]models.ApplicationRequirement, error)
 	GetRequirementsByType(ctx context.Context, appType models.ApplicationType) ([]models.ApplicationRequirement, error)
+	GetRequirementByID(ctx context.Context, id primitive.ObjectID) (*models.ApplicationRequirement, error)
 	CreateRequirement(ctx context.Context, requirement *models.ApplicationRequirement) error
 	UpdateRequirement(ctx context.Context, id primitive.ObjectID, requirement *models.ApplicationRequirement) error
+	ReorderRequirements(ctx context.Context, ids []primitive.ObjectID) error
 	DeleteRequirement(ctx context.Context, id primitive.ObjectID) error
 
 	// Individual Applications
 	CreateIndividualApplication(ctx context.Context, application *models.IndividualApplication) error
 	GetIndividualApplicationByID(ctx context.Context, id primitive.ObjectID) (*models.IndividualApplication, error)
-	GetAllIndividualApplications(ctx context.Context) ([]models.IndividualApplication, error)
-	GetIndividualApplicationsByStatus(ctx context.Context, status models.ApplicationStatus) ([]models.IndividualApplication, error)
+	GetIndividualApplicationByReference(ctx context.Context, reference string) (*models.IndividualApplication, error)
+	GetIndividualApplicationsByApplicant(ctx context.Context, userID primitive.ObjectID, email string) ([]models.IndividualApplication, error)
+	ListIndividualApplications(ctx context.Context, filter models.ApplicationFilter) ([]models.IndividualApplication, int64, error)
 	UpdateIndividualApplicationStatus(ctx context.Context, id primitive.ObjectID, status models.ApplicationStatus, notes string, reviewedBy primitive.ObjectID) error
+	ApproveIndividualApplication(ctx context.Context, id primitive.ObjectID, notes string, reviewedBy primitive.ObjectID, memberType string) (*models.IndividualMember, error)
 
 	// Firm Applications
 	CreateFirmApplication(ctx context.Context, application *models.FirmApplication) error
 	GetFirmApplicationByID(ctx context.Context, id primitive.ObjectID) (*models.FirmApplication, error)
-	GetAllFirmApplications(ctx context.Context) ([]models.FirmApplication, error)
-	GetFirmApplicationsByStatus(ctx context.Context, status models.ApplicationStatus) ([]models.FirmApplication, error)
+	GetFirmApplicationByReference(ctx context.Context, reference string) (*models.FirmApplication, error)
+	GetFirmApplicationsByApplicant(ctx context.Context, userID primitive.ObjectID, email string) ([]models.FirmApplication, error)
+	ListFirmApplications(ctx context.Context, filter models.ApplicationFilter) ([]models.FirmApplication, int64, error)
 	UpdateFirmApplicationStatus(ctx context.Context, id primitive.ObjectID, status models.ApplicationStatus, notes string, reviewedBy primitive.ObjectID) error
+	ApproveFirmApplication(ctx context.Context, id primitive.ObjectID, notes string, reviewedBy primitive.ObjectID) (*models.FirmMember, error)
+
+	// Application Documents
+	CreateDocument(ctx context.Context, document *models.ApplicationDocument) error
+	GetDocumentByID(ctx context.Context, id primitive.ObjectID) (*models.ApplicationDocument, error)
+	AttachDocuments(ctx context.Context, ids []primitive.ObjectID, applicationID primitive.ObjectID) error
+	GetDocumentsByApplication(ctx context.Context, applicationID primitive.ObjectID) ([]models.ApplicationDocument, error)
+	AddApplicationDocument(ctx context.Context, appType models.ApplicationType, applicationID primitive.ObjectID, document *models.ApplicationDocument) error
+	UnattachedDocumentsBefore(ctx context.Context, cutoff time.Time, limit int64) ([]models.ApplicationDocument, error)
+	DeleteUnattachedDocument(ctx context.Context, id primitive.ObjectID) (bool, error)
+
+	// Reviewer Lists
+	ListApplications(ctx context.Context, filter models.ApplicationFilter) ([]models.ApplicationSummary, int64, error)
+	CountApplicationsByStatus(ctx context.Context, filter models.ApplicationFilter) (*models.ApplicationStatusCounts, error)
+
+	// Duplicate Detection
+	FindDuplicateCandidates(ctx context.Context, appType models.ApplicationType, probe models.DuplicateProbe) (*models.DuplicateCandidates, error)
+
+	// Drafts
+	CreateDraft(ctx context.Context, draft *models.ApplicationDraft) error
+	GetDraftByID(ctx context.Context, id primitive.ObjectID) (*models.ApplicationDraft, error)
+	UpdateDraft(ctx context.Context, draft *models.ApplicationDraft) error
+	DeleteDraft(ctx context.Context, id primitive.ObjectID) error
+
+	// Reviewers and Recommendations
+	GetApplicationReview(ctx context.Context, appType models.ApplicationType, id primitive.ObjectID) (*models.ApplicationReview, error)
+	AssignReviewer(ctx context.Context, appType models.ApplicationType, id primitive.ObjectID, assignment models.ReviewerAssignment) error
+	RemoveReviewer(ctx context.Context, appType models.ApplicationType, id primitive.ObjectID, reviewerID primitive.ObjectID) error
+	RecordRecommendation(ctx context.Context, appType models.ApplicationType, id primitive.ObjectID, recommendation models.ReviewRecommendation) error
+
+	// Review Comments
+	CreateComment(ctx context.Context, comment *models.ApplicationComment) error
+	GetCommentByID(ctx context.Context, id primitive.ObjectID) (*models.ApplicationComment, error)
+	GetComments(ctx context.Context, applicationID primitive.ObjectID) ([]models.ApplicationComment, error)
+
+	// Quorum Rules
+	GetQuorumRule(ctx context.Context, appType models.ApplicationType) (*models.QuorumRule, error)
+	SaveQuorumRule(ctx context.Context, rule *models.QuorumRule) error
 }
 
 type applicationRepository struct {
+	client                          *mongo.Client
 	requirementCollection           *mongo.Collection
 	individualApplicationCollection *mongo.Collection
 	firmApplicationCollection       *mongo.Collection
+	documentCollection              *mongo.Collection
+	draftCollection                 *mongo.Collection
+	commentCollection               *mongo.Collection
+	quorumRuleCollection            *mongo.Collection
+
+	// Written to when an approval creates the member record
+	individualMemberCollection *mongo.Collection
+	firmMemberCollection       *mongo.Collection
+	userCollection             *mongo.Collection
+	counterCollection          *mongo.Collection
+
+	// Read when an application is put under review
+	feeCollection     *mongo.Collection
+	invoiceCollection *mongo.Collection
 }
 
 func NewApplicationRepository(db *mongo.Database) ApplicationRepository {
 	return &applicationRepository{
+		client:                          db.Client(),
 		requirementCollection:           db.Collection("application_requirements"),
 		individualApplicationCollection: db.Collection("individual_applications"),
 		firmApplicationCollection:       db.Collection("firm_applications"),
+		documentCollection:              db.Collection("application_documents"),
+		draftCollection:                 db.Collection("application_drafts"),
+		commentCollection:               db.Collection("application_comments"),
+		quorumRuleCollection:            db.Collection("review_quorum_rules"),
+		individualMemberCollection:      db.Collection("individual_members"),
+		firmMemberCollection:            db.Collection("firm_members"),
+		userCollection:                  db.Collection("users"),
+		counterCollection:               db.Collection("counters"),
+		feeCollection:                   db.Collection("application_fees"),
+		invoiceCollection:               db.Collection("invoices"),
+	}
+}
+
+// EnsureApplicationIndexes creates the reference number, applicant lookup, reviewer list,
+// text search, comment and quorum rule indexes, the TTL index that removes abandoned drafts
+// and the duplicate key indexes of applications and members
+func (r *applicationRepository) EnsureApplicationIndexes(ctx context.Context) error {
+	for _, collection := range []*mongo.Collection{r.individualApplicationCollection, r.firmApplicationCollection} {
+		_, err := collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+			{
+				// Applications submitted before reference numbers existed have none
+				Keys: bson.D{{Key: "reference_number", Value: 1}},
+				Options: options.Index().SetUnique(true).SetPartialFilterExpression(bson.M{
+					"reference_number": bson.M{"$type": "string", "$gt": ""},
+				}),
+			},
+			{Keys: bson.D{{Key: "user_id", Value: 1}}},
+			{Keys: bson.D{{Key: "email", Value: 1}}},
+			{Keys: bson.D{{Key: "status", Value: 1}, {Key: "submitted_at", Value: -1}}},
+			{Keys: bson.D{{Key: "reviewers.user_id", Value: 1}}},
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	// Reviewer search on names and emails
+	textIndexes := map[*mongo.Collection]bson.D{
+		r.individualApplicationCollection: {
+			{Key: "first_name", Value: "text"}, {Key: "middle_name", Value: "text"},
+			{Key: "last_name", Value: "text"}, {Key: "email", Value: "text"},
+		},
+		r.firmApplicationCollection: {
+			{Key: "firm_name", Value: "text"}, {Key: "trade_name", Value: "text"},
+			{Key: "representative_name", Value: "text"}, {Key: "email", Value: "text"},
+			{Key: "representative_email", Value: "text"},
+		},
+	}
+	for collection, keys := range textIndexes {
+		if _, err := collection.Indexes().CreateOne(ctx, mongo.IndexModel{Keys: keys}); err != nil {
+			return err
+		}
+	}
+
+	// Also serves the cleanup of uploads never attached to an application
+	_, err := r.documentCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "application_id", Value: 1}, {Key: "created_at", Value: 1}},
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = r.commentCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "application_id", Value: 1}, {Key: "created_at", Value: 1}},
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = r.quorumRuleCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "application_type", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = r.draftCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "expires_at", Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	})
+	if err != nil {
+		return err
 	}
+
+	return r.ensureDuplicateKeys(ctx)
 }
 
 // ============= Application Requirements =============
@@ -80,6 +256,14 @@ func (r *applicationRepository) GetRequirementsByType(ctx context.Context, appTy
 	return requirements, nil
 }
 
+func (r *applicationRepository) GetRequirementByID(ctx context.Context, id primitive.ObjectID) (*models.ApplicationRequirement, error) {
+	var requirement models.ApplicationRequirement
+	if err := r.requirementCollection.FindOne(ctx, bson.M{"_id": id}).Decode(&requirement); err != nil {
+		return nil, err
+	}
+	return &requirement, nil
+}
+
 func (r *applicationRepository) CreateRequirement(ctx context.Context, requirement *models.ApplicationRequirement) error {
 	requirement.CreatedAt = time.Now()
 	requirement.UpdatedAt = time.Now()
@@ -102,28 +286,79 @@ func (r *applicationRepository) UpdateRequirement(ctx context.Context, id primit
 			"description":      requirement.Description,
 			"icon":             requirement.Icon,
 			"application_type": requirement.ApplicationType,
+			"document_kind":    requirement.DocumentKind,
 			"is_required":      requirement.IsRequired,
 			"order_index":      requirement.OrderIndex,
 			"updated_at":       requirement.UpdatedAt,
 		},
 	}
 
-	_, err := r.requirementCollection.UpdateOne(ctx, bson.M{"_id": id}, update)
+	result, err := r.requirementCollection.UpdateOne(ctx, bson.M{"_id": id}, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+// ReorderRequirements sets the order_index of each requirement to its position in ids, starting at 1
+func (r *applicationRepository) ReorderRequirements(ctx context.Context, ids []primitive.ObjectID) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	writes := make([]mongo.WriteModel, 0, len(ids))
+	for i, id := range ids {
+		writes = append(writes, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"_id": id}).
+			SetUpdate(bson.M{"$set": bson.M{"order_index": i + 1, "updated_at": now}}))
+	}
+
+	_, err := r.requirementCollection.BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(false))
 	return err
 }
 
 func (r *applicationRepository) DeleteRequirement(ctx context.Context, id primitive.ObjectID) error {
-	_, err := r.requirementCollection.DeleteOne(ctx, bson.M{"_id": id})
-	return err
+	result, err := r.requirementCollection.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
 }
 
 // ============= Individual Applications =============
 
 func (r *applicationRepository) CreateIndividualApplication(ctx context.Context, application *models.IndividualApplication) error {
+	reference, err := r.nextReference(ctx, models.ApplicationTypeIndividual)
+	if err != nil {
+		return err
+	}
+	application.ReferenceNumber = reference
 	application.Status = models.ApplicationStatusPending
 	application.SubmittedAt = time.Now()
 	application.CreatedAt = time.Now()
 	application.UpdatedAt = time.Now()
+	application.StatusHistory = []models.ApplicationStatusChange{{
+		To:        models.ApplicationStatusPending,
+		ChangedAt: application.SubmittedAt,
+	}}
+
+	// Review fields are set by staff only, never by the submitted body
+	application.ID = primitive.NilObjectID
+	application.ReviewedAt = nil
+	application.ReviewedBy = nil
+	application.ReviewNotes = ""
+	application.MemberID = nil
+	application.Reviewers = nil
+	application.Recommendations = nil
+
+	application.DuplicateKeys = individualApplicationKeys(application)
 
 	result, err := r.individualApplicationCollection.InsertOne(ctx, application)
 	if err != nil {
@@ -143,23 +378,20 @@ func (r *applicationRepository) GetIndividualApplicationByID(ctx context.Context
 	return &application, nil
 }
 
-func (r *applicationRepository) GetAllIndividualApplications(ctx context.Context) ([]models.IndividualApplication, error) {
-	cursor, err := r.individualApplicationCollection.Find(ctx, bson.M{}, options.Find().SetSort(bson.D{{Key: "submitted_at", Value: -1}}))
+func (r *applicationRepository) GetIndividualApplicationByReference(ctx context.Context, reference string) (*models.IndividualApplication, error) {
+	var application models.IndividualApplication
+	err := r.individualApplicationCollection.FindOne(ctx, bson.M{"reference_number": reference}).Decode(&application)
 	if err != nil {
 		return nil, err
 	}
-	defer cursor.Close(ctx)
-
-	var applications []models.IndividualApplication
-	if err := cursor.All(ctx, &applications); err != nil {
-		return nil, err
-	}
-
-	return applications, nil
+	return &application, nil
 }
 
-func (r *applicationRepository) GetIndividualApplicationsByStatus(ctx context.Context, status models.ApplicationStatus) ([]models.IndividualApplication, error) {
-	cursor, err := r.individualApplicationCollection.Find(ctx, bson.M{"status": status}, options.Find().SetSort(bson.D{{Key: "submitted_at", Value: -1}}))
+// GetIndividualApplicationsByApplicant lists the applications submitted from an account or
+// with its email address, newest first
+func (r *applicationRepository) GetIndividualApplicationsByApplicant(ctx context.Context, userID primitive.ObjectID, email string) ([]models.IndividualApplication, error) {
+	filter := bson.M{"$or": append(applicantEmailFilter(email, "email"), bson.M{"user_id": userID})}
+	cursor, err := r.individualApplicationCollection.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "submitted_at", Value: -1}}))
 	if err != nil {
 		return nil, err
 	}
@@ -173,29 +405,58 @@ func (r *applicationRepository) GetIndividualApplicationsByStatus(ctx context.Co
 	return applications, nil
 }
 
-func (r *applicationRepository) UpdateIndividualApplicationStatus(ctx context.Context, id primitive.ObjectID, status models.ApplicationStatus, notes string, reviewedBy primitive.ObjectID) error {
-	now := time.Now()
-	update := bson.M{
-		"$set": bson.M{
-			"status":       status,
-			"review_notes": notes,
-			"reviewed_by":  reviewedBy,
-			"reviewed_at":  now,
-			"updated_at":   now,
-		},
+// ListIndividualApplications returns a page of the applications matching the filter and their total count
+func (r *applicationRepository) ListIndividualApplications(ctx context.Context, filter models.ApplicationFilter) ([]models.IndividualApplication, int64, error) {
+	applications := []models.IndividualApplication{}
+	total, err := r.listApplications(ctx, models.ApplicationTypeIndividual, filter, &applications)
+	if err != nil {
+		return nil, 0, err
 	}
+	return applications, total, nil
+}
 
-	_, err := r.individualApplicationCollection.UpdateOne(ctx, bson.M{"_id": id}, update)
-	return err
+// UpdateIndividualApplicationStatus moves an application to a new status if the state machine allows it.
+// Approvals go through ApproveIndividualApplication so the member record is created.
+func (r *applicationRepository) UpdateIndividualApplicationStatus(ctx context.Context, id primitive.ObjectID, status models.ApplicationStatus, notes string, reviewedBy primitive.ObjectID) error {
+	if status == models.ApplicationStatusApproved {
+		_, err := r.ApproveIndividualApplication(ctx, id, notes, reviewedBy, "")
+		return err
+	}
+	if status == models.ApplicationStatusUnderReview {
+		if err := r.requirePaidInvoice(ctx, models.ApplicationTypeIndividual, id); err != nil {
+			return err
+		}
+	}
+	return transitionApplication(ctx, r.individualApplicationCollection, id, status, notes, reviewedBy)
 }
 
 // ============= Firm Applications =============
 
 func (r *applicationRepository) CreateFirmApplication(ctx context.Context, application *models.FirmApplication) error {
+	reference, err := r.nextReference(ctx, models.ApplicationTypeFirm)
+	if err != nil {
+		return err
+	}
+	application.ReferenceNumber = reference
 	application.Status = models.ApplicationStatusPending
 	application.SubmittedAt = time.Now()
 	application.CreatedAt = time.Now()
 	application.UpdatedAt = time.Now()
+	application.StatusHistory = []models.ApplicationStatusChange{{
+		To:        models.ApplicationStatusPending,
+		ChangedAt: application.SubmittedAt,
+	}}
+
+	// Review fields are set by staff only, never by the submitted body
+	application.ID = primitive.NilObjectID
+	application.ReviewedAt = nil
+	application.ReviewedBy = nil
+	application.ReviewNotes = ""
+	application.MemberID = nil
+	application.Reviewers = nil
+	application.Recommendations = nil
+
+	application.DuplicateKeys = firmApplicationKeys(application)
 
 	result, err := r.firmApplicationCollection.InsertOne(ctx, application)
 	if err != nil {
@@ -215,8 +476,20 @@ func (r *applicationRepository) GetFirmApplicationByID(ctx context.Context, id p
 	return &application, nil
 }
 
-func (r *applicationRepository) GetAllFirmApplications(ctx context.Context) ([]models.FirmApplication, error) {
-	cursor, err := r.firmApplicationCollection.Find(ctx, bson.M{}, options.Find().SetSort(bson.D{{Key: "submitted_at", Value: -1}}))
+func (r *applicationRepository) GetFirmApplicationByReference(ctx context.Context, reference string) (*models.FirmApplication, error) {
+	var application models.FirmApplication
+	err := r.firmApplicationCollection.FindOne(ctx, bson.M{"reference_number": reference}).Decode(&application)
+	if err != nil {
+		return nil, err
+	}
+	return &application, nil
+}
+
+// GetFirmApplicationsByApplicant lists the applications submitted from an account or
+// with its email address, newest first
+func (r *applicationRepository) GetFirmApplicationsByApplicant(ctx context.Context, userID primitive.ObjectID, email string) ([]models.FirmApplication, error) {
+	filter := bson.M{"$or": append(applicantEmailFilter(email, "email", "representative_email"), bson.M{"user_id": userID})}
+	cursor, err := r.firmApplicationCollection.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "submitted_at", Value: -1}}))
 	if err != nil {
 		return nil, err
 	}
@@ -230,33 +503,1168 @@ func (r *applicationRepository) GetAllFirmApplications(ctx context.Context) ([]m
 	return applications, nil
 }
 
-func (r *applicationRepository) GetFirmApplicationsByStatus(ctx context.Context, status models.ApplicationStatus) ([]models.FirmApplication, error) {
-	cursor, err := r.firmApplicationCollection.Find(ctx, bson.M{"status": status}, options.Find().SetSort(bson.D{{Key: "submitted_at", Value: -1}}))
+// ListFirmApplications returns a page of the applications matching the filter and their total count
+func (r *applicationRepository) ListFirmApplications(ctx context.Context, filter models.ApplicationFilter) ([]models.FirmApplication, int64, error) {
+	applications := []models.FirmApplication{}
+	total, err := r.listApplications(ctx, models.ApplicationTypeFirm, filter, &applications)
+	if err != nil {
+		return nil, 0, err
+	}
+	return applications, total, nil
+}
+
+// UpdateFirmApplicationStatus moves an application to a new status if the state machine allows it.
+// Approvals go through ApproveFirmApplication so the member record is created.
+func (r *applicationRepository) UpdateFirmApplicationStatus(ctx context.Context, id primitive.ObjectID, status models.ApplicationStatus, notes string, reviewedBy primitive.ObjectID) error {
+	if status == models.ApplicationStatusApproved {
+		_, err := r.ApproveFirmApplication(ctx, id, notes, reviewedBy)
+		return err
+	}
+	if status == models.ApplicationStatusUnderReview {
+		if err := r.requirePaidInvoice(ctx, models.ApplicationTypeFirm, id); err != nil {
+			return err
+		}
+	}
+	return transitionApplication(ctx, r.firmApplicationCollection, id, status, notes, reviewedBy)
+}
+
+// ============= Application Documents =============
+
+func (r *applicationRepository) CreateDocument(ctx context.Context, document *models.ApplicationDocument) error {
+	document.CreatedAt = time.Now()
+
+	result, err := r.documentCollection.InsertOne(ctx, document)
+	if err != nil {
+		return err
+	}
+
+	document.ID = result.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+func (r *applicationRepository) GetDocumentByID(ctx context.Context, id primitive.ObjectID) (*models.ApplicationDocument, error) {
+	var document models.ApplicationDocument
+	err := r.documentCollection.FindOne(ctx, bson.M{"_id": id}).Decode(&document)
+	if err != nil {
+		return nil, err
+	}
+	return &document, nil
+}
+
+// AttachDocuments assigns uploaded documents to a submitted application.
+// Documents already attached to an application are left untouched.
+func (r *applicationRepository) AttachDocuments(ctx context.Context, ids []primitive.ObjectID, applicationID primitive.ObjectID) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	_, err := r.documentCollection.UpdateMany(ctx,
+		bson.M{"_id": bson.M{"$in": ids}, "application_id": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"application_id": applicationID}},
+	)
+	return err
+}
+
+// GetDocumentsByApplication lists the documents attached to an application, oldest first
+func (r *applicationRepository) GetDocumentsByApplication(ctx context.Context, applicationID primitive.ObjectID) ([]models.ApplicationDocument, error) {
+	cursor, err := r.documentCollection.Find(ctx, bson.M{"application_id": applicationID}, options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}}))
 	if err != nil {
 		return nil, err
 	}
 	defer cursor.Close(ctx)
 
-	var applications []models.FirmApplication
-	if err := cursor.All(ctx, &applications); err != nil {
+	var documents []models.ApplicationDocument
+	if err := cursor.All(ctx, &documents); err != nil {
 		return nil, err
 	}
 
-	return applications, nil
+	return documents, nil
 }
 
-func (r *applicationRepository) UpdateFirmApplicationStatus(ctx context.Context, id primitive.ObjectID, status models.ApplicationStatus, notes string, reviewedBy primitive.ObjectID) error {
-	now := time.Now()
-	update := bson.M{
+// AddApplicationDocument attaches a document uploaded by the applicant to a submitted
+// application and puts it in the application's slot for its kind. It only succeeds while
+// the application is waiting for information from the applicant.
+func (r *applicationRepository) AddApplicationDocument(ctx context.Context, appType models.ApplicationType, applicationID primitive.ObjectID, document *models.ApplicationDocument) error {
+	collection := r.individualApplicationCollection
+	if appType == models.ApplicationTypeFirm {
+		collection = r.firmApplicationCollection
+	}
+
+	field, multiple := models.DocumentField(document.Kind)
+	set := bson.M{"updated_at": time.Now()}
+	update := bson.M{"$set": set}
+	if multiple {
+		update["$push"] = bson.M{field: document.ID.Hex()}
+	} else {
+		set[field] = document.ID.Hex()
+	}
+
+	return r.withTransaction(ctx, func(sc mongo.SessionContext) error {
+		result, err := collection.UpdateOne(sc, bson.M{"_id": applicationID, "status": models.ApplicationStatusNeedsInfo}, update)
+		if err != nil {
+			return err
+		}
+		if result.MatchedCount == 0 {
+			return ErrDocumentsLocked
+		}
+
+		result, err = r.documentCollection.UpdateOne(sc,
+			bson.M{"_id": document.ID, "application_id": bson.M{"$exists": false}},
+			bson.M{"$set": bson.M{"application_id": applicationID}},
+		)
+		if err != nil {
+			return err
+		}
+		if result.MatchedCount == 0 {
+			return fmt.Errorf("document %s is already attached to an application", document.ID.Hex())
+		}
+		document.ApplicationID = &applicationID
+		return nil
+	})
+}
+
+// UnattachedDocumentsBefore lists up to limit documents uploaded before the cutoff that
+// were never attached to an application. Documents a saved draft points at are skipped,
+// since the draft keeps them until it is submitted or expires.
+func (r *applicationRepository) UnattachedDocumentsBefore(ctx context.Context, cutoff time.Time, limit int64) ([]models.ApplicationDocument, error) {
+	cursor, err := r.documentCollection.Find(ctx,
+		bson.M{"application_id": bson.M{"$exists": false}, "created_at": bson.M{"$lt": cutoff}},
+		options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}}).SetLimit(limit),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var candidates []models.ApplicationDocument
+	if err := cursor.All(ctx, &candidates); err != nil {
+		return nil, err
+	}
+
+	documents := make([]models.ApplicationDocument, 0, len(candidates))
+	for _, document := range candidates {
+		field, _ := models.DocumentField(document.Kind)
+		drafts, err := r.draftCollection.CountDocuments(ctx, bson.M{"data." + field: document.ID.Hex()}, options.Count().SetLimit(1))
+		if err != nil {
+			return nil, err
+		}
+		if drafts == 0 {
+			documents = append(documents, document)
+		}
+	}
+	return documents, nil
+}
+
+// DeleteUnattachedDocument removes the metadata of a document unless it was attached to an
+// application in the meantime, and reports whether it was removed
+func (r *applicationRepository) DeleteUnattachedDocument(ctx context.Context, id primitive.ObjectID) (bool, error) {
+	result, err := r.documentCollection.DeleteOne(ctx, bson.M{"_id": id, "application_id": bson.M{"$exists": false}})
+	if err != nil {
+		return false, err
+	}
+	return result.DeletedCount == 1, nil
+}
+
+// ============= Drafts =============
+
+func (r *applicationRepository) CreateDraft(ctx context.Context, draft *models.ApplicationDraft) error {
+	draft.CreatedAt = time.Now()
+	draft.UpdatedAt = draft.CreatedAt
+	if draft.Data == nil {
+		draft.Data = map[string]interface{}{}
+	}
+	if draft.CompletedSteps == nil {
+		draft.CompletedSteps = []string{}
+	}
+
+	result, err := r.draftCollection.InsertOne(ctx, draft)
+	if err != nil {
+		return err
+	}
+
+	draft.ID = result.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+// GetDraftByID returns an unexpired draft. The TTL monitor only runs once a minute,
+// so expired drafts it has not removed yet are filtered out here.
+func (r *applicationRepository) GetDraftByID(ctx context.Context, id primitive.ObjectID) (*models.ApplicationDraft, error) {
+	var draft models.ApplicationDraft
+	err := r.draftCollection.FindOne(ctx, bson.M{"_id": id, "expires_at": bson.M{"$gt": time.Now()}}).Decode(&draft)
+	if err != nil {
+		return nil, err
+	}
+	return &draft, nil
+}
+
+// UpdateDraft saves the draft's data, completed steps and expiry
+func (r *applicationRepository) UpdateDraft(ctx context.Context, draft *models.ApplicationDraft) error {
+	draft.UpdatedAt = time.Now()
+
+	result, err := r.draftCollection.UpdateOne(ctx, bson.M{"_id": draft.ID}, bson.M{
 		"$set": bson.M{
-			"status":       status,
-			"review_notes": notes,
-			"reviewed_by":  reviewedBy,
-			"reviewed_at":  now,
-			"updated_at":   now,
+			"data":            draft.Data,
+			"completed_steps": draft.CompletedSteps,
+			"updated_at":      draft.UpdatedAt,
+			"expires_at":      draft.ExpiresAt,
+		},
+	})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+func (r *applicationRepository) DeleteDraft(ctx context.Context, id primitive.ObjectID) error {
+	result, err := r.draftCollection.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+// ============= Reviewers and Recommendations =============
+
+// GetApplicationReview returns the status, reviewers and recommendations of an application.
+// The quorum rule and its evaluation are left to the caller.
+func (r *applicationRepository) GetApplicationReview(ctx context.Context, appType models.ApplicationType, id primitive.ObjectID) (*models.ApplicationReview, error) {
+	var review struct {
+		Status          models.ApplicationStatus      `bson:"status"`
+		Reviewers       []models.ReviewerAssignment   `bson:"reviewers"`
+		Recommendations []models.ReviewRecommendation `bson:"recommendations"`
+	}
+	err := r.applicationCollection(appType).FindOne(ctx, bson.M{"_id": id},
+		options.FindOne().SetProjection(bson.M{"status": 1, "reviewers": 1, "recommendations": 1}),
+	).Decode(&review)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &models.ApplicationReview{
+		ApplicationType: appType,
+		ApplicationID:   id,
+		Status:          review.Status,
+		Reviewers:       review.Reviewers,
+		Recommendations: review.Recommendations,
+	}
+	if result.Reviewers == nil {
+		result.Reviewers = []models.ReviewerAssignment{}
+	}
+	if result.Recommendations == nil {
+		result.Recommendations = []models.ReviewRecommendation{}
+	}
+	return result, nil
+}
+
+// AssignReviewer adds a reviewer to an undecided application
+func (r *applicationRepository) AssignReviewer(ctx context.Context, appType models.ApplicationType, id primitive.ObjectID, assignment models.ReviewerAssignment) error {
+	assignment.AssignedAt = time.Now()
+
+	result, err := r.applicationCollection(appType).UpdateOne(ctx,
+		bson.M{
+			"_id":               id,
+			"status":            bson.M{"$in": openApplicationStatuses()},
+			"reviewers.user_id": bson.M{"$ne": assignment.UserID},
 		},
+		bson.M{
+			"$push": bson.M{"reviewers": assignment},
+			"$set":  bson.M{"updated_at": assignment.AssignedAt},
+		},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return r.reviewUpdateError(ctx, appType, id, assignment.UserID, ErrReviewerAssigned)
+	}
+	return nil
+}
+
+// RemoveReviewer removes a reviewer and their recommendation from an undecided application
+func (r *applicationRepository) RemoveReviewer(ctx context.Context, appType models.ApplicationType, id primitive.ObjectID, reviewerID primitive.ObjectID) error {
+	result, err := r.applicationCollection(appType).UpdateOne(ctx,
+		bson.M{
+			"_id":               id,
+			"status":            bson.M{"$in": openApplicationStatuses()},
+			"reviewers.user_id": reviewerID,
+		},
+		bson.M{
+			"$pull": bson.M{
+				"reviewers":       bson.M{"user_id": reviewerID},
+				"recommendations": bson.M{"reviewer_id": reviewerID},
+			},
+			"$set": bson.M{"updated_at": time.Now()},
+		},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return r.reviewUpdateError(ctx, appType, id, reviewerID, ErrNotAssignedReviewer)
+	}
+	return nil
+}
+
+// RecordRecommendation stores an assigned reviewer's recommendation on an undecided
+// application, replacing their previous one in the same update
+func (r *applicationRepository) RecordRecommendation(ctx context.Context, appType models.ApplicationType, id primitive.ObjectID, recommendation models.ReviewRecommendation) error {
+	recommendation.RecordedAt = time.Now()
+
+	others := bson.M{"$filter": bson.M{
+		"input": bson.M{"$ifNull": bson.A{"$recommendations", bson.A{}}},
+		"cond":  bson.M{"$ne": bson.A{"$$this.reviewer_id", recommendation.ReviewerID}},
+	}}
+	result, err := r.applicationCollection(appType).UpdateOne(ctx,
+		bson.M{
+			"_id":               id,
+			"status":            bson.M{"$in": openApplicationStatuses()},
+			"reviewers.user_id": recommendation.ReviewerID,
+		},
+		// $literal keeps notes starting with "$" from being read as field paths
+		mongo.Pipeline{{{Key: "$set", Value: bson.M{
+			"recommendations": bson.M{"$concatArrays": bson.A{others, bson.A{bson.M{"$literal": recommendation}}}},
+			"updated_at":      recommendation.RecordedAt,
+		}}}},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return r.reviewUpdateError(ctx, appType, id, recommendation.ReviewerID, ErrNotAssignedReviewer)
+	}
+	return nil
+}
+
+// reviewUpdateError explains why a reviewer update matched no application:
+// it does not exist, it was decided, or the reviewer check failed with reviewerErr
+func (r *applicationRepository) reviewUpdateError(ctx context.Context, appType models.ApplicationType, id, reviewerID primitive.ObjectID, reviewerErr error) error {
+	review, err := r.GetApplicationReview(ctx, appType, id)
+	if err != nil {
+		return err
+	}
+	if review.Status.IsFinal() {
+		return ErrReviewClosed
+	}
+	return reviewerErr
+}
+
+// openApplicationStatuses lists the statuses of applications still waiting for a decision
+func openApplicationStatuses() []models.ApplicationStatus {
+	return []models.ApplicationStatus{
+		models.ApplicationStatusPending, models.ApplicationStatusUnderReview,
+		models.ApplicationStatusNeedsInfo, models.ApplicationStatusResubmitted,
+	}
+}
+
+// ============= Review Comments =============
+
+func (r *applicationRepository) CreateComment(ctx context.Context, comment *models.ApplicationComment) error {
+	comment.CreatedAt = time.Now()
+
+	result, err := r.commentCollection.InsertOne(ctx, comment)
+	if err != nil {
+		return err
+	}
+
+	comment.ID = result.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+func (r *applicationRepository) GetCommentByID(ctx context.Context, id primitive.ObjectID) (*models.ApplicationComment, error) {
+	var comment models.ApplicationComment
+	if err := r.commentCollection.FindOne(ctx, bson.M{"_id": id}).Decode(&comment); err != nil {
+		return nil, err
+	}
+	return &comment, nil
+}
+
+// GetComments returns the comments on an application, oldest first
+func (r *applicationRepository) GetComments(ctx context.Context, applicationID primitive.ObjectID) ([]models.ApplicationComment, error) {
+	cursor, err := r.commentCollection.Find(ctx,
+		bson.M{"application_id": applicationID},
+		options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: 1}}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	comments := []models.ApplicationComment{}
+	if err := cursor.All(ctx, &comments); err != nil {
+		return nil, err
+	}
+	return comments, nil
+}
+
+// ============= Quorum Rules =============
+
+func (r *applicationRepository) GetQuorumRule(ctx context.Context, appType models.ApplicationType) (*models.QuorumRule, error) {
+	var rule models.QuorumRule
+	if err := r.quorumRuleCollection.FindOne(ctx, bson.M{"application_type": appType}).Decode(&rule); err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+// SaveQuorumRule creates or replaces the quorum rule of the rule's application type
+func (r *applicationRepository) SaveQuorumRule(ctx context.Context, rule *models.QuorumRule) error {
+	rule.UpdatedAt = time.Now()
+
+	var saved models.QuorumRule
+	err := r.quorumRuleCollection.FindOneAndUpdate(ctx,
+		bson.M{"application_type": rule.ApplicationType},
+		bson.M{"$set": bson.M{
+			"min_approvals":        rule.MinApprovals,
+			"required_areas":       rule.RequiredAreas,
+			"require_all_assigned": rule.RequireAllAssigned,
+			"allow_rejections":     rule.AllowRejections,
+			"updated_by":           rule.UpdatedBy,
+			"updated_at":           rule.UpdatedAt,
+		}},
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+	).Decode(&saved)
+	if err != nil {
+		return err
+	}
+
+	rule.ID = saved.ID
+	return nil
+}
+
+// ============= Reference Numbers =============
+
+// nextReference allocates the next reference number of the current year for an application type
+func (r *applicationRepository) nextReference(ctx context.Context, appType models.ApplicationType) (string, error) {
+	year := time.Now().Year()
+	sequence, err := nextCounterValue(ctx, r.counterCollection, models.ApplicationReferenceCounterKey(appType, year))
+	if err != nil {
+		return "", err
+	}
+	return models.FormatApplicationReference(appType, year, sequence), nil
+}
+
+// applicantEmailFilter matches any of the given email fields case-insensitively,
+// since applications keep the address as the applicant typed it
+func applicantEmailFilter(email string, fields ...string) []bson.M {
+	email = strings.TrimSpace(email)
+	if email == "" {
+		return []bson.M{}
+	}
+	pattern := primitive.Regex{Pattern: "^" + regexp.QuoteMeta(email) + "$", Options: "i"}
+	filters := make([]bson.M, 0, len(fields))
+	for _, field := range fields {
+		filters = append(filters, bson.M{field: pattern})
+	}
+	return filters
+}
+
+// ============= Reviewer Lists =============
+
+// applicationNameSortKeys are the fields the "name" sort uses for each application type
+var applicationNameSortKeys = map[models.ApplicationType][]string{
+	models.ApplicationTypeIndividual: {"last_name", "first_name"},
+	models.ApplicationTypeFirm:       {"firm_name"},
+}
+
+// listApplications finds a page of one application collection into out
+func (r *applicationRepository) listApplications(ctx context.Context, appType models.ApplicationType, filter models.ApplicationFilter, out interface{}) (int64, error) {
+	collection := r.applicationCollection(appType)
+	query := applicationQuery(filter, true)
+
+	total, err := collection.CountDocuments(ctx, query)
+	if err != nil {
+		return 0, err
+	}
+
+	sortBy := []string{filter.SortBy}
+	if filter.SortBy == "name" {
+		sortBy = applicationNameSortKeys[appType]
+	}
+	opts := options.Find().SetSort(applicationSort(filter, sortBy...))
+	if filter.Limit > 0 {
+		opts.SetLimit(int64(filter.Limit))
+	}
+	if filter.Offset > 0 {
+		opts.SetSkip(int64(filter.Offset))
+	}
+
+	cursor, err := collection.Find(ctx, query, opts)
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(ctx)
+
+	if err := cursor.All(ctx, out); err != nil {
+		return 0, err
+	}
+	return total, nil
+}
+
+// ListApplications returns a page of individual and firm applications as one list,
+// or of one type when the filter has a type, with the total count
+func (r *applicationRepository) ListApplications(ctx context.Context, filter models.ApplicationFilter) ([]models.ApplicationSummary, int64, error) {
+	var collection *mongo.Collection
+	var pipeline mongo.Pipeline
+	switch filter.Type {
+	case models.ApplicationTypeIndividual, models.ApplicationTypeFirm:
+		collection = r.applicationCollection(filter.Type)
+		pipeline = applicationSummaryPipeline(filter.Type, filter)
+	default:
+		collection = r.individualApplicationCollection
+		pipeline = append(applicationSummaryPipeline(models.ApplicationTypeIndividual, filter), bson.D{{Key: "$unionWith", Value: bson.M{
+			"coll":     r.firmApplicationCollection.Name(),
+			"pipeline": applicationSummaryPipeline(models.ApplicationTypeFirm, filter),
+		}}})
+	}
+
+	page := bson.A{bson.M{"$skip": filter.Offset}}
+	if filter.Limit > 0 {
+		page = append(page, bson.M{"$limit": filter.Limit})
+	}
+	pipeline = append(pipeline,
+		bson.D{{Key: "$sort", Value: applicationSort(filter, filter.SortBy)}},
+		bson.D{{Key: "$facet", Value: bson.M{
+			"items": page,
+			"total": bson.A{bson.M{"$count": "count"}},
+		}}},
+	)
+
+	cursor, err := collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var result []struct {
+		Items []models.ApplicationSummary `bson:"items"`
+		Total []struct {
+			Count int64 `bson:"count"`
+		} `bson:"total"`
+	}
+	if err := cursor.All(ctx, &result); err != nil {
+		return nil, 0, err
+	}
+
+	summaries := []models.ApplicationSummary{}
+	var total int64
+	if len(result) > 0 {
+		summaries = append(summaries, result[0].Items...)
+		if len(result[0].Total) > 0 {
+			total = result[0].Total[0].Count
+		}
+	}
+	return summaries, total, nil
+}
+
+// CountApplicationsByStatus counts the applications matching the filter in each status.
+// The filter's statuses are ignored so the dashboard always shows every status.
+func (r *applicationRepository) CountApplicationsByStatus(ctx context.Context, filter models.ApplicationFilter) (*models.ApplicationStatusCounts, error) {
+	counts := &models.ApplicationStatusCounts{
+		Individual: map[models.ApplicationStatus]int64{},
+		Firm:       map[models.ApplicationStatus]int64{},
+		Total:      map[models.ApplicationStatus]int64{},
+	}
+	for _, status := range models.ApplicationStatuses {
+		counts.Individual[status], counts.Firm[status], counts.Total[status] = 0, 0, 0
+	}
+
+	query := applicationQuery(filter, false)
+	for appType, byStatus := range map[models.ApplicationType]map[models.ApplicationStatus]int64{
+		models.ApplicationTypeIndividual: counts.Individual,
+		models.ApplicationTypeFirm:       counts.Firm,
+	} {
+		if filter.Type != "" && filter.Type != appType {
+			continue
+		}
+
+		cursor, err := r.applicationCollection(appType).Aggregate(ctx, mongo.Pipeline{
+			{{Key: "$match", Value: query}},
+			{{Key: "$group", Value: bson.M{"_id": "$status", "count": bson.M{"$sum": 1}}}},
+		})
+		if err != nil {
+			return nil, err
+		}
+		var groups []struct {
+			Status models.ApplicationStatus `bson:"_id"`
+			Count  int64                    `bson:"count"`
+		}
+		err = cursor.All(ctx, &groups)
+		cursor.Close(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, group := range groups {
+			byStatus[group.Status] += group.Count
+			counts.Total[group.Status] += group.Count
+		}
+	}
+	return counts, nil
+}
+
+// applicationCollection returns the collection holding applications of a type
+func (r *applicationRepository) applicationCollection(appType models.ApplicationType) *mongo.Collection {
+	if appType == models.ApplicationTypeFirm {
+		return r.firmApplicationCollection
+	}
+	return r.individualApplicationCollection
+}
+
+// applicationQuery builds the Mongo filter for an ApplicationFilter
+func applicationQuery(filter models.ApplicationFilter, withStatus bool) bson.M {
+	query := bson.M{}
+	if filter.Search != "" {
+		query["$text"] = bson.M{"$search": textSearch(filter.Search)}
+	}
+	if withStatus && len(filter.Statuses) > 0 {
+		query["status"] = bson.M{"$in": filter.Statuses}
+	}
+	if filter.From != nil || filter.To != nil {
+		submittedAt := bson.M{}
+		if filter.From != nil {
+			submittedAt["$gte"] = *filter.From
+		}
+		if filter.To != nil {
+			submittedAt["$lte"] = *filter.To
+		}
+		query["submitted_at"] = submittedAt
+	}
+	if city := strings.TrimSpace(filter.City); city != "" {
+		query["city"] = primitive.Regex{Pattern: "^" + regexp.QuoteMeta(city) + "$", Options: "i"}
+	}
+	return query
+}
+
+// textSearch quotes email addresses so they match as a phrase; unquoted, the text
+// index would match any application sharing their domain
+func textSearch(search string) string {
+	terms := strings.Fields(search)
+	for i, term := range terms {
+		if strings.Contains(term, "@") {
+			terms[i] = `"` + strings.ReplaceAll(term, `"`, "") + `"`
+		}
+	}
+	return strings.Join(terms, " ")
+}
+
+// applicationSort sorts on the given fields, newest submissions first by default,
+// with the ID as a tie-breaker so pages never overlap
+func applicationSort(filter models.ApplicationFilter, fields ...string) bson.D {
+	direction := 1
+	if filter.Descending {
+		direction = -1
+	}
+	sort := bson.D{}
+	for _, field := range fields {
+		if field != "" {
+			sort = append(sort, bson.E{Key: field, Value: direction})
+		}
+	}
+	if len(sort) == 0 {
+		direction = -1
+		sort = append(sort, bson.E{Key: "submitted_at", Value: direction})
+	}
+	return append(sort, bson.E{Key: "_id", Value: direction})
+}
+
+// applicationSummaryPipeline matches applications of one type and shapes them as ApplicationSummary
+func applicationSummaryPipeline(appType models.ApplicationType, filter models.ApplicationFilter) mongo.Pipeline {
+	name := interface{}("$firm_name")
+	if appType == models.ApplicationTypeIndividual {
+		name = bson.M{"$trim": bson.M{"input": bson.M{"$concat": bson.A{
+			bson.M{"$ifNull": bson.A{"$first_name", ""}}, " ",
+			bson.M{"$ifNull": bson.A{"$last_name", ""}},
+		}}}}
+	}
+
+	return mongo.Pipeline{
+		{{Key: "$match", Value: applicationQuery(filter, true)}},
+		{{Key: "$project", Value: bson.M{
+			"type":                bson.M{"$literal": appType},
+			"reference_number":    1,
+			"name":                name,
+			"email":               1,
+			"city":                1,
+			"status":              1,
+			"possible_duplicates": bson.M{"$size": bson.M{"$ifNull": bson.A{"$possible_duplicates", bson.A{}}}},
+			"submitted_at":        1,
+			"updated_at":          1,
+		}}},
+	}
+}
+
+// ============= Duplicate Detection =============
+
+// duplicateCandidateLimit caps the candidates loaded from each collection
+const duplicateCandidateLimit = 25
+
+// duplicateKeyFields are the indexed fields duplicate candidates are found by
+var duplicateKeyFields = []string{
+	"duplicate_keys.emails", "duplicate_keys.phones", "duplicate_keys.registrations", "duplicate_keys.names",
+}
+
+// FindDuplicateCandidates loads the open applications and members of the application type
+// that share an email, phone number, registration number or name prefix with the probe.
+// Each value is matched against the normalized keys stored with the records; scoring the
+// candidates is left to the caller.
+func (r *applicationRepository) FindDuplicateCandidates(ctx context.Context, appType models.ApplicationType, probe models.DuplicateProbe) (*models.DuplicateCandidates, error) {
+	candidates := &models.DuplicateCandidates{}
+	opts := options.Find().SetLimit(duplicateCandidateLimit)
+
+	var applicationCollection, memberCollection *mongo.Collection
+	var applications, members interface{}
+
+	switch appType {
+	case models.ApplicationTypeIndividual:
+		applicationCollection, memberCollection = r.individualApplicationCollection, r.individualMemberCollection
+		applications, members = &candidates.IndividualApplications, &candidates.IndividualMembers
+	case models.ApplicationTypeFirm:
+		applicationCollection, memberCollection = r.firmApplicationCollection, r.firmMemberCollection
+		applications, members = &candidates.FirmApplications, &candidates.FirmMembers
+	default:
+		return nil, fmt.Errorf("unknown application type %q", appType)
+	}
+
+	conditions := duplicateConditions(probe)
+	// Nothing to compare
+	if len(conditions) == 0 {
+		return candidates, nil
+	}
+
+	applicationFilter := bson.M{
+		"_id":    bson.M{"$ne": probe.ExcludeApplicationID},
+		"status": bson.M{"$in": models.OpenApplicationStatuses},
+		"$or":    conditions,
+	}
+	cursor, err := applicationCollection.Find(ctx, applicationFilter, opts)
+	if err != nil {
+		return nil, err
+	}
+	if err := cursor.All(ctx, applications); err != nil {
+		return nil, err
+	}
+
+	memberFilter := bson.M{"_id": bson.M{"$ne": probe.ExcludeMemberID}, "$or": conditions}
+	cursor, err = memberCollection.Find(ctx, memberFilter, opts)
+	if err != nil {
+		return nil, err
+	}
+	if err := cursor.All(ctx, members); err != nil {
+		return nil, err
+	}
+
+	return candidates, nil
+}
+
+// duplicateConditions matches the stored keys equal to any probe value
+func duplicateConditions(probe models.DuplicateProbe) []bson.M {
+	var conditions []bson.M
+	if len(probe.Emails) > 0 {
+		conditions = append(conditions, bson.M{"duplicate_keys.emails": bson.M{"$in": probe.Emails}})
+	}
+	if phones := phoneKeys(probe.Phones...); len(phones) > 0 {
+		conditions = append(conditions, bson.M{"duplicate_keys.phones": bson.M{"$in": phones}})
+	}
+	if probe.RegistrationNumber != "" {
+		conditions = append(conditions, bson.M{"duplicate_keys.registrations": probe.RegistrationNumber})
+	}
+	if probe.NamePrefix != "" {
+		conditions = append(conditions, bson.M{"duplicate_keys.names": probe.NamePrefix})
+	}
+	return conditions
+}
+
+// phoneKeys keeps the last seven digits of normalized phone numbers, which identify
+// the line whatever prefix was typed
+func phoneKeys(phones ...string) []string {
+	keys := make([]string, 0, len(phones))
+	for _, phone := range phones {
+		if len(phone) > 7 {
+			phone = phone[len(phone)-7:]
+		}
+		if phone != "" {
+			keys = append(keys, phone)
+		}
+	}
+	return keys
+}
+
+// normalizedKeys normalizes values and drops empty results and repeats
+func normalizedKeys(normalize func(string) string, values ...string) []string {
+	var keys []string
+	for _, value := range values {
+		if value = normalize(value); value != "" && !slices.Contains(keys, value) {
+			keys = append(keys, value)
+		}
+	}
+	return keys
+}
+
+func individualApplicationKeys(a *models.IndividualApplication) *models.DuplicateKeys {
+	return &models.DuplicateKeys{
+		Emails: normalizedKeys(utils.NormalizeEmail, a.Email),
+		Phones: phoneKeys(normalizedKeys(utils.NormalizePhone, a.Phone, a.MobilePhone)...),
+		Names:  utils.NamePrefixes(utils.NormalizeName(a.LastName), models.IndividualNamePrefixLength),
+	}
+}
+
+func individualMemberKeys(m *models.IndividualMember) *models.DuplicateKeys {
+	return &models.DuplicateKeys{
+		Emails: normalizedKeys(utils.NormalizeEmail, m.Email),
+		Phones: phoneKeys(normalizedKeys(utils.NormalizePhone, m.Phone)...),
+		Names:  utils.NamePrefixes(utils.NormalizeName(m.LastName), models.IndividualNamePrefixLength),
+	}
+}
+
+func firmApplicationKeys(a *models.FirmApplication) *models.DuplicateKeys {
+	return &models.DuplicateKeys{
+		Emails:        normalizedKeys(utils.NormalizeEmail, a.Email, a.RepresentativeEmail),
+		Phones:        phoneKeys(normalizedKeys(utils.NormalizePhone, a.Phone, a.RepresentativePhone)...),
+		Registrations: normalizedKeys(utils.NormalizeRegistrationNumber, a.RegistrationNumber),
+		Names:         utils.NamePrefixes(utils.NormalizeFirmName(a.FirmName+" "+a.TradeName), models.FirmNamePrefixLength),
+	}
+}
+
+func firmMemberKeys(m *models.FirmMember) *models.DuplicateKeys {
+	return &models.DuplicateKeys{
+		Emails:        normalizedKeys(utils.NormalizeEmail, m.PrimaryEmail, m.ContactPersonEmail),
+		Phones:        phoneKeys(normalizedKeys(utils.NormalizePhone, m.PrimaryPhone, m.SecondaryPhone, m.ContactPersonPhone)...),
+		Registrations: normalizedKeys(utils.NormalizeRegistrationNumber, m.RegistrationNumber, m.CommercialLicense),
+		Names:         utils.NamePrefixes(utils.NormalizeFirmName(m.FirmName), models.FirmNamePrefixLength),
+	}
+}
+
+// ensureDuplicateKeys indexes the duplicate keys of applications and members and stores
+// the keys of records saved before they existed
+func (r *applicationRepository) ensureDuplicateKeys(ctx context.Context) error {
+	backfills := map[*mongo.Collection]func(bson.Raw) (*models.DuplicateKeys, error){
+		r.individualApplicationCollection: func(raw bson.Raw) (*models.DuplicateKeys, error) {
+			var application models.IndividualApplication
+			err := bson.Unmarshal(raw, &application)
+			return individualApplicationKeys(&application), err
+		},
+		r.firmApplicationCollection: func(raw bson.Raw) (*models.DuplicateKeys, error) {
+			var application models.FirmApplication
+			err := bson.Unmarshal(raw, &application)
+			return firmApplicationKeys(&application), err
+		},
+		r.individualMemberCollection: func(raw bson.Raw) (*models.DuplicateKeys, error) {
+			var member models.IndividualMember
+			err := bson.Unmarshal(raw, &member)
+			return individualMemberKeys(&member), err
+		},
+		r.firmMemberCollection: func(raw bson.Raw) (*models.DuplicateKeys, error) {
+			var firm models.FirmMember
+			err := bson.Unmarshal(raw, &firm)
+			return firmMemberKeys(&firm), err
+		},
+	}
+
+	indexes := make([]mongo.IndexModel, 0, len(duplicateKeyFields))
+	for _, field := range duplicateKeyFields {
+		indexes = append(indexes, mongo.IndexModel{Keys: bson.D{{Key: field, Value: 1}}})
+	}
+
+	for collection, keysOf := range backfills {
+		if _, err := collection.Indexes().CreateMany(ctx, indexes); err != nil {
+			return err
+		}
+
+		if err := backfillDuplicateKeys(ctx, collection, keysOf); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// backfillDuplicateKeys sets the keys of the records of a collection that have none
+func backfillDuplicateKeys(ctx context.Context, collection *mongo.Collection, keysOf func(bson.Raw) (*models.DuplicateKeys, error)) error {
+	cursor, err := collection.Find(ctx, bson.M{"duplicate_keys": bson.M{"$exists": false}})
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		keys, err := keysOf(cursor.Current)
+		if err != nil {
+			return err
+		}
+		_, err = collection.UpdateOne(ctx,
+			bson.M{"_id": cursor.Current.Lookup("_id").ObjectID()},
+			bson.M{"$set": bson.M{"duplicate_keys": keys}},
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return cursor.Err()
+}
+
+// ============= Status Transitions =============
+
+// transitionApplication applies a status change to an individual or firm application,
+// made by the applicant when changedBy is zero and by a reviewer otherwise.
+// The update only matches while the application still has the status that was checked,
+// so two reviewers cannot both move it from the same state.
+func transitionApplication(ctx context.Context, collection *mongo.Collection, id primitive.ObjectID, status models.ApplicationStatus, notes string, changedBy primitive.ObjectID) error {
+	var current struct {
+		Status models.ApplicationStatus `bson:"status"`
+	}
+	err := collection.FindOne(ctx, bson.M{"_id": id}, options.FindOne().SetProjection(bson.M{"status": 1})).Decode(&current)
+	if err != nil {
+		return err
+	}
+
+	// Without a reviewer the applicant is acting on their own application
+	allowed := current.Status.CanTransitionTo(status)
+	if changedBy.IsZero() {
+		allowed = current.Status.CanApplicantTransitionTo(status)
+	}
+	if !allowed {
+		return fmt.Errorf("%w: %s to %s", ErrInvalidStatusTransition, current.Status, status)
+	}
+
+	now := time.Now()
+	var reviewer *primitive.ObjectID
+	if !changedBy.IsZero() {
+		reviewer = &changedBy
+	}
+	change := models.ApplicationStatusChange{
+		From:      current.Status,
+		To:        status,
+		Notes:     notes,
+		ChangedBy: reviewer,
+		ChangedAt: now,
+	}
+
+	set := bson.M{
+		"status":     status,
+		"updated_at": now,
+	}
+	if reviewer != nil {
+		set["review_notes"] = notes
+		set["reviewed_by"] = changedBy
+		set["reviewed_at"] = now
+	}
+
+	result, err := collection.UpdateOne(ctx,
+		bson.M{"_id": id, "status": current.Status},
+		bson.M{
+			"$set":  set,
+			"$push": bson.M{"status_history": change},
+		},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrStatusConflict
+	}
+	return nil
+}
+
+// ============= Approvals =============
+
+// ApproveIndividualApplication approves an application and creates its member record in one
+// transaction: the status change, the LACPA ID, the member and the account link either all
+// happen or none do. The approval fails with ErrQuorumNotMet until the assigned reviewers'
+// recommendations meet the quorum rule. Transactions need MongoDB running as a replica set.
+func (r *applicationRepository) ApproveIndividualApplication(ctx context.Context, id primitive.ObjectID, notes string, reviewedBy primitive.ObjectID, memberType string) (*models.IndividualMember, error) {
+	if memberType == "" {
+		memberType = models.MemberTypeApprentices
+	}
+
+	var member *models.IndividualMember
+	err := r.withTransaction(ctx, func(sc mongo.SessionContext) error {
+		var application models.IndividualApplication
+		if err := r.individualApplicationCollection.FindOne(sc, bson.M{"_id": id}).Decode(&application); err != nil {
+			return err
+		}
+		if err := transitionApplication(sc, r.individualApplicationCollection, id, models.ApplicationStatusApproved, notes, reviewedBy); err != nil {
+			return err
+		}
+		if err := r.requireQuorum(sc, models.ApplicationTypeIndividual, application.Reviewers, application.Recommendations); err != nil {
+			return err
+		}
+
+		member = application.ToMember(memberType, time.Now())
+		lacpaID, err := nextLACPAID(sc, r.counterCollection, models.LACPAIDIndividual)
+		if err != nil {
+			return err
+		}
+		member.LacpaID = lacpaID
+		member.DuplicateKeys = individualMemberKeys(member)
+
+		result, err := r.individualMemberCollection.InsertOne(sc, member)
+		if err != nil {
+			return err
+		}
+		member.ID = result.InsertedID.(primitive.ObjectID)
+
+		userID, err := r.linkApplicant(sc, application.Email, "individual_member_id", member.ID)
+		if err != nil {
+			return err
+		}
+		if userID != nil {
+			member.UserID = userID
+			if _, err := r.individualMemberCollection.UpdateOne(sc, bson.M{"_id": member.ID}, bson.M{"$set": bson.M{"user_id": userID}}); err != nil {
+				return err
+			}
+		}
+
+		_, err = r.individualApplicationCollection.UpdateOne(sc, bson.M{"_id": id}, bson.M{"$set": bson.M{"member_id": member.ID}})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return member, nil
+}
+
+// ApproveFirmApplication approves a firm application and creates its firm member record
+// in one transaction, like ApproveIndividualApplication.
+func (r *applicationRepository) ApproveFirmApplication(ctx context.Context, id primitive.ObjectID, notes string, reviewedBy primitive.ObjectID) (*models.FirmMember, error) {
+	var firm *models.FirmMember
+	err := r.withTransaction(ctx, func(sc mongo.SessionContext) error {
+		var application models.FirmApplication
+		if err := r.firmApplicationCollection.FindOne(sc, bson.M{"_id": id}).Decode(&application); err != nil {
+			return err
+		}
+		if err := transitionApplication(sc, r.firmApplicationCollection, id, models.ApplicationStatusApproved, notes, reviewedBy); err != nil {
+			return err
+		}
+		if err := r.requireQuorum(sc, models.ApplicationTypeFirm, application.Reviewers, application.Recommendations); err != nil {
+			return err
+		}
+
+		firm = application.ToMember(time.Now())
+		lacpaID, err := nextLACPAID(sc, r.counterCollection, models.LACPAIDFirm)
+		if err != nil {
+			return err
+		}
+		firm.LacpaID = lacpaID
+		firm.DuplicateKeys = firmMemberKeys(firm)
+
+		result, err := r.firmMemberCollection.InsertOne(sc, firm)
+		if err != nil {
+			return err
+		}
+		firm.ID = result.InsertedID.(primitive.ObjectID)
+
+		// The representative's account manages the firm profile
+		email := application.RepresentativeEmail
+		if email == "" {
+			email = application.Email
+		}
+		userID, err := r.linkApplicant(sc, email, "firm_member_id", firm.ID)
+		if err != nil {
+			return err
+		}
+		if userID != nil {
+			firm.UserID = userID
+			if _, err := r.firmMemberCollection.UpdateOne(sc, bson.M{"_id": firm.ID}, bson.M{"$set": bson.M{"user_id": userID}}); err != nil {
+				return err
+			}
+		}
+
+		_, err = r.firmApplicationCollection.UpdateOne(sc, bson.M{"_id": id}, bson.M{"$set": bson.M{"member_id": firm.ID}})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return firm, nil
+}
+
+// requireQuorum checks the recommendations of an application's assigned reviewers against
+// the quorum rule of its type, or the default rule when none is stored
+func (r *applicationRepository) requireQuorum(ctx context.Context, appType models.ApplicationType, reviewers []models.ReviewerAssignment, recommendations []models.ReviewRecommendation) error {
+	rule, err := r.GetQuorumRule(ctx, appType)
+	if err == mongo.ErrNoDocuments {
+		rule = models.DefaultQuorumRule(appType)
+	} else if err != nil {
+		return err
+	}
+
+	if quorum := rule.Evaluate(reviewers, recommendations); !quorum.Met {
+		return fmt.Errorf("%w: %s", ErrQuorumNotMet, strings.Join(quorum.Unmet, "; "))
+	}
+	return nil
+}
+
+// requirePaidInvoice blocks the review of an application until its fee is paid.
+// An application without an invoice is only blocked while its type charges a fee,
+// so the invoice has to be issued first.
+func (r *applicationRepository) requirePaidInvoice(ctx context.Context, appType models.ApplicationType, applicationID primitive.ObjectID) error {
+	var invoice models.Invoice
+	err := r.invoiceCollection.FindOne(ctx, bson.M{"application_id": applicationID}).Decode(&invoice)
+	if err == mongo.ErrNoDocuments {
+		var fee models.ApplicationFee
+		err := r.feeCollection.FindOne(ctx, bson.M{"application_type": appType}).Decode(&fee)
+		if err == mongo.ErrNoDocuments || (err == nil && !fee.Charged()) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		return fmt.Errorf("%w: no invoice has been issued yet", ErrFeeUnpaid)
+	}
+	if err != nil {
+		return err
+	}
+	if invoice.Status != models.InvoiceStatusPaid {
+		return fmt.Errorf("%w: invoice %s is %s", ErrFeeUnpaid, invoice.Number, invoice.Status)
+	}
+	return nil
+}
+
+// linkApplicant links the new member record to the verified account registered with the
+// applicant's email, unless that account already has a profile of this kind.
+// It returns nil when there is no account to link.
+func (r *applicationRepository) linkApplicant(sc mongo.SessionContext, email, field string, memberID primitive.ObjectID) (*primitive.ObjectID, error) {
+	email = strings.ToLower(strings.TrimSpace(email))
+	if email == "" {
+		return nil, nil
+	}
+
+	var user struct {
+		ID primitive.ObjectID `bson:"_id"`
+	}
+	err := r.userCollection.FindOneAndUpdate(
+		sc,
+		bson.M{
+			"email":       email,
+			"is_verified": true,
+			field:         bson.M{"$exists": false},
+		},
+		bson.M{"$set": bson.M{field: memberID, "updated_at": time.Now()}},
+		options.FindOneAndUpdate().SetProjection(bson.M{"_id": 1}),
+	).Decode(&user)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &user.ID, nil
+}
+
+// withTransaction runs fn in a transaction, retrying on transient errors
+func (r *applicationRepository) withTransaction(ctx context.Context, fn func(sc mongo.SessionContext) error) error {
+	session, err := r.client.StartSession()
+	if err != nil {
+		return err
 	}
+	defer session.EndSession(ctx)
 
-	_, err := r.firmApplicationCollection.UpdateOne(ctx, bson.M{"_id": id}, update)
+	_, err = session.WithTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+		return nil, fn(sc)
+	})
 	return err
 }
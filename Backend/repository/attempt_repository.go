@@ -0,0 +1,126 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/AliSleiman0/Lacpa/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type AttemptRepository struct {
+	collection *mongo.Collection
+}
+
+func NewAttemptRepository(db *mongo.Database) *AttemptRepository {
+	return &AttemptRepository{
+		collection: db.Collection("auth_attempts"),
+	}
+}
+
+// EnsureIndexes creates the unique key index and the TTL index that forgets stale counters
+func (r *AttemptRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "key", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys:    bson.D{{Key: "expires_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+	})
+	return err
+}
+
+// GetAttempt retrieves the counter for a key, or nil if there were no recent failures
+func (r *AttemptRepository) GetAttempt(ctx context.Context, key string) (*models.AuthAttempt, error) {
+	var attempt models.AuthAttempt
+	err := r.collection.FindOne(ctx, bson.M{"key": key}).Decode(&attempt)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &attempt, nil
+}
+
+// RecordFailure increments the failure counter of a key and applies a lockout
+// once the policy's limit is reached. The returned bool is true when this
+// failure started a new lockout.
+func (r *AttemptRepository) RecordFailure(ctx context.Context, key string, policy models.LockoutPolicy) (*models.AuthAttempt, bool, error) {
+	now := time.Now()
+
+	// A lockout that already elapsed starts a fresh count
+	_, err := r.collection.DeleteOne(ctx, bson.M{"key": key, "locked_until": bson.M{"$lte": now}})
+	if err != nil {
+		return nil, false, err
+	}
+
+	var attempt models.AuthAttempt
+	err = r.collection.FindOneAndUpdate(
+		ctx,
+		bson.M{"key": key},
+		bson.M{
+			"$inc": bson.M{"failures": 1},
+			"$set": bson.M{
+				"last_failure_at": now,
+				"expires_at":      now.Add(policy.Window),
+			},
+		},
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+	).Decode(&attempt)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if attempt.Failures < policy.MaxFailures || attempt.LockedUntil != nil {
+		return &attempt, false, nil
+	}
+
+	lockedUntil := now.Add(policy.LockoutDuration)
+	expiresAt := lockedUntil
+	if window := now.Add(policy.Window); window.After(expiresAt) {
+		expiresAt = window
+	}
+	_, err = r.collection.UpdateOne(
+		ctx,
+		bson.M{"key": key},
+		bson.M{"$set": bson.M{"locked_until": lockedUntil, "expires_at": expiresAt}},
+	)
+	if err != nil {
+		return nil, false, err
+	}
+	attempt.LockedUntil = &lockedUntil
+	return &attempt, true, nil
+}
+
+// Release takes back one failure counted for a key, lifting the lockout if the
+// count drops below the policy's limit again
+func (r *AttemptRepository) Release(ctx context.Context, key string, policy models.LockoutPolicy) error {
+	_, err := r.collection.UpdateOne(
+		ctx,
+		bson.M{"key": key, "failures": bson.M{"$gt": 0}},
+		mongo.Pipeline{
+			{{Key: "$set", Value: bson.M{"failures": bson.M{"$subtract": bson.A{"$failures", 1}}}}},
+			{{Key: "$set", Value: bson.M{"locked_until": bson.M{"$cond": bson.A{
+				bson.M{"$gte": bson.A{"$failures", policy.MaxFailures}},
+				"$locked_until",
+				"$$REMOVE",
+			}}}}},
+		},
+	)
+	return err
+}
+
+// Reset clears the counters of the given keys
+func (r *AttemptRepository) Reset(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	_, err := r.collection.DeleteMany(ctx, bson.M{"key": bson.M{"$in": keys}})
+	return err
+}
@@ -8,6 +8,7 @@ import (
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type AuthRepository struct {
@@ -20,18 +21,42 @@ func NewAuthRepository(db *mongo.Database) *AuthRepository {
 	}
 }
 
-// CreateUser creates a new user
+// EnsureIndexes creates the unique indexes on LACPA ID, email and SSO subject
+func (r *AuthRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "lacpa_id", Value: 1}},
+			Options: options.Index().SetUnique(true).SetName("lacpa_id_unique"),
+		},
+		{
+			Keys:    bson.D{{Key: "email", Value: 1}},
+			Options: options.Index().SetUnique(true).SetName("email_unique"),
+		},
+		{
+			Keys:    bson.D{{Key: "oidc_subject", Value: 1}},
+			Options: options.Index().SetUnique(true).SetSparse(true).SetName("oidc_subject_unique"),
+		},
+	})
+	return err
+}
+
+// CreateUser creates a new user. The caller decides whether the email is
+// verified: self-registered accounts start unverified, staff accounts do not.
 func (r *AuthRepository) CreateUser(user *models.User) error {
-	user.ID = primitive.NewObjectID()
-	user.CreatedAt = time.Now()
-	user.UpdatedAt = time.Now()
-	user.IsActive = true
-	user.IsVerified = false
+	prepareNewUser(user, time.Now())
 
 	_, err := r.collection.InsertOne(context.Background(), user)
 	return err
 }
 
+// prepareNewUser fills in the fields every new account starts with
+func prepareNewUser(user *models.User, now time.Time) {
+	user.ID = primitive.NewObjectID()
+	user.CreatedAt = now
+	user.UpdatedAt = now
+	user.IsActive = true
+}
+
 // GetUserByEmail retrieves a user by email
 func (r *AuthRepository) GetUserByEmail(email string) (*models.User, error) {
 	var user models.User
@@ -73,40 +98,6 @@ func (r *AuthRepository) UpdateUser(user *models.User) error {
 	return err
 }
 
-// SetOTP sets OTP and expiry for a user
-func (r *AuthRepository) SetOTP(email, otp string, expiry time.Time) error {
-	_, err := r.collection.UpdateOne(
-		context.Background(),
-		bson.M{"email": email},
-		bson.M{
-			"$set": bson.M{
-				"otp":        otp,
-				"otp_expiry": expiry,
-				"updated_at": time.Now(),
-			},
-		},
-	)
-	return err
-}
-
-// ClearOTP clears OTP fields for a user
-func (r *AuthRepository) ClearOTP(email string) error {
-	_, err := r.collection.UpdateOne(
-		context.Background(),
-		bson.M{"email": email},
-		bson.M{
-			"$unset": bson.M{
-				"otp":        "",
-				"otp_expiry": "",
-			},
-			"$set": bson.M{
-				"updated_at": time.Now(),
-			},
-		},
-	)
-	return err
-}
-
 // SetResetToken sets reset token and expiry for a user
 func (r *AuthRepository) SetResetToken(email, token string, expiry time.Time) error {
 	_, err := r.collection.UpdateOne(
@@ -159,16 +150,40 @@ func (r *AuthRepository) ClearResetToken(userID primitive.ObjectID) error {
 	return err
 }
 
-// UpdatePassword updates user password
-func (r *AuthRepository) UpdatePassword(userID primitive.ObjectID, hashedPassword string) error {
+// UpdatePassword updates user password and keeps the replaced hash in the
+// password history, trimmed to the last historySize entries
+func (r *AuthRepository) UpdatePassword(userID primitive.ObjectID, hashedPassword string, historySize int) error {
+	if historySize <= 0 {
+		_, err := r.collection.UpdateOne(
+			context.Background(),
+			bson.M{"_id": userID},
+			bson.M{
+				"$set": bson.M{
+					"password":   hashedPassword,
+					"updated_at": time.Now(),
+				},
+				"$unset": bson.M{"password_history": ""},
+			},
+		)
+		return err
+	}
+
+	// Pipeline update so the old hash is read and moved in the same write
 	_, err := r.collection.UpdateOne(
 		context.Background(),
 		bson.M{"_id": userID},
-		bson.M{
-			"$set": bson.M{
+		mongo.Pipeline{
+			{{Key: "$set", Value: bson.M{
+				"password_history": bson.M{"$slice": bson.A{
+					bson.M{"$concatArrays": bson.A{
+						bson.A{"$password"},
+						bson.M{"$ifNull": bson.A{"$password_history", bson.A{}}},
+					}},
+					historySize,
+				}},
 				"password":   hashedPassword,
 				"updated_at": time.Now(),
-			},
+			}}},
 		},
 	)
 	return err
@@ -203,3 +218,234 @@ func (r *AuthRepository) VerifyUser(email string) error {
 	)
 	return err
 }
+
+// AddSecurityEvent appends an event to the user's security history, keeping the latest 100
+func (r *AuthRepository) AddSecurityEvent(userID primitive.ObjectID, event models.SecurityEvent) error {
+	_, err := r.collection.UpdateOne(
+		context.Background(),
+		bson.M{"_id": userID},
+		bson.M{
+			"$push": bson.M{
+				"security_history": bson.M{
+					"$each":  []models.SecurityEvent{event},
+					"$slice": -100,
+				},
+			},
+		},
+	)
+	return err
+}
+
+// SetPendingTwoFactorSecret stores an encrypted TOTP secret awaiting confirmation
+func (r *AuthRepository) SetPendingTwoFactorSecret(userID primitive.ObjectID, encryptedSecret string) error {
+	_, err := r.collection.UpdateOne(
+		context.Background(),
+		bson.M{"_id": userID},
+		bson.M{
+			"$set": bson.M{
+				"two_factor.pending_secret": encryptedSecret,
+				"updated_at":                time.Now(),
+			},
+		},
+	)
+	return err
+}
+
+// EnableTwoFactor promotes the confirmed secret and stores the recovery code hashes.
+// step is the time step of the confirming code so it cannot be replayed at login.
+func (r *AuthRepository) EnableTwoFactor(userID primitive.ObjectID, encryptedSecret string, recoveryCodeHashes []string, step int64) error {
+	now := time.Now()
+	_, err := r.collection.UpdateOne(
+		context.Background(),
+		bson.M{"_id": userID},
+		bson.M{
+			"$set": bson.M{
+				"two_factor": models.TwoFactor{
+					Enabled:       true,
+					Secret:        encryptedSecret,
+					RecoveryCodes: recoveryCodeHashes,
+					LastUsedStep:  step,
+					EnabledAt:     &now,
+				},
+				"updated_at": now,
+			},
+		},
+	)
+	return err
+}
+
+// DisableTwoFactor removes the TOTP enrollment and recovery codes
+func (r *AuthRepository) DisableTwoFactor(userID primitive.ObjectID) error {
+	_, err := r.collection.UpdateOne(
+		context.Background(),
+		bson.M{"_id": userID},
+		bson.M{
+			"$unset": bson.M{"two_factor": ""},
+			"$set":   bson.M{"updated_at": time.Now()},
+		},
+	)
+	return err
+}
+
+// UseTOTPStep records a time step as used. It returns false when the step,
+// or a later one, was already accepted, which means the code is a replay.
+func (r *AuthRepository) UseTOTPStep(userID primitive.ObjectID, step int64) (bool, error) {
+	result, err := r.collection.UpdateOne(
+		context.Background(),
+		bson.M{
+			"_id": userID,
+			"$or": bson.A{
+				bson.M{"two_factor.last_used_step": bson.M{"$lt": step}},
+				bson.M{"two_factor.last_used_step": bson.M{"$exists": false}},
+			},
+		},
+		bson.M{"$set": bson.M{"two_factor.last_used_step": step}},
+	)
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount == 1, nil
+}
+
+// ConsumeRecoveryCode removes a recovery code hash. It returns false when the code was not available.
+func (r *AuthRepository) ConsumeRecoveryCode(userID primitive.ObjectID, codeHash string) (bool, error) {
+	result, err := r.collection.UpdateOne(
+		context.Background(),
+		bson.M{"_id": userID, "two_factor.recovery_codes": codeHash},
+		bson.M{
+			"$pull": bson.M{"two_factor.recovery_codes": codeHash},
+			"$set":  bson.M{"updated_at": time.Now()},
+		},
+	)
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount == 1, nil
+}
+
+// ReplaceRecoveryCodes swaps all recovery code hashes for a new set
+func (r *AuthRepository) ReplaceRecoveryCodes(userID primitive.ObjectID, recoveryCodeHashes []string) error {
+	_, err := r.collection.UpdateOne(
+		context.Background(),
+		bson.M{"_id": userID},
+		bson.M{
+			"$set": bson.M{
+				"two_factor.recovery_codes": recoveryCodeHashes,
+				"updated_at":                time.Now(),
+			},
+		},
+	)
+	return err
+}
+
+// LinkMemberProfile stores the claimed individual or firm record on the user.
+// field is "individual_member_id" or "firm_member_id".
+func (r *AuthRepository) LinkMemberProfile(userID primitive.ObjectID, field string, memberID primitive.ObjectID) error {
+	_, err := r.collection.UpdateOne(
+		context.Background(),
+		bson.M{"_id": userID},
+		bson.M{
+			"$set": bson.M{
+				field:        memberID,
+				"updated_at": time.Now(),
+			},
+		},
+	)
+	return err
+}
+
+// UpdateEmail changes the email of a user. Fails with a duplicate key error if it is taken.
+func (r *AuthRepository) UpdateEmail(userID primitive.ObjectID, email string) error {
+	_, err := r.collection.UpdateOne(
+		context.Background(),
+		bson.M{"_id": userID},
+		bson.M{
+			"$set": bson.M{
+				"email":       email,
+				"is_verified": true,
+				"updated_at":  time.Now(),
+			},
+		},
+	)
+	return err
+}
+
+// UpdateFullName changes the full name of a user
+func (r *AuthRepository) UpdateFullName(userID primitive.ObjectID, fullName string) error {
+	_, err := r.collection.UpdateOne(
+		context.Background(),
+		bson.M{"_id": userID},
+		bson.M{
+			"$set": bson.M{
+				"full_name":  fullName,
+				"updated_at": time.Now(),
+			},
+		},
+	)
+	return err
+}
+
+// SetActive activates or deactivates a user
+func (r *AuthRepository) SetActive(userID primitive.ObjectID, active bool) error {
+	_, err := r.collection.UpdateOne(
+		context.Background(),
+		bson.M{"_id": userID},
+		bson.M{
+			"$set": bson.M{
+				"is_active":  active,
+				"updated_at": time.Now(),
+			},
+		},
+	)
+	return err
+}
+
+// DeleteUser permanently deletes a user
+func (r *AuthRepository) DeleteUser(userID primitive.ObjectID) error {
+	_, err := r.collection.DeleteOne(context.Background(), bson.M{"_id": userID})
+	return err
+}
+
+// GetUserByOIDCSubject retrieves the user linked to a single sign-on identity
+func (r *AuthRepository) GetUserByOIDCSubject(subject string) (*models.User, error) {
+	var user models.User
+	err := r.collection.FindOne(context.Background(), bson.M{"oidc_subject": subject}).Decode(&user)
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// LinkOIDCSubject links a user to a single sign-on identity. Only an unlinked
+// user can be linked, so an account never silently moves to another identity.
+func (r *AuthRepository) LinkOIDCSubject(userID primitive.ObjectID, subject string) (bool, error) {
+	result, err := r.collection.UpdateOne(
+		context.Background(),
+		bson.M{"_id": userID, "oidc_subject": bson.M{"$exists": false}},
+		bson.M{
+			"$set": bson.M{
+				"oidc_subject": subject,
+				"updated_at":   time.Now(),
+			},
+		},
+	)
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount == 1, nil
+}
+
+// UpdateRole sets the role of a user
+func (r *AuthRepository) UpdateRole(userID primitive.ObjectID, role string) error {
+	_, err := r.collection.UpdateOne(
+		context.Background(),
+		bson.M{"_id": userID},
+		bson.M{
+			"$set": bson.M{
+				"role":       role,
+				"updated_at": time.Now(),
+			},
+		},
+	)
+	return err
+}
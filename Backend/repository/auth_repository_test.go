@@ -0,0 +1,39 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"github.com/AliSleiman0/Lacpa/models"
+)
+
+func TestPrepareNewUserKeepsVerification(t *testing.T) {
+	tests := []struct {
+		name string
+		user models.User
+	}{
+		{name: "self-registered member", user: models.User{Email: "member@example.com", Role: models.RoleMember}},
+		{name: "admin created by an admin", user: models.User{Email: "admin@example.com", Role: models.RoleAdmin, IsVerified: true}},
+	}
+
+	now := time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user := tt.user
+			prepareNewUser(&user, now)
+
+			if user.IsVerified != tt.user.IsVerified {
+				t.Fatalf("IsVerified = %v, want %v", user.IsVerified, tt.user.IsVerified)
+			}
+			if !user.IsActive {
+				t.Fatal("new user is not active")
+			}
+			if user.ID.IsZero() {
+				t.Fatal("new user has no ID")
+			}
+			if !user.CreatedAt.Equal(now) || !user.UpdatedAt.Equal(now) {
+				t.Fatalf("timestamps = %v, %v, want %v", user.CreatedAt, user.UpdatedAt, now)
+			}
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/AliSleiman0/Lacpa/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// CounterRepository hands out atomic sequence numbers from the counters collection
+type CounterRepository struct {
+	collection *mongo.Collection
+}
+
+func NewCounterRepository(db *mongo.Database) *CounterRepository {
+	return &CounterRepository{
+		collection: db.Collection("counters"),
+	}
+}
+
+// Next increments the named counter and returns its new value, starting at 1
+func (r *CounterRepository) Next(ctx context.Context, key string) (int64, error) {
+	return nextCounterValue(ctx, r.collection, key)
+}
+
+// NextLACPAID allocates the next LACPA ID of the current year for an entity type
+func (r *CounterRepository) NextLACPAID(ctx context.Context, entity string) (string, error) {
+	return nextLACPAID(ctx, r.collection, entity)
+}
+
+// nextCounterValue increments a counter document. Pass a session context to
+// allocate inside a transaction, so an aborted transaction does not burn the number.
+func nextCounterValue(ctx context.Context, collection *mongo.Collection, key string) (int64, error) {
+	var counter models.Counter
+	err := collection.FindOneAndUpdate(
+		ctx,
+		bson.M{"_id": key},
+		bson.M{"$inc": bson.M{"value": 1}},
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+	).Decode(&counter)
+	if err != nil {
+		return 0, err
+	}
+	return counter.Value, nil
+}
+
+// nextLACPAID allocates the next LACPA ID of the current year from the counters collection
+func nextLACPAID(ctx context.Context, collection *mongo.Collection, entity string) (string, error) {
+	year := time.Now().Year()
+	sequence, err := nextCounterValue(ctx, collection, models.LACPAIDCounterKey(entity, year))
+	if err != nil {
+		return "", err
+	}
+	return models.FormatLACPAID(entity, year, sequence), nil
+}
+
+// IsDuplicateKeyOn reports whether err is a duplicate key error on the given field
+func IsDuplicateKeyOn(err error, field string) bool {
+	return mongo.IsDuplicateKeyError(err) && strings.Contains(err.Error(), field)
+}
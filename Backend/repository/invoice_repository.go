@@ -0,0 +1,287 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/AliSleiman0/Lacpa/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ErrInvoicePaid is returned when a payment is recorded for an invoice that is already paid
+var ErrInvoicePaid = errors.New("invoice is already paid")
+
+// InvoiceRepository stores the application fees and the invoices issued for them
+type InvoiceRepository interface {
+	EnsureInvoiceIndexes(ctx context.Context) error
+
+	// Application Fees
+	GetFees(ctx context.Context) ([]models.ApplicationFee, error)
+	GetFee(ctx context.Context, appType models.ApplicationType) (*models.ApplicationFee, error)
+	SaveFee(ctx context.Context, fee *models.ApplicationFee) error
+
+	// Invoices
+	CreateInvoice(ctx context.Context, invoice *models.Invoice) error
+	GetInvoiceByID(ctx context.Context, id primitive.ObjectID) (*models.Invoice, error)
+	GetInvoiceByNumber(ctx context.Context, number string) (*models.Invoice, error)
+	GetInvoiceByApplication(ctx context.Context, applicationID primitive.ObjectID) (*models.Invoice, error)
+	ListInvoices(ctx context.Context, filter models.InvoiceFilter) ([]models.Invoice, int64, error)
+	SetInvoiceCheckout(ctx context.Context, id primitive.ObjectID, provider, sessionID string) error
+	MarkInvoicePaid(ctx context.Context, id primitive.ObjectID, payment *models.InvoicePayment) error
+}
+
+type invoiceRepository struct {
+	client            *mongo.Client
+	feeCollection     *mongo.Collection
+	invoiceCollection *mongo.Collection
+	counterCollection *mongo.Collection
+}
+
+func NewInvoiceRepository(db *mongo.Database) InvoiceRepository {
+	return &invoiceRepository{
+		client:            db.Client(),
+		feeCollection:     db.Collection("application_fees"),
+		invoiceCollection: db.Collection("invoices"),
+		counterCollection: db.Collection("counters"),
+	}
+}
+
+// EnsureInvoiceIndexes creates the one-fee-per-type, invoice number, one-invoice-per-application
+// and overdue indexes
+func (r *invoiceRepository) EnsureInvoiceIndexes(ctx context.Context) error {
+	_, err := r.feeCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "application_type", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = r.invoiceCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "number", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys:    bson.D{{Key: "application_id", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "due_date", Value: 1}}},
+	})
+	return err
+}
+
+// ============= Application Fees =============
+
+func (r *invoiceRepository) GetFees(ctx context.Context) ([]models.ApplicationFee, error) {
+	cursor, err := r.feeCollection.Find(ctx, bson.M{}, options.Find().SetSort(bson.D{{Key: "application_type", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	fees := []models.ApplicationFee{}
+	if err := cursor.All(ctx, &fees); err != nil {
+		return nil, err
+	}
+	return fees, nil
+}
+
+func (r *invoiceRepository) GetFee(ctx context.Context, appType models.ApplicationType) (*models.ApplicationFee, error) {
+	var fee models.ApplicationFee
+	if err := r.feeCollection.FindOne(ctx, bson.M{"application_type": appType}).Decode(&fee); err != nil {
+		return nil, err
+	}
+	return &fee, nil
+}
+
+// SaveFee creates or replaces the fee of the fee's application type
+func (r *invoiceRepository) SaveFee(ctx context.Context, fee *models.ApplicationFee) error {
+	fee.UpdatedAt = time.Now()
+
+	var saved models.ApplicationFee
+	err := r.feeCollection.FindOneAndUpdate(ctx,
+		bson.M{"application_type": fee.ApplicationType},
+		bson.M{"$set": bson.M{
+			"amount":     fee.Amount,
+			"currency":   fee.Currency,
+			"due_days":   fee.DueDays,
+			"is_active":  fee.IsActive,
+			"updated_by": fee.UpdatedBy,
+			"updated_at": fee.UpdatedAt,
+		}},
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+	).Decode(&saved)
+	if err != nil {
+		return err
+	}
+
+	fee.ID = saved.ID
+	return nil
+}
+
+// ============= Invoices =============
+
+// CreateInvoice allocates the next invoice number of the current year and stores the invoice
+// in one transaction, so a failed insert does not leave a gap in the numbering.
+// An application can only have one invoice; a second one fails with a duplicate key error.
+func (r *invoiceRepository) CreateInvoice(ctx context.Context, invoice *models.Invoice) error {
+	return r.withTransaction(ctx, func(sc mongo.SessionContext) error {
+		now := time.Now()
+		sequence, err := nextCounterValue(sc, r.counterCollection, models.InvoiceCounterKey(now.Year()))
+		if err != nil {
+			return err
+		}
+		invoice.Number = models.FormatInvoiceNumber(now.Year(), sequence)
+		invoice.Status = models.InvoiceStatusUnpaid
+		invoice.IssuedAt = now
+		invoice.UpdatedAt = now
+
+		result, err := r.invoiceCollection.InsertOne(sc, invoice)
+		if err != nil {
+			return err
+		}
+
+		invoice.ID = result.InsertedID.(primitive.ObjectID)
+		return nil
+	})
+}
+
+func (r *invoiceRepository) GetInvoiceByID(ctx context.Context, id primitive.ObjectID) (*models.Invoice, error) {
+	return r.findInvoice(ctx, bson.M{"_id": id})
+}
+
+func (r *invoiceRepository) GetInvoiceByNumber(ctx context.Context, number string) (*models.Invoice, error) {
+	return r.findInvoice(ctx, bson.M{"number": number})
+}
+
+func (r *invoiceRepository) GetInvoiceByApplication(ctx context.Context, applicationID primitive.ObjectID) (*models.Invoice, error) {
+	return r.findInvoice(ctx, bson.M{"application_id": applicationID})
+}
+
+func (r *invoiceRepository) findInvoice(ctx context.Context, filter bson.M) (*models.Invoice, error) {
+	var invoice models.Invoice
+	if err := r.invoiceCollection.FindOne(ctx, filter).Decode(&invoice); err != nil {
+		return nil, err
+	}
+	return &invoice, nil
+}
+
+// ListInvoices returns a page of invoices, newest first, and the number of matching invoices
+func (r *invoiceRepository) ListInvoices(ctx context.Context, filter models.InvoiceFilter) ([]models.Invoice, int64, error) {
+	query := bson.M{}
+	if filter.Status != "" {
+		query["status"] = filter.Status
+	}
+	if filter.ApplicationType != "" {
+		query["application_type"] = filter.ApplicationType
+	}
+	if filter.Overdue {
+		query["status"] = models.InvoiceStatusUnpaid
+		query["due_date"] = bson.M{"$lt": time.Now()}
+	}
+	if search := strings.TrimSpace(filter.Search); search != "" {
+		pattern := primitive.Regex{Pattern: regexp.QuoteMeta(search), Options: "i"}
+		query["$or"] = []bson.M{
+			{"number": pattern},
+			{"reference_number": pattern},
+			{"billed_to": pattern},
+			{"email": pattern},
+		}
+	}
+
+	total, err := r.invoiceCollection.CountDocuments(ctx, query)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "issued_at", Value: -1}, {Key: "_id", Value: -1}}).
+		SetSkip(int64(filter.Offset))
+	if filter.Limit > 0 {
+		opts.SetLimit(int64(filter.Limit))
+	}
+	cursor, err := r.invoiceCollection.Find(ctx, query, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	invoices := []models.Invoice{}
+	if err := cursor.All(ctx, &invoices); err != nil {
+		return nil, 0, err
+	}
+	return invoices, total, nil
+}
+
+// SetInvoiceCheckout records the payment provider checkout started for an unpaid invoice
+func (r *invoiceRepository) SetInvoiceCheckout(ctx context.Context, id primitive.ObjectID, provider, sessionID string) error {
+	result, err := r.invoiceCollection.UpdateOne(ctx,
+		bson.M{"_id": id, "status": models.InvoiceStatusUnpaid},
+		bson.M{"$set": bson.M{
+			"payment_provider":   provider,
+			"payment_session_id": sessionID,
+			"updated_at":         time.Now(),
+		}},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return r.unpaidInvoiceError(ctx, id)
+	}
+	return nil
+}
+
+// MarkInvoicePaid records the payment of an unpaid invoice.
+// The update only matches while the invoice is unpaid, so a payment is never recorded twice.
+func (r *invoiceRepository) MarkInvoicePaid(ctx context.Context, id primitive.ObjectID, payment *models.InvoicePayment) error {
+	result, err := r.invoiceCollection.UpdateOne(ctx,
+		bson.M{"_id": id, "status": models.InvoiceStatusUnpaid},
+		bson.M{"$set": bson.M{
+			"status":     models.InvoiceStatusPaid,
+			"payment":    payment,
+			"paid_at":    payment.PaidAt,
+			"updated_at": time.Now(),
+		}},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return r.unpaidInvoiceError(ctx, id)
+	}
+	return nil
+}
+
+// unpaidInvoiceError explains why an update of an unpaid invoice matched nothing
+func (r *invoiceRepository) unpaidInvoiceError(ctx context.Context, id primitive.ObjectID) error {
+	count, err := r.invoiceCollection.CountDocuments(ctx, bson.M{"_id": id})
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return ErrInvoicePaid
+}
+
+// withTransaction runs fn in a transaction, retrying on transient errors
+func (r *invoiceRepository) withTransaction(ctx context.Context, fn func(sc mongo.SessionContext) error) error {
+	session, err := r.client.StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+		return nil, fn(sc)
+	})
+	return err
+}
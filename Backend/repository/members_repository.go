@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"github.com/AliSleiman0/Lacpa/models"
 	"go.mongodb.org/mongo-driver/bson"
@@ -21,6 +22,12 @@ type MembersRepository interface {
 	DeleteIndividualMember(ctx context.Context, id primitive.ObjectID) error
 	CountIndividualMembers(ctx context.Context) (int64, error)
 	GetIndividualMemberMetrics(ctx context.Context) (*models.MemberMetrics, error)
+	EnsureMemberIndexes(ctx context.Context) error
+	GetIndividualMemberByLacpaID(ctx context.Context, lacpaID string) (*models.IndividualMember, error)
+	LinkIndividualMemberUser(ctx context.Context, id, userID primitive.ObjectID) (bool, error)
+	UnlinkIndividualMemberUser(ctx context.Context, id, userID primitive.ObjectID) error
+	SearchIndividualMembers(ctx context.Context, filter models.MemberDirectoryFilter) ([]*models.IndividualMember, int64, error)
+	CountIndividualMemberFacets(ctx context.Context, filter models.MemberDirectoryFilter) (*models.MemberFacets, error)
 
 	// Firm Members
 	GetFirmMemberByID(ctx context.Context, id primitive.ObjectID) (*models.FirmMember, error)
@@ -32,6 +39,11 @@ type MembersRepository interface {
 	DeleteFirmMember(ctx context.Context, id primitive.ObjectID) error
 	CountFirmMembers(ctx context.Context) (int64, error)
 	GetFirmMemberMetrics(ctx context.Context) (*models.FirmMetrics, error)
+	GetFirmMemberByLacpaID(ctx context.Context, lacpaID string) (*models.FirmMember, error)
+	LinkFirmMemberUser(ctx context.Context, id, userID primitive.ObjectID) (bool, error)
+	UnlinkFirmMemberUser(ctx context.Context, id, userID primitive.ObjectID) error
+	SearchFirmMembers(ctx context.Context, filter models.MemberDirectoryFilter) ([]*models.FirmMember, int64, error)
+	CountFirmMemberFacets(ctx context.Context, filter models.MemberDirectoryFilter) (*models.MemberFacets, error)
 }
 
 // membersRepository implements MembersRepository interface
@@ -50,6 +62,57 @@ func NewMembersRepository(db *mongo.Database) MembersRepository {
 	}
 }
 
+// ========================================
+// INDEXES
+// ========================================
+
+// EnsureMemberIndexes creates the unique registry number indexes and the text indexes
+// of the directory search. Records without a number are not indexed.
+func (r *membersRepository) EnsureMemberIndexes(ctx context.Context) error {
+	index := mongo.IndexModel{
+		Keys: bson.D{{Key: "lacpa_id", Value: 1}},
+		Options: options.Index().
+			SetUnique(true).
+			SetName("lacpa_id_unique").
+			SetPartialFilterExpression(bson.M{"lacpa_id": bson.M{"$type": "string", "$gt": ""}}),
+	}
+	if _, err := r.individualMembersCol.Indexes().CreateOne(ctx, index); err != nil {
+		return err
+	}
+	if _, err := r.firmMembersCol.Indexes().CreateOne(ctx, index); err != nil {
+		return err
+	}
+
+	// Names weigh most, then the firm, specializations and city
+	textIndexes := map[*mongo.Collection]mongo.IndexModel{
+		r.individualMembersCol: {
+			Keys: bson.D{
+				{Key: "full_name", Value: "text"}, {Key: "first_name", Value: "text"}, {Key: "last_name", Value: "text"},
+				{Key: "firm", Value: "text"}, {Key: "specializations", Value: "text"}, {Key: "city", Value: "text"},
+				{Key: "search_tags", Value: "text"},
+			},
+			Options: options.Index().SetName("directory_search").SetWeights(bson.M{
+				"full_name": 10, "first_name": 10, "last_name": 10, "firm": 5, "specializations": 3, "city": 2,
+			}),
+		},
+		r.firmMembersCol: {
+			Keys: bson.D{
+				{Key: "firm_name", Value: "text"}, {Key: "specializations", Value: "text"},
+				{Key: "city", Value: "text"}, {Key: "search_tags", Value: "text"},
+			},
+			Options: options.Index().SetName("directory_search").SetWeights(bson.M{
+				"firm_name": 10, "specializations": 3, "city": 2,
+			}),
+		},
+	}
+	for collection, textIndex := range textIndexes {
+		if _, err := collection.Indexes().CreateOne(ctx, textIndex); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // ========================================
 // INDIVIDUAL MEMBERS METHODS
 // ========================================
@@ -138,6 +201,7 @@ func (r *membersRepository) GetIndividualMembersByType(ctx context.Context, memb
 
 // CreateIndividualMember creates a new individual member
 func (r *membersRepository) CreateIndividualMember(ctx context.Context, member *models.IndividualMember) error {
+	member.DuplicateKeys = individualMemberKeys(member)
 	result, err := r.individualMembersCol.InsertOne(ctx, member)
 	if err != nil {
 		return err
@@ -148,6 +212,7 @@ func (r *membersRepository) CreateIndividualMember(ctx context.Context, member *
 
 // UpdateIndividualMember updates an existing individual member
 func (r *membersRepository) UpdateIndividualMember(ctx context.Context, member *models.IndividualMember) error {
+	member.DuplicateKeys = individualMemberKeys(member)
 	filter := bson.M{"_id": member.ID}
 	update := bson.M{"$set": member}
 	_, err := r.individualMembersCol.UpdateOne(ctx, filter, update)
@@ -320,6 +385,7 @@ func (r *membersRepository) GetFirmMembersBySize(ctx context.Context, firmSize s
 
 // CreateFirmMember creates a new firm member
 func (r *membersRepository) CreateFirmMember(ctx context.Context, firm *models.FirmMember) error {
+	firm.DuplicateKeys = firmMemberKeys(firm)
 	result, err := r.firmMembersCol.InsertOne(ctx, firm)
 	if err != nil {
 		return err
@@ -330,6 +396,7 @@ func (r *membersRepository) CreateFirmMember(ctx context.Context, firm *models.F
 
 // UpdateFirmMember updates an existing firm member
 func (r *membersRepository) UpdateFirmMember(ctx context.Context, firm *models.FirmMember) error {
+	firm.DuplicateKeys = firmMemberKeys(firm)
 	filter := bson.M{"_id": firm.ID}
 	update := bson.M{"$set": firm}
 	_, err := r.firmMembersCol.UpdateOne(ctx, filter, update)
@@ -378,3 +445,221 @@ func (r *membersRepository) GetFirmMemberMetrics(ctx context.Context) (*models.F
 
 	return metrics, nil
 }
+
+// ========================================
+// ACCOUNT LINKING METHODS
+// ========================================
+
+// GetIndividualMemberByLacpaID retrieves an individual member by registry number
+func (r *membersRepository) GetIndividualMemberByLacpaID(ctx context.Context, lacpaID string) (*models.IndividualMember, error) {
+	var member models.IndividualMember
+	err := r.individualMembersCol.FindOne(ctx, bson.M{"lacpa_id": lacpaID}).Decode(&member)
+	if err != nil {
+		return nil, err
+	}
+	return &member, nil
+}
+
+// LinkIndividualMemberUser records the account that claimed a member record.
+// It returns false when the record is already claimed by another account.
+func (r *membersRepository) LinkIndividualMemberUser(ctx context.Context, id, userID primitive.ObjectID) (bool, error) {
+	return linkMemberUser(ctx, r.individualMembersCol, id, userID)
+}
+
+// UnlinkIndividualMemberUser releases a member record claimed by the account
+func (r *membersRepository) UnlinkIndividualMemberUser(ctx context.Context, id, userID primitive.ObjectID) error {
+	return unlinkMemberUser(ctx, r.individualMembersCol, id, userID)
+}
+
+// GetFirmMemberByLacpaID retrieves a firm member by registry number
+func (r *membersRepository) GetFirmMemberByLacpaID(ctx context.Context, lacpaID string) (*models.FirmMember, error) {
+	var firm models.FirmMember
+	err := r.firmMembersCol.FindOne(ctx, bson.M{"lacpa_id": lacpaID}).Decode(&firm)
+	if err != nil {
+		return nil, err
+	}
+	return &firm, nil
+}
+
+// LinkFirmMemberUser records the account that claimed a firm record.
+// It returns false when the record is already claimed by another account.
+func (r *membersRepository) LinkFirmMemberUser(ctx context.Context, id, userID primitive.ObjectID) (bool, error) {
+	return linkMemberUser(ctx, r.firmMembersCol, id, userID)
+}
+
+// UnlinkFirmMemberUser releases a firm record claimed by the account
+func (r *membersRepository) UnlinkFirmMemberUser(ctx context.Context, id, userID primitive.ObjectID) error {
+	return unlinkMemberUser(ctx, r.firmMembersCol, id, userID)
+}
+
+// linkMemberUser sets user_id on a record that is unclaimed or already claimed by the same user
+func linkMemberUser(ctx context.Context, collection *mongo.Collection, id, userID primitive.ObjectID) (bool, error) {
+	result, err := collection.UpdateOne(
+		ctx,
+		bson.M{
+			"_id": id,
+			"$or": bson.A{
+				bson.M{"user_id": bson.M{"$exists": false}},
+				bson.M{"user_id": nil},
+				bson.M{"user_id": userID},
+			},
+		},
+		bson.M{"$set": bson.M{"user_id": userID, "updated_at": time.Now()}},
+	)
+	if err != nil {
+		return false, err
+	}
+	return result.MatchedCount == 1, nil
+}
+
+// unlinkMemberUser clears user_id when it still points at the given user
+func unlinkMemberUser(ctx context.Context, collection *mongo.Collection, id, userID primitive.ObjectID) error {
+	_, err := collection.UpdateOne(
+		ctx,
+		bson.M{"_id": id, "user_id": userID},
+		bson.M{
+			"$unset": bson.M{"user_id": ""},
+			"$set":   bson.M{"updated_at": time.Now()},
+		},
+	)
+	return err
+}
+
+// ========================================
+// DIRECTORY SEARCH METHODS
+// ========================================
+
+// SearchIndividualMembers returns a page of the individual member directory with the total count.
+// Text searches list the best matches first, other searches sort by name.
+func (r *membersRepository) SearchIndividualMembers(ctx context.Context, filter models.MemberDirectoryFilter) ([]*models.IndividualMember, int64, error) {
+	members := []*models.IndividualMember{}
+	sort := bson.D{{Key: "last_name", Value: 1}, {Key: "first_name", Value: 1}}
+	total, err := searchDirectory(ctx, r.individualMembersCol, filter, individualDirectoryFacets(filter), sort, &members)
+	if err != nil {
+		return nil, 0, err
+	}
+	return members, total, nil
+}
+
+// CountIndividualMemberFacets counts the individual members matching a search for each facet value
+func (r *membersRepository) CountIndividualMemberFacets(ctx context.Context, filter models.MemberDirectoryFilter) (*models.MemberFacets, error) {
+	return countDirectoryFacets(ctx, r.individualMembersCol, filter, individualDirectoryFacets(filter))
+}
+
+// SearchFirmMembers returns a page of the firm directory with the total count
+func (r *membersRepository) SearchFirmMembers(ctx context.Context, filter models.MemberDirectoryFilter) ([]*models.FirmMember, int64, error) {
+	firms := []*models.FirmMember{}
+	sort := bson.D{{Key: "firm_name", Value: 1}}
+	total, err := searchDirectory(ctx, r.firmMembersCol, filter, firmDirectoryFacets(filter), sort, &firms)
+	if err != nil {
+		return nil, 0, err
+	}
+	return firms, total, nil
+}
+
+// CountFirmMemberFacets counts the firms matching a search for each facet value
+func (r *membersRepository) CountFirmMemberFacets(ctx context.Context, filter models.MemberDirectoryFilter) (*models.MemberFacets, error) {
+	return countDirectoryFacets(ctx, r.firmMembersCol, filter, firmDirectoryFacets(filter))
+}
+
+// directoryFacet is a facet of the directory, the field it counts and the value chosen in the filter
+type directoryFacet struct {
+	name  string // Key in models.MemberFacets
+	field string
+	value string
+}
+
+func individualDirectoryFacets(filter models.MemberDirectoryFilter) []directoryFacet {
+	return []directoryFacet{
+		{name: "member_type", field: "member_type", value: filter.MemberType},
+		{name: "governorate", field: "governorate", value: filter.Governorate},
+		{name: "district", field: "district", value: filter.District},
+		{name: "specialization", field: "specializations", value: filter.Specialization},
+		{name: "dues_status", field: "dues_status", value: filter.DuesStatus},
+	}
+}
+
+// firmDirectoryFacets uses the firm type as member type and adds the firm size
+func firmDirectoryFacets(filter models.MemberDirectoryFilter) []directoryFacet {
+	facets := individualDirectoryFacets(filter)
+	facets[0].field = "firm_type"
+	return append(facets, directoryFacet{name: "firm_size", field: "firm_size", value: filter.FirmSize})
+}
+
+// directoryQuery builds the Mongo filter of the chosen facet values, leaving out the facet named except
+func directoryQuery(facets []directoryFacet, except string) bson.M {
+	query := bson.M{}
+	for _, facet := range facets {
+		if facet.value != "" && facet.name != except {
+			query[facet.field] = facet.value
+		}
+	}
+	return query
+}
+
+// searchDirectory finds a page of a member collection into out and counts every match
+func searchDirectory(ctx context.Context, collection *mongo.Collection, filter models.MemberDirectoryFilter, facets []directoryFacet, sort bson.D, out interface{}) (int64, error) {
+	query := directoryQuery(facets, "")
+	if filter.Search != "" {
+		query["$text"] = bson.M{"$search": filter.Search}
+		sort = append(bson.D{{Key: "score", Value: bson.M{"$meta": "textScore"}}}, sort...)
+	}
+
+	total, err := collection.CountDocuments(ctx, query)
+	if err != nil {
+		return 0, err
+	}
+
+	opts := options.Find().
+		SetSkip(int64(filter.Offset)).
+		SetLimit(int64(filter.Limit)).
+		SetSort(append(sort, bson.E{Key: "_id", Value: 1}))
+	cursor, err := collection.Find(ctx, query, opts)
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(ctx)
+
+	if err := cursor.All(ctx, out); err != nil {
+		return 0, err
+	}
+	return total, nil
+}
+
+// countDirectoryFacets counts the values of every facet in one aggregation.
+// The text search must be the first stage, so it runs before the $facet branches.
+func countDirectoryFacets(ctx context.Context, collection *mongo.Collection, filter models.MemberDirectoryFilter, facets []directoryFacet) (*models.MemberFacets, error) {
+	pipeline := mongo.Pipeline{}
+	if filter.Search != "" {
+		pipeline = append(pipeline, bson.D{{Key: "$match", Value: bson.M{"$text": bson.M{"$search": filter.Search}}}})
+	}
+
+	branches := bson.M{}
+	for _, facet := range facets {
+		stages := bson.A{bson.M{"$match": directoryQuery(facets, facet.name)}}
+		if facet.field == "specializations" {
+			stages = append(stages, bson.M{"$unwind": "$" + facet.field})
+		}
+		branches[facet.name] = append(stages,
+			bson.M{"$match": bson.M{facet.field: bson.M{"$nin": bson.A{"", nil}}}},
+			bson.M{"$group": bson.M{"_id": "$" + facet.field, "count": bson.M{"$sum": 1}}},
+			bson.M{"$sort": bson.D{{Key: "count", Value: -1}, {Key: "_id", Value: 1}}},
+		)
+	}
+	pipeline = append(pipeline, bson.D{{Key: "$facet", Value: branches}})
+
+	cursor, err := collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var result []models.MemberFacets
+	if err := cursor.All(ctx, &result); err != nil {
+		return nil, err
+	}
+	if len(result) == 0 {
+		return &models.MemberFacets{}, nil
+	}
+	return &result[0], nil
+}
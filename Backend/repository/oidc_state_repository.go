@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/AliSleiman0/Lacpa/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// OIDCStateRepository stores pending single sign-on logins between the
+// redirect to the provider and the callback
+type OIDCStateRepository struct {
+	collection *mongo.Collection
+}
+
+func NewOIDCStateRepository(db *mongo.Database) *OIDCStateRepository {
+	return &OIDCStateRepository{
+		collection: db.Collection("oidc_login_states"),
+	}
+}
+
+// EnsureIndexes creates the unique state index and the TTL index
+func (r *OIDCStateRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "state_hash", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys:    bson.D{{Key: "expires_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+	})
+	return err
+}
+
+// CreateState stores a pending login
+func (r *OIDCStateRepository) CreateState(ctx context.Context, state *models.OIDCLoginState) error {
+	state.CreatedAt = time.Now()
+	_, err := r.collection.InsertOne(ctx, state)
+	return err
+}
+
+// ConsumeState removes and returns an unexpired pending login, so each state
+// can complete at most one callback
+func (r *OIDCStateRepository) ConsumeState(ctx context.Context, stateHash string) (*models.OIDCLoginState, error) {
+	var state models.OIDCLoginState
+	err := r.collection.FindOneAndDelete(ctx, bson.M{
+		"state_hash": stateHash,
+		"expires_at": bson.M{"$gt": time.Now()},
+	}).Decode(&state)
+	if err != nil {
+		return nil, err
+	}
+	return &state, nil
+}
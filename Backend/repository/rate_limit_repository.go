@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/AliSleiman0/Lacpa/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// RateLimitRepository stores token buckets in Mongo so every instance shares them.
+// It satisfies middleware.RateLimitStore.
+type RateLimitRepository struct {
+	collection *mongo.Collection
+}
+
+func NewRateLimitRepository(db *mongo.Database) *RateLimitRepository {
+	return &RateLimitRepository{
+		collection: db.Collection("rate_limit_buckets"),
+	}
+}
+
+// EnsureIndexes creates the TTL index that drops idle buckets
+func (r *RateLimitRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "expires_at", Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	})
+	return err
+}
+
+// Take refills and consumes a token atomically with a single pipeline update,
+// so concurrent requests on different instances cannot overspend the bucket
+func (r *RateLimitRepository) Take(ctx context.Context, key string, capacity int, refillEvery time.Duration) (models.RateLimitResult, error) {
+	now := time.Now()
+	refillMillis := float64(refillEvery.Milliseconds())
+	fullAfter := time.Duration(float64(capacity) * float64(refillEvery))
+
+	pipeline := mongo.Pipeline{
+		// Refill for the time elapsed since the last update
+		{{Key: "$set", Value: bson.M{
+			"tokens": bson.M{"$min": bson.A{
+				float64(capacity),
+				bson.M{"$add": bson.A{
+					bson.M{"$ifNull": bson.A{"$tokens", float64(capacity)}},
+					bson.M{"$divide": bson.A{
+						bson.M{"$subtract": bson.A{now, bson.M{"$ifNull": bson.A{"$updated_at", now}}}},
+						refillMillis,
+					}},
+				}},
+			}},
+			"updated_at": now,
+			"expires_at": now.Add(fullAfter),
+		}}},
+		// Decide and consume
+		{{Key: "$set", Value: bson.M{
+			"allowed": bson.M{"$gte": bson.A{"$tokens", 1}},
+		}}},
+		{{Key: "$set", Value: bson.M{
+			"tokens": bson.M{"$cond": bson.A{"$allowed", bson.M{"$subtract": bson.A{"$tokens", 1}}, "$tokens"}},
+		}}},
+	}
+
+	var bucket models.RateLimitBucket
+	err := r.collection.FindOneAndUpdate(
+		ctx,
+		bson.M{"_id": key},
+		pipeline,
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+	).Decode(&bucket)
+	if err != nil {
+		return models.RateLimitResult{}, err
+	}
+
+	return models.NewRateLimitResult(bucket.Allowed, bucket.Tokens, capacity, refillEvery), nil
+}
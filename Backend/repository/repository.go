@@ -46,6 +46,7 @@ type Repository interface {
 	EventRepository
 	MembersRepository
 	ApplicationRepository
+	InvoiceRepository
 }
 type MongoRepositoryManager struct {
 	MainRepository
@@ -53,6 +54,7 @@ type MongoRepositoryManager struct {
 	EventRepository
 	MembersRepository
 	ApplicationRepository
+	InvoiceRepository
 	// Future repositories will be added here as embedded interfaces
 	// ItemRepository
 	// UserRepository
@@ -65,6 +67,7 @@ func NewMongoRepository(db *mongo.Database) Repository {
 		EventRepository:       NewEventRepository(db),
 		MembersRepository:     NewMembersRepository(db),
 		ApplicationRepository: NewApplicationRepository(db),
+		InvoiceRepository:     NewInvoiceRepository(db),
 		// Future repositories will be initialized here:
 		// OrderRepository: NewOrderRepository(db),
 	}
@@ -0,0 +1,178 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/AliSleiman0/Lacpa/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ErrRoleInUse is returned when deleting a role that is still assigned to users
+var ErrRoleInUse = errors.New("role is still assigned to users")
+
+// ErrSystemRole is returned when deleting a built-in role
+var ErrSystemRole = errors.New("built-in roles cannot be deleted")
+
+type RoleRepository struct {
+	collection      *mongo.Collection
+	usersCollection *mongo.Collection
+}
+
+func NewRoleRepository(db *mongo.Database) *RoleRepository {
+	return &RoleRepository{
+		collection:      db.Collection("roles"),
+		usersCollection: db.Collection("users"),
+	}
+}
+
+// EnsureIndexes creates the unique index on role names
+func (r *RoleRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "name", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	return err
+}
+
+// EnsureDefaultRoles inserts the built-in roles that do not exist yet.
+// Existing roles are left untouched so admin edits survive restarts, except
+// that the admin role is topped up with newly added permissions.
+func (r *RoleRepository) EnsureDefaultRoles(ctx context.Context) error {
+	now := time.Now()
+	for _, role := range models.DefaultRoles() {
+		_, err := r.collection.UpdateOne(
+			ctx,
+			bson.M{"name": role.Name},
+			bson.M{
+				"$setOnInsert": bson.M{
+					"description": role.Description,
+					"permissions": role.Permissions,
+					"version":     1,
+					"is_system":   role.IsSystem,
+					"require_mfa": role.RequireMFA,
+					"created_at":  now,
+					"updated_at":  now,
+				},
+			},
+			options.Update().SetUpsert(true),
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	// The admin role always holds every permission, including ones added by later releases
+	_, err := r.collection.UpdateOne(
+		ctx,
+		bson.M{"name": models.RoleAdmin, "permissions": bson.M{"$not": bson.M{"$all": models.AllPermissions}}},
+		bson.M{
+			"$addToSet": bson.M{"permissions": bson.M{"$each": models.AllPermissions}},
+			"$inc":      bson.M{"version": 1},
+			"$set":      bson.M{"updated_at": now},
+		},
+	)
+	return err
+}
+
+// GetAllRoles retrieves every role sorted by name
+func (r *RoleRepository) GetAllRoles(ctx context.Context) ([]models.Role, error) {
+	cursor, err := r.collection.Find(ctx, bson.M{}, options.Find().SetSort(bson.D{{Key: "name", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var roles []models.Role
+	if err := cursor.All(ctx, &roles); err != nil {
+		return nil, err
+	}
+	return roles, nil
+}
+
+// GetRoleByName retrieves a role by its unique name
+func (r *RoleRepository) GetRoleByName(ctx context.Context, name string) (*models.Role, error) {
+	var role models.Role
+	err := r.collection.FindOne(ctx, bson.M{"name": name}).Decode(&role)
+	if err != nil {
+		return nil, err
+	}
+	return &role, nil
+}
+
+// GetRoleVersion returns the current version of a role
+func (r *RoleRepository) GetRoleVersion(ctx context.Context, name string) (int, error) {
+	role, err := r.GetRoleByName(ctx, name)
+	if err != nil {
+		return 0, err
+	}
+	return role.Version, nil
+}
+
+// CreateRole creates a new custom role
+func (r *RoleRepository) CreateRole(ctx context.Context, role *models.Role) error {
+	role.ID = primitive.NewObjectID()
+	role.Version = 1
+	role.IsSystem = false
+	role.CreatedAt = time.Now()
+	role.UpdatedAt = time.Now()
+
+	_, err := r.collection.InsertOne(ctx, role)
+	return err
+}
+
+// UpdateRole applies the fields set in the request to a role and bumps its version
+// so tokens minted with the old permissions are rejected
+func (r *RoleRepository) UpdateRole(ctx context.Context, name string, update models.UpdateRoleRequest) (*models.Role, error) {
+	set := bson.M{"updated_at": time.Now()}
+	if update.Description != nil {
+		set["description"] = *update.Description
+	}
+	if update.Permissions != nil {
+		set["permissions"] = update.Permissions
+	}
+	if update.RequireMFA != nil {
+		set["require_mfa"] = *update.RequireMFA
+	}
+
+	var role models.Role
+	err := r.collection.FindOneAndUpdate(
+		ctx,
+		bson.M{"name": name},
+		bson.M{
+			"$set": set,
+			"$inc": bson.M{"version": 1},
+		},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&role)
+	if err != nil {
+		return nil, err
+	}
+	return &role, nil
+}
+
+// DeleteRole deletes a custom role that is not assigned to any user
+func (r *RoleRepository) DeleteRole(ctx context.Context, name string) error {
+	role, err := r.GetRoleByName(ctx, name)
+	if err != nil {
+		return err
+	}
+	if role.IsSystem {
+		return ErrSystemRole
+	}
+
+	inUse, err := r.usersCollection.CountDocuments(ctx, bson.M{"role": name})
+	if err != nil {
+		return err
+	}
+	if inUse > 0 {
+		return ErrRoleInUse
+	}
+
+	_, err = r.collection.DeleteOne(ctx, bson.M{"_id": role.ID})
+	return err
+}
@@ -0,0 +1,142 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/AliSleiman0/Lacpa/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type SessionRepository struct {
+	collection *mongo.Collection
+}
+
+func NewSessionRepository(db *mongo.Database) *SessionRepository {
+	return &SessionRepository{
+		collection: db.Collection("sessions"),
+	}
+}
+
+// EnsureIndexes creates lookup indexes and the TTL index that purges expired sessions
+func (r *SessionRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "refresh_token_hash", Value: 1}}},
+		{Keys: bson.D{{Key: "previous_token_hash", Value: 1}}},
+		{Keys: bson.D{{Key: "user_id", Value: 1}}},
+		{
+			Keys:    bson.D{{Key: "expires_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+	})
+	return err
+}
+
+// CreateSession creates a new session
+func (r *SessionRepository) CreateSession(ctx context.Context, session *models.Session) error {
+	session.ID = primitive.NewObjectID()
+	session.CreatedAt = time.Now()
+	session.LastUsedAt = session.CreatedAt
+
+	_, err := r.collection.InsertOne(ctx, session)
+	return err
+}
+
+// GetSessionByRefreshHash finds the session whose current or previous refresh token has the given hash
+func (r *SessionRepository) GetSessionByRefreshHash(ctx context.Context, hash string) (*models.Session, error) {
+	var session models.Session
+	err := r.collection.FindOne(ctx, bson.M{
+		"$or": []bson.M{
+			{"refresh_token_hash": hash},
+			{"previous_token_hash": hash},
+		},
+	}).Decode(&session)
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// RotateRefreshToken replaces the refresh token of an active session.
+// It only succeeds if oldHash is still current, so concurrent refreshes
+// with the same token cannot both win.
+func (r *SessionRepository) RotateRefreshToken(ctx context.Context, id primitive.ObjectID, oldHash, newHash, ipAddress, userAgent string) (bool, error) {
+	result, err := r.collection.UpdateOne(
+		ctx,
+		bson.M{
+			"_id":                id,
+			"refresh_token_hash": oldHash,
+			"revoked_at":         bson.M{"$exists": false},
+		},
+		bson.M{
+			"$set": bson.M{
+				"refresh_token_hash":  newHash,
+				"previous_token_hash": oldHash,
+				"ip_address":          ipAddress,
+				"user_agent":          userAgent,
+				"last_used_at":        time.Now(),
+			},
+		},
+	)
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount == 1, nil
+}
+
+// IsSessionActive checks if the session exists, is not revoked and has not expired
+func (r *SessionRepository) IsSessionActive(ctx context.Context, id string) (bool, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return false, nil
+	}
+
+	count, err := r.collection.CountDocuments(ctx, bson.M{
+		"_id":        objectID,
+		"revoked_at": bson.M{"$exists": false},
+		"expires_at": bson.M{"$gt": time.Now()},
+	})
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// RevokeSession revokes a single session
+func (r *SessionRepository) RevokeSession(ctx context.Context, id primitive.ObjectID, reason string) error {
+	_, err := r.collection.UpdateOne(
+		ctx,
+		bson.M{"_id": id, "revoked_at": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"revoked_at": time.Now(), "revoked_reason": reason}},
+	)
+	return err
+}
+
+// RevokeAllForUser revokes every active session of a user and returns how many were revoked
+func (r *SessionRepository) RevokeAllForUser(ctx context.Context, userID primitive.ObjectID, reason string) (int64, error) {
+	result, err := r.collection.UpdateMany(
+		ctx,
+		bson.M{"user_id": userID, "revoked_at": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"revoked_at": time.Now(), "revoked_reason": reason}},
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.ModifiedCount, nil
+}
+
+// RevokeOtherSessions revokes every active session of a user except the given one
+func (r *SessionRepository) RevokeOtherSessions(ctx context.Context, userID, keepID primitive.ObjectID, reason string) (int64, error) {
+	result, err := r.collection.UpdateMany(
+		ctx,
+		bson.M{"user_id": userID, "_id": bson.M{"$ne": keepID}, "revoked_at": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"revoked_at": time.Now(), "revoked_reason": reason}},
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.ModifiedCount, nil
+}
@@ -0,0 +1,117 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/AliSleiman0/Lacpa/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// VerificationRepository stores hashed email verification codes
+type VerificationRepository struct {
+	collection *mongo.Collection
+}
+
+func NewVerificationRepository(db *mongo.Database) *VerificationRepository {
+	return &VerificationRepository{
+		collection: db.Collection("verification_codes"),
+	}
+}
+
+// EnsureIndexes creates the one-code-per-email-and-purpose index and the TTL index
+func (r *VerificationRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "email", Value: 1}, {Key: "purpose", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys:    bson.D{{Key: "expires_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+	})
+	return err
+}
+
+// ReplaceCode stores a code, replacing any earlier code for the same email and purpose
+func (r *VerificationRepository) ReplaceCode(ctx context.Context, code *models.VerificationCode) error {
+	code.CreatedAt = time.Now()
+	code.Attempts = 0
+
+	var stored models.VerificationCode
+	err := r.collection.FindOneAndUpdate(
+		ctx,
+		bson.M{"email": code.Email, "purpose": code.Purpose},
+		bson.M{
+			"$set": bson.M{
+				"code_hash":  code.CodeHash,
+				"attempts":   0,
+				"subject":    code.Subject,
+				"created_at": code.CreatedAt,
+				"expires_at": code.ExpiresAt,
+			},
+		},
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+	).Decode(&stored)
+	if err != nil {
+		return err
+	}
+	code.ID = stored.ID
+	return nil
+}
+
+// GetCode retrieves the unexpired code for an email and purpose issued for the subject
+func (r *VerificationRepository) GetCode(ctx context.Context, email string, purpose models.VerificationPurpose, subject string) (*models.VerificationCode, error) {
+	var code models.VerificationCode
+	err := r.collection.FindOne(ctx, liveCodeFilter(email, purpose, subject)).Decode(&code)
+	if err != nil {
+		return nil, err
+	}
+	return &code, nil
+}
+
+// ReserveAttempt counts a guess against the unexpired code for an email and purpose
+// issued for the subject before it is checked, and returns the code. It returns
+// mongo.ErrNoDocuments when there is no such code or its guesses are used up, so
+// concurrent guesses cannot exceed the limit and nobody else can spend them.
+func (r *VerificationRepository) ReserveAttempt(ctx context.Context, email string, purpose models.VerificationPurpose, subject string, maxAttempts int) (*models.VerificationCode, error) {
+	filter := liveCodeFilter(email, purpose, subject)
+	filter["attempts"] = bson.M{"$lt": maxAttempts}
+
+	var code models.VerificationCode
+	err := r.collection.FindOneAndUpdate(
+		ctx,
+		filter,
+		bson.M{"$inc": bson.M{"attempts": 1}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&code)
+	if err != nil {
+		return nil, err
+	}
+	return &code, nil
+}
+
+// liveCodeFilter matches the unexpired code for an email and purpose issued for the subject.
+// Codes without a subject were stored with an empty one.
+func liveCodeFilter(email string, purpose models.VerificationPurpose, subject string) bson.M {
+	return bson.M{
+		"email":      email,
+		"purpose":    purpose,
+		"subject":    subject,
+		"expires_at": bson.M{"$gt": time.Now()},
+	}
+}
+
+// DeleteCode removes a code. It returns false when the code was already gone,
+// so two concurrent redemptions cannot both succeed.
+func (r *VerificationRepository) DeleteCode(ctx context.Context, id primitive.ObjectID) (bool, error) {
+	result, err := r.collection.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return false, err
+	}
+	return result.DeletedCount == 1, nil
+}
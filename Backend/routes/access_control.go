@@ -0,0 +1,79 @@
+package routes
+
+import (
+	"github.com/AliSleiman0/Lacpa/middleware"
+	"github.com/AliSleiman0/Lacpa/models"
+	"github.com/gofiber/fiber/v2"
+)
+
+// AccessTable is the single route-to-permission table for the API.
+//
+// ROLE: Authorization Policy
+//   - Every mutating or admin-only endpoint must be listed here
+//   - Routes not listed are public
+//   - Rules are matched in order, so specific routes come before the
+//     /api/admin catch-all that keeps new admin endpoints closed by default
+//
+// The table is enforced by middleware.Authorize, registered in main.go
+// before any route group.
+var AccessTable = []middleware.AccessRule{
+	// Admin user management
+	{Method: fiber.MethodPost, Path: "/api/admin/create-admin", Permission: models.PermUsersManage},
+	{Method: fiber.MethodPost, Path: "/api/admin/update-role", Permission: models.PermUsersManage},
+	{Method: fiber.MethodGet, Path: "/api/admin/users", Permission: models.PermUsersManage},
+	{Method: fiber.MethodPost, Path: "/api/admin/deactivate-user", Permission: models.PermUsersManage},
+	{Method: fiber.MethodPost, Path: "/api/admin/activate-user", Permission: models.PermUsersManage},
+	{Method: fiber.MethodPost, Path: "/api/admin/unlock-user", Permission: models.PermUsersManage},
+	{Method: fiber.MethodGet, Path: "/api/admin/security-history", Permission: models.PermUsersManage},
+	{Method: fiber.MethodPost, Path: "/api/admin/reset-2fa", Permission: models.PermUsersManage},
+
+	// Audit trail
+	{Method: fiber.MethodGet, Path: "/api/admin/audit", Permission: models.PermAuditRead},
+	{Method: fiber.MethodGet, Path: "/api/admin/audit/export", Permission: models.PermAuditRead},
+
+	// Role management
+	{Method: "*", Path: "/api/admin/roles/*", Permission: models.PermRolesManage},
+
+	// Hero slides management
+	{Method: fiber.MethodGet, Path: "/api/admin/slides/*", Permission: models.PermSlidesRead},
+	{Method: fiber.MethodPost, Path: "/api/admin/slides/*", Permission: models.PermSlidesWrite},
+	{Method: fiber.MethodPatch, Path: "/api/admin/slides/:id", Permission: models.PermSlidesWrite},
+	{Method: fiber.MethodDelete, Path: "/api/admin/slides/:id", Permission: models.PermSlidesWrite},
+
+	// Membership application requirements
+	{Method: "*", Path: "/api/admin/requirements/*", Permission: models.PermRequirementsManage},
+
+	// Any other admin endpoint
+	{Method: "*", Path: "/api/admin/*", Permission: models.PermAdminAccess},
+
+	// Membership application review
+	{Method: fiber.MethodGet, Path: "/api/applications", Permission: models.PermApplicationsRead},
+	{Method: fiber.MethodGet, Path: "/api/applications/individual", Permission: models.PermApplicationsRead},
+	{Method: fiber.MethodGet, Path: "/api/applications/firm", Permission: models.PermApplicationsRead},
+	{Method: fiber.MethodGet, Path: "/api/applications/individual/:id/duplicates", Permission: models.PermApplicationsRead},
+	{Method: fiber.MethodGet, Path: "/api/applications/firm/:id/duplicates", Permission: models.PermApplicationsRead},
+	{Method: fiber.MethodPut, Path: "/api/applications/individual/:id/status", Permission: models.PermApplicationsReview},
+	{Method: fiber.MethodPut, Path: "/api/applications/firm/:id/status", Permission: models.PermApplicationsReview},
+	{Method: fiber.MethodGet, Path: "/api/applications/documents/:id", Permission: models.PermApplicationsRead},
+	{Method: fiber.MethodGet, Path: "/api/applications/documents/:id/link", Permission: models.PermApplicationsRead},
+
+	// Application review; recommending also requires being an assigned reviewer
+	{Method: fiber.MethodGet, Path: "/api/applications/:type/:id/review", Permission: models.PermApplicationsRead},
+	{Method: fiber.MethodPost, Path: "/api/applications/:type/:id/reviewers", Permission: models.PermApplicationsAssign},
+	{Method: fiber.MethodDelete, Path: "/api/applications/:type/:id/reviewers/:userId", Permission: models.PermApplicationsAssign},
+	{Method: fiber.MethodPut, Path: "/api/applications/:type/:id/recommendation", Permission: models.PermApplicationsRecommend},
+	{Method: fiber.MethodGet, Path: "/api/applications/:type/:id/comments", Permission: models.PermApplicationsRead},
+	{Method: "*", Path: "/api/applications/:type/:id/comments", Permission: models.PermApplicationsRecommend},
+
+	// Application fees, invoices and payments
+	{Method: fiber.MethodGet, Path: "/api/finance/*", Permission: models.PermFinanceRead},
+	{Method: "*", Path: "/api/finance/*", Permission: models.PermFinanceManage},
+
+	// Council management
+	{Method: fiber.MethodPost, Path: "/api/council/position", Permission: models.PermCouncilAssign},
+	{Method: fiber.MethodPut, Path: "/api/council/position/:positionId", Permission: models.PermCouncilAssign},
+	{Method: fiber.MethodDelete, Path: "/api/council/position/:positionId", Permission: models.PermCouncilAssign},
+	{Method: fiber.MethodPost, Path: "/api/council", Permission: models.PermCouncilWrite},
+	{Method: fiber.MethodPut, Path: "/api/council/:id", Permission: models.PermCouncilWrite},
+	{Method: fiber.MethodDelete, Path: "/api/council/:id", Permission: models.PermCouncilWrite},
+}
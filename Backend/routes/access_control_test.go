@@ -0,0 +1,128 @@
+package routes
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/AliSleiman0/Lacpa/middleware"
+	"github.com/AliSleiman0/Lacpa/models"
+	"github.com/AliSleiman0/Lacpa/utils"
+	"github.com/gofiber/fiber/v2"
+)
+
+// newAccessTestApp returns an app that answers 200 on every route behind the access table
+func newAccessTestApp() *fiber.App {
+	app := fiber.New()
+	app.Use(middleware.Authorize(AccessTable))
+	app.Use(func(c *fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusOK)
+	})
+	return app
+}
+
+// concreteRequest turns an access rule into a method and path it must match
+func concreteRequest(rule middleware.AccessRule) (string, string) {
+	method := rule.Method
+	if method == "*" {
+		method = fiber.MethodPost
+	}
+
+	parts := strings.Split(strings.Trim(rule.Path, "/"), "/")
+	for i, part := range parts {
+		if strings.HasPrefix(part, ":") || part == "*" {
+			parts[i] = "64b000000000000000000001"
+		}
+	}
+	return method, "/" + strings.Join(parts, "/")
+}
+
+// mixedCase upper-cases every other letter of a path
+func mixedCase(path string) string {
+	var builder strings.Builder
+	for i, r := range path {
+		if i%2 == 0 {
+			builder.WriteString(strings.ToUpper(string(r)))
+		} else {
+			builder.WriteRune(r)
+		}
+	}
+	return builder.String()
+}
+
+func accessToken(t *testing.T, role string, permissions []string) string {
+	t.Helper()
+	token, err := utils.GenerateJWT(utils.TokenSubject{
+		UserID:      "64b0000000000000000000aa",
+		Email:       role + "@example.com",
+		Role:        role,
+		Permissions: permissions,
+	})
+	if err != nil {
+		t.Fatalf("GenerateJWT: %v", err)
+	}
+	return token
+}
+
+func TestAccessTableRejectsUnauthorizedCallers(t *testing.T) {
+	app := newAccessTestApp()
+	memberToken := accessToken(t, models.RoleMember, []string{})
+
+	allPermissions := make([]string, 0, len(models.AllPermissions))
+	for _, permission := range models.AllPermissions {
+		allPermissions = append(allPermissions, string(permission))
+	}
+	adminToken := accessToken(t, models.RoleAdmin, allPermissions)
+
+	callers := []struct {
+		name   string
+		token  string
+		upper  bool
+		status int
+	}{
+		{name: "anonymous", status: fiber.StatusUnauthorized},
+		{name: "member", token: memberToken, status: fiber.StatusForbidden},
+		{name: "anonymous mixed case", upper: true, status: fiber.StatusUnauthorized},
+		{name: "member mixed case", token: memberToken, upper: true, status: fiber.StatusForbidden},
+		{name: "admin", token: adminToken, status: fiber.StatusOK},
+	}
+
+	for _, rule := range AccessTable {
+		method, path := concreteRequest(rule)
+		for _, caller := range callers {
+			requestPath := path
+			if caller.upper {
+				requestPath = mixedCase(path)
+			}
+
+			t.Run(caller.name+" "+method+" "+requestPath, func(t *testing.T) {
+				req := httptest.NewRequest(method, requestPath, nil)
+				if caller.token != "" {
+					req.Header.Set("Authorization", "Bearer "+caller.token)
+				}
+
+				resp, err := app.Test(req)
+				if err != nil {
+					t.Fatalf("request failed: %v", err)
+				}
+				if resp.StatusCode != caller.status {
+					t.Fatalf("status = %d, want %d", resp.StatusCode, caller.status)
+				}
+			})
+		}
+	}
+}
+
+func TestAccessTableLeavesPublicRoutesOpen(t *testing.T) {
+	app := newAccessTestApp()
+
+	for _, path := range []string{"/api/health", "/api/otp/send", "/api/auth/login"} {
+		resp, err := app.Test(httptest.NewRequest(fiber.MethodPost, path, nil))
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		if resp.StatusCode != fiber.StatusOK {
+			t.Fatalf("%s: status = %d, want %d", path, resp.StatusCode, fiber.StatusOK)
+		}
+	}
+}
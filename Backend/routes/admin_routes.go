@@ -8,10 +8,9 @@ import (
 
 // SetupAdminRoutes sets up all admin-only routes
 func SetupAdminRoutes(app *fiber.App, adminUserHandler *handler.AdminHandler, heroSlideHandler *adminHandler.AdminHeroSlideHandler) {
-	// Create admin group with authentication and admin role required
+	// Create admin group - authentication and permissions are enforced by
+	// middleware.Authorize using the entries in AccessTable
 	admin := app.Group("/api/admin")
-	//admin.Use(middleware.AuthMiddleware)
-	//	admin.Use(middleware.RoleMiddleware("admin"))
 
 	// Admin user management
 	admin.Post("/create-admin", adminUserHandler.CreateAdmin)
@@ -19,6 +18,19 @@ func SetupAdminRoutes(app *fiber.App, adminUserHandler *handler.AdminHandler, he
 	admin.Get("/users", adminUserHandler.ListUsers)
 	admin.Post("/deactivate-user", adminUserHandler.DeactivateUser)
 	admin.Post("/activate-user", adminUserHandler.ActivateUser)
+	admin.Post("/unlock-user", adminUserHandler.UnlockUser)
+	admin.Get("/security-history", adminUserHandler.GetSecurityHistory)
+	admin.Post("/reset-2fa", adminUserHandler.ResetTwoFactor)
+
+	// Audit trail
+	admin.Get("/audit", adminUserHandler.ListAuditEvents)
+	admin.Get("/audit/export", adminUserHandler.ExportAuditEvents)
+
+	// Role management
+	admin.Get("/roles", adminUserHandler.ListRoles)
+	admin.Post("/roles", adminUserHandler.CreateRole)
+	admin.Put("/roles/:name", adminUserHandler.UpdateRole)
+	admin.Delete("/roles/:name", adminUserHandler.DeleteRole)
 
 	// Hero Slides Management
 	admin.Get("/slides", heroSlideHandler.GetAllSlides)
@@ -0,0 +1,23 @@
+package routes
+
+import (
+	"github.com/AliSleiman0/Lacpa/handler"
+	"github.com/gofiber/fiber/v2"
+)
+
+// SetupReviewRoutes configures reviewer assignment, recommendations, internal comments
+// and the quorum rules of the application types (protected through AccessTable)
+func SetupReviewRoutes(app *fiber.App, reviewHandler *handler.ReviewHandler) {
+	// :type is individual or firm
+	review := app.Group("/api/applications/:type/:id")
+	review.Get("/review", reviewHandler.GetReview)
+	review.Post("/reviewers", reviewHandler.AssignReviewer)
+	review.Delete("/reviewers/:userId", reviewHandler.RemoveReviewer)
+	review.Put("/recommendation", reviewHandler.Recommend)
+	review.Get("/comments", reviewHandler.ListComments)
+	review.Post("/comments", reviewHandler.AddComment)
+
+	quorum := app.Group("/api/admin/quorum-rules")
+	quorum.Get("/", reviewHandler.ListQuorumRules)
+	quorum.Put("/:type", reviewHandler.UpdateQuorumRule)
+}
@@ -2,28 +2,40 @@ package routes
 
 import (
 	"github.com/AliSleiman0/Lacpa/handler"
+	"github.com/AliSleiman0/Lacpa/middleware"
 	"github.com/AliSleiman0/Lacpa/repository"
 	"github.com/gofiber/fiber/v2"
 )
 
 // SetupApplicationRoutes configures all application-related routes
-func SetupApplicationRoutes(app *fiber.App, repo repository.Repository) {
-	// Create application handler (repo already includes ApplicationRepository)
-	appHandler := handler.NewApplicationHandler(repo)
+func SetupApplicationRoutes(app *fiber.App, repo repository.Repository, audit *handler.AuditService) {
+	// Create application handler (repo already includes ApplicationRepository and InvoiceRepository)
+	appHandler := handler.NewApplicationHandler(repo, repo, audit)
 
 	// Public routes - viewing requirements and submitting applications
 	app.Get("/membership/apply-now", appHandler.GetApplyNowPage)
 	app.Get("/membership/apply/firm", appHandler.GetApplyFirmPage)
 	app.Get("/membership/apply/individual", appHandler.GetApplyIndividualPage)
 
-	// API routes for application submission
+	// API routes for application submission; a token links the application to the account
 	api := app.Group("/api/applications")
-	api.Post("/individual", appHandler.SubmitIndividualApplication)
-	api.Post("/firm", appHandler.SubmitFirmApplication)
+	api.Post("/individual", middleware.OptionalAuthMiddleware, appHandler.SubmitIndividualApplication)
+	api.Post("/firm", middleware.OptionalAuthMiddleware, appHandler.SubmitFirmApplication)
 
-	// Admin routes for managing applications (to be protected with auth middleware later)
-	api.Get("/individual", appHandler.GetAllIndividualApplications)
-	api.Get("/firm", appHandler.GetAllFirmApplications)
+	// Drafts saved step by step; the resume token or the account that started one opens it
+	drafts := api.Group("/drafts", middleware.OptionalAuthMiddleware)
+	drafts.Post("/", appHandler.CreateDraft)
+	drafts.Get("/:id", appHandler.GetDraft)
+	drafts.Put("/:id/steps/:step", appHandler.SaveDraftStep)
+	drafts.Post("/:id/submit", appHandler.SubmitDraft)
+	drafts.Delete("/:id", appHandler.DiscardDraft)
+
+	// Admin routes for managing applications (protected through AccessTable)
+	app.Get("/api/applications", appHandler.ListApplications)
+	api.Get("/individual", appHandler.ListIndividualApplications)
+	api.Get("/firm", appHandler.ListFirmApplications)
+	api.Get("/individual/:id/duplicates", appHandler.GetIndividualApplicationDuplicates)
+	api.Get("/firm/:id/duplicates", appHandler.GetFirmApplicationDuplicates)
 	api.Put("/individual/:id/status", appHandler.UpdateIndividualApplicationStatus)
 	api.Put("/firm/:id/status", appHandler.UpdateFirmApplicationStatus)
 }
@@ -0,0 +1,33 @@
+package routes
+
+import (
+	"github.com/AliSleiman0/Lacpa/handler"
+	"github.com/AliSleiman0/Lacpa/middleware"
+	"github.com/gofiber/fiber/v2"
+)
+
+// SetupApplicationTrackingRoutes configures the applicant portal.
+// The HTMX pages under /membership/track and the JSON API under
+// /api/applications/track share the same handlers.
+func SetupApplicationTrackingRoutes(app *fiber.App, trackingHandler *handler.ApplicationTrackingHandler) {
+	app.Get("/membership/track", trackingHandler.GetTrackPage)
+
+	for _, prefix := range []string{"/membership/track", "/api/applications/track"} {
+		track := app.Group(prefix)
+
+		// Open an application with its reference number and a code sent to its email
+		track.Post("/request-code", trackingHandler.RequestCode)
+		track.Post("/verify", trackingHandler.VerifyCode)
+
+		// An access token from /verify or the applicant's account authorizes these
+		track.Get("/:type/:id", middleware.OptionalAuthMiddleware, trackingHandler.GetApplication)
+		track.Post("/:type/:id/documents", middleware.OptionalAuthMiddleware, trackingHandler.UploadDocument)
+		track.Post("/:type/:id/resubmit", middleware.OptionalAuthMiddleware, trackingHandler.Resubmit)
+		track.Post("/:type/:id/withdraw", middleware.OptionalAuthMiddleware, trackingHandler.Withdraw)
+		track.Post("/:type/:id/invoice/pay", middleware.OptionalAuthMiddleware, trackingHandler.PayInvoice)
+	}
+
+	// Applications of the signed-in user; the page shows nothing to anonymous visitors
+	app.Get("/membership/track/mine", middleware.OptionalAuthMiddleware, trackingHandler.GetMyApplications)
+	app.Get("/api/applications/mine", middleware.AuthMiddleware, trackingHandler.GetMyApplications)
+}
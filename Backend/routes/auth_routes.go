@@ -18,8 +18,37 @@ func SetupAuthRoutes(app *fiber.App, authHandler *handler.AuthHandler) {
 	auth.Post("/verify-otp", authHandler.VerifyOTP)
 	auth.Post("/resend-otp", authHandler.ResendOTP)
 	auth.Post("/reset-password", authHandler.ResetPassword)
+	auth.Post("/refresh", authHandler.RefreshToken)
+	auth.Post("/login/2fa", authHandler.LoginTwoFactor)
+
+	// Two-factor enrollment (access token, or enrollment challenge token during login)
+	auth.Post("/2fa/setup", middleware.OptionalAuthMiddleware, authHandler.SetupTwoFactor)
+	auth.Post("/2fa/enable", middleware.OptionalAuthMiddleware, authHandler.EnableTwoFactor)
 
 	// Protected routes (authentication required)
 	auth.Get("/profile", middleware.AuthMiddleware, authHandler.GetProfile)
 	auth.Post("/logout", middleware.AuthMiddleware, authHandler.Logout)
+	auth.Post("/logout-all", middleware.AuthMiddleware, authHandler.LogoutAll)
+	auth.Post("/2fa/disable", middleware.AuthMiddleware, authHandler.DisableTwoFactor)
+	auth.Post("/2fa/recovery-codes", middleware.AuthMiddleware, authHandler.RegenerateRecoveryCodes)
+
+	// Self-service account management
+	auth.Put("/me/password", middleware.AuthMiddleware, authHandler.ChangePassword)
+	auth.Post("/me/email", middleware.AuthMiddleware, authHandler.RequestEmailChange)
+	auth.Post("/me/email/verify", middleware.AuthMiddleware, authHandler.ConfirmEmailChange)
+	auth.Put("/me/name", middleware.AuthMiddleware, authHandler.UpdateName)
+	auth.Post("/me/deactivate", middleware.AuthMiddleware, authHandler.DeactivateAccount)
+	auth.Delete("/me", middleware.AuthMiddleware, authHandler.DeleteAccount)
+
+	// Claim an existing member or firm registry record
+	auth.Post("/me/claim", middleware.AuthMiddleware, authHandler.ClaimProfile)
+	auth.Post("/me/claim/verify", middleware.AuthMiddleware, authHandler.VerifyClaim)
+}
+
+// SetupOIDCRoutes sets up the staff single sign-on routes.
+// Only registered when an OIDC provider is configured.
+func SetupOIDCRoutes(app *fiber.App, oidcHandler *handler.OIDCHandler) {
+	oidc := app.Group("/api/auth/oidc")
+	oidc.Get("/login", oidcHandler.Login)
+	oidc.Get("/callback", oidcHandler.Callback)
 }
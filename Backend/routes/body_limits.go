@@ -0,0 +1,31 @@
+package routes
+
+import (
+	"github.com/AliSleiman0/Lacpa/middleware"
+	"github.com/AliSleiman0/Lacpa/utils"
+	"github.com/gofiber/fiber/v2"
+)
+
+// multipartOverhead leaves room for the form fields and boundaries around an uploaded file
+const multipartOverhead = 1 << 20
+
+// BodyLimitRules returns the routes allowed a body larger than fiber.DefaultBodyLimit.
+//
+// ROLE: Upload Size Policy
+//   - Only file upload routes are raised, to the size their handler accepts
+//   - Every other route keeps the default limit
+//
+// The rules are enforced by middleware.BodyLimit, registered in main.go.
+func BodyLimitRules() []middleware.BodyLimitRule {
+	documentLimit := int(utils.DocumentMaxSize()) + multipartOverhead
+
+	return []middleware.BodyLimitRule{
+		// Application documents
+		{Method: fiber.MethodPost, Path: "/api/applications/documents", Limit: documentLimit},
+		{Method: fiber.MethodPost, Path: "/api/applications/track/:type/:id/documents", Limit: documentLimit},
+		{Method: fiber.MethodPost, Path: "/membership/track/:type/:id/documents", Limit: documentLimit},
+
+		// Hero slide images (the handler accepts up to 10MB)
+		{Method: fiber.MethodPost, Path: "/api/admin/slides/:id/upload-image", Limit: 10<<20 + multipartOverhead},
+	}
+}
@@ -7,10 +7,10 @@ import (
 )
 
 // SetupCouncilRoutes configures all council-related routes
-func SetupCouncilRoutes(app *fiber.App, repo repository.Repository) {
-	councilHandler := handler.NewCouncilHandler(repo)
+func SetupCouncilRoutes(app *fiber.App, repo repository.Repository, audit *handler.AuditService) {
+	councilHandler := handler.NewCouncilHandler(repo, audit)
 
-	// Council routes
+	// Council routes - mutations are protected through AccessTable
 	api := app.Group("/api/council")
 
 	// Council CRUD operations
@@ -0,0 +1,20 @@
+package routes
+
+import (
+	"github.com/AliSleiman0/Lacpa/handler"
+	"github.com/AliSleiman0/Lacpa/middleware"
+	"github.com/gofiber/fiber/v2"
+)
+
+// SetupDocumentRoutes configures application document uploads and downloads
+func SetupDocumentRoutes(app *fiber.App, documentHandler *handler.DocumentHandler) {
+	// Applicants upload before submitting; a token is optional and only records the uploader
+	app.Post("/api/applications/documents", middleware.OptionalAuthMiddleware, documentHandler.UploadDocument)
+
+	// Staff downloads (protected through AccessTable)
+	app.Get("/api/applications/documents/:id", documentHandler.DownloadDocument)
+	app.Get("/api/applications/documents/:id/link", documentHandler.DocumentLink)
+
+	// Signed links handed out by DocumentLink; the signature is the authorization
+	app.Get("/api/documents/:id", documentHandler.DownloadSignedDocument)
+}
@@ -0,0 +1,23 @@
+package routes
+
+import (
+	"github.com/AliSleiman0/Lacpa/handler"
+	"github.com/gofiber/fiber/v2"
+)
+
+// SetupInvoiceRoutes configures application fees and invoices for finance staff
+// (protected through AccessTable) and the payment provider's notifications
+func SetupInvoiceRoutes(app *fiber.App, invoiceHandler *handler.InvoiceHandler) {
+	fees := app.Group("/api/finance/fees")
+	fees.Get("/", invoiceHandler.ListFees)
+	fees.Put("/:type", invoiceHandler.UpdateFee)
+
+	invoices := app.Group("/api/finance/invoices")
+	invoices.Get("/", invoiceHandler.ListInvoices)
+	invoices.Post("/", invoiceHandler.IssueInvoice)
+	invoices.Get("/:id", invoiceHandler.GetInvoice)
+	invoices.Post("/:id/payments", invoiceHandler.RecordPayment)
+
+	// Public; the provider authenticates each notification
+	app.Post("/api/payments/:provider/notify", invoiceHandler.PaymentNotification)
+}
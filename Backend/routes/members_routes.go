@@ -7,13 +7,19 @@ import (
 )
 
 // SetupMembersRoutes configures all members page routes
-func SetupMembersRoutes(app *fiber.App, repo repository.Repository) {
+func SetupMembersRoutes(app *fiber.App, repo repository.Repository, audit *handler.AuditService) {
 	membersHandler := handler.NewMembersHandler(repo)
-	councilHandler := handler.NewCouncilHandler(repo)
+	councilHandler := handler.NewCouncilHandler(repo, audit)
 
 	// Members page routes - support both URL patterns
 	app.Get("/members/individuals", membersHandler.GetIndividualsPage)
 	app.Get("/membership", membersHandler.GetIndividualsPage)                   // Clean URL alias
 	app.Get("/membership/firms", membersHandler.GetFirmsPage)                   // Firms page
 	app.Get("/discover/board-of-directors", councilHandler.GetBoardMembersPage) // Board members page
+
+	// Directory search: HTMX fragments for the pages above and the same search as JSON
+	app.Get("/members/individuals/search", membersHandler.SearchIndividuals)
+	app.Get("/membership/firms/search", membersHandler.SearchFirms)
+	app.Get("/api/members/individuals/search", membersHandler.SearchIndividuals)
+	app.Get("/api/members/firms/search", membersHandler.SearchFirms)
 }
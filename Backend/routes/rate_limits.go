@@ -0,0 +1,70 @@
+package routes
+
+import (
+	"strings"
+	"time"
+
+	"github.com/AliSleiman0/Lacpa/middleware"
+	"github.com/AliSleiman0/Lacpa/utils"
+	"github.com/gofiber/fiber/v2"
+)
+
+// RateLimitPolicies returns the per-route token bucket policies.
+//
+// ROLE: Abuse Protection Policy
+//   - Covers endpoints that send email, check passwords or codes, or create records anonymously
+//   - Buckets are keyed by client IP and, where the body has one, target email
+//   - Capacity and refill interval can be tuned per policy with
+//     RATE_LIMIT_<NAME>_CAPACITY and RATE_LIMIT_<NAME>_REFILL_SECONDS
+//
+// The policies are enforced by middleware.RateLimit, registered in main.go
+// when ENABLE_RATE_LIMIT is on.
+func RateLimitPolicies() []middleware.RateLimitPolicy {
+	byIPAndEmail := []string{middleware.RateLimitByIP, middleware.RateLimitByEmail}
+
+	return []middleware.RateLimitPolicy{
+		// Endpoints that send an OTP email
+		rateLimitPolicy("otp-send", fiber.MethodPost, "/api/otp/send", 3, time.Minute, byIPAndEmail),
+		rateLimitPolicy("resend-otp", fiber.MethodPost, "/api/auth/resend-otp", 3, time.Minute, byIPAndEmail),
+		rateLimitPolicy("forgot-password", fiber.MethodPost, "/api/auth/forgot-password", 3, 5*time.Minute, byIPAndEmail),
+		rateLimitPolicy("claim-profile", fiber.MethodPost, "/api/auth/me/claim", 3, 5*time.Minute, []string{middleware.RateLimitByIP}),
+		rateLimitPolicy("change-email", fiber.MethodPost, "/api/auth/me/email", 3, 5*time.Minute, []string{middleware.RateLimitByIP}),
+		rateLimitPolicy("signup", fiber.MethodPost, "/api/auth/signup", 5, 10*time.Minute, byIPAndEmail),
+
+		// Sign-in, backing up the lockout counters against bursts from one client
+		rateLimitPolicy("login", fiber.MethodPost, "/api/auth/login", 10, time.Minute, []string{middleware.RateLimitByIP}),
+		rateLimitPolicy("login-2fa", fiber.MethodPost, "/api/auth/login/2fa", 10, time.Minute, []string{middleware.RateLimitByIP}),
+
+		// Code verification that has no per-client attempt counter of its own
+		rateLimitPolicy("otp-verify", fiber.MethodPost, "/api/otp/verify", 10, 10*time.Minute, []string{middleware.RateLimitByIP}),
+
+		// Membership application submissions
+		rateLimitPolicy("application-individual", fiber.MethodPost, "/api/applications/individual", 5, 10*time.Minute, byIPAndEmail),
+		rateLimitPolicy("application-firm", fiber.MethodPost, "/api/applications/firm", 5, 10*time.Minute, byIPAndEmail),
+		rateLimitPolicy("document-upload", fiber.MethodPost, "/api/applications/documents", 20, 10*time.Minute, []string{middleware.RateLimitByIP}),
+		rateLimitPolicy("application-draft", fiber.MethodPost, "/api/applications/drafts", 5, 10*time.Minute, byIPAndEmail),
+		rateLimitPolicy("application-draft-submit", fiber.MethodPost, "/api/applications/drafts/:id/submit", 5, 10*time.Minute, []string{middleware.RateLimitByIP}),
+
+		// Applicant portal; the page and API routes of a policy share its buckets
+		rateLimitPolicy("application-track-code", fiber.MethodPost, "/api/applications/track/request-code", 3, 5*time.Minute, byIPAndEmail),
+		rateLimitPolicy("application-track-code", fiber.MethodPost, "/membership/track/request-code", 3, 5*time.Minute, byIPAndEmail),
+		rateLimitPolicy("application-track-verify", fiber.MethodPost, "/api/applications/track/verify", 10, 10*time.Minute, []string{middleware.RateLimitByIP}),
+		rateLimitPolicy("application-track-verify", fiber.MethodPost, "/membership/track/verify", 10, 10*time.Minute, []string{middleware.RateLimitByIP}),
+		rateLimitPolicy("document-upload", fiber.MethodPost, "/api/applications/track/:type/:id/documents", 20, 10*time.Minute, []string{middleware.RateLimitByIP}),
+		rateLimitPolicy("document-upload", fiber.MethodPost, "/membership/track/:type/:id/documents", 20, 10*time.Minute, []string{middleware.RateLimitByIP}),
+	}
+}
+
+// rateLimitPolicy builds a policy, applying any environment overrides
+func rateLimitPolicy(name, method, path string, capacity int, refillEvery time.Duration, keyBy []string) middleware.RateLimitPolicy {
+	prefix := "RATE_LIMIT_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+
+	return middleware.RateLimitPolicy{
+		Name:        name,
+		Method:      method,
+		Path:        path,
+		Capacity:    utils.GetEnvInt(prefix+"_CAPACITY", capacity),
+		RefillEvery: time.Duration(utils.GetEnvInt(prefix+"_REFILL_SECONDS", int(refillEvery.Seconds()))) * time.Second,
+		KeyBy:       keyBy,
+	}
+}
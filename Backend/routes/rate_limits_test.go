@@ -0,0 +1,124 @@
+package routes
+
+import (
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/AliSleiman0/Lacpa/middleware"
+	"github.com/gofiber/fiber/v2"
+)
+
+// newRateLimitTestApp applies the real policy table; the client IP comes from X-Forwarded-For
+func newRateLimitTestApp() *fiber.App {
+	app := fiber.New(fiber.Config{ProxyHeader: fiber.HeaderXForwardedFor})
+	app.Use(middleware.RateLimit(RateLimitPolicies(), middleware.NewMemoryRateLimitStore()))
+	app.Use(func(c *fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusCreated)
+	})
+	return app
+}
+
+func TestCreateDraftIsRateLimited(t *testing.T) {
+	post := func(app *fiber.App, ip, email string) int {
+		body := fmt.Sprintf(`{"application_type":"Individual","email":%q}`, email)
+		req := httptest.NewRequest(fiber.MethodPost, "/api/applications/drafts", strings.NewReader(body))
+		req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+		req.Header.Set(fiber.HeaderXForwardedFor, ip)
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		return resp.StatusCode
+	}
+
+	t.Run("one client, many addresses", func(t *testing.T) {
+		app := newRateLimitTestApp()
+		for i := 0; i < 5; i++ {
+			if status := post(app, "203.0.113.7", fmt.Sprintf("victim%d@example.com", i)); status != fiber.StatusCreated {
+				t.Fatalf("request %d: status = %d, want %d", i+1, status, fiber.StatusCreated)
+			}
+		}
+		if status := post(app, "203.0.113.7", "victim5@example.com"); status != fiber.StatusTooManyRequests {
+			t.Fatalf("request 6: status = %d, want %d", status, fiber.StatusTooManyRequests)
+		}
+	})
+
+	t.Run("many clients, one address", func(t *testing.T) {
+		app := newRateLimitTestApp()
+		for i := 0; i < 5; i++ {
+			if status := post(app, fmt.Sprintf("198.51.100.%d", i), "victim@example.com"); status != fiber.StatusCreated {
+				t.Fatalf("request %d: status = %d, want %d", i+1, status, fiber.StatusCreated)
+			}
+		}
+		if status := post(app, "198.51.100.99", "Victim@Example.com"); status != fiber.StatusTooManyRequests {
+			t.Fatalf("request 6: status = %d, want %d", status, fiber.StatusTooManyRequests)
+		}
+	})
+}
+
+func TestOTPVerifyIsRateLimitedPerIP(t *testing.T) {
+	app := newRateLimitTestApp()
+	verify := func(ip, email string) int {
+		body := fmt.Sprintf(`{"email":%q,"otp":"000000"}`, email)
+		req := httptest.NewRequest(fiber.MethodPost, "/api/otp/verify", strings.NewReader(body))
+		req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+		req.Header.Set(fiber.HeaderXForwardedFor, ip)
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		return resp.StatusCode
+	}
+
+	for i := 0; i < 10; i++ {
+		if status := verify("203.0.113.7", fmt.Sprintf("victim%d@example.com", i)); status != fiber.StatusCreated {
+			t.Fatalf("guess %d: status = %d, want %d", i+1, status, fiber.StatusCreated)
+		}
+	}
+	if status := verify("203.0.113.7", "victim10@example.com"); status != fiber.StatusTooManyRequests {
+		t.Fatalf("guess 11: status = %d, want %d", status, fiber.StatusTooManyRequests)
+	}
+	if status := verify("198.51.100.1", "victim@example.com"); status != fiber.StatusCreated {
+		t.Fatalf("other client: status = %d, want %d", status, fiber.StatusCreated)
+	}
+}
+
+func TestLoginIsRateLimitedPerIP(t *testing.T) {
+	tests := []struct {
+		name string
+		path string
+		body string
+	}{
+		{name: "password", path: "/api/auth/login", body: `{"lacpa_id":"LACPA-2025-00001","password":"guess"}`},
+		{name: "second factor", path: "/api/auth/login/2fa", body: `{"challenge_token":"token","code":"000000"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newRateLimitTestApp()
+			attempt := func(ip string) int {
+				req := httptest.NewRequest(fiber.MethodPost, tt.path, strings.NewReader(tt.body))
+				req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+				req.Header.Set(fiber.HeaderXForwardedFor, ip)
+				resp, err := app.Test(req)
+				if err != nil {
+					t.Fatalf("request failed: %v", err)
+				}
+				return resp.StatusCode
+			}
+
+			for i := 0; i < 10; i++ {
+				if status := attempt("203.0.113.7"); status != fiber.StatusCreated {
+					t.Fatalf("attempt %d: status = %d, want %d", i+1, status, fiber.StatusCreated)
+				}
+			}
+			if status := attempt("203.0.113.7"); status != fiber.StatusTooManyRequests {
+				t.Fatalf("attempt 11: status = %d, want %d", status, fiber.StatusTooManyRequests)
+			}
+			if status := attempt("198.51.100.1"); status != fiber.StatusCreated {
+				t.Fatalf("other client: status = %d, want %d", status, fiber.StatusCreated)
+			}
+		})
+	}
+}
@@ -0,0 +1,22 @@
+package routes
+
+import (
+	"github.com/AliSleiman0/Lacpa/handler"
+	"github.com/AliSleiman0/Lacpa/repository"
+	"github.com/gofiber/fiber/v2"
+)
+
+// SetupRequirementRoutes configures the CMS management of application requirements
+// (protected through AccessTable)
+func SetupRequirementRoutes(app *fiber.App, repo repository.Repository, audit *handler.AuditService) {
+	requirementHandler := handler.NewRequirementHandler(repo, audit)
+
+	requirements := app.Group("/api/admin/requirements")
+	requirements.Get("/", requirementHandler.ListRequirements)
+	requirements.Get("/render", requirementHandler.RenderRequirements) // Returns HTML fragment
+	requirements.Post("/", requirementHandler.CreateRequirement)
+	requirements.Put("/order", requirementHandler.ReorderRequirements)
+	requirements.Put("/:id", requirementHandler.UpdateRequirement)
+	requirements.Patch("/:id/required", requirementHandler.ToggleRequired)
+	requirements.Delete("/:id", requirementHandler.DeleteRequirement)
+}
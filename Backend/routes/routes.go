@@ -33,7 +33,9 @@ import (
 //
 //	app: Main Fiber application instance
 //	repo: Unified repository interface providing all data access methods
-func SetupRoutes(app *fiber.App, repo repository.Repository) {
+//	verification: Email verification code service shared with the auth routes
+//	audit: Audit trail service for handlers that mutate data
+func SetupRoutes(app *fiber.App, repo repository.Repository, verification *handler.VerificationService, audit *handler.AuditService) {
 	// Create API route group - all API routes will be under /api prefix
 	api := app.Group("/api")
 
@@ -50,19 +52,20 @@ func SetupRoutes(app *fiber.App, repo repository.Repository) {
 	SetupMainPageRoutes(api, mainPageHandler) // Configures /api/main/* routes
 
 	// Council routes - API endpoints for council management
-	SetupCouncilRoutes(app, repo) // Configures /api/council/* routes
+	SetupCouncilRoutes(app, repo, audit) // Configures /api/council/* routes
 
 	// Members page routes - HTML page rendering
-	SetupMembersRoutes(app, repo) // Configures /members/* routes
+	SetupMembersRoutes(app, repo, audit) // Configures /members/* routes
 
 	// Events page routes - HTML page rendering
 	SetupEventsRoutes(app, repo) // Configures /events route
 
 	// Application routes - Membership applications
-	SetupApplicationRoutes(app, repo) // Configures /membership/apply-now and /api/applications/* routes
+	SetupApplicationRoutes(app, repo, audit) // Configures /membership/apply-now and /api/applications/* routes
+	SetupRequirementRoutes(app, repo, audit) // Configures /api/admin/requirements/* routes
 
-	// OTP routes - Email OTP verification
-	otpHandler := handler.NewOTPHandler()
+	// OTP routes - Email confirmation codes (application confirmation)
+	otpHandler := handler.NewOTPHandler(verification)
 	api.Post("/otp/send", otpHandler.SendOTP)     // Send OTP to email
 	api.Post("/otp/verify", otpHandler.VerifyOTP) // Verify OTP code
 
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/AliSleiman0/Lacpa/config"
+	"github.com/AliSleiman0/Lacpa/repository"
+	"github.com/joho/godotenv"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// uniqueField is a field that must be unique within a collection
+type uniqueField struct {
+	collection      string
+	field           string
+	caseInsensitive bool
+}
+
+// Fields covered by the unique indexes created at startup
+var uniqueFields = []uniqueField{
+	{collection: "users", field: "lacpa_id"},
+	{collection: "users", field: "email", caseInsensitive: true},
+	{collection: "individual_members", field: "lacpa_id"},
+	{collection: "firm_members", field: "lacpa_id"},
+}
+
+// duplicate is a value held by more than one document
+type duplicate struct {
+	Value string        `bson:"_id"`
+	IDs   []interface{} `bson:"ids"`
+	Count int           `bson:"count"`
+}
+
+// Reports documents that would violate the unique indexes on LACPA IDs and
+// emails, and optionally creates the indexes once the data is clean.
+//
+// Usage (from Backend/scripts/check_duplicates):
+//
+//	go run . [-create-indexes]
+//
+// Exits with status 1 when duplicates are found.
+func main() {
+	createIndexes := flag.Bool("create-indexes", false, "create the unique indexes when no duplicates are found")
+	flag.Parse()
+
+	// Load environment variables
+	if err := godotenv.Load("../../.env"); err != nil {
+		log.Println("No .env file found, using system environment variables")
+	}
+
+	// Initialize MongoDB connection
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	mongoClient, err := config.ConnectMongoDB(ctx)
+	if err != nil {
+		log.Fatal("Failed to connect to MongoDB:", err)
+	}
+	defer func() {
+		if err := mongoClient.Disconnect(context.Background()); err != nil {
+			log.Fatal(err)
+		}
+	}()
+
+	databaseName := os.Getenv("MONGO_DATABASE")
+	if databaseName == "" {
+		databaseName = "lacpa"
+	}
+	database := mongoClient.Database(databaseName)
+
+	total := 0
+	for _, unique := range uniqueFields {
+		duplicates, err := findDuplicates(ctx, database, unique)
+		if err != nil {
+			log.Fatalf("Failed to check %s.%s: %v", unique.collection, unique.field, err)
+		}
+
+		if len(duplicates) == 0 {
+			fmt.Printf("✅ %s.%s: no duplicates\n", unique.collection, unique.field)
+			continue
+		}
+
+		fmt.Printf("❌ %s.%s: %d duplicated values\n", unique.collection, unique.field, len(duplicates))
+		for _, dup := range duplicates {
+			fmt.Printf("   %q held by %d documents: %v\n", dup.Value, dup.Count, dup.IDs)
+		}
+		total += len(duplicates)
+	}
+
+	if total > 0 {
+		fmt.Printf("\nFound %d duplicated values. Resolve them before creating the unique indexes.\n", total)
+		os.Exit(1)
+	}
+
+	if !*createIndexes {
+		fmt.Println("\nNo duplicates found. Re-run with -create-indexes to create the unique indexes.")
+		return
+	}
+
+	if err := repository.NewAuthRepository(database).EnsureIndexes(ctx); err != nil {
+		log.Fatal("Failed to create user indexes:", err)
+	}
+	if err := repository.NewMembersRepository(database).EnsureMemberIndexes(ctx); err != nil {
+		log.Fatal("Failed to create member indexes:", err)
+	}
+	fmt.Println("\n✅ Unique indexes created")
+}
+
+// findDuplicates groups documents by the field and returns values held more than once.
+// Empty values are skipped since they are not indexed.
+func findDuplicates(ctx context.Context, database *mongo.Database, unique uniqueField) ([]duplicate, error) {
+	var key interface{} = "$" + unique.field
+	if unique.caseInsensitive {
+		key = bson.M{"$toLower": "$" + unique.field}
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{unique.field: bson.M{"$type": "string", "$gt": ""}}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":   key,
+			"ids":   bson.M{"$push": "$_id"},
+			"count": bson.M{"$sum": 1},
+		}}},
+		{{Key: "$match", Value: bson.M{"count": bson.M{"$gt": 1}}}},
+		{{Key: "$sort", Value: bson.M{"count": -1}}},
+	}
+
+	cursor, err := database.Collection(unique.collection).Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var duplicates []duplicate
+	if err := cursor.All(ctx, &duplicates); err != nil {
+		return nil, err
+	}
+	return duplicates, nil
+}
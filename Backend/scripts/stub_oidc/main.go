@@ -0,0 +1,52 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/AliSleiman0/Lacpa/scripts/stub_oidc/oidcstub"
+)
+
+// Local stub OIDC identity provider for developing and testing staff single sign-on.
+// The provider itself lives in oidcstub so the OIDC tests can run against it.
+//
+// Usage:
+//
+//	go run ./scripts/stub_oidc -addr :9000
+//
+// then start the API with:
+//
+//	OIDC_ISSUER=http://localhost:9000 OIDC_CLIENT_ID=lacpa-cms \
+//	OIDC_GROUP_ROLES=lacpa-admins=admin,lacpa-editors=content_editor
+//
+// and open http://localhost:3000/api/auth/oidc/login.
+// Roles requiring 2FA still get a TOTP challenge unless "mfa" is listed in the
+// authentication methods on the sign-in page.
+// Never expose this server: it signs in anyone as anyone.
+func main() {
+	addr := flag.String("addr", ":9000", "listen address")
+	issuer := flag.String("issuer", "http://localhost:9000", "issuer URL, must match OIDC_ISSUER")
+	clientID := flag.String("client-id", "lacpa-cms", "accepted client ID, must match OIDC_CLIENT_ID")
+	subject := flag.String("sub", "staff-0001", "default subject")
+	email := flag.String("email", "staff@lacpa.org.lb", "default email")
+	name := flag.String("name", "LACPA Staff", "default name")
+	groups := flag.String("groups", "lacpa-admins", "default comma separated groups")
+	amr := flag.String("amr", "pwd", "default comma separated authentication methods")
+	flag.Parse()
+
+	provider, err := oidcstub.New(*issuer, *clientID, oidcstub.Identity{
+		Subject: *subject,
+		Email:   *email,
+		Name:    *name,
+		Groups:  strings.Split(*groups, ","),
+		AMR:     strings.Split(*amr, ","),
+	})
+	if err != nil {
+		log.Fatal("Failed to generate signing key:", err)
+	}
+
+	log.Printf("Stub OIDC provider %s listening on %s (client %s)", provider.Issuer, *addr, provider.ClientID)
+	log.Fatal(http.ListenAndServe(*addr, provider.Handler()))
+}
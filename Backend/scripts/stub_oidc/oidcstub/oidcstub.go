@@ -0,0 +1,303 @@
+// Package oidcstub is a stub OIDC identity provider for developing and testing
+// staff single sign-on. It implements just enough of OpenID Connect for
+// /api/auth/oidc: discovery, JWKS, an authorization page that lets you pick the
+// identity, and a token endpoint that checks PKCE and returns an RS256 ID token.
+//
+// It is served by scripts/stub_oidc and used by the OIDC tests.
+// Never expose it: it signs in anyone as anyone.
+package oidcstub
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"html/template"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// KeyID is the ID of the provider's only signing key
+const KeyID = "stub-key-1"
+
+// Identity is who the provider signs in as
+type Identity struct {
+	Subject string
+	Email   string
+	Name    string
+	Groups  []string
+	AMR     []string // Authentication methods, e.g. "pwd" and "mfa"
+}
+
+// authorization is an issued code waiting to be redeemed at the token endpoint
+type authorization struct {
+	clientID      string
+	redirectURI   string
+	codeChallenge string
+	nonce         string
+	identity      Identity
+	expiresAt     time.Time
+}
+
+// Provider is the stub identity provider
+type Provider struct {
+	Issuer   string
+	ClientID string
+	Defaults Identity // Prefilled on the authorization page
+	key      *rsa.PrivateKey
+
+	mu    sync.Mutex
+	codes map[string]*authorization
+}
+
+var authorizePage = template.Must(template.New("authorize").Parse(`<!DOCTYPE html>
+<html><head><title>Stub OIDC Provider</title></head>
+<body style="font-family: sans-serif; max-width: 480px; margin: 40px auto;">
+<h2>Stub OIDC Provider</h2>
+<p>Choose the identity to sign in as.</p>
+<form method="POST" action="/authorize">
+{{range $k, $v := .Params}}<input type="hidden" name="{{$k}}" value="{{$v}}">
+{{end}}
+<p><label>Subject<br><input name="sub" value="{{.Subject}}" size="40"></label></p>
+<p><label>Email<br><input name="email" value="{{.Email}}" size="40"></label></p>
+<p><label>Name<br><input name="name" value="{{.Name}}" size="40"></label></p>
+<p><label>Groups (comma separated)<br><input name="groups" value="{{.Groups}}" size="40"></label></p>
+<p><label>Authentication methods (comma separated, add "mfa" to assert a second factor)<br><input name="amr" value="{{.AMR}}" size="40"></label></p>
+<p><button type="submit">Sign in</button></p>
+</form>
+</body></html>`))
+
+// New creates a provider with a fresh signing key
+func New(issuer, clientID string, defaults Identity) (*Provider, error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, err
+	}
+	return &Provider{
+		Issuer:   strings.TrimRight(issuer, "/"),
+		ClientID: clientID,
+		Defaults: defaults,
+		key:      key,
+		codes:    map[string]*authorization{},
+	}, nil
+}
+
+// Handler serves the discovery document, JWKS, authorization and token endpoints
+func (p *Provider) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", p.discovery)
+	mux.HandleFunc("/jwks", p.jwks)
+	mux.HandleFunc("/authorize", p.authorize)
+	mux.HandleFunc("/token", p.token)
+	return mux
+}
+
+// Claims returns the ID token claims the provider issues for an identity
+func (p *Provider) Claims(identity Identity, nonce string) jwt.MapClaims {
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"iss":            p.Issuer,
+		"sub":            identity.Subject,
+		"aud":            p.ClientID,
+		"iat":            now.Unix(),
+		"exp":            now.Add(5 * time.Minute).Unix(),
+		"nonce":          nonce,
+		"email":          identity.Email,
+		"email_verified": true,
+		"name":           identity.Name,
+		"groups":         identity.Groups,
+	}
+	if len(identity.AMR) > 0 {
+		claims["amr"] = identity.AMR
+	}
+	return claims
+}
+
+// Sign signs ID token claims with the provider's key
+func (p *Provider) Sign(claims jwt.MapClaims) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = KeyID
+	return token.SignedString(p.key)
+}
+
+func (p *Provider) discovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"issuer":                                p.Issuer,
+		"authorization_endpoint":                p.Issuer + "/authorize",
+		"token_endpoint":                        p.Issuer + "/token",
+		"jwks_uri":                              p.Issuer + "/jwks",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"code_challenge_methods_supported":      []string{"S256"},
+	})
+}
+
+func (p *Provider) jwks(w http.ResponseWriter, r *http.Request) {
+	pub := p.key.PublicKey
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"keys": []map[string]string{{
+			"kid": KeyID,
+			"kty": "RSA",
+			"alg": "RS256",
+			"use": "sig",
+			"n":   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+		}},
+	})
+}
+
+// authorize shows the identity form on GET and issues a code on POST
+func (p *Provider) authorize(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if r.Form.Get("client_id") != p.ClientID {
+		http.Error(w, "unknown client_id", http.StatusBadRequest)
+		return
+	}
+	if r.Form.Get("response_type") != "code" || r.Form.Get("code_challenge_method") != "S256" || r.Form.Get("code_challenge") == "" {
+		http.Error(w, "only the code flow with S256 PKCE is supported", http.StatusBadRequest)
+		return
+	}
+
+	if r.Method == http.MethodGet {
+		params := map[string]string{}
+		for _, key := range []string{"client_id", "redirect_uri", "response_type", "scope", "state", "nonce", "code_challenge", "code_challenge_method"} {
+			params[key] = r.Form.Get(key)
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_ = authorizePage.Execute(w, map[string]interface{}{
+			"Params":  params,
+			"Subject": p.Defaults.Subject,
+			"Email":   p.Defaults.Email,
+			"Name":    p.Defaults.Name,
+			"Groups":  strings.Join(p.Defaults.Groups, ","),
+			"AMR":     strings.Join(p.Defaults.AMR, ","),
+		})
+		return
+	}
+
+	code := randomString()
+	auth := &authorization{
+		clientID:      p.ClientID,
+		redirectURI:   r.Form.Get("redirect_uri"),
+		codeChallenge: r.Form.Get("code_challenge"),
+		nonce:         r.Form.Get("nonce"),
+		identity: Identity{
+			Subject: r.Form.Get("sub"),
+			Email:   r.Form.Get("email"),
+			Name:    r.Form.Get("name"),
+			Groups:  splitList(r.Form.Get("groups")),
+			AMR:     splitList(r.Form.Get("amr")),
+		},
+		expiresAt: time.Now().Add(time.Minute),
+	}
+
+	p.mu.Lock()
+	p.codes[code] = auth
+	p.mu.Unlock()
+
+	target, err := url.Parse(auth.redirectURI)
+	if err != nil {
+		http.Error(w, "invalid redirect_uri", http.StatusBadRequest)
+		return
+	}
+	query := target.Query()
+	query.Set("code", code)
+	query.Set("state", r.Form.Get("state"))
+	target.RawQuery = query.Encode()
+	http.Redirect(w, r, target.String(), http.StatusFound)
+}
+
+// token redeems a code once, checking the client, redirect URI and PKCE verifier
+func (p *Provider) token(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		tokenError(w, "invalid_request")
+		return
+	}
+
+	p.mu.Lock()
+	auth := p.codes[r.Form.Get("code")]
+	delete(p.codes, r.Form.Get("code"))
+	p.mu.Unlock()
+
+	clientID := r.Form.Get("client_id")
+	if basicID, _, ok := r.BasicAuth(); ok {
+		clientID, _ = url.QueryUnescape(basicID)
+	}
+
+	switch {
+	case r.Form.Get("grant_type") != "authorization_code":
+		tokenError(w, "unsupported_grant_type")
+		return
+	case auth == nil || time.Now().After(auth.expiresAt):
+		tokenError(w, "invalid_grant")
+		return
+	case clientID != auth.clientID || r.Form.Get("redirect_uri") != auth.redirectURI:
+		tokenError(w, "invalid_grant")
+		return
+	}
+
+	sum := sha256.Sum256([]byte(r.Form.Get("code_verifier")))
+	if base64.RawURLEncoding.EncodeToString(sum[:]) != auth.codeChallenge {
+		tokenError(w, "invalid_grant")
+		return
+	}
+
+	idToken, err := p.Sign(p.Claims(auth.identity, auth.nonce))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token": randomString(),
+		"token_type":   "Bearer",
+		"expires_in":   300,
+		"id_token":     idToken,
+	})
+}
+
+func tokenError(w http.ResponseWriter, code string) {
+	writeJSON(w, http.StatusBadRequest, map[string]string{"error": code})
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+// splitList splits a comma separated form value, dropping empty items
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func randomString() string {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(buf)
+}
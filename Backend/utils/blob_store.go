@@ -0,0 +1,129 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrBlobNotFound is returned when no blob is stored under a key
+var ErrBlobNotFound = errors.New("blob not found")
+
+// BlobStore stores uploaded files by key.
+//
+// ROLE: File Storage Abstraction
+// - Handlers only see keys, never disk paths or bucket URLs
+// - LocalBlobStore keeps files on disk outside every static directory
+// - S3BlobStore talks to AWS S3 or any S3-compatible server (MinIO, R2, ...)
+// - Selected with BLOB_STORE, see NewBlobStoreFromEnv
+type BlobStore interface {
+	Put(ctx context.Context, key string, body io.Reader, size int64, contentType string) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// NewBlobStoreFromEnv creates the blob store selected by BLOB_STORE
+//
+// ROLE: Blob Store Configuration
+//   - BLOB_STORE=local (default): files under BLOB_LOCAL_DIR (default ./storage)
+//   - BLOB_STORE=s3: S3_ENDPOINT, S3_REGION, S3_BUCKET, S3_ACCESS_KEY_ID,
+//     S3_SECRET_ACCESS_KEY and S3_PATH_STYLE (default true, needed by MinIO)
+func NewBlobStoreFromEnv() (BlobStore, error) {
+	switch backend := strings.ToLower(GetEnv("BLOB_STORE", "local")); backend {
+	case "local":
+		return NewLocalBlobStore(GetEnv("BLOB_LOCAL_DIR", "./storage"))
+	case "s3":
+		return NewS3BlobStore(S3Config{
+			Endpoint:        GetEnv("S3_ENDPOINT", "https://s3.amazonaws.com"),
+			Region:          GetEnv("S3_REGION", "us-east-1"),
+			Bucket:          GetEnv("S3_BUCKET", ""),
+			AccessKeyID:     GetEnv("S3_ACCESS_KEY_ID", ""),
+			SecretAccessKey: GetEnv("S3_SECRET_ACCESS_KEY", ""),
+			PathStyle:       GetEnvBool("S3_PATH_STYLE", true),
+		})
+	default:
+		return nil, fmt.Errorf("unknown BLOB_STORE %q, expected local or s3", backend)
+	}
+}
+
+// LocalBlobStore keeps blobs as files under a root directory
+type LocalBlobStore struct {
+	root string
+}
+
+// NewLocalBlobStore creates the root directory if needed
+func NewLocalBlobStore(root string) (*LocalBlobStore, error) {
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(root, 0750); err != nil {
+		return nil, err
+	}
+	return &LocalBlobStore{root: root}, nil
+}
+
+// Put writes the blob to a temporary file and renames it into place,
+// so a failed upload never leaves a partial file under the key
+func (s *LocalBlobStore) Put(ctx context.Context, key string, body io.Reader, size int64, contentType string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, body); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Get opens the blob for reading
+func (s *LocalBlobStore) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrBlobNotFound
+	}
+	return file, err
+}
+
+// Delete removes the blob; deleting a missing blob is not an error
+func (s *LocalBlobStore) Delete(ctx context.Context, key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// path maps a key to a file under the root, rejecting keys that escape it
+func (s *LocalBlobStore) path(key string) (string, error) {
+	path := filepath.Join(s.root, filepath.FromSlash(key))
+	if key == "" || !strings.HasPrefix(path, s.root+string(filepath.Separator)) {
+		return "", fmt.Errorf("invalid blob key %q", key)
+	}
+	return path, nil
+}
@@ -1,11 +1,16 @@
 package utils
 
 import (
+	"errors"
+	"net/url"
 	"os"
 	"strconv"
 	"strings"
 )
 
+// ErrPublicBaseURLMissing is returned when PUBLIC_BASE_URL is not an absolute http(s) address
+var ErrPublicBaseURLMissing = errors.New("PUBLIC_BASE_URL must be set to the site's absolute http(s) address")
+
 // Config holds application configuration
 type Config struct {
 	// Server settings
@@ -30,6 +35,25 @@ type Config struct {
 	EnableRateLimit bool
 	EnableCORS      bool
 	EnableLogger    bool
+
+	// Rate limiting
+	RateLimitStore string // "memory" or "mongo"
+
+	// Reverse proxy: the client IP is read from ProxyHeader only on requests
+	// coming from one of TrustedProxies (IPs or CIDR ranges)
+	ProxyHeader    string
+	TrustedProxies []string
+}
+
+// PublicBaseURL returns the site address emailed and redirect links start with (PUBLIC_BASE_URL).
+// It is never derived from the request, whose Host header the client controls.
+func PublicBaseURL() (string, error) {
+	base := strings.TrimRight(GetEnv("PUBLIC_BASE_URL", ""), "/")
+	parsed, err := url.Parse(base)
+	if base == "" || err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return "", ErrPublicBaseURLMissing
+	}
+	return base, nil
 }
 
 // LoadConfig loads configuration from environment variables
@@ -66,6 +90,13 @@ func LoadConfig() (*Config, error) {
 		EnableRateLimit: GetEnvBool("ENABLE_RATE_LIMIT", true),
 		EnableCORS:      GetEnvBool("ENABLE_CORS", true),
 		EnableLogger:    GetEnvBool("ENABLE_LOGGER", true),
+
+		// Rate limiting defaults
+		RateLimitStore: GetEnv("RATE_LIMIT_STORE", "memory"),
+
+		// Reverse proxy defaults: no proxy is trusted, so the socket address is the client IP
+		ProxyHeader:    GetEnv("PROXY_HEADER", "X-Real-IP"),
+		TrustedProxies: GetEnvSlice("TRUSTED_PROXIES", nil),
 	}
 
 	return config, nil
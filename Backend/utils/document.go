@@ -0,0 +1,87 @@
+package utils
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"encoding/hex"
+	"strconv"
+	"time"
+)
+
+// documentSignatures maps the magic bytes of accepted uploads to their MIME type.
+// The browser's Content-Type and the file extension are never trusted.
+var documentSignatures = []struct {
+	magic       []byte
+	contentType string
+}{
+	{[]byte("%PDF-"), "application/pdf"},
+	{[]byte("\x89PNG\r\n\x1a\n"), "image/png"},
+	{[]byte("\xff\xd8\xff"), "image/jpeg"},
+}
+
+// DocumentSniffLength is how many leading bytes DetectDocumentType needs
+const DocumentSniffLength = 8
+
+// DetectDocumentType identifies an accepted document type from the first bytes of a file
+func DetectDocumentType(head []byte) (string, bool) {
+	for _, signature := range documentSignatures {
+		if bytes.HasPrefix(head, signature.magic) {
+			return signature.contentType, true
+		}
+	}
+	return "", false
+}
+
+// DocumentExtension returns the file extension used for downloads of a detected type
+func DocumentExtension(contentType string) string {
+	switch contentType {
+	case "application/pdf":
+		return ".pdf"
+	case "image/png":
+		return ".png"
+	case "image/jpeg":
+		return ".jpg"
+	}
+	return ""
+}
+
+// DocumentMaxSize returns the upload size limit in bytes (DOCUMENT_MAX_SIZE_MB, default 10)
+func DocumentMaxSize() int64 {
+	return int64(GetEnvInt("DOCUMENT_MAX_SIZE_MB", 10)) << 20
+}
+
+// DocumentUnattachedTTL returns how long an upload may wait to be attached to an
+// application before it is deleted (DOCUMENT_UNATTACHED_TTL_HOURS, default 24)
+func DocumentUnattachedTTL() time.Duration {
+	return time.Duration(GetEnvInt("DOCUMENT_UNATTACHED_TTL_HOURS", 24)) * time.Hour
+}
+
+// DocumentLinkTTL returns how long signed download links stay valid (DOCUMENT_LINK_TTL_SECONDS, default 300)
+func DocumentLinkTTL() time.Duration {
+	return time.Duration(GetEnvInt("DOCUMENT_LINK_TTL_SECONDS", 300)) * time.Second
+}
+
+// SignDocumentLink returns the signature of a download link for a document
+func SignDocumentLink(documentID string, expires time.Time) string {
+	return hex.EncodeToString(documentLinkMAC(documentID, expires.Unix()))
+}
+
+// VerifyDocumentLink checks a download link signature and its expiry
+func VerifyDocumentLink(documentID, expires, signature string) bool {
+	expiresAt, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || time.Now().Unix() > expiresAt {
+		return false
+	}
+	given, err := hex.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+	return hmac.Equal(given, documentLinkMAC(documentID, expiresAt))
+}
+
+// documentLinkMAC signs the document ID and expiry with DOCUMENT_SIGNING_SECRET,
+// falling back to the JWT secret
+func documentLinkMAC(documentID string, expires int64) []byte {
+	secret := GetEnv("DOCUMENT_SIGNING_SECRET", GetJWTSecret())
+	return hmacSHA256([]byte(secret), "document:"+documentID+":"+strconv.FormatInt(expires, 10))
+}
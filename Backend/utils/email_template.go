@@ -1,7 +1,10 @@
 package utils
 
-// OTPEmailTemplate returns a beautifully designed HTML email template for OTP
-func OTPEmailTemplate(otp, recipientName string) string {
+import "html"
+
+// OTPEmailTemplate returns a beautifully designed HTML email template for OTP.
+// message explains why the code was sent and validity states when it expires.
+func OTPEmailTemplate(otp, recipientName, message, validity string) string {
 	// If no name provided, use generic greeting
 	if recipientName == "" {
 		recipientName = "User"
@@ -228,7 +231,7 @@ func OTPEmailTemplate(otp, recipientName string) string {
             <div class="greeting">Hello ` + recipientName + `,</div>
             
             <div class="message">
-                We received a request to verify your email address. Please use the One-Time Password (OTP) below to complete your verification.
+                ` + message + `
             </div>
 
             <div class="otp-container">
@@ -236,7 +239,7 @@ func OTPEmailTemplate(otp, recipientName string) string {
                 <div class="otp-code">` + otp + `</div>
                 <div class="otp-validity">
                     <span class="clock-icon">⏰</span>
-                    <span class="validity-text">Expires in 5 minutes</span>
+                    <span class="validity-text">` + validity + `</span>
                 </div>
             </div>
 
@@ -290,3 +293,114 @@ func OTPEmailTemplate(otp, recipientName string) string {
 </body>
 </html>`
 }
+
+// NoticeEmailTemplate returns a plain HTML email informing a user about a change
+// to their account. Used for security notices that carry no code.
+func NoticeEmailTemplate(recipientName, message string) string {
+	if recipientName == "" {
+		recipientName = "User"
+	}
+
+	return `<!DOCTYPE html>
+<html lang="en">
+<head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <title>LACPA Account Notice</title>
+</head>
+<body style="font-family: -apple-system, BlinkMacSystemFont, 'Segoe UI', Roboto, Arial, sans-serif; background-color: #f5f5f5; padding: 20px;">
+    <div style="max-width: 600px; margin: 0 auto; background-color: #ffffff; border-radius: 16px; overflow: hidden;">
+        <div style="background: linear-gradient(135deg, #0ea5e9 0%, #0284c7 100%); padding: 30px; text-align: center;">
+            <div style="font-size: 32px; font-weight: bold; color: #ffffff;">LACPA</div>
+            <div style="color: rgba(255, 255, 255, 0.95); font-size: 16px;">Lebanese Association of Certified Public Accountants</div>
+        </div>
+        <div style="padding: 40px 30px;">
+            <div style="font-size: 24px; color: #1e293b; margin-bottom: 20px; font-weight: 600;">Hello ` + recipientName + `,</div>
+            <div style="color: #475569; font-size: 16px; line-height: 1.8;">` + message + `</div>
+            <div style="background-color: #fef3c7; border-left: 4px solid #f59e0b; padding: 16px; border-radius: 8px; margin-top: 25px; color: #92400e; font-size: 14px;">
+                <strong>⚠️ Important:</strong> If you did not make this change, please contact LACPA support immediately.
+            </div>
+        </div>
+        <div style="background-color: #1e293b; padding: 20px; text-align: center; color: #94a3b8; font-size: 12px;">
+            This is an automated message from LACPA. Please do not reply to this email.
+        </div>
+    </div>
+</body>
+</html>`
+}
+
+// ActionEmailTemplate returns a plain HTML email with a button linking back to the site.
+// Used for links the recipient asked for, such as resuming a draft application.
+// The message is plain text and is escaped.
+func ActionEmailTemplate(recipientName, message, actionText, actionURL, validity string) string {
+	if recipientName == "" {
+		recipientName = "User"
+	}
+
+	return `<!DOCTYPE html>
+<html lang="en">
+<head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <title>LACPA</title>
+</head>
+<body style="font-family: -apple-system, BlinkMacSystemFont, 'Segoe UI', Roboto, Arial, sans-serif; background-color: #f5f5f5; padding: 20px;">
+    <div style="max-width: 600px; margin: 0 auto; background-color: #ffffff; border-radius: 16px; overflow: hidden;">
+        <div style="background: linear-gradient(135deg, #0ea5e9 0%, #0284c7 100%); padding: 30px; text-align: center;">
+            <div style="font-size: 32px; font-weight: bold; color: #ffffff;">LACPA</div>
+            <div style="color: rgba(255, 255, 255, 0.95); font-size: 16px;">Lebanese Association of Certified Public Accountants</div>
+        </div>
+        <div style="padding: 40px 30px;">
+            <div style="font-size: 24px; color: #1e293b; margin-bottom: 20px; font-weight: 600;">Hello ` + html.EscapeString(recipientName) + `,</div>
+            <div style="color: #475569; font-size: 16px; line-height: 1.8;">` + html.EscapeString(message) + `</div>
+            <div style="text-align: center; margin: 30px 0;">
+                <a href="` + html.EscapeString(actionURL) + `" style="display: inline-block; background: linear-gradient(135deg, #0ea5e9 0%, #0284c7 100%); color: #ffffff; text-decoration: none; padding: 14px 32px; border-radius: 8px; font-weight: 600;">` + html.EscapeString(actionText) + `</a>
+            </div>
+            <div style="color: #64748b; font-size: 14px; text-align: center;">` + html.EscapeString(validity) + `</div>
+            <div style="background-color: #fef3c7; border-left: 4px solid #f59e0b; padding: 16px; border-radius: 8px; margin-top: 25px; color: #92400e; font-size: 14px;">
+                <strong>⚠️ Important:</strong> Anyone with this link can open it. Do not forward this email.
+            </div>
+        </div>
+        <div style="background-color: #1e293b; padding: 20px; text-align: center; color: #94a3b8; font-size: 12px;">
+            This is an automated message from LACPA. Please do not reply to this email.
+        </div>
+    </div>
+</body>
+</html>`
+}
+
+// NotificationEmailTemplate returns a plain HTML email telling staff about activity in the CMS,
+// with a button opening it. The message is plain text and is escaped, since it may quote
+// text written by other users.
+func NotificationEmailTemplate(recipientName, message, actionText, actionURL string) string {
+	if recipientName == "" {
+		recipientName = "User"
+	}
+
+	return `<!DOCTYPE html>
+<html lang="en">
+<head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <title>LACPA Notification</title>
+</head>
+<body style="font-family: -apple-system, BlinkMacSystemFont, 'Segoe UI', Roboto, Arial, sans-serif; background-color: #f5f5f5; padding: 20px;">
+    <div style="max-width: 600px; margin: 0 auto; background-color: #ffffff; border-radius: 16px; overflow: hidden;">
+        <div style="background: linear-gradient(135deg, #0ea5e9 0%, #0284c7 100%); padding: 30px; text-align: center;">
+            <div style="font-size: 32px; font-weight: bold; color: #ffffff;">LACPA</div>
+            <div style="color: rgba(255, 255, 255, 0.95); font-size: 16px;">Lebanese Association of Certified Public Accountants</div>
+        </div>
+        <div style="padding: 40px 30px;">
+            <div style="font-size: 24px; color: #1e293b; margin-bottom: 20px; font-weight: 600;">Hello ` + html.EscapeString(recipientName) + `,</div>
+            <div style="color: #475569; font-size: 16px; line-height: 1.8; white-space: pre-line;">` + html.EscapeString(message) + `</div>
+            <div style="text-align: center; margin: 30px 0;">
+                <a href="` + html.EscapeString(actionURL) + `" style="display: inline-block; background: linear-gradient(135deg, #0ea5e9 0%, #0284c7 100%); color: #ffffff; text-decoration: none; padding: 14px 32px; border-radius: 8px; font-weight: 600;">` + html.EscapeString(actionText) + `</a>
+            </div>
+        </div>
+        <div style="background-color: #1e293b; padding: 20px; text-align: center; color: #94a3b8; font-size: 12px;">
+            This is an automated message from LACPA. Please do not reply to this email.
+        </div>
+    </div>
+</body>
+</html>`
+}
@@ -1,6 +1,7 @@
 package utils
 
 import (
+	"errors"
 	"os"
 	"time"
 
@@ -9,13 +10,28 @@ import (
 
 // JWTClaims represents the JWT claims
 type JWTClaims struct {
-	UserID  string `json:"user_id"`
-	LACPAID string `json:"lacpa_id"`
-	Email   string `json:"email"`
-	Role    string `json:"role"`
+	UserID      string   `json:"user_id"`
+	LACPAID     string   `json:"lacpa_id"`
+	Email       string   `json:"email"`
+	Role        string   `json:"role"`
+	Permissions []string `json:"permissions"`       // Permissions granted by the role when the token was issued
+	RoleVersion int      `json:"role_version"`      // Role version the permissions were read from
+	SessionID   string   `json:"sid"`               // Server-side session the token belongs to
+	Purpose     string   `json:"purpose,omitempty"` // Set on challenge tokens, empty on access tokens
 	jwt.RegisteredClaims
 }
 
+// TokenSubject holds the user and role data embedded in an access token
+type TokenSubject struct {
+	UserID      string
+	LACPAID     string
+	Email       string
+	Role        string
+	Permissions []string
+	RoleVersion int
+	SessionID   string
+}
+
 // GetJWTSecret retrieves the JWT secret from environment or uses default
 func GetJWTSecret() string {
 	secret := os.Getenv("JWT_SECRET")
@@ -26,15 +42,39 @@ func GetJWTSecret() string {
 	return secret
 }
 
+// AccessTokenTTL returns the lifetime of access tokens (ACCESS_TOKEN_TTL_MINUTES, default 15)
+func AccessTokenTTL() time.Duration {
+	return time.Duration(GetEnvInt("ACCESS_TOKEN_TTL_MINUTES", 15)) * time.Minute
+}
+
+// RefreshTokenTTL returns the lifetime of refresh tokens and their sessions (REFRESH_TOKEN_TTL_DAYS, default 30)
+func RefreshTokenTTL() time.Duration {
+	return time.Duration(GetEnvInt("REFRESH_TOKEN_TTL_DAYS", 30)) * 24 * time.Hour
+}
+
+// ChallengeTokenTTL is the lifetime of the token bridging the password and TOTP steps of a login
+const ChallengeTokenTTL = 5 * time.Minute
+
+// ApplicationAccessTTL returns how long a tracking code unlocks an application (APPLICATION_ACCESS_TTL_MINUTES, default 30)
+func ApplicationAccessTTL() time.Duration {
+	return time.Duration(GetEnvInt("APPLICATION_ACCESS_TTL_MINUTES", 30)) * time.Minute
+}
+
+// ErrWrongTokenPurpose is returned when a challenge token is used as an access token or vice versa
+var ErrWrongTokenPurpose = errors.New("token is not valid for this purpose")
+
 // GenerateJWT generates a new JWT token
-func GenerateJWT(userID, lacpaID, email, role string) (string, error) {
+func GenerateJWT(subject TokenSubject) (string, error) {
 	claims := JWTClaims{
-		UserID:  userID,
-		LACPAID: lacpaID,
-		Email:   email,
-		Role:    role,
+		UserID:      subject.UserID,
+		LACPAID:     subject.LACPAID,
+		Email:       subject.Email,
+		Role:        subject.Role,
+		Permissions: subject.Permissions,
+		RoleVersion: subject.RoleVersion,
+		SessionID:   subject.SessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)), // 24 hours
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(AccessTokenTTL())),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			NotBefore: jwt.NewNumericDate(time.Now()),
 		},
@@ -54,9 +94,55 @@ func ValidateJWT(tokenString string) (*JWTClaims, error) {
 		return nil, err
 	}
 
-	if claims, ok := token.Claims.(*JWTClaims); ok && token.Valid {
-		return claims, nil
+	claims, ok := token.Claims.(*JWTClaims)
+	if !ok || !token.Valid {
+		return nil, jwt.ErrSignatureInvalid
+	}
+
+	// Challenge tokens only prove the password step and never grant access
+	if claims.Purpose != "" {
+		return nil, ErrWrongTokenPurpose
 	}
 
-	return nil, jwt.ErrSignatureInvalid
+	return claims, nil
+}
+
+// GenerateChallengeToken issues a short-lived token proving the password step of a login
+func GenerateChallengeToken(userID, purpose string) (string, error) {
+	return GeneratePurposeToken(userID, purpose, ChallengeTokenTTL)
+}
+
+// GeneratePurposeToken issues a token that only validates through ValidateChallengeToken
+// with the same purpose. subject is stored in the user_id claim.
+func GeneratePurposeToken(subject, purpose string, ttl time.Duration) (string, error) {
+	claims := JWTClaims{
+		UserID:  subject,
+		Purpose: purpose,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(ttl)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			NotBefore: jwt.NewNumericDate(time.Now()),
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString([]byte(GetJWTSecret()))
+}
+
+// ValidateChallengeToken validates a challenge token issued for the given purpose
+func ValidateChallengeToken(tokenString, purpose string) (*JWTClaims, error) {
+	claims := &JWTClaims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		return []byte(GetJWTSecret()), nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+	if err != nil {
+		return nil, err
+	}
+	if !token.Valid {
+		return nil, jwt.ErrSignatureInvalid
+	}
+	if claims.Purpose != purpose {
+		return nil, ErrWrongTokenPurpose
+	}
+	return claims, nil
 }
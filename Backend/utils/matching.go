@@ -0,0 +1,181 @@
+package utils
+
+import (
+	"slices"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// nameParticles are dropped when comparing names: "Boushra El Obeid" and
+// "Boushra Obeid" are the same person
+var nameParticles = map[string]bool{"el": true, "al": true, "bin": true, "ibn": true}
+
+// firmNameSuffixes are legal forms and filler words dropped when comparing firm names
+var firmNameSuffixes = map[string]bool{
+	"sal": true, "sarl": true, "sa": true, "llc": true, "ltd": true, "limited": true, "inc": true,
+	"co": true, "company": true, "and": true, "partners": true, "group": true, "holding": true, "offshore": true,
+}
+
+// NormalizeEmail lowercases an address and drops any +tag from the local part
+func NormalizeEmail(email string) string {
+	email = strings.ToLower(strings.TrimSpace(email))
+	at := strings.LastIndex(email, "@")
+	if at <= 0 {
+		return email
+	}
+	local, domain := email[:at], email[at:]
+	if plus := strings.Index(local, "+"); plus > 0 {
+		local = local[:plus]
+	}
+	return local + domain
+}
+
+// NormalizePhone reduces a Lebanese phone number to its national digits without the
+// trunk 0, so "+961 01 123 456", "01-123456" and "1123456" compare equal.
+// It returns "" when too few digits are left to identify a line.
+func NormalizePhone(phone string) string {
+	var digits strings.Builder
+	for _, r := range phone {
+		if r >= '0' && r <= '9' {
+			digits.WriteRune(r)
+		}
+	}
+	number := digits.String()
+	switch {
+	case strings.HasPrefix(number, "00961"):
+		number = number[5:]
+	case strings.HasPrefix(number, "961") && len(number) > 9:
+		number = number[3:]
+	}
+	number = strings.TrimLeft(number, "0")
+	if len(number) < 7 {
+		return ""
+	}
+	return number
+}
+
+// NormalizeRegistrationNumber keeps only the letters and digits of a registration number
+func NormalizeRegistrationNumber(number string) string {
+	var normalized strings.Builder
+	for _, r := range strings.ToLower(number) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			normalized.WriteRune(r)
+		}
+	}
+	return normalized.String()
+}
+
+// NormalizeName lowercases a person's name, turns punctuation into spaces and drops particles
+func NormalizeName(name string) string {
+	return strings.Join(nameTokens(name, nameParticles), " ")
+}
+
+// NormalizeFirmName is NormalizeName for firms, also dropping legal forms such as "SAL"
+func NormalizeFirmName(name string) string {
+	return strings.Join(nameTokens(name, firmNameSuffixes), " ")
+}
+
+// NamePrefixes returns the first length letters of each word of a normalized name,
+// without repeats
+func NamePrefixes(name string, length int) []string {
+	var prefixes []string
+	for _, word := range strings.Fields(name) {
+		runes := []rune(word)
+		if len(runes) > length {
+			runes = runes[:length]
+		}
+		prefix := string(runes)
+		if !slices.Contains(prefixes, prefix) {
+			prefixes = append(prefixes, prefix)
+		}
+	}
+	return prefixes
+}
+
+func nameTokens(name string, drop map[string]bool) []string {
+	fields := strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	tokens := fields[:0]
+	for _, field := range fields {
+		if !drop[field] {
+			tokens = append(tokens, field)
+		}
+	}
+	return tokens
+}
+
+// NameSimilarity scores two normalized names from 0 (unrelated) to 1 (identical).
+// Word order is ignored, so "Obeid Boushra" matches "Boushra Obeid".
+func NameSimilarity(a, b string) float64 {
+	if a == "" || b == "" {
+		return 0
+	}
+	if a == b {
+		return 1
+	}
+	sortedA, sortedB := strings.Fields(a), strings.Fields(b)
+	sort.Strings(sortedA)
+	sort.Strings(sortedB)
+
+	score := JaroWinkler(a, b)
+	if sorted := JaroWinkler(strings.Join(sortedA, " "), strings.Join(sortedB, " ")); sorted > score {
+		score = sorted
+	}
+	return score
+}
+
+// JaroWinkler returns the Jaro-Winkler similarity of two strings, between 0 and 1.
+// It tolerates typos and transliteration differences while rewarding a shared prefix.
+func JaroWinkler(a, b string) float64 {
+	s1, s2 := []rune(a), []rune(b)
+	if len(s1) == 0 || len(s2) == 0 {
+		return 0
+	}
+
+	window := max(len(s1), len(s2))/2 - 1
+	if window < 0 {
+		window = 0
+	}
+
+	matched1 := make([]bool, len(s1))
+	matched2 := make([]bool, len(s2))
+	matches := 0
+	for i := range s1 {
+		start, end := max(0, i-window), min(len(s2), i+window+1)
+		for j := start; j < end; j++ {
+			if !matched2[j] && s1[i] == s2[j] {
+				matched1[i], matched2[j] = true, true
+				matches++
+				break
+			}
+		}
+	}
+	if matches == 0 {
+		return 0
+	}
+
+	transpositions, k := 0, 0
+	for i := range s1 {
+		if !matched1[i] {
+			continue
+		}
+		for !matched2[k] {
+			k++
+		}
+		if s1[i] != s2[k] {
+			transpositions++
+		}
+		k++
+	}
+
+	m := float64(matches)
+	jaro := (m/float64(len(s1)) + m/float64(len(s2)) + (m-float64(transpositions)/2)/m) / 3
+
+	prefix := 0
+	for prefix < min(4, len(s1), len(s2)) && s1[prefix] == s2[prefix] {
+		prefix++
+	}
+	return jaro + float64(prefix)*0.1*(1-jaro)
+}
@@ -0,0 +1,117 @@
+package utils
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestNormalizePhone(t *testing.T) {
+	tests := []struct {
+		phone string
+		want  string
+	}{
+		{phone: "+961 01 123 456", want: "1123456"},
+		{phone: "00961 1 123456", want: "1123456"},
+		{phone: "01-123456", want: "1123456"},
+		{phone: "1123456", want: "1123456"},
+		{phone: "+961 3 123 456", want: "3123456"},
+		{phone: "03/123456", want: "3123456"},
+		{phone: "+961 71 123 456", want: "71123456"},
+		{phone: "71 123 456", want: "71123456"},
+		// Nine digits or fewer starting with 961 are a local number, not the country code
+		{phone: "961 2345", want: "9612345"},
+		{phone: "123 456", want: ""},
+		{phone: "ext. 12", want: ""},
+		{phone: "", want: ""},
+	}
+
+	for _, tt := range tests {
+		if got := NormalizePhone(tt.phone); got != tt.want {
+			t.Errorf("NormalizePhone(%q) = %q, want %q", tt.phone, got, tt.want)
+		}
+	}
+}
+
+func TestNormalizeNames(t *testing.T) {
+	tests := []struct {
+		name      string
+		normalize func(string) string
+		value     string
+		want      string
+	}{
+		{name: "particles dropped", normalize: NormalizeName, value: "Boushra El-Obeid", want: "boushra obeid"},
+		{name: "punctuation and case", normalize: NormalizeName, value: "  JEAN-PAUL  o'Hara ", want: "jean paul o hara"},
+		{name: "legal forms dropped", normalize: NormalizeFirmName, value: "Haddad & Partners SAL", want: "haddad"},
+		{name: "filler words dropped", normalize: NormalizeFirmName, value: "Khoury and Co SARL", want: "khoury"},
+		{name: "email tag dropped", normalize: NormalizeEmail, value: " Rami+Lacpa@Example.com ", want: "rami@example.com"},
+		{name: "registration punctuation dropped", normalize: NormalizeRegistrationNumber, value: "CR-12/345 B", want: "cr12345b"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.normalize(tt.value); got != tt.want {
+				t.Fatalf("normalize(%q) = %q, want %q", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNamePrefixes(t *testing.T) {
+	if got, want := NamePrefixes("haddad hadi ha", 3), []string{"had", "ha"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("NamePrefixes = %q, want %q", got, want)
+	}
+	if got := NamePrefixes("", 3); got != nil {
+		t.Fatalf("NamePrefixes of an empty name = %q, want nil", got)
+	}
+}
+
+func TestJaroWinkler(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want float64
+	}{
+		// Reference values from Winkler's paper
+		{a: "martha", b: "marhta", want: 0.9611},
+		{a: "dwayne", b: "duane", want: 0.84},
+		{a: "dixon", b: "dicksonx", want: 0.8133},
+		{a: "same", b: "same", want: 1},
+		{a: "abc", b: "xyz", want: 0},
+		{a: "", b: "abc", want: 0},
+		// Runes, not bytes, are compared
+		{a: "élie", b: "elie", want: 0.8333},
+	}
+
+	for _, tt := range tests {
+		got := JaroWinkler(tt.a, tt.b)
+		if math.Abs(got-tt.want) > 0.0001 {
+			t.Errorf("JaroWinkler(%q, %q) = %.4f, want %.4f", tt.a, tt.b, got, tt.want)
+		}
+		if reverse := JaroWinkler(tt.b, tt.a); math.Abs(reverse-got) > 1e-9 {
+			t.Errorf("JaroWinkler is not symmetric for %q and %q: %.4f vs %.4f", tt.a, tt.b, got, reverse)
+		}
+	}
+}
+
+func TestNameSimilarity(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		min  float64
+		max  float64
+	}{
+		{name: "identical", a: "boushra obeid", b: "boushra obeid", min: 1, max: 1},
+		{name: "word order ignored", a: "obeid boushra", b: "boushra obeid", min: 1, max: 1},
+		{name: "transliteration", a: "mohammad haddad", b: "mohamad haddad", min: 0.95, max: 0.99},
+		{name: "different people", a: "rami khoury", b: "lina saab", min: 0, max: 0.6},
+		{name: "empty name", a: "", b: "rami khoury", min: 0, max: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NameSimilarity(tt.a, tt.b); got < tt.min || got > tt.max {
+				t.Fatalf("NameSimilarity(%q, %q) = %.4f, want between %.2f and %.2f", tt.a, tt.b, got, tt.min, tt.max)
+			}
+		})
+	}
+}
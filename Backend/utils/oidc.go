@@ -0,0 +1,436 @@
+package utils
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// OIDCConfig holds the settings of the staff single sign-on provider
+type OIDCConfig struct {
+	Issuer            string
+	ClientID          string
+	ClientSecret      string // Optional; public clients rely on PKCE alone
+	RedirectURL       string // Our callback, registered with the provider
+	Scopes            []string
+	GroupsClaim       string            // ID token claim listing the user's groups
+	GroupRoles        map[string]string // Provider group -> LACPA role name
+	GroupOrder        []string          // Groups in configured order; the first match wins
+	MFAMethods        []string          // "amr" values that prove a second factor
+	MFAContexts       []string          // "acr" values that prove a second factor
+	PostLoginRedirect string            // Page that receives the tokens after login
+}
+
+// LoadOIDCConfig reads the OIDC settings. Enabled is false when OIDC_ISSUER is not set.
+//
+// OIDC_GROUP_ROLES maps provider groups to roles, e.g. "lacpa-admins=admin,lacpa-editors=content_editor".
+// When a user is in several mapped groups the first listed mapping wins.
+//
+// OIDC_MFA_AMR and OIDC_MFA_ACR list the "amr" and "acr" values by which the provider
+// asserts a second factor. Without such a claim, roles requiring 2FA get a TOTP challenge.
+func LoadOIDCConfig() (cfg OIDCConfig, enabled bool) {
+	cfg = OIDCConfig{
+		Issuer:            strings.TrimRight(GetEnv("OIDC_ISSUER", ""), "/"),
+		ClientID:          GetEnv("OIDC_CLIENT_ID", ""),
+		ClientSecret:      GetEnv("OIDC_CLIENT_SECRET", ""),
+		RedirectURL:       GetEnv("OIDC_REDIRECT_URL", "http://localhost:3000/api/auth/oidc/callback"),
+		Scopes:            strings.Fields(GetEnv("OIDC_SCOPES", "openid email profile")),
+		GroupsClaim:       GetEnv("OIDC_GROUPS_CLAIM", "groups"),
+		GroupRoles:        map[string]string{},
+		MFAMethods:        strings.FieldsFunc(GetEnv("OIDC_MFA_AMR", "mfa"), isListSeparator),
+		MFAContexts:       strings.FieldsFunc(GetEnv("OIDC_MFA_ACR", ""), isListSeparator),
+		PostLoginRedirect: GetEnv("OIDC_POST_LOGIN_REDIRECT", "/admin/src/login.html"),
+	}
+
+	for _, pair := range strings.Split(GetEnv("OIDC_GROUP_ROLES", ""), ",") {
+		group, role, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok || group == "" || role == "" {
+			continue
+		}
+		group = strings.TrimSpace(group)
+		if _, exists := cfg.GroupRoles[group]; !exists {
+			cfg.GroupOrder = append(cfg.GroupOrder, group)
+		}
+		cfg.GroupRoles[group] = strings.TrimSpace(role)
+	}
+
+	return cfg, cfg.Issuer != "" && cfg.ClientID != ""
+}
+
+// RoleForGroups returns the role of the first configured group the user belongs to
+func (cfg OIDCConfig) RoleForGroups(groups []string) (string, bool) {
+	member := make(map[string]bool, len(groups))
+	for _, group := range groups {
+		member[group] = true
+	}
+	for _, group := range cfg.GroupOrder {
+		if member[group] {
+			return cfg.GroupRoles[group], true
+		}
+	}
+	return "", false
+}
+
+// MFAAsserted reports whether the provider says the user logged in with a second factor,
+// through an "amr" method or the "acr" context of the ID token
+func (cfg OIDCConfig) MFAAsserted(identity *OIDCIdentity) bool {
+	for _, method := range identity.AMR {
+		for _, accepted := range cfg.MFAMethods {
+			if method == accepted {
+				return true
+			}
+		}
+	}
+	for _, accepted := range cfg.MFAContexts {
+		if identity.ACR != "" && identity.ACR == accepted {
+			return true
+		}
+	}
+	return false
+}
+
+// isListSeparator splits configuration lists on commas and whitespace
+func isListSeparator(r rune) bool {
+	return r == ',' || r == ' ' || r == '\t'
+}
+
+// OIDCIdentity is the verified content of an ID token
+type OIDCIdentity struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+	Groups        []string
+	AMR           []string // Authentication methods used at the provider
+	ACR           string   // Authentication context class reached at the provider
+}
+
+// oidcDiscovery is the subset of the provider metadata we use
+type oidcDiscovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// oidcJWK is one RSA key of the provider's JSON Web Key Set
+type oidcJWK struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+// oidcMetadataTTL is how long discovery metadata and signing keys are cached
+const oidcMetadataTTL = time.Hour
+
+// OIDCProvider runs the authorization code flow with PKCE against an OIDC provider.
+//
+// ROLE: Staff Single Sign-On Client
+// - Reads endpoints from the provider's discovery document
+// - Exchanges authorization codes together with the PKCE verifier
+// - Verifies RS256 ID tokens against the provider's JWKS, refetching keys on rotation
+// - Checks issuer, audience, expiry and nonce
+type OIDCProvider struct {
+	Config OIDCConfig
+	client *http.Client
+
+	mu        sync.Mutex
+	discovery *oidcDiscovery
+	keys      map[string]*rsa.PublicKey
+	fetchedAt time.Time
+}
+
+// NewOIDCProvider creates a provider client; metadata is fetched on first use
+func NewOIDCProvider(cfg OIDCConfig) *OIDCProvider {
+	return &OIDCProvider{
+		Config: cfg,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// GeneratePKCEVerifier returns a random code verifier (RFC 7636)
+func GeneratePKCEVerifier() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// PKCEChallenge returns the S256 challenge of a code verifier
+func PKCEChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// AuthCodeURL builds the provider URL the browser is sent to
+func (p *OIDCProvider) AuthCodeURL(ctx context.Context, state, nonce, codeChallenge string) (string, error) {
+	discovery, err := p.metadata(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	query := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {p.Config.ClientID},
+		"redirect_uri":          {p.Config.RedirectURL},
+		"scope":                 {strings.Join(p.Config.Scopes, " ")},
+		"state":                 {state},
+		"nonce":                 {nonce},
+		"code_challenge":        {codeChallenge},
+		"code_challenge_method": {"S256"},
+	}
+
+	separator := "?"
+	if strings.Contains(discovery.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+	return discovery.AuthorizationEndpoint + separator + query.Encode(), nil
+}
+
+// Exchange redeems an authorization code and returns the raw ID token
+func (p *OIDCProvider) Exchange(ctx context.Context, code, codeVerifier string) (string, error) {
+	discovery, err := p.metadata(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {p.Config.RedirectURL},
+		"client_id":     {p.Config.ClientID},
+		"code_verifier": {codeVerifier},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, discovery.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if p.Config.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(p.Config.ClientID), url.QueryEscape(p.Config.ClientSecret))
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("token endpoint returned %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var tokens struct {
+		IDToken string `json:"id_token"`
+	}
+	if err := json.Unmarshal(body, &tokens); err != nil {
+		return "", err
+	}
+	if tokens.IDToken == "" {
+		return "", errors.New("token response has no id_token")
+	}
+	return tokens.IDToken, nil
+}
+
+// VerifyIDToken checks the signature and claims of an ID token and returns the identity
+func (p *OIDCProvider) VerifyIDToken(ctx context.Context, rawIDToken, nonce string) (*OIDCIdentity, error) {
+	discovery, err := p.metadata(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	claims := jwt.MapClaims{}
+	_, err = jwt.ParseWithClaims(rawIDToken, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return p.signingKey(ctx, kid)
+	},
+		jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Alg()}),
+		jwt.WithIssuer(discovery.Issuer),
+		jwt.WithAudience(p.Config.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(time.Minute),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if tokenNonce, _ := claims["nonce"].(string); tokenNonce == "" || tokenNonce != nonce {
+		return nil, errors.New("id token nonce mismatch")
+	}
+
+	identity := &OIDCIdentity{
+		Groups: stringList(claims[p.Config.GroupsClaim]),
+		AMR:    stringList(claims["amr"]),
+	}
+	identity.Subject, _ = claims["sub"].(string)
+	identity.ACR, _ = claims["acr"].(string)
+	identity.Email, _ = claims["email"].(string)
+	identity.Name, _ = claims["name"].(string)
+	switch verified := claims["email_verified"].(type) {
+	case bool:
+		identity.EmailVerified = verified
+	case string:
+		identity.EmailVerified = verified == "true"
+	}
+	if identity.Subject == "" {
+		return nil, errors.New("id token has no subject")
+	}
+	return identity, nil
+}
+
+// metadata returns the cached discovery document, fetching it when stale
+func (p *OIDCProvider) metadata(ctx context.Context) (*oidcDiscovery, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.discovery != nil && time.Since(p.fetchedAt) < oidcMetadataTTL {
+		return p.discovery, nil
+	}
+	if err := p.refresh(ctx); err != nil {
+		return nil, err
+	}
+	return p.discovery, nil
+}
+
+// signingKey returns the key with the given ID, refetching the JWKS once if it is unknown
+func (p *OIDCProvider) signingKey(ctx context.Context, kid string) (*rsa.PublicKey, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if key := p.lookupKey(kid); key != nil {
+		return key, nil
+	}
+	// The provider may have rotated its keys
+	if err := p.refresh(ctx); err != nil {
+		return nil, err
+	}
+	if key := p.lookupKey(kid); key != nil {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+// lookupKey finds a key by ID; without an ID it accepts the only key of the set
+func (p *OIDCProvider) lookupKey(kid string) *rsa.PublicKey {
+	if kid == "" && len(p.keys) == 1 {
+		for _, key := range p.keys {
+			return key
+		}
+	}
+	return p.keys[kid]
+}
+
+// refresh fetches the discovery document and signing keys. Callers hold p.mu.
+func (p *OIDCProvider) refresh(ctx context.Context) error {
+	var discovery oidcDiscovery
+	if err := p.getJSON(ctx, p.Config.Issuer+"/.well-known/openid-configuration", &discovery); err != nil {
+		return fmt.Errorf("oidc discovery: %w", err)
+	}
+	if strings.TrimRight(discovery.Issuer, "/") != p.Config.Issuer {
+		return fmt.Errorf("oidc discovery: issuer %q does not match %q", discovery.Issuer, p.Config.Issuer)
+	}
+
+	var jwks struct {
+		Keys []oidcJWK `json:"keys"`
+	}
+	if err := p.getJSON(ctx, discovery.JWKSURI, &jwks); err != nil {
+		return fmt.Errorf("oidc jwks: %w", err)
+	}
+
+	keys := map[string]*rsa.PublicKey{}
+	for _, jwk := range jwks.Keys {
+		if jwk.Kty != "RSA" || (jwk.Use != "" && jwk.Use != "sig") {
+			continue
+		}
+		key, err := rsaPublicKey(jwk)
+		if err != nil {
+			continue
+		}
+		keys[jwk.Kid] = key
+	}
+	if len(keys) == 0 {
+		return errors.New("oidc jwks: no usable RSA signing keys")
+	}
+
+	p.discovery = &discovery
+	p.keys = keys
+	p.fetchedAt = time.Now()
+	return nil
+}
+
+// getJSON fetches a URL and decodes its JSON body
+func (p *OIDCProvider) getJSON(ctx context.Context, target string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned %d", target, resp.StatusCode)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(out)
+}
+
+// rsaPublicKey builds an RSA public key from its JWK modulus and exponent
+func rsaPublicKey(jwk oidcJWK) (*rsa.PublicKey, error) {
+	n, err := base64.RawURLEncoding.DecodeString(jwk.N)
+	if err != nil {
+		return nil, err
+	}
+	e, err := base64.RawURLEncoding.DecodeString(jwk.E)
+	if err != nil {
+		return nil, err
+	}
+	exponent := new(big.Int).SetBytes(e)
+	if !exponent.IsInt64() || exponent.Int64() < 3 {
+		return nil, errors.New("invalid RSA exponent")
+	}
+	return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exponent.Int64())}, nil
+}
+
+// stringList reads a claim that may be a single string or a list of strings
+func stringList(claim interface{}) []string {
+	switch value := claim.(type) {
+	case string:
+		return []string{value}
+	case []interface{}:
+		list := make([]string, 0, len(value))
+		for _, item := range value {
+			if s, ok := item.(string); ok {
+				list = append(list, s)
+			}
+		}
+		return list
+	}
+	return nil
+}
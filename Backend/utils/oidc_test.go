@@ -0,0 +1,200 @@
+package utils
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AliSleiman0/Lacpa/scripts/stub_oidc/oidcstub"
+)
+
+// startStubProvider serves the stub identity provider and returns a client configured for it
+func startStubProvider(t *testing.T) (*oidcstub.Provider, *OIDCProvider) {
+	t.Helper()
+
+	server := httptest.NewUnstartedServer(nil)
+	stub, err := oidcstub.New("http://"+server.Listener.Addr().String(), "lacpa-cms", oidcstub.Identity{})
+	if err != nil {
+		t.Fatalf("oidcstub.New: %v", err)
+	}
+	server.Config.Handler = stub.Handler()
+	server.Start()
+	t.Cleanup(server.Close)
+
+	cfg := OIDCConfig{
+		Issuer:      stub.Issuer,
+		ClientID:    stub.ClientID,
+		RedirectURL: "http://localhost:3000/api/auth/oidc/callback",
+		Scopes:      []string{"openid", "email", "profile"},
+		GroupsClaim: "groups",
+		GroupRoles:  map[string]string{"lacpa-admins": "admin", "lacpa-editors": "content_editor"},
+		GroupOrder:  []string{"lacpa-admins", "lacpa-editors"},
+		MFAMethods:  []string{"mfa"},
+	}
+	return stub, NewOIDCProvider(cfg)
+}
+
+// authorize signs in at the stub as the identity and returns the authorization code
+func authorize(t *testing.T, provider *OIDCProvider, identity oidcstub.Identity, nonce, challenge string) string {
+	t.Helper()
+
+	authURL, err := provider.AuthCodeURL(context.Background(), "state-1", nonce, challenge)
+	if err != nil {
+		t.Fatalf("AuthCodeURL: %v", err)
+	}
+	target, _ := url.Parse(authURL)
+	form := target.Query()
+	form.Set("sub", identity.Subject)
+	form.Set("email", identity.Email)
+	form.Set("name", identity.Name)
+	form.Set("groups", strings.Join(identity.Groups, ","))
+	form.Set("amr", strings.Join(identity.AMR, ","))
+
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err := client.PostForm(target.Scheme+"://"+target.Host+target.Path, form)
+	if err != nil {
+		t.Fatalf("authorize: %v", err)
+	}
+	resp.Body.Close()
+
+	location, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil || location.Query().Get("code") == "" {
+		t.Fatalf("authorize did not redirect with a code: %q", resp.Header.Get("Location"))
+	}
+	if location.Query().Get("state") != "state-1" {
+		t.Fatalf("state = %q, want state-1", location.Query().Get("state"))
+	}
+	return location.Query().Get("code")
+}
+
+func TestOIDCCodeFlowMapsGroupsToRole(t *testing.T) {
+	_, provider := startStubProvider(t)
+	verifier, _ := GeneratePKCEVerifier()
+
+	code := authorize(t, provider, oidcstub.Identity{
+		Subject: "staff-1",
+		Email:   "staff@lacpa.org.lb",
+		Name:    "Staff One",
+		Groups:  []string{"lacpa-editors", "lacpa-admins"},
+	}, "nonce-1", PKCEChallenge(verifier))
+
+	rawIDToken, err := provider.Exchange(context.Background(), code, verifier)
+	if err != nil {
+		t.Fatalf("Exchange: %v", err)
+	}
+	identity, err := provider.VerifyIDToken(context.Background(), rawIDToken, "nonce-1")
+	if err != nil {
+		t.Fatalf("VerifyIDToken: %v", err)
+	}
+	if identity.Subject != "staff-1" || identity.Email != "staff@lacpa.org.lb" || !identity.EmailVerified {
+		t.Fatalf("identity = %+v", identity)
+	}
+
+	// The first configured group wins, whatever the order of the claim
+	role, ok := provider.Config.RoleForGroups(identity.Groups)
+	if !ok || role != "admin" {
+		t.Fatalf("RoleForGroups = %q, %v; want admin", role, ok)
+	}
+	if provider.Config.MFAAsserted(identity) {
+		t.Fatal("MFAAsserted = true without an amr claim")
+	}
+}
+
+func TestOIDCExchangeRejectsWrongVerifier(t *testing.T) {
+	_, provider := startStubProvider(t)
+	verifier, _ := GeneratePKCEVerifier()
+
+	code := authorize(t, provider, oidcstub.Identity{Subject: "staff-1"}, "nonce-1", PKCEChallenge(verifier))
+	if _, err := provider.Exchange(context.Background(), code, verifier+"x"); err == nil {
+		t.Fatal("Exchange accepted a wrong PKCE verifier")
+	}
+}
+
+func TestOIDCVerifyIDTokenRejectsInvalidTokens(t *testing.T) {
+	stub, provider := startStubProvider(t)
+	identity := oidcstub.Identity{Subject: "staff-1", Email: "staff@lacpa.org.lb", Groups: []string{"lacpa-admins"}}
+
+	tests := []struct {
+		name   string
+		nonce  string
+		modify func(claims map[string]interface{})
+	}{
+		{name: "nonce mismatch", nonce: "other-nonce"},
+		{name: "wrong audience", nonce: "nonce-1", modify: func(claims map[string]interface{}) {
+			claims["aud"] = "another-client"
+		}},
+		{name: "wrong issuer", nonce: "nonce-1", modify: func(claims map[string]interface{}) {
+			claims["iss"] = "https://evil.example.org"
+		}},
+		{name: "expired", nonce: "nonce-1", modify: func(claims map[string]interface{}) {
+			claims["exp"] = time.Now().Add(-10 * time.Minute).Unix()
+		}},
+		{name: "missing expiry", nonce: "nonce-1", modify: func(claims map[string]interface{}) {
+			delete(claims, "exp")
+		}},
+		{name: "missing subject", nonce: "nonce-1", modify: func(claims map[string]interface{}) {
+			claims["sub"] = ""
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := stub.Claims(identity, "nonce-1")
+			if tt.modify != nil {
+				tt.modify(claims)
+			}
+			rawIDToken, err := stub.Sign(claims)
+			if err != nil {
+				t.Fatalf("Sign: %v", err)
+			}
+			if _, err := provider.VerifyIDToken(context.Background(), rawIDToken, tt.nonce); err == nil {
+				t.Fatal("VerifyIDToken accepted the token")
+			}
+		})
+	}
+}
+
+func TestOIDCMFAAsserted(t *testing.T) {
+	stub, provider := startStubProvider(t)
+	provider.Config.MFAContexts = []string{"urn:lacpa:mfa"}
+
+	tests := []struct {
+		name string
+		amr  []string
+		acr  string
+		want bool
+	}{
+		{name: "password only", amr: []string{"pwd"}, want: false},
+		{name: "amr mfa", amr: []string{"pwd", "mfa"}, want: true},
+		{name: "accepted acr", acr: "urn:lacpa:mfa", want: true},
+		{name: "other acr", acr: "urn:lacpa:basic", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := stub.Claims(oidcstub.Identity{Subject: "staff-1", AMR: tt.amr}, "nonce-1")
+			if tt.acr != "" {
+				claims["acr"] = tt.acr
+			}
+			rawIDToken, _ := stub.Sign(claims)
+			identity, err := provider.VerifyIDToken(context.Background(), rawIDToken, "nonce-1")
+			if err != nil {
+				t.Fatalf("VerifyIDToken: %v", err)
+			}
+			if got := provider.Config.MFAAsserted(identity); got != tt.want {
+				t.Fatalf("MFAAsserted = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOIDCRoleForUnmappedGroups(t *testing.T) {
+	_, provider := startStubProvider(t)
+	if role, ok := provider.Config.RoleForGroups([]string{"everyone"}); ok {
+		t.Fatalf("RoleForGroups = %q, want no role", role)
+	}
+}
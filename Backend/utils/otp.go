@@ -2,6 +2,8 @@ package utils
 
 import (
 	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"math/big"
 )
@@ -26,3 +28,9 @@ func GenerateResetToken() (string, error) {
 	}
 	return fmt.Sprintf("%x", b), nil
 }
+
+// HashToken returns the SHA-256 hex digest of a token so it can be stored and looked up without keeping the raw value
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
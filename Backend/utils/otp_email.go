@@ -15,13 +15,6 @@ type EmailConfig struct {
 	SenderPass  string
 }
 
-// OTPEmail holds OTP data
-type OTPEmail struct {
-	Email     string
-	OTP       string
-	ExpiresAt time.Time
-}
-
 // GetEmailConfig returns the email configuration
 func GetEmailConfig() EmailConfig {
 	return EmailConfig{
@@ -32,39 +25,8 @@ func GetEmailConfig() EmailConfig {
 	}
 }
 
-// SendOTPEmail sends an OTP email to the specified recipient
-// Generates a new OTP internally
-func SendOTPEmail(recipientEmail string) (*OTPEmail, error) {
-	otp, err := GenerateOTP()
-	if err != nil {
-		log.Printf("Error generating OTP: %v", err)
-		return nil, fmt.Errorf("failed to generate OTP: %w", err)
-	}
-	return SendOTPEmailWithCode(recipientEmail, "", otp)
-}
-
-// SendOTPEmailWithName sends an OTP email to the specified recipient with personalized name
-// Generates a new OTP internally
-func SendOTPEmailWithName(recipientEmail, recipientName string) (*OTPEmail, error) {
-	otp, err := GenerateOTP()
-	if err != nil {
-		log.Printf("Error generating OTP: %v", err)
-		return nil, fmt.Errorf("failed to generate OTP: %w", err)
-	}
-	return SendOTPEmailWithCode(recipientEmail, recipientName, otp)
-}
-
-// SendOTPEmailWithCode sends a specific OTP code via email
-// Use this when you've already generated an OTP and stored it in database
-func SendOTPEmailWithCode(recipientEmail, recipientName, otp string) (*OTPEmail, error) {
-	expiresAt := time.Now().Add(5 * time.Minute)
-
-	// Log OTP to console for testing
-	log.Printf("========================================")
-	log.Printf("Generated OTP for %s: %s", recipientEmail, otp)
-	log.Printf("Expires at: %s", expiresAt.Format("2006-01-02 15:04:05"))
-	log.Printf("========================================")
-
+// SendEmail sends an HTML email to a single recipient
+func SendEmail(recipientEmail, subject, htmlBody string) error {
 	// Get email config
 	config := GetEmailConfig()
 
@@ -79,10 +41,6 @@ func SendOTPEmailWithCode(recipientEmail, recipientName, otp string) (*OTPEmail,
 	// Setup authentication
 	auth := smtp.PlainAuth("", config.SenderEmail, password, config.SMTPHost)
 
-	// Compose email with HTML template
-	subject := "Your OTP Code - LACPA Verification"
-	htmlBody := OTPEmailTemplate(otp, recipientName)
-
 	// Email headers and body with HTML content
 	message := []byte(
 		"From: LACPA <" + config.SenderEmail + ">\r\n" +
@@ -96,40 +54,26 @@ func SendOTPEmailWithCode(recipientEmail, recipientName, otp string) (*OTPEmail,
 
 	// Send email
 	smtpAddr := config.SMTPHost + ":" + config.SMTPPort
-	err := smtp.SendMail(smtpAddr, auth, config.SenderEmail, []string{recipientEmail}, message)
-	if err != nil {
-		log.Printf("Failed to send OTP email to %s: %v", recipientEmail, err)
-		return nil, fmt.Errorf("failed to send email: %w", err)
+	if err := smtp.SendMail(smtpAddr, auth, config.SenderEmail, []string{recipientEmail}, message); err != nil {
+		log.Printf("Failed to send email to %s: %v", recipientEmail, err)
+		return fmt.Errorf("failed to send email: %w", err)
 	}
 
-	log.Printf("OTP email sent successfully to %s", recipientEmail)
-
-	return &OTPEmail{
-		Email:     recipientEmail,
-		OTP:       otp,
-		ExpiresAt: expiresAt,
-	}, nil
+	log.Printf("Email %q sent successfully to %s", subject, recipientEmail)
+	return nil
 }
 
-// VerifyOTP verifies if the provided OTP matches and hasn't expired
-func VerifyOTP(storedOTP *OTPEmail, providedOTP string) bool {
-	if storedOTP == nil {
-		log.Printf("No OTP found")
-		return false
-	}
-
-	// Check if OTP has expired
-	if time.Now().After(storedOTP.ExpiresAt) {
-		log.Printf("OTP has expired")
-		return false
-	}
-
-	// Check if OTP matches
-	if storedOTP.OTP != providedOTP {
-		log.Printf("OTP mismatch: expected %s, got %s", storedOTP.OTP, providedOTP)
-		return false
+// SendVerificationEmail sends a verification code using the OTP email template.
+// message explains what the code is for and validFor is shown as its expiry.
+func SendVerificationEmail(recipientEmail, recipientName, code, subject, message string, validFor time.Duration) error {
+	// Log the code to the console when developing without a mailbox
+	if GetEnv("APP_ENV", "development") == "development" {
+		log.Printf("========================================")
+		log.Printf("Verification code for %s: %s", recipientEmail, code)
+		log.Printf("Expires at: %s", time.Now().Add(validFor).Format("2006-01-02 15:04:05"))
+		log.Printf("========================================")
 	}
 
-	log.Printf("OTP verified successfully")
-	return true
+	validity := fmt.Sprintf("Expires in %d minutes", int(validFor.Minutes()))
+	return SendEmail(recipientEmail, subject, OTPEmailTemplate(code, recipientName, message, validity))
 }
@@ -0,0 +1,141 @@
+package utils
+
+import (
+	_ "embed"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+//go:embed common_passwords.txt
+var commonPasswordsFile string
+
+var (
+	commonPasswordsOnce sync.Once
+	commonPasswords     map[string]struct{}
+)
+
+// PasswordPolicy describes the rules new passwords must follow
+type PasswordPolicy struct {
+	MinLength      int
+	MaxLength      int // bcrypt only uses the first 72 bytes
+	RequireUpper   bool
+	RequireLower   bool
+	RequireDigit   bool
+	RequireSpecial bool
+	HistorySize    int  // Number of previous passwords that cannot be reused
+	RejectCommon   bool // Reject passwords from the bundled common-password list
+}
+
+// LoadPasswordPolicy reads the password policy from the environment
+//
+// ROLE: Password Policy Configuration
+// - PASSWORD_MIN_LENGTH (default 8) and PASSWORD_MAX_LENGTH (default 72)
+// - PASSWORD_REQUIRE_UPPER, _LOWER, _DIGIT, _SPECIAL (default true)
+// - PASSWORD_HISTORY_SIZE (default 5, 0 disables the reuse check)
+// - PASSWORD_REJECT_COMMON (default true)
+func LoadPasswordPolicy() PasswordPolicy {
+	policy := PasswordPolicy{
+		MinLength:      GetEnvInt("PASSWORD_MIN_LENGTH", 8),
+		MaxLength:      GetEnvInt("PASSWORD_MAX_LENGTH", 72),
+		RequireUpper:   GetEnvBool("PASSWORD_REQUIRE_UPPER", true),
+		RequireLower:   GetEnvBool("PASSWORD_REQUIRE_LOWER", true),
+		RequireDigit:   GetEnvBool("PASSWORD_REQUIRE_DIGIT", true),
+		RequireSpecial: GetEnvBool("PASSWORD_REQUIRE_SPECIAL", true),
+		HistorySize:    GetEnvInt("PASSWORD_HISTORY_SIZE", 5),
+		RejectCommon:   GetEnvBool("PASSWORD_REJECT_COMMON", true),
+	}
+	if policy.MaxLength <= 0 || policy.MaxLength > 72 {
+		policy.MaxLength = 72
+	}
+	if policy.MinLength > policy.MaxLength {
+		policy.MinLength = policy.MaxLength
+	}
+	if policy.HistorySize < 0 {
+		policy.HistorySize = 0
+	}
+	return policy
+}
+
+// Validate checks a password against the policy and adds one error per broken rule
+//
+// PARAMETERS:
+//   - ve: ValidationErrors instance to add errors to
+//   - field: Field name
+//   - password: Password to validate
+//
+// RETURNS:
+//   - bool: true if valid, false if invalid
+func (p PasswordPolicy) Validate(ve *ValidationErrors, field, password string) bool {
+	valid := true
+
+	if utf8.RuneCountInString(password) < p.MinLength {
+		ve.AddError(field, "Password must be at least "+strconv.Itoa(p.MinLength)+" characters long", "")
+		valid = false
+	}
+	if len(password) > p.MaxLength {
+		ve.AddError(field, "Password must be at most "+strconv.Itoa(p.MaxLength)+" bytes long", "")
+		valid = false
+	}
+
+	hasUpper := false
+	hasLower := false
+	hasDigit := false
+	hasSpecial := false
+
+	for _, char := range password {
+		switch {
+		case unicode.IsUpper(char):
+			hasUpper = true
+		case unicode.IsLower(char):
+			hasLower = true
+		case unicode.IsDigit(char):
+			hasDigit = true
+		case unicode.IsPunct(char) || unicode.IsSymbol(char) || unicode.IsSpace(char):
+			hasSpecial = true
+		}
+	}
+
+	if p.RequireUpper && !hasUpper {
+		ve.AddError(field, "Password must contain at least one uppercase letter", "")
+		valid = false
+	}
+	if p.RequireLower && !hasLower {
+		ve.AddError(field, "Password must contain at least one lowercase letter", "")
+		valid = false
+	}
+	if p.RequireDigit && !hasDigit {
+		ve.AddError(field, "Password must contain at least one digit", "")
+		valid = false
+	}
+	if p.RequireSpecial && !hasSpecial {
+		ve.AddError(field, "Password must contain at least one special character", "")
+		valid = false
+	}
+
+	if p.RejectCommon && IsCommonPassword(password) {
+		ve.AddError(field, "Password is too common and appears in known password breaches", "")
+		valid = false
+	}
+
+	return valid
+}
+
+// IsCommonPassword reports whether the password is on the bundled common-password list
+func IsCommonPassword(password string) bool {
+	commonPasswordsOnce.Do(func() {
+		commonPasswords = map[string]struct{}{}
+		for _, line := range strings.Split(commonPasswordsFile, "\n") {
+			line = strings.TrimSpace(line)
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			commonPasswords[strings.ToLower(line)] = struct{}{}
+		}
+	})
+
+	_, found := commonPasswords[strings.ToLower(strings.TrimSpace(password))]
+	return found
+}
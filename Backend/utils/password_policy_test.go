@@ -0,0 +1,90 @@
+package utils
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPasswordPolicyValidate(t *testing.T) {
+	strict := PasswordPolicy{
+		MinLength:      8,
+		MaxLength:      72,
+		RequireUpper:   true,
+		RequireLower:   true,
+		RequireDigit:   true,
+		RequireSpecial: true,
+		RejectCommon:   true,
+	}
+	lenient := PasswordPolicy{MinLength: 4, MaxLength: 72}
+
+	tests := []struct {
+		name     string
+		policy   PasswordPolicy
+		password string
+		want     []string
+	}{
+		{name: "meets every rule", policy: strict, password: "Tr1cky-Walrus", want: nil},
+		{name: "space counts as special", policy: strict, password: "Tr1cky Walrus", want: nil},
+		{name: "too short", policy: strict, password: "Ab1!", want: []string{"Password must be at least 8 characters long"}},
+		{
+			name:     "too long",
+			policy:   strict,
+			password: "Aa1!" + strings.Repeat("x", 69),
+			want:     []string{"Password must be at most 72 bytes long"},
+		},
+		{
+			// Seven runes in eleven bytes: the minimum counts runes, the maximum bytes
+			name:     "multi-byte characters count once towards the minimum",
+			policy:   strict,
+			password: "Éé1!ñññ",
+			want:     []string{"Password must be at least 8 characters long"},
+		},
+		{name: "no uppercase", policy: strict, password: "tr1cky-walrus", want: []string{"Password must contain at least one uppercase letter"}},
+		{name: "no lowercase", policy: strict, password: "TR1CKY-WALRUS", want: []string{"Password must contain at least one lowercase letter"}},
+		{name: "no digit", policy: strict, password: "Tricky-Walrus", want: []string{"Password must contain at least one digit"}},
+		{name: "no special character", policy: strict, password: "Tr1ckyWalrus", want: []string{"Password must contain at least one special character"}},
+		{
+			name:     "common password in any case",
+			policy:   strict,
+			password: "PASSWORD1!",
+			want: []string{
+				"Password must contain at least one lowercase letter",
+				"Password is too common and appears in known password breaches",
+			},
+		},
+		{
+			name:     "every rule broken",
+			policy:   strict,
+			password: "abc",
+			want: []string{
+				"Password must be at least 8 characters long",
+				"Password must contain at least one uppercase letter",
+				"Password must contain at least one digit",
+				"Password must contain at least one special character",
+			},
+		},
+		{name: "disabled rules are skipped", policy: lenient, password: "password1", want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ve := NewValidationErrors()
+			valid := tt.policy.Validate(ve, "password", tt.password)
+
+			var got []string
+			for _, e := range ve.Errors {
+				if e.Field != "password" {
+					t.Fatalf("error on field %q, want password", e.Field)
+				}
+				got = append(got, e.Message)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("errors = %q, want %q", got, tt.want)
+			}
+			if valid != (len(tt.want) == 0) {
+				t.Fatalf("Validate = %v with errors %q", valid, got)
+			}
+		})
+	}
+}
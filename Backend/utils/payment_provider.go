@@ -0,0 +1,138 @@
+package utils
+
+import (
+	"context"
+	"crypto/hmac"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrInvalidPaymentNotification is returned for notifications that fail the provider's checks
+var ErrInvalidPaymentNotification = errors.New("invalid payment notification")
+
+// PaymentProvider takes online payments of invoices.
+//
+// ROLE: Payment Provider Abstraction
+//   - Handlers start a checkout for an invoice and send the applicant to its URL
+//   - The provider reports the outcome to POST /api/payments/:provider/notify,
+//     which ParseNotification authenticates and decodes
+//   - FakePaymentProvider stands in for a real provider in development and testing
+//   - Selected with PAYMENT_PROVIDER, see NewPaymentProviderFromEnv
+type PaymentProvider interface {
+	Name() string
+	CreateCheckout(ctx context.Context, checkout PaymentCheckoutRequest) (*PaymentCheckout, error)
+	ParseNotification(ctx context.Context, body []byte, header func(string) string) (*PaymentNotification, error)
+}
+
+// PaymentCheckoutRequest describes the invoice to collect
+type PaymentCheckoutRequest struct {
+	InvoiceNumber string
+	Amount        float64
+	Currency      string
+	Description   string
+	ReturnURL     string // Where the provider sends the applicant back to
+}
+
+// PaymentCheckout is a checkout started with a provider
+type PaymentCheckout struct {
+	SessionID string `json:"session_id"`
+	URL       string `json:"url"` // Payment page of the provider
+}
+
+// PaymentNotification is a provider's report on a checkout
+type PaymentNotification struct {
+	SessionID     string  `json:"session_id"`
+	InvoiceNumber string  `json:"invoice_number"`
+	Amount        float64 `json:"amount"`
+	Currency      string  `json:"currency"`
+	Paid          bool    `json:"paid"`
+	Reference     string  `json:"reference"` // Provider transaction number
+}
+
+// NewPaymentProviderFromEnv creates the payment provider selected by PAYMENT_PROVIDER.
+// A nil provider means online payment is disabled and finance staff record every payment.
+//
+// ROLE: Payment Provider Configuration
+//   - PAYMENT_PROVIDER=none (default): no online payment
+//   - PAYMENT_PROVIDER=fake: FakePaymentProvider signing with PAYMENT_FAKE_SECRET,
+//     which must be set; refused when APP_ENV is production
+func NewPaymentProviderFromEnv() (PaymentProvider, error) {
+	switch provider := strings.ToLower(GetEnv("PAYMENT_PROVIDER", "none")); provider {
+	case "none":
+		return nil, nil
+	case "fake":
+		if GetEnv("APP_ENV", "development") == "production" {
+			return nil, errors.New("the fake payment provider cannot be used in production")
+		}
+		secret := GetEnv("PAYMENT_FAKE_SECRET", "")
+		if secret == "" {
+			return nil, errors.New("PAYMENT_FAKE_SECRET is required by the fake payment provider")
+		}
+		return NewFakePaymentProvider(secret, GetEnv("PUBLIC_BASE_URL", "")), nil
+	default:
+		return nil, fmt.Errorf("unknown PAYMENT_PROVIDER %q, expected none or fake", provider)
+	}
+}
+
+// FakePaymentSignatureHeader carries the HMAC-SHA256 of a fake notification body
+const FakePaymentSignatureHeader = "X-Fake-Signature"
+
+// FakePaymentProvider accepts every checkout and trusts notifications signed with its secret.
+// Notifications are posted by hand or by scripts, e.g. with a body made by FakeNotification.
+type FakePaymentProvider struct {
+	secret  []byte
+	baseURL string
+}
+
+func NewFakePaymentProvider(secret, baseURL string) *FakePaymentProvider {
+	return &FakePaymentProvider{secret: []byte(secret), baseURL: strings.TrimRight(baseURL, "/")}
+}
+
+func (p *FakePaymentProvider) Name() string {
+	return "fake"
+}
+
+// CreateCheckout returns a session whose "payment page" is the return URL
+func (p *FakePaymentProvider) CreateCheckout(ctx context.Context, checkout PaymentCheckoutRequest) (*PaymentCheckout, error) {
+	token, err := GenerateResetToken()
+	if err != nil {
+		return nil, err
+	}
+	sessionID := "fake_" + token[:24]
+
+	url := checkout.ReturnURL
+	if url == "" {
+		url = p.baseURL + "/membership/track"
+	}
+	return &PaymentCheckout{SessionID: sessionID, URL: url}, nil
+}
+
+// ParseNotification checks the body's signature and decodes it
+func (p *FakePaymentProvider) ParseNotification(ctx context.Context, body []byte, header func(string) string) (*PaymentNotification, error) {
+	given, err := hex.DecodeString(header(FakePaymentSignatureHeader))
+	if err != nil || !hmac.Equal(given, p.sign(body)) {
+		return nil, ErrInvalidPaymentNotification
+	}
+
+	var notification PaymentNotification
+	if err := json.Unmarshal(body, &notification); err != nil || notification.SessionID == "" {
+		return nil, ErrInvalidPaymentNotification
+	}
+	return &notification, nil
+}
+
+// FakeNotification returns a notification body and its signature header value
+func (p *FakePaymentProvider) FakeNotification(notification PaymentNotification) ([]byte, string, error) {
+	body, err := json.Marshal(notification)
+	if err != nil {
+		return nil, "", err
+	}
+	return body, hex.EncodeToString(p.sign(body)), nil
+}
+
+func (p *FakePaymentProvider) sign(body []byte) []byte {
+	return hmacSHA256(p.secret, string(body))
+}
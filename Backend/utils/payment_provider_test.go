@@ -0,0 +1,23 @@
+package utils
+
+import "testing"
+
+func TestFakePaymentProviderRequiresSecret(t *testing.T) {
+	t.Setenv("PAYMENT_PROVIDER", "fake")
+	t.Setenv("APP_ENV", "development")
+
+	t.Setenv("PAYMENT_FAKE_SECRET", "")
+	if provider, err := NewPaymentProviderFromEnv(); err == nil || provider != nil {
+		t.Fatalf("NewPaymentProviderFromEnv without a secret = %v, %v; want an error", provider, err)
+	}
+
+	t.Setenv("PAYMENT_FAKE_SECRET", "test-payment-secret")
+	if provider, err := NewPaymentProviderFromEnv(); err != nil || provider == nil {
+		t.Fatalf("NewPaymentProviderFromEnv with a secret = %v, %v; want the fake provider", provider, err)
+	}
+
+	t.Setenv("APP_ENV", "production")
+	if _, err := NewPaymentProviderFromEnv(); err == nil {
+		t.Fatal("NewPaymentProviderFromEnv in production: want an error")
+	}
+}
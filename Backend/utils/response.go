@@ -229,6 +229,27 @@ func SendBadRequest(c *fiber.Ctx, message ...string) error {
 	return SendError(c, fiber.StatusBadRequest, errorMessage, "400.html")
 }
 
+// SendValidationErrors sends a 400 Bad Request response with per-field errors
+//
+// ROLE: Field Validation Response Handler
+// - Sends 400 status with every broken rule, not just the first
+// - Keeps the "error" summary used by the other auth responses
+// - Lets forms show each message next to its field
+//
+// PARAMETERS:
+//   - c: Fiber context
+//   - ve: Collected validation errors
+//
+// RETURNS:
+//   - error: Fiber error if response fails
+func SendValidationErrors(c *fiber.Ctx, ve *ValidationErrors) error {
+	return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+		"success": false,
+		"error":   ve.Error(),
+		"errors":  ve.Errors,
+	})
+}
+
 // SendInternalError sends a 500 Internal Server Error response
 //
 // ROLE: Internal Error Response Handler
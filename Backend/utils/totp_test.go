@@ -0,0 +1,111 @@
+package utils
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfc6238Secret is the SHA-1 seed of the RFC 6238 appendix B test vectors
+var rfc6238Secret = base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+
+func TestTOTPCodeMatchesRFC6238(t *testing.T) {
+	// The RFC lists 8-digit codes; 6-digit codes are their last six digits
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{unix: 59, want: "287082"},
+		{unix: 1111111109, want: "081804"},
+		{unix: 1111111111, want: "050471"},
+		{unix: 1234567890, want: "005924"},
+		{unix: 2000000000, want: "279037"},
+		{unix: 20000000000, want: "353130"},
+	}
+
+	for _, tt := range tests {
+		got, err := TOTPCode(rfc6238Secret, TOTPStep(time.Unix(tt.unix, 0)))
+		if err != nil {
+			t.Fatalf("T=%d: %v", tt.unix, err)
+		}
+		if got != tt.want {
+			t.Errorf("T=%d: code = %s, want %s", tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestValidateTOTPDriftWindow(t *testing.T) {
+	now := time.Unix(1234567890, 0)
+	current := TOTPStep(now)
+
+	tests := []struct {
+		name   string
+		offset int64
+		want   bool
+	}{
+		{name: "two steps early", offset: -2, want: false},
+		{name: "one step early", offset: -1, want: true},
+		{name: "current step", offset: 0, want: true},
+		{name: "one step late", offset: 1, want: true},
+		{name: "two steps late", offset: 2, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, err := TOTPCode(rfc6238Secret, current+tt.offset)
+			if err != nil {
+				t.Fatal(err)
+			}
+			step, ok := ValidateTOTP(rfc6238Secret, code, now)
+			if ok != tt.want {
+				t.Fatalf("ValidateTOTP ok = %v, want %v", ok, tt.want)
+			}
+			if ok && step != current+tt.offset {
+				t.Fatalf("step = %d, want %d", step, current+tt.offset)
+			}
+		})
+	}
+}
+
+func TestValidateTOTPRejectsMalformedCodes(t *testing.T) {
+	now := time.Unix(1234567890, 0)
+	for _, code := range []string{"", "05924", "0059240", "abcdef"} {
+		if _, ok := ValidateTOTP(rfc6238Secret, code, now); ok {
+			t.Errorf("ValidateTOTP(%q) accepted", code)
+		}
+	}
+	if _, ok := ValidateTOTP(rfc6238Secret, " 005924 ", now); !ok {
+		t.Error("code with surrounding spaces rejected")
+	}
+}
+
+func TestGenerateRecoveryCodes(t *testing.T) {
+	codes, err := GenerateRecoveryCodes(50)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(codes) != 50 {
+		t.Fatalf("got %d codes, want 50", len(codes))
+	}
+
+	seen := map[string]bool{}
+	for _, code := range codes {
+		if len(code) != 11 || code[5] != '-' {
+			t.Fatalf("code %q is not formatted as xxxxx-xxxxx", code)
+		}
+		for _, r := range strings.ReplaceAll(code, "-", "") {
+			if !strings.ContainsRune(recoveryCodeAlphabet, r) {
+				t.Fatalf("code %q has %q outside the alphabet", code, r)
+			}
+		}
+		if seen[code] {
+			t.Fatalf("code %q generated twice", code)
+		}
+		seen[code] = true
+
+		if got := NormalizeRecoveryCode(" " + strings.ToUpper(strings.ReplaceAll(code, "-", "")) + " "); got != code {
+			t.Fatalf("NormalizeRecoveryCode = %q, want %q", got, code)
+		}
+	}
+}
@@ -2,11 +2,12 @@ package utils
 
 import (
 	"fmt"
+	"net/url"
 	"reflect"
 	"regexp"
 	"strconv"
 	"strings"
-	"unicode"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 )
@@ -225,7 +226,7 @@ func ValidateMaxLength(ve *ValidationErrors, field, value string, maxLength int)
 	return true
 }
 
-// ValidatePasswordStrength validates password strength
+// ValidatePasswordStrength validates a password against the configured password policy
 //
 // PARAMETERS:
 //   - ve: ValidationErrors instance to add errors to
@@ -235,50 +236,7 @@ func ValidateMaxLength(ve *ValidationErrors, field, value string, maxLength int)
 // RETURNS:
 //   - bool: true if valid, false if invalid
 func ValidatePasswordStrength(ve *ValidationErrors, field, password string) bool {
-	if len(password) < 8 {
-		ve.AddError(field, "Password must be at least 8 characters long", "")
-		return false
-	}
-
-	hasUpper := false
-	hasLower := false
-	hasDigit := false
-	hasSpecial := false
-
-	for _, char := range password {
-		switch {
-		case unicode.IsUpper(char):
-			hasUpper = true
-		case unicode.IsLower(char):
-			hasLower = true
-		case unicode.IsDigit(char):
-			hasDigit = true
-		case unicode.IsPunct(char) || unicode.IsSymbol(char):
-			hasSpecial = true
-		}
-	}
-
-	if !hasUpper {
-		ve.AddError(field, "Password must contain at least one uppercase letter", "")
-		return false
-	}
-
-	if !hasLower {
-		ve.AddError(field, "Password must contain at least one lowercase letter", "")
-		return false
-	}
-
-	if !hasDigit {
-		ve.AddError(field, "Password must contain at least one digit", "")
-		return false
-	}
-
-	if !hasSpecial {
-		ve.AddError(field, "Password must contain at least one special character", "")
-		return false
-	}
-
-	return true
+	return LoadPasswordPolicy().Validate(ve, field, password)
 }
 
 // SanitizeString removes potentially dangerous characters from string input
@@ -376,7 +334,10 @@ func Paginate(page, pageSize, totalItems int) (offset, limit int, meta Paginatio
 }
 
 // ValidateStruct validates a struct based on its validate tags
-// This is a simple implementation that handles basic validation tags
+// This is a simple implementation that handles basic validation tags:
+// required, email, min, max, len and oneof for strings, gte and lte for numbers,
+// notfuture and min_age for dates, and format=name for the validationFormats.
+// email, oneof, min_age and format skip empty values; combine them with required.
 func ValidateStruct(s interface{}) error {
 	ve := NewValidationErrors()
 	v := reflect.ValueOf(s)
@@ -465,6 +426,62 @@ func ValidateStruct(s interface{}) error {
 				}
 				continue
 			}
+
+			// Handle "oneof=a b c" rule
+			if strings.HasPrefix(rule, "oneof=") {
+				allowed := strings.Fields(strings.TrimPrefix(rule, "oneof="))
+				if value.Kind() == reflect.String && value.String() != "" && !containsString(allowed, value.String()) {
+					ve.AddError(fieldName, "Must be one of: "+strings.Join(allowed, ", "), value.String())
+				}
+				continue
+			}
+
+			// Handle "gte=X" and "lte=X" rules (numbers)
+			if strings.HasPrefix(rule, "gte=") || strings.HasPrefix(rule, "lte=") {
+				bound, err := strconv.ParseFloat(rule[4:], 64)
+				number, ok := numericValue(value)
+				if err != nil || !ok {
+					continue
+				}
+				if strings.HasPrefix(rule, "gte=") && number < bound {
+					ve.AddError(fieldName, "Must be at least "+rule[4:], fmt.Sprint(value.Interface()))
+				}
+				if strings.HasPrefix(rule, "lte=") && number > bound {
+					ve.AddError(fieldName, "Must be at most "+rule[4:], fmt.Sprint(value.Interface()))
+				}
+				continue
+			}
+
+			// Handle "notfuture" rule (dates, or years as numbers)
+			if rule == "notfuture" {
+				if date, ok := value.Interface().(time.Time); ok {
+					if date.After(time.Now()) {
+						ve.AddError(fieldName, "Cannot be in the future", date.Format("2006-01-02"))
+					}
+				} else if year, ok := numericValue(value); ok && int(year) > time.Now().Year() {
+					ve.AddError(fieldName, fmt.Sprintf("Cannot be after %d", time.Now().Year()), fmt.Sprint(value.Interface()))
+				}
+				continue
+			}
+
+			// Handle "min_age=X" rule (dates of birth)
+			if strings.HasPrefix(rule, "min_age=") {
+				years, err := strconv.Atoi(strings.TrimPrefix(rule, "min_age="))
+				date, ok := value.Interface().(time.Time)
+				if err == nil && ok && !date.IsZero() && date.After(time.Now().AddDate(-years, 0, 0)) {
+					ve.AddError(fieldName, fmt.Sprintf("Must be at least %d years old", years), date.Format("2006-01-02"))
+				}
+				continue
+			}
+
+			// Handle "format=name" rule, see validationFormats
+			if strings.HasPrefix(rule, "format=") {
+				format, ok := validationFormats[strings.TrimPrefix(rule, "format=")]
+				if ok && value.Kind() == reflect.String && value.String() != "" && !format.check(value.String()) {
+					ve.AddError(fieldName, format.message, value.String())
+				}
+				continue
+			}
 		}
 	}
 
@@ -492,6 +509,81 @@ func isEmptyValue(v reflect.Value) bool {
 		return v.IsNil()
 	case reflect.Slice, reflect.Map, reflect.Array:
 		return v.Len() == 0
+	case reflect.Struct:
+		if date, ok := v.Interface().(time.Time); ok {
+			return date.IsZero()
+		}
 	}
 	return false
 }
+
+// numericValue reads an int, uint or float field as a float64
+func numericValue(v reflect.Value) (float64, bool) {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), true
+	}
+	return 0, false
+}
+
+func containsString(values []string, value string) bool {
+	for _, candidate := range values {
+		if candidate == value {
+			return true
+		}
+	}
+	return false
+}
+
+// validationFormats are the named formats of the "format=name" validate rule
+var validationFormats = map[string]struct {
+	check   func(string) bool
+	message string
+}{
+	"lb_phone": {
+		check:   IsLebanesePhone,
+		message: "Please enter a valid Lebanese phone number, e.g. 01 123456, 03 123456 or +961 71 123456",
+	},
+	"registration_number": {
+		check:   registrationNumberPattern.MatchString,
+		message: "Please enter the commercial register number, e.g. 2012345 or 2012345/Beirut",
+	},
+	"url": {
+		check:   isWebURL,
+		message: "Please enter a full web address starting with http:// or https://",
+	},
+}
+
+var (
+	// phoneSeparators are stripped before checking a phone number
+	phoneSeparators = strings.NewReplacer(" ", "", "-", "", ".", "", "(", "", ")", "", "/", "")
+
+	// lebanesePhonePattern matches a national number: a landline or 03 number with an
+	// optional leading 0 (01 123456, 3 123456) or a mobile number (70, 71, 76, 78, 79, 81)
+	lebanesePhonePattern = regexp.MustCompile(`^(0?[1-9]\d{6}|(70|71|76|78|79|81)\d{6})$`)
+
+	// registrationNumberPattern matches a commercial register number with an optional register office
+	registrationNumberPattern = regexp.MustCompile(`^\d{1,10}(/[A-Za-z][A-Za-z .-]{1,39})?$`)
+)
+
+// IsLebanesePhone checks a Lebanese phone number, written nationally or with the +961 / 00961 prefix
+func IsLebanesePhone(phone string) bool {
+	phone = phoneSeparators.Replace(strings.TrimSpace(phone))
+	switch {
+	case strings.HasPrefix(phone, "+961"):
+		phone = strings.TrimPrefix(phone, "+961")
+	case strings.HasPrefix(phone, "00961"):
+		phone = strings.TrimPrefix(phone, "00961")
+	}
+	return lebanesePhonePattern.MatchString(phone)
+}
+
+// isWebURL checks for an absolute http or https URL
+func isWebURL(value string) bool {
+	parsed, err := url.Parse(value)
+	return err == nil && (parsed.Scheme == "http" || parsed.Scheme == "https") && parsed.Host != ""
+}
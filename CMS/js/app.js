@@ -103,6 +103,15 @@ function initializeSidebar() {
     });
 }
 
+// Attach the stored auth token to every HTMX request so protected
+// /api/admin endpoints accept it
+document.addEventListener('htmx:configRequest', function(event) {
+    const token = localStorage.getItem('authToken');
+    if (token) {
+        event.detail.headers['Authorization'] = `Bearer ${token}`;
+    }
+});
+
 // Listen for HTMX after swap event
 document.addEventListener('htmx:afterSwap', function(event) {
     console.log('HTMX afterSwap event triggered');
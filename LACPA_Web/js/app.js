@@ -99,6 +99,8 @@ function loadPageFromURL() {
         '/membership': 'http://localhost:3000/membership',
         '/members/individuals': 'http://localhost:3000/membership', // Legacy support
         '/membership/apply-now': 'http://localhost:3000/membership/apply-now',
+        '/membership/apply/individual': 'http://localhost:3000/membership/apply/individual', // Draft resume links
+        '/membership/apply/firm': 'http://localhost:3000/membership/apply/firm',
         '/membership/firms': 'http://localhost:3000/membership/firms',
         '/events': 'http://localhost:3000/events',
         '/academy': 'http://localhost:3000/main/academy',